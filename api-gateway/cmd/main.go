@@ -32,7 +32,8 @@ func main() {
     // Apply middleware
     handler := middleware.CorsMiddleware(mux)
     handler = middleware.LoggingMiddleware(handler)
-    handler = middleware.AuthMiddleware(jwtService)(handler) // ADD AUTH MIDDLEWARE
+    sessions := middleware.NewSessionCheckerFromEnv(cfg.Services["auth-service"].URL)
+    handler = middleware.AuthMiddleware(jwtService, sessions, cfg.RequireVerifiedEmail)(handler) // ADD AUTH MIDDLEWARE
 
     
     port := cfg.Port
@@ -42,5 +43,8 @@ func main() {
         log.Printf("   - %s: %s", name, service.URL)
     }
     log.Printf("🔐 JWT Authentication: ENABLED")
+    if cfg.RequireVerifiedEmail {
+        log.Printf("📧 Verified email required")
+    }
     log.Fatal(http.ListenAndServe(":"+port, handler))
 }
@@ -8,6 +8,7 @@ import (
 func LoadConfig() *models.GatewayConfig {
     return &models.GatewayConfig{
         Port: getEnv("PORT", "8080"),
+        RequireVerifiedEmail: getEnv("REQUIRE_VERIFIED_EMAIL", "false") == "true",
         Services: map[string]models.ServiceConfig{
             "user-service": {
                 Name: "user-service",
@@ -39,7 +39,8 @@ func (j *JWTService) ValidateToken(tokenString string) (*Claims, error) {
 }
 
 type Claims struct {
-    UserID string `json:"user_id"`
-    Email  string `json:"email"`
+    UserID        string `json:"user_id"`
+    Email         string `json:"email"`
+    EmailVerified bool   `json:"email_verified"`
     jwt.StandardClaims
 }
\ No newline at end of file
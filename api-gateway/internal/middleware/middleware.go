@@ -1,7 +1,9 @@
 package middleware
 
 import (
+    "bufio"
     "log"
+    "net"
     "net/http"
     "time"
     "strings"
@@ -27,7 +29,7 @@ func CorsMiddleware(next http.Handler) http.Handler {
     return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
         w.Header().Set("Access-Control-Allow-Origin", "*")
         w.Header().Set("Access-Control-Allow-Methods", "GET, POST, PUT, DELETE, OPTIONS")
-        w.Header().Set("Access-Control-Allow-Headers", "content-type, authorization")   
+        w.Header().Set("Access-Control-Allow-Headers", "content-type, authorization, x-admin-token, last-event-id, idempotency-key")   
               
         if r.Method == "OPTIONS" {
             w.WriteHeader(http.StatusOK)
@@ -49,9 +51,35 @@ func (rw *responseWriter) WriteHeader(code int) {
     rw.ResponseWriter.WriteHeader(code)
 }
 
+// Flush and Hijack let streamed responses and WebSocket upgrades reach the
+// underlying connection through the wrapper
+func (rw *responseWriter) Flush() {
+    if flusher, ok := rw.ResponseWriter.(http.Flusher); ok {
+        flusher.Flush()
+    }
+}
 
+func (rw *responseWriter) Hijack() (net.Conn, *bufio.ReadWriter, error) {
+    hijacker, ok := rw.ResponseWriter.(http.Hijacker)
+    if !ok {
+        return nil, nil, http.ErrNotSupported
+    }
+    if rw.statusCode == http.StatusOK {
+        rw.statusCode = http.StatusSwitchingProtocols
+    }
+    return hijacker.Hijack()
+}
+
+func (rw *responseWriter) Unwrap() http.ResponseWriter {
+    return rw.ResponseWriter
+}
 
-func AuthMiddleware(jwtService *jwt.JWTService) func(http.Handler) http.Handler {
+
+
+// AuthMiddleware requires a valid token with an active session on every
+// route but the public ones. With requireVerified, accounts that have not
+// verified their email address are refused until they do.
+func AuthMiddleware(jwtService *jwt.JWTService, sessions *SessionChecker, requireVerified bool) func(http.Handler) http.Handler {
     return func(next http.Handler) http.Handler {
         return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
             // Skip auth for public routes
@@ -62,6 +90,9 @@ func AuthMiddleware(jwtService *jwt.JWTService) func(http.Handler) http.Handler
 
             // Get token from header
             authHeader := r.Header.Get("Authorization")
+            if authHeader == "" && isStreamRoute(r.URL.Path) {
+                authHeader = streamToken(r)
+            }
             if authHeader == "" {
                 http.Error(w, `{"error": "Authorization header required"}`, http.StatusUnauthorized)
                 return
@@ -83,6 +114,23 @@ func AuthMiddleware(jwtService *jwt.JWTService) func(http.Handler) http.Handler
                 return
             }
 
+            // A valid signature is not enough once the session is revoked
+            active, err := sessions.Active(token, claims)
+            if err != nil {
+                log.Printf("❌ Session check failed: %v", err)
+                http.Error(w, `{"error": "Authentication service unavailable"}`, http.StatusServiceUnavailable)
+                return
+            }
+            if !active {
+                http.Error(w, `{"error": "Invalid or expired token"}`, http.StatusUnauthorized)
+                return
+            }
+
+            if requireVerified && !claims.EmailVerified {
+                http.Error(w, `{"error": "Email address not verified"}`, http.StatusForbidden)
+                return
+            }
+
             // Add user info to context for downstream services
             ctx := context.WithValue(r.Context(), "user_id", claims.UserID)
             ctx = context.WithValue(ctx, "user_email", claims.Email)
@@ -96,6 +144,26 @@ func AuthMiddleware(jwtService *jwt.JWTService) func(http.Handler) http.Handler
     }
 }
 
+// isStreamRoute reports whether path is a notification stream. Browsers
+// cannot set headers on EventSource or WebSocket connections, so these routes
+// also accept the token as ?access_token=.
+func isStreamRoute(path string) bool {
+    return strings.HasSuffix(path, "/notifications/stream") || strings.HasSuffix(path, "/notifications/ws")
+}
+
+// streamToken moves ?access_token= into an Authorization header value and
+// strips it from the URL so it is not forwarded or logged downstream
+func streamToken(r *http.Request) string {
+    query := r.URL.Query()
+    token := query.Get("access_token")
+    if token == "" {
+        return ""
+    }
+    query.Del("access_token")
+    r.URL.RawQuery = query.Encode()
+    return "Bearer " + token
+}
+
 func isPublicRoute(path string) bool {
     publicRoutes := []string{
         "/api/v1/auth/login",
@@ -0,0 +1,125 @@
+package middleware
+
+import (
+    "fmt"
+    "log"
+    "net/http"
+    "os"
+    "strings"
+    "sync"
+    "time"
+    "apigateway/internal/jwt"
+)
+
+// SessionChecker asks auth-service whether a token's session is still
+// active, so tokens stop working once their session is revoked. This adds a
+// request to auth-service's /api/v1/auth/session in front of an
+// authenticated request whenever the session's answer is not cached; answers
+// are cached for ttl per session, so a busy session costs one extra hop per
+// ttl and a revocation takes up to ttl to reach the gateway. While
+// auth-service is unreachable, uncached sessions are refused with 503.
+type SessionChecker struct {
+    authURL string
+    ttl     time.Duration
+    client  *http.Client
+
+    // legacyCutover, when set, ends the grace period for tokens issued
+    // before sessions existed
+    legacyCutover time.Time
+
+    mu    sync.Mutex
+    cache map[string]sessionStatus
+}
+
+type sessionStatus struct {
+    active    bool
+    checkedAt time.Time
+}
+
+func NewSessionChecker(authURL string, ttl time.Duration, legacyCutover time.Time) *SessionChecker {
+    return &SessionChecker{
+        authURL:       strings.TrimRight(authURL, "/"),
+        ttl:           ttl,
+        client:        &http.Client{Timeout: 5 * time.Second},
+        legacyCutover: legacyCutover,
+        cache:         make(map[string]sessionStatus),
+    }
+}
+
+// NewSessionCheckerFromEnv reads SESSION_CACHE_TTL, how long after a
+// revocation a token may still be accepted (default 30s), and
+// SESSION_CUTOVER, an RFC 3339 time after which tokens without a session
+// are no longer issued. Such tokens issued before the cutover, or any of
+// them when it is unset, keep working until they expire.
+func NewSessionCheckerFromEnv(authURL string) *SessionChecker {
+    ttl := 30 * time.Second
+    if value, err := time.ParseDuration(os.Getenv("SESSION_CACHE_TTL")); err == nil && value >= 0 {
+        ttl = value
+    }
+    var cutover time.Time
+    if value := os.Getenv("SESSION_CUTOVER"); value != "" {
+        parsed, err := time.Parse(time.RFC3339, value)
+        if err != nil {
+            log.Printf("⚠️ Ignoring invalid SESSION_CUTOVER %q: %v", value, err)
+        } else {
+            cutover = parsed
+        }
+    }
+    return NewSessionChecker(authURL, ttl, cutover)
+}
+
+// Active reports whether the session behind the token is active. Tokens
+// without a session ID were issued before sessions existed; they cannot be
+// revoked, so they are accepted only until they expire and only if issued
+// before the cutover.
+func (s *SessionChecker) Active(token string, claims *jwt.Claims) (bool, error) {
+    sessionID := claims.Id
+    if sessionID == "" {
+        if claims.ExpiresAt == 0 {
+            return false, nil
+        }
+        return s.legacyCutover.IsZero() || time.Unix(claims.IssuedAt, 0).Before(s.legacyCutover), nil
+    }
+
+    now := time.Now()
+    s.mu.Lock()
+    status, ok := s.cache[sessionID]
+    s.mu.Unlock()
+    if ok && now.Sub(status.checkedAt) < s.ttl {
+        return status.active, nil
+    }
+
+    req, err := http.NewRequest(http.MethodGet, s.authURL+"/api/v1/auth/session", nil)
+    if err != nil {
+        return false, err
+    }
+    req.Header.Set("Authorization", "Bearer "+token)
+
+    resp, err := s.client.Do(req)
+    if err != nil {
+        return false, err
+    }
+    resp.Body.Close()
+
+    var active bool
+    switch resp.StatusCode {
+    case http.StatusOK:
+        active = true
+    case http.StatusUnauthorized:
+        active = false
+    default:
+        return false, fmt.Errorf("auth service returned status %d", resp.StatusCode)
+    }
+
+    s.mu.Lock()
+    defer s.mu.Unlock()
+    if len(s.cache) > 10000 {
+        for id, cached := range s.cache {
+            if now.Sub(cached.checkedAt) >= s.ttl {
+                delete(s.cache, id)
+            }
+        }
+    }
+    s.cache[sessionID] = sessionStatus{active: active, checkedAt: now}
+    return active, nil
+}
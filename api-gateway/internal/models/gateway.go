@@ -9,6 +9,9 @@ type ServiceConfig struct {
 type GatewayConfig struct {
     Port     string                   `json:"port"`
     Services map[string]ServiceConfig `json:"services"`
+    // RequireVerifiedEmail rejects tokens of accounts that have not
+    // verified their email address
+    RequireVerifiedEmail bool `json:"require_verified_email"`
 }
 
 type HealthResponse struct {
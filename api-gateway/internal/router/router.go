@@ -30,16 +30,23 @@ func (sr *ServiceRouter) RouteRequest(w http.ResponseWriter, r *http.Request) {
     servicePath := strings.TrimPrefix(path, "/api/v1")
     
     switch {
-    case strings.HasPrefix(servicePath, "/auth"):
+    case strings.HasPrefix(servicePath, "/auth"), strings.HasPrefix(servicePath, "/admin/auth"):
         log.Printf("  → Routing AUTH to Auth Service") 
         sr.proxyRequest(w, r, "auth-service")
+    case isUserSubresource(servicePath, "tasks"), isUserSubresource(servicePath, "task-views"):
+        log.Printf("  → Routing USER TASKS to Task Service")
+        sr.proxyRequest(w, r, "task-service")
+    case isUserSubresource(servicePath, "notifications"), isUserSubresource(servicePath, "notification-preferences"),
+        isUserSubresource(servicePath, "webhooks"), isUserSubresource(servicePath, "push-subscriptions"):
+        log.Printf("  → Routing USER NOTIFICATIONS to Notification Service")
+        sr.proxyRequest(w, r, "notification-service")
     case strings.HasPrefix(servicePath, "/users"):
         log.Printf("  → Routing USERS to User Service") 
         sr.proxyRequest(w, r, "user-service")
     case strings.HasPrefix(servicePath, "/tasks"):
         log.Printf("  → Routing TASKS to Task Service")
         sr.proxyRequest(w, r, "task-service")
-    case strings.HasPrefix(servicePath, "/notifications"):
+    case strings.HasPrefix(servicePath, "/notifications"), strings.HasPrefix(servicePath, "/admin/notifications"):
         log.Printf("  → Routing NOTIFICATIONS to Notification Service")
         sr.proxyRequest(w, r, "notification-service")
     case path == "/health":
@@ -50,6 +57,13 @@ func (sr *ServiceRouter) RouteRequest(w http.ResponseWriter, r *http.Request) {
     }
 }
 
+// isUserSubresource reports whether path is /users/{id}/{resource} or below it,
+// which belongs to the service owning the resource rather than user-service
+func isUserSubresource(path, resource string) bool {
+    parts := strings.SplitN(strings.TrimPrefix(path, "/users/"), "/", 3)
+    return strings.HasPrefix(path, "/users/") && len(parts) >= 2 && parts[1] == resource
+}
+
 func (sr *ServiceRouter) proxyRequest(w http.ResponseWriter, r *http.Request, serviceName string) {
     service, exists := sr.services[serviceName]
     if !exists {
@@ -68,6 +82,10 @@ func (sr *ServiceRouter) proxyRequest(w http.ResponseWriter, r *http.Request, se
     log.Printf("  → Proxying to: %s%s", targetURL.String(), r.URL.Path)
     
     proxy := httputil.NewSingleHostReverseProxy(targetURL)
+    if isStreamingRequest(r) {
+        // Pass event-stream writes through as they arrive instead of buffering
+        proxy.FlushInterval = -1
+    }
     
     // Modify the request to preserve the original path
     r.URL.Host = targetURL.Host
@@ -78,6 +96,16 @@ func (sr *ServiceRouter) proxyRequest(w http.ResponseWriter, r *http.Request, se
     proxy.ServeHTTP(w, r)
 }
 
+// isStreamingRequest reports whether r opens a long-lived connection, either
+// a WebSocket upgrade or a Server-Sent Events stream. ReverseProxy tunnels
+// upgrades itself once the backend answers 101 Switching Protocols.
+func isStreamingRequest(r *http.Request) bool {
+    if strings.EqualFold(r.Header.Get("Upgrade"), "websocket") {
+        return true
+    }
+    return strings.Contains(r.Header.Get("Accept"), "text/event-stream")
+}
+
 func (sr *ServiceRouter) HealthCheck(w http.ResponseWriter, r *http.Request) {
     healthStatus := make(map[string]interface{})
     
@@ -1,11 +1,18 @@
 package main
 
 import (
+	"context"
 	"log"
 	"net/http"
 	"os"
 	"auth-service/internal/database"
+	"auth-service/internal/events"
 	"auth-service/internal/handlers"
+	"auth-service/internal/lockout"
+	"auth-service/internal/mfa"
+	"auth-service/internal/notifier"
+	"auth-service/internal/outbox"
+	"auth-service/internal/passwords"
 	"github.com/gorilla/mux"
 )
 
@@ -23,8 +30,39 @@ func main() {
 		log.Fatal("❌ Auth Service: Database initialization failed:", err)
 	}
 
+	// Publish user events written to the outbox
+	bus, err := events.NewBusFromEnv("auth-service")
+	if err != nil {
+		log.Fatal("❌ Auth Service: Event bus connection failed:", err)
+	}
+	defer bus.Close()
+	go outbox.NewRelayFromEnv(db, bus, "auth-service").Run(context.Background())
+
+	// Security notifications are sent to notification-service in the background
+	notificationClient := notifier.NewClientFromEnv()
+	go notificationClient.Run(context.Background())
+	if os.Getenv("SERVICE_TOKEN") == "" {
+		log.Println("⚠️ SERVICE_TOKEN is not set; notification-service will refuse verification, password reset and security emails")
+	}
+
+	// TOTP secrets are stored encrypted
+	keyring, err := mfa.NewKeyringFromEnv()
+	if err != nil {
+		log.Fatal("❌ Auth Service: MFA key setup failed:", err)
+	}
+
+	// Failed sign-ins are throttled per account and per client address
+	guard := lockout.NewGuard(lockout.NewStoreFromEnv(db), lockout.PolicyFromEnv())
+	go guard.Run(context.Background())
+
+	// New passwords are checked against the policy and a breached list
+	validator, err := passwords.NewValidatorFromEnv()
+	if err != nil {
+		log.Fatal("❌ Auth Service: Password policy setup failed:", err)
+	}
+
 	// Initialize handlers
-	authHandler := handlers.NewAuthHandler(db)
+	authHandler := handlers.NewAuthHandler(db, notificationClient, keyring, guard, validator)
 
 	// Setup routes
 	r := mux.NewRouter()
@@ -36,8 +74,29 @@ func main() {
 	// API routes
 	r.HandleFunc("/api/v1/auth/signup", authHandler.Signup).Methods("POST", "OPTIONS")
 	r.HandleFunc("/api/v1/auth/login", authHandler.Login).Methods("POST", "OPTIONS")
+	r.HandleFunc("/api/v1/auth/login/mfa", authHandler.LoginMFA).Methods("POST", "OPTIONS")
+	r.HandleFunc("/api/v1/auth/mfa/enroll", authHandler.EnrollMFA).Methods("POST", "OPTIONS")
+	r.HandleFunc("/api/v1/auth/mfa/confirm", authHandler.ConfirmMFA).Methods("POST", "OPTIONS")
+	r.HandleFunc("/api/v1/auth/mfa/recovery-codes", authHandler.RegenerateRecoveryCodes).Methods("POST", "OPTIONS")
+	r.HandleFunc("/api/v1/auth/mfa/disable", authHandler.DisableMFA).Methods("POST", "OPTIONS")
+	r.HandleFunc("/api/v1/auth/session", authHandler.GetSession).Methods("GET", "OPTIONS")
+	r.HandleFunc("/api/v1/auth/refresh", authHandler.RefreshToken).Methods("POST", "OPTIONS")
+	r.HandleFunc("/api/v1/auth/verify", authHandler.VerifyEmail).Methods("GET", "OPTIONS")
+	r.HandleFunc("/api/v1/auth/verify/resend", authHandler.ResendVerification).Methods("POST", "OPTIONS")
+	r.HandleFunc("/api/v1/auth/password", authHandler.ChangePassword).Methods("PUT", "OPTIONS")
+	r.HandleFunc("/api/v1/auth/email", authHandler.ChangeEmail).Methods("POST", "OPTIONS")
+	r.HandleFunc("/api/v1/auth/email/confirm", authHandler.ConfirmEmailChange).Methods("GET", "OPTIONS")
+	r.HandleFunc("/api/v1/auth/password/policy", authHandler.GetPasswordPolicy).Methods("GET", "OPTIONS")
+	r.HandleFunc("/api/v1/auth/password/forgot", authHandler.ForgotPassword).Methods("POST", "OPTIONS")
+	r.HandleFunc("/api/v1/auth/password/reset", authHandler.ResetPassword).Methods("POST", "OPTIONS")
 	r.HandleFunc("/health", authHandler.HealthCheck).Methods("GET")
 
+	// Operator endpoints, guarded by ADMIN_TOKEN
+	admin := r.PathPrefix("/api/v1/admin/auth").Subrouter()
+	admin.Use(handlers.AdminMiddleware)
+	admin.HandleFunc("/lockouts", authHandler.GetLockoutStatus).Methods("GET")
+	admin.HandleFunc("/lockouts/unlock", authHandler.UnlockLogin).Methods("POST")
+
 	// Log all registered routes
 	log.Println("📋 AUTH SERVICE - REGISTERED ROUTES:")
 	r.Walk(func(route *mux.Route, router *mux.Router, ancestors []*mux.Route) error {
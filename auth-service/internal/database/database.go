@@ -4,6 +4,7 @@ import (
 	"database/sql"
 	"fmt"
 	"log"
+	"auth-service/internal/events"
 	"auth-service/internal/models"
     "os"
     "regexp"
@@ -93,6 +94,119 @@ func (db *DB) Init() error {
     END $$;
 
     CREATE INDEX IF NOT EXISTS idx_users_email ON users(email);
+
+    -- Transactional outbox: events are written with the change that caused
+    -- them and published to the event bus by the outbox relay
+    CREATE TABLE IF NOT EXISTS outbox_events (
+        id BIGSERIAL PRIMARY KEY,
+        event_id UUID NOT NULL DEFAULT gen_random_uuid(),
+        event_type VARCHAR(100) NOT NULL,
+        aggregate_id VARCHAR(255) NOT NULL,
+        payload JSONB NOT NULL,
+        created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+        published_at TIMESTAMP,
+        attempts INTEGER NOT NULL DEFAULT 0,
+        last_error TEXT
+    );
+    CREATE INDEX IF NOT EXISTS idx_outbox_events_unpublished ON outbox_events(id) WHERE published_at IS NULL;
+    CREATE INDEX IF NOT EXISTS idx_outbox_events_published_at ON outbox_events(published_at) WHERE published_at IS NOT NULL;
+
+    -- Devices and addresses each user has signed in from, to spot new ones
+    CREATE TABLE IF NOT EXISTS login_devices (
+        user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+        device_hash CHAR(64) NOT NULL,
+        ip VARCHAR(45) NOT NULL,
+        user_agent TEXT NOT NULL DEFAULT '',
+        first_seen_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+        last_seen_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+        PRIMARY KEY (user_id, device_hash, ip)
+    );
+
+    -- Each issued token belongs to a session; revoking it logs the token out
+    CREATE TABLE IF NOT EXISTS sessions (
+        id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+        user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+        ip VARCHAR(45) NOT NULL DEFAULT '',
+        user_agent TEXT NOT NULL DEFAULT '',
+        created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+        expires_at TIMESTAMP NOT NULL,
+        revoked_at TIMESTAMP
+    );
+    CREATE INDEX IF NOT EXISTS idx_sessions_user_id ON sessions(user_id);
+
+    -- Password reset tokens are stored as SHA-256 hashes and used once
+    CREATE TABLE IF NOT EXISTS password_reset_tokens (
+        id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+        user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+        token_hash CHAR(64) UNIQUE NOT NULL,
+        created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+        expires_at TIMESTAMP NOT NULL,
+        used_at TIMESTAMP
+    );
+    CREATE INDEX IF NOT EXISTS idx_password_reset_tokens_user_id ON password_reset_tokens(user_id);
+
+    -- Migration: email verification. Accounts created before verification
+    -- existed are treated as verified; new ones start unverified.
+    ALTER TABLE users ADD COLUMN IF NOT EXISTS email_verified BOOLEAN NOT NULL DEFAULT TRUE;
+    ALTER TABLE users ALTER COLUMN email_verified SET DEFAULT FALSE;
+    ALTER TABLE users ADD COLUMN IF NOT EXISTS email_verified_at TIMESTAMP;
+
+    -- Verification tokens confirm one address; a token stops working if the
+    -- account's email changes before it is used
+    CREATE TABLE IF NOT EXISTS email_verification_tokens (
+        id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+        user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+        email VARCHAR(255) NOT NULL,
+        token_hash CHAR(64) UNIQUE NOT NULL,
+        created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+        expires_at TIMESTAMP NOT NULL,
+        used_at TIMESTAMP
+    );
+    CREATE INDEX IF NOT EXISTS idx_email_verification_tokens_user_id ON email_verification_tokens(user_id, created_at);
+
+    -- Migration: TOTP multi-factor authentication. Secrets are encrypted;
+    -- mfa_last_step is the last time step accepted, so codes are not replayed.
+    ALTER TABLE users ADD COLUMN IF NOT EXISTS mfa_enabled BOOLEAN NOT NULL DEFAULT FALSE;
+    ALTER TABLE users ADD COLUMN IF NOT EXISTS mfa_secret TEXT;
+    ALTER TABLE users ADD COLUMN IF NOT EXISTS mfa_pending_secret TEXT;
+    ALTER TABLE users ADD COLUMN IF NOT EXISTS mfa_last_step BIGINT NOT NULL DEFAULT 0;
+
+    CREATE TABLE IF NOT EXISTS mfa_recovery_codes (
+        id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+        user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+        code_hash CHAR(64) NOT NULL,
+        created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+        used_at TIMESTAMP,
+        UNIQUE (user_id, code_hash)
+    );
+
+    -- A password checked for an MFA account opens a challenge that a TOTP or
+    -- recovery code completes
+    CREATE TABLE IF NOT EXISTS mfa_challenges (
+        id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+        user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+        token_hash CHAR(64) UNIQUE NOT NULL,
+        attempts INTEGER NOT NULL DEFAULT 0,
+        created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+        expires_at TIMESTAMP NOT NULL,
+        used_at TIMESTAMP
+    );
+    CREATE INDEX IF NOT EXISTS idx_mfa_challenges_user_id ON mfa_challenges(user_id);
+
+    -- Migration: failed sign-in throttling, keyed by account email or client IP
+    CREATE TABLE IF NOT EXISTS login_attempts (
+        key TEXT PRIMARY KEY,
+        failures INTEGER NOT NULL DEFAULT 0,
+        lockouts INTEGER NOT NULL DEFAULT 0,
+        last_failure_at TIMESTAMP,
+        delayed_until TIMESTAMP,
+        locked_until TIMESTAMP
+    );
+    CREATE INDEX IF NOT EXISTS idx_login_attempts_last_failure_at ON login_attempts(last_failure_at);
+
+    -- Migration: email tokens either verify the current address or confirm
+    -- a change to a new one
+    ALTER TABLE email_verification_tokens ADD COLUMN IF NOT EXISTS purpose VARCHAR(20) NOT NULL DEFAULT 'verify';
     `
 
     _, err := db.Exec(query)
@@ -116,27 +230,74 @@ func (db *DB) CreateUser(user *models.User, password string) error {
 	VALUES ($1, $2, $3, $4)
 	RETURNING id, created_at, updated_at`
 
-	err = db.QueryRow(query, user.Email, string(hashedPassword), user.FirstName, user.LastName).Scan(
+	// The user and its user.signed_up event are committed together
+	tx, err := db.Begin()
+	if err != nil {
+		return fmt.Errorf("failed to create user: %w", err)
+	}
+	defer tx.Rollback()
+
+	err = tx.QueryRow(query, user.Email, string(hashedPassword), user.FirstName, user.LastName).Scan(
 		&user.ID, &user.CreatedAt, &user.UpdatedAt)
 
 	if err != nil {
 		return fmt.Errorf("failed to create user: %w", err)
 	}
 
+	err = addOutboxEvent(tx, events.UserSignedUp, user.ID, map[string]string{
+		"user_id":    user.ID,
+		"email":      user.Email,
+		"first_name": user.FirstName,
+		"last_name":  user.LastName,
+	})
+	if err != nil {
+		return err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("failed to create user: %w", err)
+	}
+
 	return nil
 }
 
-func (db *DB) GetUserByEmail(email string) (*models.User, error) {
+const userColumns = `id, email, password_hash, first_name, last_name, email_verified, mfa_enabled, created_at, updated_at`
+
+// rowScanner is satisfied by both *sql.Row and *sql.Rows
+type rowScanner interface {
+	Scan(dest ...interface{}) error
+}
+
+// scanUser reads the userColumns of a row. The password hash is kept on the
+// user for verification and never serialized.
+func scanUser(row rowScanner) (*models.User, error) {
 	var user models.User
-	var passwordHash string
+	err := row.Scan(&user.ID, &user.Email, &user.Password, &user.FirstName, &user.LastName,
+		&user.EmailVerified, &user.MFAEnabled, &user.CreatedAt, &user.UpdatedAt)
+	if err != nil {
+		return nil, err
+	}
+	return &user, nil
+}
+
+func (db *DB) GetUserByEmail(email string) (*models.User, error) {
+	query := `SELECT ` + userColumns + ` FROM users WHERE email = $1`
+
+	user, err := scanUser(db.QueryRow(query, email))
+	if err == sql.ErrNoRows {
+		return nil, fmt.Errorf("user not found")
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get user: %w", err)
+	}
 
-	query := `SELECT id, email, password_hash, first_name, last_name, created_at, updated_at 
-			  FROM users WHERE email = $1`
+	return user, nil
+}
 
-	err := db.QueryRow(query, email).Scan(
-		&user.ID, &user.Email, &passwordHash, &user.FirstName, &user.LastName, 
-		&user.CreatedAt, &user.UpdatedAt)
+func (db *DB) GetUserByID(id string) (*models.User, error) {
+	query := `SELECT ` + userColumns + ` FROM users WHERE id = $1`
 
+	user, err := scanUser(db.QueryRow(query, id))
 	if err == sql.ErrNoRows {
 		return nil, fmt.Errorf("user not found")
 	}
@@ -144,8 +305,7 @@ func (db *DB) GetUserByEmail(email string) (*models.User, error) {
 		return nil, fmt.Errorf("failed to get user: %w", err)
 	}
 
-	user.Password = passwordHash // Store hash for verification
-	return &user, nil
+	return user, nil
 }
 
 func (db *DB) Close() error {
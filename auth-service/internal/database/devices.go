@@ -0,0 +1,41 @@
+package database
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+)
+
+// RecordLogin remembers that the user signed in from ip with userAgent and
+// reports whether either had not been seen for this user before. The first
+// sign-in ever recorded for a user is not new, since there is nothing to
+// compare it with.
+func (db *DB) RecordLogin(userID, ip, userAgent string) (newDevice, newIP bool, err error) {
+	hash := sha256.Sum256([]byte(userAgent))
+	deviceHash := hex.EncodeToString(hash[:])
+
+	// known reads the rows as they were before this sign-in is recorded
+	query := `
+	WITH known AS (
+		SELECT COUNT(*) AS total,
+		       COUNT(*) FILTER (WHERE device_hash = $2) AS device,
+		       COUNT(*) FILTER (WHERE ip = $3) AS ip
+		FROM login_devices WHERE user_id = $1
+	), recorded AS (
+		INSERT INTO login_devices (user_id, device_hash, ip, user_agent)
+		VALUES ($1, $2, $3, $4)
+		ON CONFLICT (user_id, device_hash, ip) DO UPDATE SET last_seen_at = CURRENT_TIMESTAMP
+	)
+	SELECT total, device, ip FROM known`
+
+	var total, device, address int
+	err = db.QueryRow(query, userID, deviceHash, ip, userAgent).Scan(&total, &device, &address)
+	if err != nil {
+		return false, false, fmt.Errorf("failed to record login: %w", err)
+	}
+	if total == 0 {
+		return false, false, nil
+	}
+
+	return device == 0, address == 0, nil
+}
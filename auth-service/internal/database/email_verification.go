@@ -0,0 +1,189 @@
+package database
+
+import (
+	"auth-service/internal/models"
+	"database/sql"
+	"fmt"
+	"math"
+	"time"
+
+	"github.com/lib/pq"
+)
+
+// VerificationLimits bounds how often verification emails are sent to one user
+type VerificationLimits struct {
+	TTL        time.Duration
+	Interval   time.Duration
+	DailyLimit int
+}
+
+// Purposes of an email token
+const (
+	purposeVerify = "verify"
+	purposeChange = "change"
+)
+
+// CreateEmailVerificationToken stores the hash of a new token confirming
+// email for the user and retires any earlier unused one. When the limits
+// allow no new token yet it stores nothing and returns how long to wait.
+func (db *DB) CreateEmailVerificationToken(userID, email, tokenHash string, limits VerificationLimits) (time.Duration, error) {
+	return db.createEmailToken(userID, email, purposeVerify, tokenHash, limits)
+}
+
+// CreateEmailChangeToken stores the hash of a token that moves the user to
+// newEmail once confirmed. It shares the limits of verification emails.
+func (db *DB) CreateEmailChangeToken(userID, newEmail, tokenHash string, limits VerificationLimits) (time.Duration, error) {
+	return db.createEmailToken(userID, newEmail, purposeChange, tokenHash, limits)
+}
+
+func (db *DB) createEmailToken(userID, email, purpose, tokenHash string, limits VerificationLimits) (time.Duration, error) {
+	tx, err := db.Begin()
+	if err != nil {
+		return 0, fmt.Errorf("failed to create verification token: %w", err)
+	}
+	defer tx.Rollback()
+
+	// Serialize requests for the same user so two cannot both pass the limits
+	if _, err := tx.Exec(`SELECT id FROM users WHERE id = $1 FOR UPDATE`, userID); err != nil {
+		return 0, fmt.Errorf("failed to create verification token: %w", err)
+	}
+
+	var sent int
+	var sinceLast, sinceFirst sql.NullFloat64
+	err = tx.QueryRow(`
+	SELECT COUNT(*),
+	       EXTRACT(EPOCH FROM CURRENT_TIMESTAMP - MAX(created_at)),
+	       EXTRACT(EPOCH FROM CURRENT_TIMESTAMP - MIN(created_at))
+	FROM email_verification_tokens
+	WHERE user_id = $1 AND created_at > CURRENT_TIMESTAMP - INTERVAL '1 day'`, userID).Scan(&sent, &sinceLast, &sinceFirst)
+	if err != nil {
+		return 0, fmt.Errorf("failed to create verification token: %w", err)
+	}
+	if limits.DailyLimit > 0 && sent >= limits.DailyLimit {
+		return waitFor(24*time.Hour, sinceFirst.Float64), nil
+	}
+	if sinceLast.Valid && sinceLast.Float64 < limits.Interval.Seconds() {
+		return waitFor(limits.Interval, sinceLast.Float64), nil
+	}
+
+	// Earlier tokens are expired rather than deleted so they still count
+	// towards the daily limit
+	_, err = tx.Exec(`
+	UPDATE email_verification_tokens SET expires_at = CURRENT_TIMESTAMP
+	WHERE user_id = $1 AND used_at IS NULL AND expires_at > CURRENT_TIMESTAMP`, userID)
+	if err != nil {
+		return 0, fmt.Errorf("failed to create verification token: %w", err)
+	}
+
+	query := `
+	INSERT INTO email_verification_tokens (user_id, email, purpose, token_hash, expires_at)
+	VALUES ($1, $2, $3, $4, CURRENT_TIMESTAMP + make_interval(secs => $5))`
+	if _, err := tx.Exec(query, userID, email, purpose, tokenHash, limits.TTL.Seconds()); err != nil {
+		return 0, fmt.Errorf("failed to create verification token: %w", err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return 0, fmt.Errorf("failed to create verification token: %w", err)
+	}
+	return 0, nil
+}
+
+// waitFor returns the time left of window when elapsed seconds have passed
+func waitFor(window time.Duration, elapsed float64) time.Duration {
+	wait := window - time.Duration(elapsed*float64(time.Second))
+	return time.Duration(math.Max(float64(wait), float64(time.Second)))
+}
+
+// VerifyEmail consumes a valid verification token and marks the address it
+// was issued for as verified, provided it is still the account's address
+func (db *DB) VerifyEmail(tokenHash string) (*models.User, error) {
+	tx, err := db.Begin()
+	if err != nil {
+		return nil, fmt.Errorf("failed to verify email: %w", err)
+	}
+	defer tx.Rollback()
+
+	var tokenID, userID, email string
+	err = tx.QueryRow(`
+	SELECT id, user_id, email FROM email_verification_tokens
+	WHERE token_hash = $1 AND purpose = $2 AND used_at IS NULL AND expires_at > CURRENT_TIMESTAMP
+	FOR UPDATE`, tokenHash, purposeVerify).Scan(&tokenID, &userID, &email)
+	if err == sql.ErrNoRows {
+		return nil, fmt.Errorf("invalid or expired verification token")
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to verify email: %w", err)
+	}
+
+	user, err := scanUser(tx.QueryRow(`
+	UPDATE users
+	SET email_verified = TRUE,
+	    email_verified_at = COALESCE(email_verified_at, CURRENT_TIMESTAMP),
+	    updated_at = CURRENT_TIMESTAMP
+	WHERE id = $1 AND email = $2
+	RETURNING `+userColumns, userID, email))
+	if err == sql.ErrNoRows {
+		return nil, fmt.Errorf("invalid or expired verification token")
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to verify email: %w", err)
+	}
+
+	if _, err := tx.Exec(`UPDATE email_verification_tokens SET used_at = CURRENT_TIMESTAMP WHERE id = $1`, tokenID); err != nil {
+		return nil, fmt.Errorf("failed to verify email: %w", err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, fmt.Errorf("failed to verify email: %w", err)
+	}
+	return user, nil
+}
+
+// ConfirmEmailChange consumes a valid email change token and moves the user
+// to the new address, which the token proves they control. It returns the
+// updated user and the previous address.
+func (db *DB) ConfirmEmailChange(tokenHash string) (*models.User, string, error) {
+	tx, err := db.Begin()
+	if err != nil {
+		return nil, "", fmt.Errorf("failed to change email: %w", err)
+	}
+	defer tx.Rollback()
+
+	var tokenID, userID, newEmail string
+	err = tx.QueryRow(`
+	SELECT id, user_id, email FROM email_verification_tokens
+	WHERE token_hash = $1 AND purpose = $2 AND used_at IS NULL AND expires_at > CURRENT_TIMESTAMP
+	FOR UPDATE`, tokenHash, purposeChange).Scan(&tokenID, &userID, &newEmail)
+	if err == sql.ErrNoRows {
+		return nil, "", fmt.Errorf("invalid or expired email change token")
+	}
+	if err != nil {
+		return nil, "", fmt.Errorf("failed to change email: %w", err)
+	}
+
+	var oldEmail string
+	if err := tx.QueryRow(`SELECT email FROM users WHERE id = $1 FOR UPDATE`, userID).Scan(&oldEmail); err != nil {
+		return nil, "", fmt.Errorf("failed to change email: %w", err)
+	}
+
+	user, err := scanUser(tx.QueryRow(`
+	UPDATE users
+	SET email = $2, email_verified = TRUE, email_verified_at = CURRENT_TIMESTAMP, updated_at = CURRENT_TIMESTAMP
+	WHERE id = $1
+	RETURNING `+userColumns, userID, newEmail))
+	if pqErr, ok := err.(*pq.Error); ok && pqErr.Code == "23505" {
+		return nil, "", fmt.Errorf("email already in use")
+	}
+	if err != nil {
+		return nil, "", fmt.Errorf("failed to change email: %w", err)
+	}
+
+	if _, err := tx.Exec(`UPDATE email_verification_tokens SET used_at = CURRENT_TIMESTAMP WHERE id = $1`, tokenID); err != nil {
+		return nil, "", fmt.Errorf("failed to change email: %w", err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, "", fmt.Errorf("failed to change email: %w", err)
+	}
+	return user, oldEmail, nil
+}
@@ -0,0 +1,117 @@
+package database
+
+import (
+	"auth-service/internal/models"
+	"database/sql"
+	"fmt"
+	"time"
+
+	"github.com/lib/pq"
+)
+
+// The login_attempts methods implement lockout.Store. Times are stored as
+// UTC.
+
+func (db *DB) GetLoginAttempts(key string) (*models.LoginAttempts, error) {
+	query := `
+	SELECT key, failures, lockouts, last_failure_at, delayed_until, locked_until
+	FROM login_attempts WHERE key = $1`
+
+	attempts, err := scanLoginAttempts(db.QueryRow(query, key))
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get login attempts: %w", err)
+	}
+	return attempts, nil
+}
+
+// UpdateLoginAttempts locks the key's row for the update, so concurrent
+// failures against one account are all counted
+func (db *DB) UpdateLoginAttempts(key string, update func(*models.LoginAttempts)) (*models.LoginAttempts, error) {
+	tx, err := db.Begin()
+	if err != nil {
+		return nil, fmt.Errorf("failed to update login attempts: %w", err)
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.Exec(`INSERT INTO login_attempts (key) VALUES ($1) ON CONFLICT (key) DO NOTHING`, key); err != nil {
+		return nil, fmt.Errorf("failed to update login attempts: %w", err)
+	}
+
+	attempts, err := scanLoginAttempts(tx.QueryRow(`
+	SELECT key, failures, lockouts, last_failure_at, delayed_until, locked_until
+	FROM login_attempts WHERE key = $1 FOR UPDATE`, key))
+	if err != nil {
+		return nil, fmt.Errorf("failed to update login attempts: %w", err)
+	}
+
+	update(attempts)
+
+	_, err = tx.Exec(`
+	UPDATE login_attempts
+	SET failures = $2, lockouts = $3, last_failure_at = $4, delayed_until = $5, locked_until = $6
+	WHERE key = $1`,
+		key, attempts.Failures, attempts.Lockouts,
+		utcOrNil(attempts.LastFailureAt), utcOrNil(attempts.DelayedUntil), utcOrNil(attempts.LockedUntil))
+	if err != nil {
+		return nil, fmt.Errorf("failed to update login attempts: %w", err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, fmt.Errorf("failed to update login attempts: %w", err)
+	}
+	return attempts, nil
+}
+
+func (db *DB) DeleteLoginAttempts(keys ...string) error {
+	if _, err := db.Exec(`DELETE FROM login_attempts WHERE key = ANY($1)`, pq.Array(keys)); err != nil {
+		return fmt.Errorf("failed to delete login attempts: %w", err)
+	}
+	return nil
+}
+
+func (db *DB) PurgeLoginAttempts(before time.Time) (int, error) {
+	query := `
+	DELETE FROM login_attempts
+	WHERE (last_failure_at IS NULL OR last_failure_at < $1)
+	  AND (locked_until IS NULL OR locked_until < $1)`
+
+	result, err := db.Exec(query, before.UTC())
+	if err != nil {
+		return 0, fmt.Errorf("failed to purge login attempts: %w", err)
+	}
+	purged, _ := result.RowsAffected()
+	return int(purged), nil
+}
+
+func scanLoginAttempts(row rowScanner) (*models.LoginAttempts, error) {
+	var attempts models.LoginAttempts
+	var lastFailureAt, delayedUntil, lockedUntil sql.NullTime
+	err := row.Scan(&attempts.Key, &attempts.Failures, &attempts.Lockouts, &lastFailureAt, &delayedUntil, &lockedUntil)
+	if err != nil {
+		return nil, err
+	}
+	attempts.LastFailureAt = utcPtr(lastFailureAt)
+	attempts.DelayedUntil = utcPtr(delayedUntil)
+	attempts.LockedUntil = utcPtr(lockedUntil)
+	return &attempts, nil
+}
+
+// TIMESTAMP columns carry no zone; values are written and read back as UTC
+func utcPtr(t sql.NullTime) *time.Time {
+	if !t.Valid {
+		return nil
+	}
+	utc := time.Date(t.Time.Year(), t.Time.Month(), t.Time.Day(), t.Time.Hour(), t.Time.Minute(),
+		t.Time.Second(), t.Time.Nanosecond(), time.UTC)
+	return &utc
+}
+
+func utcOrNil(t *time.Time) interface{} {
+	if t == nil {
+		return nil
+	}
+	return t.UTC()
+}
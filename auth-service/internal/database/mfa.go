@@ -0,0 +1,194 @@
+package database
+
+import (
+	"database/sql"
+	"fmt"
+	"time"
+)
+
+// GetMFASecrets returns the user's sealed active and pending TOTP secrets,
+// either of which may be empty
+func (db *DB) GetMFASecrets(userID string) (string, string, error) {
+	query := `SELECT COALESCE(mfa_secret, ''), COALESCE(mfa_pending_secret, '') FROM users WHERE id = $1`
+
+	var secret, pending string
+	err := db.QueryRow(query, userID).Scan(&secret, &pending)
+	if err == sql.ErrNoRows {
+		return "", "", fmt.Errorf("user not found")
+	}
+	if err != nil {
+		return "", "", fmt.Errorf("failed to get MFA secrets: %w", err)
+	}
+	return secret, pending, nil
+}
+
+// SetPendingMFASecret stores a secret awaiting confirmation with a first code
+func (db *DB) SetPendingMFASecret(userID, sealed string) error {
+	query := `UPDATE users SET mfa_pending_secret = $2, updated_at = CURRENT_TIMESTAMP WHERE id = $1`
+
+	if _, err := db.Exec(query, userID, sealed); err != nil {
+		return fmt.Errorf("failed to store MFA secret: %w", err)
+	}
+	return nil
+}
+
+// EnableMFA activates the pending secret, records step as used and replaces
+// the user's recovery codes
+func (db *DB) EnableMFA(userID string, step int64, codeHashes []string) error {
+	tx, err := db.Begin()
+	if err != nil {
+		return fmt.Errorf("failed to enable MFA: %w", err)
+	}
+	defer tx.Rollback()
+
+	result, err := tx.Exec(`
+	UPDATE users
+	SET mfa_enabled = TRUE, mfa_secret = mfa_pending_secret, mfa_pending_secret = NULL,
+	    mfa_last_step = $2, updated_at = CURRENT_TIMESTAMP
+	WHERE id = $1 AND mfa_pending_secret IS NOT NULL`, userID, step)
+	if err != nil {
+		return fmt.Errorf("failed to enable MFA: %w", err)
+	}
+	if rows, _ := result.RowsAffected(); rows == 0 {
+		return fmt.Errorf("no MFA enrollment in progress")
+	}
+
+	if err := replaceRecoveryCodes(tx, userID, codeHashes); err != nil {
+		return err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("failed to enable MFA: %w", err)
+	}
+	return nil
+}
+
+// DisableMFA removes the user's secrets and recovery codes
+func (db *DB) DisableMFA(userID string) error {
+	tx, err := db.Begin()
+	if err != nil {
+		return fmt.Errorf("failed to disable MFA: %w", err)
+	}
+	defer tx.Rollback()
+
+	_, err = tx.Exec(`
+	UPDATE users
+	SET mfa_enabled = FALSE, mfa_secret = NULL, mfa_pending_secret = NULL, updated_at = CURRENT_TIMESTAMP
+	WHERE id = $1`, userID)
+	if err != nil {
+		return fmt.Errorf("failed to disable MFA: %w", err)
+	}
+	if _, err := tx.Exec(`DELETE FROM mfa_recovery_codes WHERE user_id = $1`, userID); err != nil {
+		return fmt.Errorf("failed to disable MFA: %w", err)
+	}
+	if _, err := tx.Exec(`DELETE FROM mfa_challenges WHERE user_id = $1`, userID); err != nil {
+		return fmt.Errorf("failed to disable MFA: %w", err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("failed to disable MFA: %w", err)
+	}
+	return nil
+}
+
+// ReplaceRecoveryCodes swaps the user's recovery codes for new ones
+func (db *DB) ReplaceRecoveryCodes(userID string, codeHashes []string) error {
+	tx, err := db.Begin()
+	if err != nil {
+		return fmt.Errorf("failed to replace recovery codes: %w", err)
+	}
+	defer tx.Rollback()
+
+	if err := replaceRecoveryCodes(tx, userID, codeHashes); err != nil {
+		return err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("failed to replace recovery codes: %w", err)
+	}
+	return nil
+}
+
+func replaceRecoveryCodes(tx *sql.Tx, userID string, codeHashes []string) error {
+	if _, err := tx.Exec(`DELETE FROM mfa_recovery_codes WHERE user_id = $1`, userID); err != nil {
+		return fmt.Errorf("failed to replace recovery codes: %w", err)
+	}
+	for _, hash := range codeHashes {
+		if _, err := tx.Exec(`INSERT INTO mfa_recovery_codes (user_id, code_hash) VALUES ($1, $2)`, userID, hash); err != nil {
+			return fmt.Errorf("failed to replace recovery codes: %w", err)
+		}
+	}
+	return nil
+}
+
+// UseMFAStep records a TOTP time step as used. It returns false when the
+// step is not later than the last one used, which means the code was
+// already spent.
+func (db *DB) UseMFAStep(userID string, step int64) (bool, error) {
+	query := `UPDATE users SET mfa_last_step = $2 WHERE id = $1 AND mfa_last_step < $2`
+
+	result, err := db.Exec(query, userID, step)
+	if err != nil {
+		return false, fmt.Errorf("failed to record MFA code: %w", err)
+	}
+	rows, _ := result.RowsAffected()
+	return rows == 1, nil
+}
+
+// UseRecoveryCode spends one of the user's recovery codes, returning false
+// when it does not exist or was already used
+func (db *DB) UseRecoveryCode(userID, codeHash string) (bool, error) {
+	query := `
+	UPDATE mfa_recovery_codes SET used_at = CURRENT_TIMESTAMP
+	WHERE user_id = $1 AND code_hash = $2 AND used_at IS NULL`
+
+	result, err := db.Exec(query, userID, codeHash)
+	if err != nil {
+		return false, fmt.Errorf("failed to use recovery code: %w", err)
+	}
+	rows, _ := result.RowsAffected()
+	return rows == 1, nil
+}
+
+// CreateMFAChallenge opens a challenge for the user lasting ttl
+func (db *DB) CreateMFAChallenge(userID, tokenHash string, ttl time.Duration) (time.Time, error) {
+	query := `
+	INSERT INTO mfa_challenges (user_id, token_hash, expires_at)
+	VALUES ($1, $2, CURRENT_TIMESTAMP + make_interval(secs => $3))
+	RETURNING expires_at`
+
+	var expiresAt time.Time
+	if err := db.QueryRow(query, userID, tokenHash, ttl.Seconds()).Scan(&expiresAt); err != nil {
+		return time.Time{}, fmt.Errorf("failed to create MFA challenge: %w", err)
+	}
+	return expiresAt, nil
+}
+
+// AttemptMFAChallenge counts an attempt at an open challenge and returns its
+// user. A challenge that is expired, completed or out of attempts is invalid.
+func (db *DB) AttemptMFAChallenge(tokenHash string, maxAttempts int) (string, error) {
+	query := `
+	UPDATE mfa_challenges SET attempts = attempts + 1
+	WHERE token_hash = $1 AND used_at IS NULL AND expires_at > CURRENT_TIMESTAMP AND attempts < $2
+	RETURNING user_id`
+
+	var userID string
+	err := db.QueryRow(query, tokenHash, maxAttempts).Scan(&userID)
+	if err == sql.ErrNoRows {
+		return "", fmt.Errorf("invalid or expired MFA challenge")
+	}
+	if err != nil {
+		return "", fmt.Errorf("failed to check MFA challenge: %w", err)
+	}
+	return userID, nil
+}
+
+// CompleteMFAChallenge closes a challenge once it has been passed
+func (db *DB) CompleteMFAChallenge(tokenHash string) error {
+	query := `UPDATE mfa_challenges SET used_at = CURRENT_TIMESTAMP WHERE token_hash = $1`
+
+	if _, err := db.Exec(query, tokenHash); err != nil {
+		return fmt.Errorf("failed to complete MFA challenge: %w", err)
+	}
+	return nil
+}
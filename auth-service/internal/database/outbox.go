@@ -0,0 +1,99 @@
+package database
+
+import (
+	"auth-service/internal/models"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"time"
+)
+
+// addOutboxEvent records an event in the same transaction as the change it
+// describes, so the event exists exactly when the change is committed
+func addOutboxEvent(tx *sql.Tx, eventType, aggregateID string, data interface{}) error {
+	payload, err := json.Marshal(data)
+	if err != nil {
+		return fmt.Errorf("failed to encode %s event: %w", eventType, err)
+	}
+
+	query := `INSERT INTO outbox_events (event_type, aggregate_id, payload) VALUES ($1, $2, $3)`
+	if _, err := tx.Exec(query, eventType, aggregateID, payload); err != nil {
+		return fmt.Errorf("failed to write %s event to outbox: %w", eventType, err)
+	}
+	return nil
+}
+
+// RelayOutbox passes up to limit unpublished events, oldest first, to publish
+// and marks each one published once publish returns. The batch stops at the
+// first failure, which is recorded on the event and returned, so events keep
+// their order. Rows are claimed with SKIP LOCKED, so several relays can run.
+func (db *DB) RelayOutbox(limit int, publish func(*models.OutboxEvent) error) (int, error) {
+	tx, err := db.Begin()
+	if err != nil {
+		return 0, fmt.Errorf("failed to relay outbox: %w", err)
+	}
+	defer tx.Rollback()
+
+	query := `
+	SELECT id, event_id, event_type, aggregate_id, payload, created_at, attempts
+	FROM outbox_events
+	WHERE published_at IS NULL
+	ORDER BY id
+	LIMIT $1
+	FOR UPDATE SKIP LOCKED`
+
+	rows, err := tx.Query(query, limit)
+	if err != nil {
+		return 0, fmt.Errorf("failed to query outbox: %w", err)
+	}
+
+	var batch []*models.OutboxEvent
+	for rows.Next() {
+		var event models.OutboxEvent
+		if err := rows.Scan(&event.ID, &event.EventID, &event.Type, &event.AggregateID, &event.Payload,
+			&event.CreatedAt, &event.Attempts); err != nil {
+			rows.Close()
+			return 0, fmt.Errorf("failed to scan outbox event: %w", err)
+		}
+		batch = append(batch, &event)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return 0, fmt.Errorf("failed to query outbox: %w", err)
+	}
+
+	published := 0
+	var publishErr error
+	for _, event := range batch {
+		if publishErr = publish(event); publishErr != nil {
+			_, err := tx.Exec(`UPDATE outbox_events SET attempts = attempts + 1, last_error = $1 WHERE id = $2`,
+				publishErr.Error(), event.ID)
+			if err != nil {
+				return 0, fmt.Errorf("failed to record outbox failure: %w", err)
+			}
+			break
+		}
+
+		_, err := tx.Exec(`UPDATE outbox_events SET published_at = CURRENT_TIMESTAMP, attempts = attempts + 1, last_error = NULL WHERE id = $1`, event.ID)
+		if err != nil {
+			return 0, fmt.Errorf("failed to mark outbox event published: %w", err)
+		}
+		published++
+	}
+
+	if err := tx.Commit(); err != nil {
+		return 0, fmt.Errorf("failed to relay outbox: %w", err)
+	}
+	return published, publishErr
+}
+
+// PurgePublishedOutbox deletes events published more than olderThan ago
+func (db *DB) PurgePublishedOutbox(olderThan time.Duration) (int64, error) {
+	query := `DELETE FROM outbox_events WHERE published_at < CURRENT_TIMESTAMP - make_interval(secs => $1)`
+
+	result, err := db.Exec(query, olderThan.Seconds())
+	if err != nil {
+		return 0, fmt.Errorf("failed to purge outbox: %w", err)
+	}
+	return result.RowsAffected()
+}
@@ -0,0 +1,165 @@
+package database
+
+import (
+	"auth-service/internal/models"
+	"database/sql"
+	"fmt"
+	"time"
+
+	"golang.org/x/crypto/bcrypt"
+)
+
+// CreatePasswordResetToken stores the hash of a new reset token for the user,
+// replacing any unused one. It stores nothing and returns false when a token
+// was issued less than interval ago, so repeated requests do not flood the
+// user's inbox.
+func (db *DB) CreatePasswordResetToken(userID, tokenHash string, ttl, interval time.Duration) (bool, error) {
+	tx, err := db.Begin()
+	if err != nil {
+		return false, fmt.Errorf("failed to create reset token: %w", err)
+	}
+	defer tx.Rollback()
+
+	// Serialize requests for the same user so two cannot both pass the check
+	if _, err := tx.Exec(`SELECT id FROM users WHERE id = $1 FOR UPDATE`, userID); err != nil {
+		return false, fmt.Errorf("failed to create reset token: %w", err)
+	}
+
+	var recent bool
+	err = tx.QueryRow(`
+	SELECT EXISTS (
+		SELECT 1 FROM password_reset_tokens
+		WHERE user_id = $1 AND created_at > CURRENT_TIMESTAMP - make_interval(secs => $2)
+	)`, userID, interval.Seconds()).Scan(&recent)
+	if err != nil {
+		return false, fmt.Errorf("failed to create reset token: %w", err)
+	}
+	if recent {
+		return false, nil
+	}
+
+	if _, err := tx.Exec(`DELETE FROM password_reset_tokens WHERE user_id = $1 AND used_at IS NULL`, userID); err != nil {
+		return false, fmt.Errorf("failed to create reset token: %w", err)
+	}
+
+	query := `
+	INSERT INTO password_reset_tokens (user_id, token_hash, expires_at)
+	VALUES ($1, $2, CURRENT_TIMESTAMP + make_interval(secs => $3))`
+	if _, err := tx.Exec(query, userID, tokenHash, ttl.Seconds()); err != nil {
+		return false, fmt.Errorf("failed to create reset token: %w", err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return false, fmt.Errorf("failed to create reset token: %w", err)
+	}
+	return true, nil
+}
+
+// GetPasswordResetUser returns the user a valid reset token belongs to, so
+// the new password can be checked against the account before it is set
+func (db *DB) GetPasswordResetUser(tokenHash string) (*models.User, error) {
+	query := `
+	SELECT ` + userColumns + ` FROM users
+	WHERE id = (
+		SELECT user_id FROM password_reset_tokens
+		WHERE token_hash = $1 AND used_at IS NULL AND expires_at > CURRENT_TIMESTAMP
+	)`
+
+	user, err := scanUser(db.QueryRow(query, tokenHash))
+	if err == sql.ErrNoRows {
+		return nil, fmt.Errorf("invalid or expired reset token")
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get reset token: %w", err)
+	}
+	return user, nil
+}
+
+// ResetPassword consumes a valid reset token, sets the new password and
+// revokes every session of the user, all in one transaction
+func (db *DB) ResetPassword(tokenHash, password string) (*models.User, error) {
+	hashedPassword, err := bcrypt.GenerateFromPassword([]byte(password), bcrypt.DefaultCost)
+	if err != nil {
+		return nil, fmt.Errorf("failed to hash password: %w", err)
+	}
+
+	tx, err := db.Begin()
+	if err != nil {
+		return nil, fmt.Errorf("failed to reset password: %w", err)
+	}
+	defer tx.Rollback()
+
+	var userID string
+	err = tx.QueryRow(`
+	SELECT user_id FROM password_reset_tokens
+	WHERE token_hash = $1 AND used_at IS NULL AND expires_at > CURRENT_TIMESTAMP
+	FOR UPDATE`, tokenHash).Scan(&userID)
+	if err == sql.ErrNoRows {
+		return nil, fmt.Errorf("invalid or expired reset token")
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to reset password: %w", err)
+	}
+
+	user, err := scanUser(tx.QueryRow(`
+	UPDATE users SET password_hash = $1, updated_at = CURRENT_TIMESTAMP
+	WHERE id = $2
+	RETURNING `+userColumns, string(hashedPassword), userID))
+	if err != nil {
+		return nil, fmt.Errorf("failed to reset password: %w", err)
+	}
+
+	if _, err := tx.Exec(`UPDATE password_reset_tokens SET used_at = CURRENT_TIMESTAMP WHERE user_id = $1 AND used_at IS NULL`, userID); err != nil {
+		return nil, fmt.Errorf("failed to reset password: %w", err)
+	}
+	if _, err := tx.Exec(`UPDATE sessions SET revoked_at = CURRENT_TIMESTAMP WHERE user_id = $1 AND revoked_at IS NULL`, userID); err != nil {
+		return nil, fmt.Errorf("failed to revoke sessions: %w", err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, fmt.Errorf("failed to reset password: %w", err)
+	}
+	return user, nil
+}
+
+// ChangePassword sets a new password for the user and revokes their other
+// sessions, keeping keepSessionID, in one transaction
+func (db *DB) ChangePassword(userID, password, keepSessionID string) (*models.User, error) {
+	hashedPassword, err := bcrypt.GenerateFromPassword([]byte(password), bcrypt.DefaultCost)
+	if err != nil {
+		return nil, fmt.Errorf("failed to hash password: %w", err)
+	}
+
+	tx, err := db.Begin()
+	if err != nil {
+		return nil, fmt.Errorf("failed to change password: %w", err)
+	}
+	defer tx.Rollback()
+
+	user, err := scanUser(tx.QueryRow(`
+	UPDATE users SET password_hash = $1, updated_at = CURRENT_TIMESTAMP
+	WHERE id = $2
+	RETURNING `+userColumns, string(hashedPassword), userID))
+	if err == sql.ErrNoRows {
+		return nil, fmt.Errorf("user not found")
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to change password: %w", err)
+	}
+
+	// Outstanding reset links were requested for the old password
+	if _, err := tx.Exec(`UPDATE password_reset_tokens SET used_at = CURRENT_TIMESTAMP WHERE user_id = $1 AND used_at IS NULL`, userID); err != nil {
+		return nil, fmt.Errorf("failed to change password: %w", err)
+	}
+	_, err = tx.Exec(`
+	UPDATE sessions SET revoked_at = CURRENT_TIMESTAMP
+	WHERE user_id = $1 AND revoked_at IS NULL AND id::text <> $2`, userID, keepSessionID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to revoke sessions: %w", err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, fmt.Errorf("failed to change password: %w", err)
+	}
+	return user, nil
+}
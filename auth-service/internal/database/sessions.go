@@ -0,0 +1,57 @@
+package database
+
+import (
+	"fmt"
+	"regexp"
+	"time"
+)
+
+var uuidPattern = regexp.MustCompile(`^[0-9a-fA-F]{8}-[0-9a-fA-F]{4}-[0-9a-fA-F]{4}-[0-9a-fA-F]{4}-[0-9a-fA-F]{12}$`)
+
+// CreateSession starts a session lasting ttl and returns its ID
+func (db *DB) CreateSession(userID, ip, userAgent string, ttl time.Duration) (string, error) {
+	query := `
+	INSERT INTO sessions (user_id, ip, user_agent, expires_at)
+	VALUES ($1, $2, $3, CURRENT_TIMESTAMP + make_interval(secs => $4))
+	RETURNING id`
+
+	var id string
+	if err := db.QueryRow(query, userID, ip, userAgent, ttl.Seconds()).Scan(&id); err != nil {
+		return "", fmt.Errorf("failed to create session: %w", err)
+	}
+	return id, nil
+}
+
+// IsSessionActive reports whether the user's session exists, has not
+// expired and has not been revoked
+func (db *DB) IsSessionActive(sessionID, userID string) (bool, error) {
+	if !uuidPattern.MatchString(sessionID) {
+		return false, nil
+	}
+
+	query := `
+	SELECT EXISTS (
+		SELECT 1 FROM sessions
+		WHERE id = $1 AND user_id = $2 AND revoked_at IS NULL AND expires_at > CURRENT_TIMESTAMP
+	)`
+
+	var active bool
+	if err := db.QueryRow(query, sessionID, userID).Scan(&active); err != nil {
+		return false, fmt.Errorf("failed to check session: %w", err)
+	}
+	return active, nil
+}
+
+// RevokeSessions revokes the user's active sessions except keepSessionID,
+// which may be empty to revoke them all
+func (db *DB) RevokeSessions(userID, keepSessionID string) (int64, error) {
+	query := `
+	UPDATE sessions SET revoked_at = CURRENT_TIMESTAMP
+	WHERE user_id = $1 AND revoked_at IS NULL AND expires_at > CURRENT_TIMESTAMP AND id::text <> $2`
+
+	result, err := db.Exec(query, userID, keepSessionID)
+	if err != nil {
+		return 0, fmt.Errorf("failed to revoke sessions: %w", err)
+	}
+	return result.RowsAffected()
+}
@@ -0,0 +1,209 @@
+package handlers
+
+import (
+    "encoding/json"
+    "log"
+    "math"
+    "net/http"
+    "net/url"
+    "strconv"
+    "strings"
+    "auth-service/internal/models"
+    "auth-service/internal/notifier"
+    "auth-service/internal/passwords"
+    "golang.org/x/crypto/bcrypt"
+)
+
+// checkCurrentPassword confirms the signed-in user's password before a
+// credential change. Wrong guesses count towards the sign-in lockout, so a
+// stolen session cannot be used to guess the password.
+func (h *AuthHandler) checkCurrentPassword(w http.ResponseWriter, r *http.Request, user *models.User, password string) bool {
+    wait, err := h.guard.Check(user.Email, h.clientIP(r))
+    if err != nil {
+        http.Error(w, `{"error": "`+err.Error()+`"}`, http.StatusInternalServerError)
+        return false
+    }
+    if wait > 0 {
+        tooManyAttempts(w, wait)
+        return false
+    }
+
+    if err := bcrypt.CompareHashAndPassword([]byte(user.Password), []byte(password)); err != nil {
+        if result := h.recordFailure(r, user, user.Email); result.AccountLocked {
+            tooManyAttempts(w, result.RetryAfter)
+            return false
+        }
+        http.Error(w, `{"error": "Current password is incorrect"}`, http.StatusUnauthorized)
+        return false
+    }
+    return true
+}
+
+// ChangePassword sets a new password for the signed-in user and signs out
+// their other sessions
+func (h *AuthHandler) ChangePassword(w http.ResponseWriter, r *http.Request) {
+    user, claims, ok := h.currentSession(w, r)
+    if !ok {
+        return
+    }
+
+    var req models.ChangePasswordRequest
+    if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+        http.Error(w, `{"error": "Invalid request body"}`, http.StatusBadRequest)
+        return
+    }
+
+    if req.CurrentPassword == "" || req.NewPassword == "" {
+        http.Error(w, `{"error": "Current password and new password are required"}`, http.StatusBadRequest)
+        return
+    }
+
+    if !h.checkCurrentPassword(w, r, user, req.CurrentPassword) {
+        return
+    }
+
+    if req.NewPassword == req.CurrentPassword {
+        http.Error(w, `{"error": "New password must be different from the current password"}`, http.StatusBadRequest)
+        return
+    }
+    if !h.checkPassword(w, req.NewPassword, passwords.Person{Email: user.Email, FirstName: user.FirstName, LastName: user.LastName}) {
+        return
+    }
+
+    user, err := h.db.ChangePassword(user.ID, req.NewPassword, claims.Id)
+    if err != nil {
+        http.Error(w, `{"error": "`+err.Error()+`"}`, http.StatusInternalServerError)
+        return
+    }
+
+    if err := h.guard.Succeed(user.Email); err != nil {
+        log.Printf("⚠️ %v", err)
+    }
+    h.sendSecurityAlert(user, "security_password_changed", map[string]interface{}{
+        "ip":   h.clientIP(r),
+        "time": alertTime(),
+    })
+
+    w.Header().Set("Content-Type", "application/json")
+    response := models.MessageResponse{Message: "Password changed. Your other sessions have been signed out."}
+    if err := json.NewEncoder(w).Encode(response); err != nil {
+        http.Error(w, `{"error": "Error encoding response"}`, http.StatusInternalServerError)
+    }
+}
+
+// ChangeEmail starts moving the signed-in user to a new address. Nothing
+// changes until the link emailed to the new address is opened; the current
+// address is told about the request.
+func (h *AuthHandler) ChangeEmail(w http.ResponseWriter, r *http.Request) {
+    user, _, ok := h.currentSession(w, r)
+    if !ok {
+        return
+    }
+
+    var req models.ChangeEmailRequest
+    if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+        http.Error(w, `{"error": "Invalid request body"}`, http.StatusBadRequest)
+        return
+    }
+
+    req.NewEmail = strings.TrimSpace(req.NewEmail)
+    if req.NewEmail == "" || req.Password == "" {
+        http.Error(w, `{"error": "New email and password are required"}`, http.StatusBadRequest)
+        return
+    }
+    if !validEmail(req.NewEmail) {
+        http.Error(w, `{"error": "Invalid email address"}`, http.StatusBadRequest)
+        return
+    }
+    if strings.EqualFold(req.NewEmail, user.Email) {
+        http.Error(w, `{"error": "New email is the same as the current one"}`, http.StatusBadRequest)
+        return
+    }
+
+    if !h.checkCurrentPassword(w, r, user, req.Password) {
+        return
+    }
+
+    if _, err := h.db.GetUserByEmail(req.NewEmail); err == nil {
+        http.Error(w, `{"error": "Email address is already in use"}`, http.StatusConflict)
+        return
+    }
+
+    token, tokenHash := newSecretToken()
+    retryAfter, err := h.db.CreateEmailChangeToken(user.ID, req.NewEmail, tokenHash, h.verification.limits)
+    if err != nil {
+        http.Error(w, `{"error": "`+err.Error()+`"}`, http.StatusInternalServerError)
+        return
+    }
+    if retryAfter > 0 {
+        w.Header().Set("Retry-After", strconv.Itoa(int(math.Ceil(retryAfter.Seconds()))))
+        http.Error(w, `{"error": "Too many confirmation emails requested. Try again later."}`, http.StatusTooManyRequests)
+        return
+    }
+
+    h.notifier.Send(notifier.Notification{
+        UserID:   user.ID,
+        Type:     "email",
+        Template: "verify_email_change",
+        Data: map[string]interface{}{
+            "email":         req.NewEmail,
+            "first_name":    user.FirstName,
+            "new_email":     req.NewEmail,
+            "verify_url":    h.verification.changeURL + "?token=" + url.QueryEscape(token),
+            "expires_hours": int(math.Ceil(h.verification.limits.TTL.Hours())),
+        },
+        Transactional: true,
+    })
+    h.sendSecurityAlert(user, "security_email_change_requested", map[string]interface{}{
+        "new_email": req.NewEmail,
+        "ip":        h.clientIP(r),
+        "time":      alertTime(),
+    })
+
+    w.Header().Set("Content-Type", "application/json")
+    w.WriteHeader(http.StatusAccepted)
+    response := models.MessageResponse{Message: "Open the link sent to your new email address to confirm the change"}
+    if err := json.NewEncoder(w).Encode(response); err != nil {
+        http.Error(w, `{"error": "Error encoding response"}`, http.StatusInternalServerError)
+    }
+}
+
+// ConfirmEmailChange switches the account to the new address with the token
+// from the confirmation email, and tells the previous address
+func (h *AuthHandler) ConfirmEmailChange(w http.ResponseWriter, r *http.Request) {
+    token := r.URL.Query().Get("token")
+    if token == "" {
+        http.Error(w, `{"error": "Token is required"}`, http.StatusBadRequest)
+        return
+    }
+
+    user, oldEmail, err := h.db.ConfirmEmailChange(hashToken(token))
+    if err != nil {
+        switch err.Error() {
+        case "invalid or expired email change token":
+            http.Error(w, `{"error": "Invalid or expired email change token"}`, http.StatusBadRequest)
+        case "email already in use":
+            http.Error(w, `{"error": "Email address is already in use"}`, http.StatusConflict)
+        default:
+            http.Error(w, `{"error": "`+err.Error()+`"}`, http.StatusInternalServerError)
+        }
+        return
+    }
+
+    // The alert goes to the previous address, the one that may have been
+    // taken over
+    previous := *user
+    previous.Email = oldEmail
+    h.sendSecurityAlert(&previous, "security_email_changed", map[string]interface{}{
+        "old_email": oldEmail,
+        "new_email": user.Email,
+        "ip":        h.clientIP(r),
+        "time":      alertTime(),
+    })
+
+    w.Header().Set("Content-Type", "application/json")
+    response := models.MessageResponse{Message: "Email address changed. Refresh your token to use the new address."}
+    if err := json.NewEncoder(w).Encode(response); err != nil {
+        http.Error(w, `{"error": "Error encoding response"}`, http.StatusInternalServerError)
+    }
+}
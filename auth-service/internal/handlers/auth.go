@@ -2,10 +2,16 @@ package handlers
 
 import (
     "encoding/json"
+    "log"
     "net/http"
+    "strings"
     "auth-service/internal/database"
     "auth-service/internal/models"
     "auth-service/internal/jwt" 
+    "auth-service/internal/lockout"
+    "auth-service/internal/mfa"
+    "auth-service/internal/notifier"
+    "auth-service/internal/passwords"
     "time"
     "golang.org/x/crypto/bcrypt"
 )
@@ -13,12 +19,26 @@ import (
 type AuthHandler struct {
     db        *database.DB
     jwtService *jwt.JWTService 
+    notifier   *notifier.Client
+    keyring    *mfa.Keyring
+    guard      *lockout.Guard
+    passwords  *passwords.Validator
+    passwordReset passwordResetConfig
+    verification  verificationConfig
+    proxies       *trustedProxies
 }
 
-func NewAuthHandler(db *database.DB) *AuthHandler {
+func NewAuthHandler(db *database.DB, n *notifier.Client, keyring *mfa.Keyring, guard *lockout.Guard, validator *passwords.Validator) *AuthHandler {
     return &AuthHandler{
         db:        db,
         jwtService: jwt.NewJWTService(), 
+        notifier:   n,
+        keyring:    keyring,
+        guard:      guard,
+        passwords:  validator,
+        passwordReset: passwordResetConfigFromEnv(),
+        verification:  verificationConfigFromEnv(),
+        proxies:       trustedProxiesFromEnv(),
     }
 }
 
@@ -35,6 +55,16 @@ func (h *AuthHandler) Signup(w http.ResponseWriter, r *http.Request) {
         return
     }
 
+    req.Email = strings.TrimSpace(req.Email)
+    if !validEmail(req.Email) {
+        http.Error(w, `{"error": "Invalid email address"}`, http.StatusBadRequest)
+        return
+    }
+
+    if !h.checkPassword(w, req.Password, passwords.Person{Email: req.Email, FirstName: req.FirstName, LastName: req.LastName}) {
+        return
+    }
+
     // Check if user already exists
     _, err := h.db.GetUserByEmail(req.Email)
     if err == nil {
@@ -53,8 +83,18 @@ func (h *AuthHandler) Signup(w http.ResponseWriter, r *http.Request) {
         return
     }
 
+    // The signup device is the first known one, so signing in from it later
+    // raises no alert. The welcome notification follows the user.signed_up event.
+    if _, _, err := h.db.RecordLogin(user.ID, h.clientIP(r), r.UserAgent()); err != nil {
+        log.Printf("⚠️ %v", err)
+    }
+
+    if _, err := h.sendVerificationEmail(user); err != nil {
+        log.Printf("❌ Failed to send verification email to user %s: %v", user.ID, err)
+    }
+
     // Generate JWT token instead of dummy token
-    token, err := h.jwtService.GenerateToken(user.ID, user.Email)
+    token, err := h.issueToken(user, r)
     if err != nil {
         http.Error(w, `{"error": "Failed to generate token"}`, http.StatusInternalServerError)
         return
@@ -85,21 +125,49 @@ func (h *AuthHandler) Login(w http.ResponseWriter, r *http.Request) {
         return
     }
 
+    // Delayed and locked-out attempts are refused before the password is
+    // checked, so guessing gets no answer at all
+    wait, err := h.guard.Check(req.Email, h.clientIP(r))
+    if err != nil {
+        http.Error(w, `{"error": "`+err.Error()+`"}`, http.StatusInternalServerError)
+        return
+    }
+    if wait > 0 {
+        tooManyAttempts(w, wait)
+        return
+    }
+
     user, err := h.db.GetUserByEmail(req.Email)
     if err != nil {
-        http.Error(w, `{"error": "Invalid email or password"}`, http.StatusUnauthorized)
+        if err.Error() != "user not found" {
+            http.Error(w, `{"error": "`+err.Error()+`"}`, http.StatusInternalServerError)
+            return
+        }
+        bcrypt.CompareHashAndPassword(dummyPasswordHash, []byte(req.Password))
+        h.loginFailed(w, r, nil, req.Email)
         return
     }
 
     // Verify password
     err = bcrypt.CompareHashAndPassword([]byte(user.Password), []byte(req.Password))
     if err != nil {
-        http.Error(w, `{"error": "Invalid email or password"}`, http.StatusUnauthorized)
+        h.loginFailed(w, r, user, req.Email)
         return
     }
 
+    // With MFA the password only opens a challenge; the session is created
+    // by LoginMFA, and failures are only forgotten once it is passed
+    if user.MFAEnabled {
+        h.startMFAChallenge(w, user)
+        return
+    }
+    if err := h.guard.Succeed(req.Email); err != nil {
+        log.Printf("⚠️ %v", err)
+    }
+    h.checkNewLogin(user, r)
+
     // Generate real JWT token
-    token, err := h.jwtService.GenerateToken(user.ID, user.Email)
+    token, err := h.issueToken(user, r)
     if err != nil {
         http.Error(w, `{"error": "Failed to generate token"}`, http.StatusInternalServerError)
         return
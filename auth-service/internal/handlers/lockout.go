@@ -0,0 +1,122 @@
+package handlers
+
+import (
+    "crypto/subtle"
+    "encoding/json"
+    "log"
+    "math"
+    "net/http"
+    "os"
+    "strconv"
+    "time"
+    "auth-service/internal/lockout"
+    "auth-service/internal/models"
+    "golang.org/x/crypto/bcrypt"
+)
+
+// dummyPasswordHash is compared against when no account has the email, so
+// an unknown address takes as long to reject as a wrong password
+var dummyPasswordHash, _ = bcrypt.GenerateFromPassword([]byte("no account has this password"), bcrypt.DefaultCost)
+
+// loginFailed records a failed sign-in and writes the response. user is nil
+// when no account has the email; the response is the same either way.
+func (h *AuthHandler) loginFailed(w http.ResponseWriter, r *http.Request, user *models.User, email string) {
+    if result := h.recordFailure(r, user, email); result.AccountLocked {
+        tooManyAttempts(w, result.RetryAfter)
+        return
+    }
+    http.Error(w, `{"error": "Invalid email or password"}`, http.StatusUnauthorized)
+}
+
+// recordFailure counts a failed password or code against the account and
+// the caller's address, alerting the user when it locks their account
+func (h *AuthHandler) recordFailure(r *http.Request, user *models.User, email string) lockout.Result {
+    ip := h.clientIP(r)
+    result, err := h.guard.Fail(email, ip)
+    if err != nil {
+        log.Printf("❌ Failed to record failed login: %v", err)
+    }
+
+    if result.AccountLocked && user != nil {
+        h.sendSecurityAlert(user, "security_account_locked", map[string]interface{}{
+            "attempts": result.Failures,
+            "ip":       ip,
+            "time":     alertTime(),
+            "minutes":  int(math.Ceil(result.RetryAfter.Minutes())),
+        })
+    }
+    return result
+}
+
+// tooManyAttempts refuses a sign-in while its account or address is delayed
+// or locked out
+func tooManyAttempts(w http.ResponseWriter, wait time.Duration) {
+    w.Header().Set("Retry-After", strconv.Itoa(int(math.Ceil(wait.Seconds()))))
+    http.Error(w, `{"error": "Too many failed sign-in attempts. Try again later."}`, http.StatusTooManyRequests)
+}
+
+// AdminMiddleware guards operator endpoints with the X-Admin-Token header.
+// The endpoints stay disabled when ADMIN_TOKEN is not configured.
+func AdminMiddleware(next http.Handler) http.Handler {
+    return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+        token := os.Getenv("ADMIN_TOKEN")
+        if token == "" {
+            http.Error(w, `{"error": "Admin endpoints are disabled"}`, http.StatusForbidden)
+            return
+        }
+        if subtle.ConstantTimeCompare([]byte(r.Header.Get("X-Admin-Token")), []byte(token)) != 1 {
+            http.Error(w, `{"error": "Invalid admin token"}`, http.StatusUnauthorized)
+            return
+        }
+        next.ServeHTTP(w, r)
+    })
+}
+
+// GetLockoutStatus shows the failed sign-ins recorded for ?email= and ?ip=
+func (h *AuthHandler) GetLockoutStatus(w http.ResponseWriter, r *http.Request) {
+    email := r.URL.Query().Get("email")
+    ip := r.URL.Query().Get("ip")
+    if email == "" && ip == "" {
+        http.Error(w, `{"error": "email or ip is required"}`, http.StatusBadRequest)
+        return
+    }
+
+    account, address, err := h.guard.Status(email, ip)
+    if err != nil {
+        http.Error(w, `{"error": "`+err.Error()+`"}`, http.StatusInternalServerError)
+        return
+    }
+
+    w.Header().Set("Content-Type", "application/json")
+    response := models.LockoutStatusResponse{Account: account, IP: address}
+    if err := json.NewEncoder(w).Encode(response); err != nil {
+        http.Error(w, `{"error": "Error encoding response"}`, http.StatusInternalServerError)
+    }
+}
+
+// UnlockLogin clears the failed sign-ins and any lockout of an account, an
+// address or both
+func (h *AuthHandler) UnlockLogin(w http.ResponseWriter, r *http.Request) {
+    var req models.UnlockRequest
+    if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+        http.Error(w, `{"error": "Invalid request body"}`, http.StatusBadRequest)
+        return
+    }
+
+    if req.Email == "" && req.IP == "" {
+        http.Error(w, `{"error": "email or ip is required"}`, http.StatusBadRequest)
+        return
+    }
+
+    if err := h.guard.Unlock(req.Email, req.IP); err != nil {
+        http.Error(w, `{"error": "`+err.Error()+`"}`, http.StatusInternalServerError)
+        return
+    }
+    log.Printf("🔓 Unlocked sign-ins for email %q ip %q", req.Email, req.IP)
+
+    w.Header().Set("Content-Type", "application/json")
+    response := models.MessageResponse{Message: "Sign-in unlocked"}
+    if err := json.NewEncoder(w).Encode(response); err != nil {
+        http.Error(w, `{"error": "Error encoding response"}`, http.StatusInternalServerError)
+    }
+}
@@ -0,0 +1,374 @@
+package handlers
+
+import (
+    "encoding/json"
+    "log"
+    "net/http"
+    "os"
+    "time"
+    "auth-service/internal/mfa"
+    "auth-service/internal/models"
+    "golang.org/x/crypto/bcrypt"
+)
+
+const (
+    // mfaChallengeTTL is how long the second login step may take
+    mfaChallengeTTL = 5 * time.Minute
+    // mfaChallengeAttempts caps the codes tried against one challenge
+    mfaChallengeAttempts = 5
+    recoveryCodeCount    = 10
+)
+
+// mfaIssuer is the account label authenticator apps show, from MFA_ISSUER
+func mfaIssuer() string {
+    if issuer := os.Getenv("MFA_ISSUER"); issuer != "" {
+        return issuer
+    }
+    return "TaskManager"
+}
+
+// startMFAChallenge answers a correct password for an account with MFA. No
+// session exists until the challenge is passed at /api/v1/auth/login/mfa.
+func (h *AuthHandler) startMFAChallenge(w http.ResponseWriter, user *models.User) {
+    token, tokenHash := newSecretToken()
+    expiresAt, err := h.db.CreateMFAChallenge(user.ID, tokenHash, mfaChallengeTTL)
+    if err != nil {
+        http.Error(w, `{"error": "`+err.Error()+`"}`, http.StatusInternalServerError)
+        return
+    }
+
+    response := models.MFAChallengeResponse{
+        MFARequired:    true,
+        ChallengeToken: token,
+        ExpiresAt:      expiresAt,
+    }
+
+    w.Header().Set("Content-Type", "application/json")
+    if err := json.NewEncoder(w).Encode(response); err != nil {
+        http.Error(w, `{"error": "Error encoding response"}`, http.StatusInternalServerError)
+    }
+}
+
+// LoginMFA exchanges a challenge token and a TOTP or recovery code for a
+// session token
+func (h *AuthHandler) LoginMFA(w http.ResponseWriter, r *http.Request) {
+    var req models.MFALoginRequest
+    if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+        http.Error(w, `{"error": "Invalid request body"}`, http.StatusBadRequest)
+        return
+    }
+
+    if req.ChallengeToken == "" || (req.Code == "" && req.RecoveryCode == "") {
+        http.Error(w, `{"error": "Challenge token and a code or recovery code are required"}`, http.StatusBadRequest)
+        return
+    }
+
+    challengeHash := hashToken(req.ChallengeToken)
+    userID, err := h.db.AttemptMFAChallenge(challengeHash, mfaChallengeAttempts)
+    if err != nil {
+        if err.Error() == "invalid or expired MFA challenge" {
+            http.Error(w, `{"error": "Invalid or expired MFA challenge"}`, http.StatusUnauthorized)
+            return
+        }
+        http.Error(w, `{"error": "`+err.Error()+`"}`, http.StatusInternalServerError)
+        return
+    }
+
+    user, err := h.db.GetUserByID(userID)
+    if err != nil {
+        http.Error(w, `{"error": "`+err.Error()+`"}`, http.StatusInternalServerError)
+        return
+    }
+
+    // Wrong codes count towards the same lockout as wrong passwords, since
+    // each challenge allows several guesses
+    wait, err := h.guard.Check(user.Email, h.clientIP(r))
+    if err != nil {
+        http.Error(w, `{"error": "`+err.Error()+`"}`, http.StatusInternalServerError)
+        return
+    }
+    if wait > 0 {
+        tooManyAttempts(w, wait)
+        return
+    }
+
+    valid, err := h.verifyMFA(userID, req.Code, req.RecoveryCode)
+    if err != nil {
+        http.Error(w, `{"error": "`+err.Error()+`"}`, http.StatusInternalServerError)
+        return
+    }
+    if !valid {
+        if result := h.recordFailure(r, user, user.Email); result.AccountLocked {
+            tooManyAttempts(w, result.RetryAfter)
+            return
+        }
+        http.Error(w, `{"error": "Invalid code"}`, http.StatusUnauthorized)
+        return
+    }
+
+    if err := h.db.CompleteMFAChallenge(challengeHash); err != nil {
+        http.Error(w, `{"error": "`+err.Error()+`"}`, http.StatusInternalServerError)
+        return
+    }
+    if err := h.guard.Succeed(user.Email); err != nil {
+        log.Printf("⚠️ %v", err)
+    }
+    h.checkNewLogin(user, r)
+
+    token, err := h.issueToken(user, r)
+    if err != nil {
+        http.Error(w, `{"error": "Failed to generate token"}`, http.StatusInternalServerError)
+        return
+    }
+
+    response := models.AuthResponse{
+        User:  user,
+        Token: token,
+    }
+
+    w.Header().Set("Content-Type", "application/json")
+    if err := json.NewEncoder(w).Encode(response); err != nil {
+        http.Error(w, `{"error": "Error encoding response"}`, http.StatusInternalServerError)
+    }
+}
+
+// EnrollMFA generates a new secret for the signed-in user. It only takes
+// effect once a code from it is confirmed.
+func (h *AuthHandler) EnrollMFA(w http.ResponseWriter, r *http.Request) {
+    user, _, ok := h.currentSession(w, r)
+    if !ok {
+        return
+    }
+
+    if user.MFAEnabled {
+        http.Error(w, `{"error": "Two-factor authentication is already enabled"}`, http.StatusConflict)
+        return
+    }
+
+    enrollment, err := mfa.NewEnrollment(mfaIssuer(), user.Email)
+    if err != nil {
+        http.Error(w, `{"error": "`+err.Error()+`"}`, http.StatusInternalServerError)
+        return
+    }
+
+    if err := h.db.SetPendingMFASecret(user.ID, h.keyring.Seal(enrollment.Secret)); err != nil {
+        http.Error(w, `{"error": "`+err.Error()+`"}`, http.StatusInternalServerError)
+        return
+    }
+
+    response := models.MFAEnrollResponse{
+        Secret:     enrollment.Secret,
+        OTPAuthURI: enrollment.URI,
+        QRCode:     enrollment.QRCode,
+    }
+
+    w.Header().Set("Content-Type", "application/json")
+    if err := json.NewEncoder(w).Encode(response); err != nil {
+        http.Error(w, `{"error": "Error encoding response"}`, http.StatusInternalServerError)
+    }
+}
+
+// ConfirmMFA turns on MFA once the user proves their app has the pending
+// secret, and returns the recovery codes
+func (h *AuthHandler) ConfirmMFA(w http.ResponseWriter, r *http.Request) {
+    user, _, ok := h.currentSession(w, r)
+    if !ok {
+        return
+    }
+
+    var req models.MFAConfirmRequest
+    if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+        http.Error(w, `{"error": "Invalid request body"}`, http.StatusBadRequest)
+        return
+    }
+
+    if req.Code == "" {
+        http.Error(w, `{"error": "Code is required"}`, http.StatusBadRequest)
+        return
+    }
+
+    _, pending, err := h.db.GetMFASecrets(user.ID)
+    if err != nil {
+        http.Error(w, `{"error": "`+err.Error()+`"}`, http.StatusInternalServerError)
+        return
+    }
+    if pending == "" {
+        http.Error(w, `{"error": "No two-factor enrollment in progress"}`, http.StatusBadRequest)
+        return
+    }
+
+    secret, err := h.keyring.Open(pending)
+    if err != nil {
+        http.Error(w, `{"error": "`+err.Error()+`"}`, http.StatusInternalServerError)
+        return
+    }
+
+    step, valid := mfa.MatchCode(secret, req.Code, time.Now())
+    if !valid {
+        http.Error(w, `{"error": "Invalid code"}`, http.StatusBadRequest)
+        return
+    }
+
+    codes := mfa.GenerateRecoveryCodes(recoveryCodeCount)
+    if err := h.db.EnableMFA(user.ID, step, h.hashRecoveryCodes(codes)); err != nil {
+        if err.Error() == "no MFA enrollment in progress" {
+            http.Error(w, `{"error": "No two-factor enrollment in progress"}`, http.StatusBadRequest)
+            return
+        }
+        http.Error(w, `{"error": "`+err.Error()+`"}`, http.StatusInternalServerError)
+        return
+    }
+
+    h.sendSecurityAlert(user, "security_mfa_changed", map[string]interface{}{
+        "enabled": true,
+        "ip":      h.clientIP(r),
+        "time":    alertTime(),
+    })
+
+    w.Header().Set("Content-Type", "application/json")
+    response := models.RecoveryCodesResponse{RecoveryCodes: codes}
+    if err := json.NewEncoder(w).Encode(response); err != nil {
+        http.Error(w, `{"error": "Error encoding response"}`, http.StatusInternalServerError)
+    }
+}
+
+// RegenerateRecoveryCodes replaces the user's recovery codes. A current TOTP
+// code is required so a stolen session cannot read out new ones.
+func (h *AuthHandler) RegenerateRecoveryCodes(w http.ResponseWriter, r *http.Request) {
+    user, _, ok := h.currentSession(w, r)
+    if !ok {
+        return
+    }
+
+    var req models.MFAConfirmRequest
+    if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+        http.Error(w, `{"error": "Invalid request body"}`, http.StatusBadRequest)
+        return
+    }
+
+    if !user.MFAEnabled {
+        http.Error(w, `{"error": "Two-factor authentication is not enabled"}`, http.StatusBadRequest)
+        return
+    }
+    if req.Code == "" {
+        http.Error(w, `{"error": "Code is required"}`, http.StatusBadRequest)
+        return
+    }
+
+    valid, err := h.verifyMFA(user.ID, req.Code, "")
+    if err != nil {
+        http.Error(w, `{"error": "`+err.Error()+`"}`, http.StatusInternalServerError)
+        return
+    }
+    if !valid {
+        http.Error(w, `{"error": "Invalid code"}`, http.StatusBadRequest)
+        return
+    }
+
+    codes := mfa.GenerateRecoveryCodes(recoveryCodeCount)
+    if err := h.db.ReplaceRecoveryCodes(user.ID, h.hashRecoveryCodes(codes)); err != nil {
+        http.Error(w, `{"error": "`+err.Error()+`"}`, http.StatusInternalServerError)
+        return
+    }
+
+    w.Header().Set("Content-Type", "application/json")
+    response := models.RecoveryCodesResponse{RecoveryCodes: codes}
+    if err := json.NewEncoder(w).Encode(response); err != nil {
+        http.Error(w, `{"error": "Error encoding response"}`, http.StatusInternalServerError)
+    }
+}
+
+// DisableMFA turns MFA off after checking the password and a TOTP or
+// recovery code
+func (h *AuthHandler) DisableMFA(w http.ResponseWriter, r *http.Request) {
+    user, _, ok := h.currentSession(w, r)
+    if !ok {
+        return
+    }
+
+    var req models.MFADisableRequest
+    if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+        http.Error(w, `{"error": "Invalid request body"}`, http.StatusBadRequest)
+        return
+    }
+
+    if !user.MFAEnabled {
+        http.Error(w, `{"error": "Two-factor authentication is not enabled"}`, http.StatusBadRequest)
+        return
+    }
+    if req.Password == "" || (req.Code == "" && req.RecoveryCode == "") {
+        http.Error(w, `{"error": "Password and a code or recovery code are required"}`, http.StatusBadRequest)
+        return
+    }
+
+    if err := bcrypt.CompareHashAndPassword([]byte(user.Password), []byte(req.Password)); err != nil {
+        http.Error(w, `{"error": "Invalid password"}`, http.StatusUnauthorized)
+        return
+    }
+
+    valid, err := h.verifyMFA(user.ID, req.Code, req.RecoveryCode)
+    if err != nil {
+        http.Error(w, `{"error": "`+err.Error()+`"}`, http.StatusInternalServerError)
+        return
+    }
+    if !valid {
+        http.Error(w, `{"error": "Invalid code"}`, http.StatusBadRequest)
+        return
+    }
+
+    if err := h.db.DisableMFA(user.ID); err != nil {
+        http.Error(w, `{"error": "`+err.Error()+`"}`, http.StatusInternalServerError)
+        return
+    }
+
+    h.sendSecurityAlert(user, "security_mfa_changed", map[string]interface{}{
+        "enabled": false,
+        "ip":      h.clientIP(r),
+        "time":    alertTime(),
+    })
+
+    w.Header().Set("Content-Type", "application/json")
+    response := models.MessageResponse{Message: "Two-factor authentication has been disabled"}
+    if err := json.NewEncoder(w).Encode(response); err != nil {
+        http.Error(w, `{"error": "Error encoding response"}`, http.StatusInternalServerError)
+    }
+}
+
+// verifyMFA checks a TOTP code, or a recovery code when one is given, and
+// spends it so it cannot be used again
+func (h *AuthHandler) verifyMFA(userID, code, recoveryCode string) (bool, error) {
+    if recoveryCode != "" {
+        used, err := h.db.UseRecoveryCode(userID, h.keyring.HashRecoveryCode(recoveryCode))
+        if used {
+            log.Printf("🔑 User %s used a recovery code", userID)
+        }
+        return used, err
+    }
+
+    sealed, _, err := h.db.GetMFASecrets(userID)
+    if err != nil {
+        return false, err
+    }
+    if sealed == "" {
+        return false, nil
+    }
+
+    secret, err := h.keyring.Open(sealed)
+    if err != nil {
+        return false, err
+    }
+
+    step, valid := mfa.MatchCode(secret, code, time.Now())
+    if !valid {
+        return false, nil
+    }
+    return h.db.UseMFAStep(userID, step)
+}
+
+func (h *AuthHandler) hashRecoveryCodes(codes []string) []string {
+    hashes := make([]string, len(codes))
+    for i, code := range codes {
+        hashes[i] = h.keyring.HashRecoveryCode(code)
+    }
+    return hashes
+}
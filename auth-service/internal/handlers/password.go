@@ -0,0 +1,188 @@
+package handlers
+
+import (
+    "crypto/rand"
+    "crypto/sha256"
+    "encoding/base64"
+    "encoding/hex"
+    "encoding/json"
+    "log"
+    "net/http"
+    "net/url"
+    "os"
+    "time"
+    "auth-service/internal/models"
+    "auth-service/internal/notifier"
+    "auth-service/internal/passwords"
+)
+
+// passwordResetConfig reads PASSWORD_RESET_URL, the page the emailed link
+// opens with ?token= appended, and PASSWORD_RESET_TTL (default 1h)
+type passwordResetConfig struct {
+    url      string
+    ttl      time.Duration
+    interval time.Duration
+}
+
+func passwordResetConfigFromEnv() passwordResetConfig {
+    config := passwordResetConfig{
+        url:      os.Getenv("PASSWORD_RESET_URL"),
+        ttl:      time.Hour,
+        interval: time.Minute,
+    }
+    if config.url == "" {
+        config.url = "http://localhost/reset-password"
+    }
+    if value, err := time.ParseDuration(os.Getenv("PASSWORD_RESET_TTL")); err == nil && value > 0 {
+        config.ttl = value
+    }
+    return config
+}
+
+// ForgotPassword emails a reset link when the address belongs to an
+// account. The response is the same either way so it cannot be used to find
+// out which addresses are registered.
+func (h *AuthHandler) ForgotPassword(w http.ResponseWriter, r *http.Request) {
+    var req models.ForgotPasswordRequest
+    if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+        http.Error(w, `{"error": "Invalid request body"}`, http.StatusBadRequest)
+        return
+    }
+
+    if req.Email == "" {
+        http.Error(w, `{"error": "Email is required"}`, http.StatusBadRequest)
+        return
+    }
+
+    // The token is created in the background so the response takes as long
+    // for a registered address as for an unknown one
+    if user, err := h.db.GetUserByEmail(req.Email); err == nil {
+        go h.sendPasswordReset(user)
+    }
+
+    w.Header().Set("Content-Type", "application/json")
+    w.WriteHeader(http.StatusAccepted)
+    response := models.MessageResponse{Message: "If an account exists for that email, a password reset link has been sent"}
+    if err := json.NewEncoder(w).Encode(response); err != nil {
+        http.Error(w, `{"error": "Error encoding response"}`, http.StatusInternalServerError)
+    }
+}
+
+func (h *AuthHandler) sendPasswordReset(user *models.User) {
+    token, tokenHash := newSecretToken()
+    created, err := h.db.CreatePasswordResetToken(user.ID, tokenHash, h.passwordReset.ttl, h.passwordReset.interval)
+    if err != nil {
+        log.Printf("❌ %v", err)
+        return
+    }
+    if !created {
+        return
+    }
+
+    h.notifier.Send(notifier.Notification{
+        UserID:   user.ID,
+        Type:     "email",
+        Template: "password_reset",
+        Data: map[string]interface{}{
+            "email":           user.Email,
+            "first_name":      user.FirstName,
+            "reset_url":       h.passwordReset.url + "?token=" + url.QueryEscape(token),
+            "expires_minutes": int(h.passwordReset.ttl.Minutes()),
+        },
+        Transactional: true,
+    })
+}
+
+// ResetPassword sets a new password with a token from the reset email and
+// signs the user out everywhere
+func (h *AuthHandler) ResetPassword(w http.ResponseWriter, r *http.Request) {
+    var req models.ResetPasswordRequest
+    if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+        http.Error(w, `{"error": "Invalid request body"}`, http.StatusBadRequest)
+        return
+    }
+
+    if req.Token == "" || req.Password == "" {
+        http.Error(w, `{"error": "Token and password are required"}`, http.StatusBadRequest)
+        return
+    }
+
+    tokenHash := hashToken(req.Token)
+    user, err := h.db.GetPasswordResetUser(tokenHash)
+    if err != nil {
+        if err.Error() == "invalid or expired reset token" {
+            http.Error(w, `{"error": "Invalid or expired reset token"}`, http.StatusBadRequest)
+            return
+        }
+        http.Error(w, `{"error": "`+err.Error()+`"}`, http.StatusInternalServerError)
+        return
+    }
+
+    if !h.checkPassword(w, req.Password, passwords.Person{Email: user.Email, FirstName: user.FirstName, LastName: user.LastName}) {
+        return
+    }
+
+    user, err = h.db.ResetPassword(tokenHash, req.Password)
+    if err != nil {
+        if err.Error() == "invalid or expired reset token" {
+            http.Error(w, `{"error": "Invalid or expired reset token"}`, http.StatusBadRequest)
+            return
+        }
+        http.Error(w, `{"error": "`+err.Error()+`"}`, http.StatusInternalServerError)
+        return
+    }
+
+    // Whoever reset the password owns the account, so a lockout is lifted
+    if err := h.guard.Unlock(user.Email, ""); err != nil {
+        log.Printf("⚠️ %v", err)
+    }
+    h.sendSecurityAlert(user, "security_password_changed", map[string]interface{}{
+        "ip":   h.clientIP(r),
+        "time": alertTime(),
+    })
+
+    w.Header().Set("Content-Type", "application/json")
+    response := models.MessageResponse{Message: "Password has been reset. Sign in with your new password."}
+    if err := json.NewEncoder(w).Encode(response); err != nil {
+        http.Error(w, `{"error": "Error encoding response"}`, http.StatusInternalServerError)
+    }
+}
+
+// newSecretToken returns a random URL-safe token and the hash that is stored
+// in its place
+func newSecretToken() (string, string) {
+    b := make([]byte, 32)
+    rand.Read(b)
+    token := base64.RawURLEncoding.EncodeToString(b)
+    return token, hashToken(token)
+}
+
+func hashToken(token string) string {
+    hash := sha256.Sum256([]byte(token))
+    return hex.EncodeToString(hash[:])
+}
+
+// checkPassword applies the password policy, writing a response listing
+// every failed rule when the password is rejected
+func (h *AuthHandler) checkPassword(w http.ResponseWriter, password string, person passwords.Person) bool {
+    violations := h.passwords.Validate(password, person)
+    if len(violations) == 0 {
+        return true
+    }
+
+    w.Header().Set("Content-Type", "application/json")
+    w.WriteHeader(http.StatusBadRequest)
+    response := models.PasswordPolicyError{Error: "Password does not meet the requirements", Violations: violations}
+    if err := json.NewEncoder(w).Encode(response); err != nil {
+        http.Error(w, `{"error": "Error encoding response"}`, http.StatusInternalServerError)
+    }
+    return false
+}
+
+// GetPasswordPolicy describes the rules new passwords must meet
+func (h *AuthHandler) GetPasswordPolicy(w http.ResponseWriter, r *http.Request) {
+    w.Header().Set("Content-Type", "application/json")
+    if err := json.NewEncoder(w).Encode(h.passwords.Describe()); err != nil {
+        http.Error(w, `{"error": "Error encoding response"}`, http.StatusInternalServerError)
+    }
+}
@@ -0,0 +1,171 @@
+package handlers
+
+import (
+    "log"
+    "net"
+    "net/http"
+    "os"
+    "strings"
+    "sync"
+    "time"
+    "auth-service/internal/models"
+    "auth-service/internal/notifier"
+)
+
+// checkNewLogin records where a successful sign-in came from and alerts the
+// user when the device or address is new for their account
+func (h *AuthHandler) checkNewLogin(user *models.User, r *http.Request) {
+    ip, userAgent := h.clientIP(r), r.UserAgent()
+    newDevice, newIP, err := h.db.RecordLogin(user.ID, ip, userAgent)
+    if err != nil {
+        log.Printf("⚠️ %v", err)
+        return
+    }
+    if !newDevice && !newIP {
+        return
+    }
+
+    h.sendSecurityAlert(user, "security_new_login", map[string]interface{}{
+        "device":     deviceName(userAgent),
+        "ip":         ip,
+        "time":       alertTime(),
+        "new_device": newDevice,
+    })
+}
+
+// sendSecurityAlert notifies the user in the app and by email. Alerts are
+// transactional so preferences, digests and quiet hours cannot hold them back.
+func (h *AuthHandler) sendSecurityAlert(user *models.User, template string, data map[string]interface{}) {
+    h.notifier.Send(notifier.Notification{UserID: user.ID, Type: "in_app", Template: template, Data: data, Transactional: true})
+
+    emailData := map[string]interface{}{"email": user.Email}
+    for key, value := range data {
+        emailData[key] = value
+    }
+    h.notifier.Send(notifier.Notification{UserID: user.ID, Type: "email", Template: template, Data: emailData, Transactional: true})
+}
+
+// trustedProxies reads TRUSTED_PROXIES, a comma-separated list of the IP
+// addresses, CIDR ranges or host names of the proxies in front of the
+// service (the gateway and the frontend's nginx). Only they may say who the
+// client is; with none configured X-Forwarded-For is ignored. Host names are
+// resolved again every minute so a proxy that restarts with a new address is
+// still recognised.
+type trustedProxies struct {
+    nets  []*net.IPNet
+    hosts []string
+    now   func() time.Time
+
+    mu         sync.Mutex
+    resolved   []net.IP
+    resolvedAt time.Time
+}
+
+const proxyResolveInterval = time.Minute
+
+func trustedProxiesFromEnv() *trustedProxies {
+    return newTrustedProxies(os.Getenv("TRUSTED_PROXIES"))
+}
+
+func newTrustedProxies(list string) *trustedProxies {
+    p := &trustedProxies{now: time.Now}
+    for _, entry := range strings.Split(list, ",") {
+        entry = strings.TrimSpace(entry)
+        if entry == "" {
+            continue
+        }
+        if _, network, err := net.ParseCIDR(entry); err == nil {
+            p.nets = append(p.nets, network)
+        } else if ip := net.ParseIP(entry); ip != nil {
+            bits := 8 * net.IPv6len
+            if ip.To4() != nil {
+                ip, bits = ip.To4(), 8*net.IPv4len
+            }
+            p.nets = append(p.nets, &net.IPNet{IP: ip, Mask: net.CIDRMask(bits, bits)})
+        } else {
+            p.hosts = append(p.hosts, entry)
+        }
+    }
+    return p
+}
+
+// trusted reports whether ip belongs to a configured proxy
+func (p *trustedProxies) trusted(ip net.IP) bool {
+    for _, network := range p.nets {
+        if network.Contains(ip) {
+            return true
+        }
+    }
+    if len(p.hosts) == 0 {
+        return false
+    }
+
+    p.mu.Lock()
+    defer p.mu.Unlock()
+    if now := p.now(); now.Sub(p.resolvedAt) >= proxyResolveInterval {
+        p.resolvedAt = now
+        p.resolved = nil
+        for _, host := range p.hosts {
+            addrs, err := net.LookupIP(host)
+            if err != nil {
+                log.Printf("⚠️ Failed to resolve trusted proxy %s: %v", host, err)
+                continue
+            }
+            p.resolved = append(p.resolved, addrs...)
+        }
+    }
+    for _, addr := range p.resolved {
+        if addr.Equal(ip) {
+            return true
+        }
+    }
+    return false
+}
+
+// clientIP returns the caller's address. Each trusted proxy appends the
+// address it received the request from to X-Forwarded-For, so the entries
+// are read from the right for as long as the address they came from is a
+// trusted proxy; the first one that is not is the client. Anything further
+// left could have been written by the client itself.
+func (p *trustedProxies) clientIP(r *http.Request) string {
+    remote, _, err := net.SplitHostPort(r.RemoteAddr)
+    if err != nil {
+        remote = r.RemoteAddr
+    }
+    client := net.ParseIP(remote)
+    if client == nil {
+        return remote
+    }
+
+    var hops []string
+    for _, header := range r.Header.Values("X-Forwarded-For") {
+        hops = append(hops, strings.Split(header, ",")...)
+    }
+    for i := len(hops) - 1; i >= 0 && p.trusted(client); i-- {
+        hop := net.ParseIP(strings.TrimSpace(hops[i]))
+        if hop == nil {
+            break
+        }
+        client = hop
+    }
+    return client.String()
+}
+
+// clientIP returns the address of the request's client
+func (h *AuthHandler) clientIP(r *http.Request) string {
+    return h.proxies.clientIP(r)
+}
+
+func deviceName(userAgent string) string {
+    if userAgent == "" {
+        return "Unknown device"
+    }
+    if runes := []rune(userAgent); len(runes) > 200 {
+        return string(runes[:200]) + "..."
+    }
+    return userAgent
+}
+
+func alertTime() string {
+    return time.Now().UTC().Format("Jan 2, 2006 at 15:04 UTC")
+}
@@ -0,0 +1,66 @@
+package handlers
+
+import (
+    "net/http/httptest"
+    "testing"
+    "time"
+)
+
+func TestClientIP(t *testing.T) {
+    tests := []struct {
+        name    string
+        proxies string
+        remote  string
+        xff     []string
+        want    string
+    }{
+        {name: "no proxies ignores the header", remote: "10.0.0.5:4000", xff: []string{"203.0.113.9"}, want: "10.0.0.5"},
+        {name: "untrusted remote ignores the header", proxies: "10.0.0.0/24", remote: "192.0.2.1:4000", xff: []string{"203.0.113.9"}, want: "192.0.2.1"},
+        {name: "trusted remote without header", proxies: "10.0.0.0/24", remote: "10.0.0.5:4000", want: "10.0.0.5"},
+        {name: "trusted remote", proxies: "10.0.0.0/24", remote: "10.0.0.5:4000", xff: []string{"203.0.113.9"}, want: "203.0.113.9"},
+        {name: "spoofed entry on the left", proxies: "10.0.0.0/24", remote: "10.0.0.5:4000", xff: []string{"1.2.3.4, 203.0.113.9"}, want: "203.0.113.9"},
+        {name: "chain of proxies", proxies: "10.0.0.0/24, 10.1.0.7", remote: "10.0.0.5:4000", xff: []string{"1.2.3.4, 203.0.113.9, 10.1.0.7"}, want: "203.0.113.9"},
+        {name: "single address does not cover its neighbour", proxies: "10.0.0.0/24, 10.1.0.7", remote: "10.0.0.5:4000", xff: []string{"203.0.113.9, 10.1.0.8"}, want: "10.1.0.8"},
+        {name: "several headers", proxies: "10.0.0.0/24", remote: "10.0.0.5:4000", xff: []string{"1.2.3.4", "203.0.113.9"}, want: "203.0.113.9"},
+        {name: "all proxies gives the leftmost", proxies: "10.0.0.0/24", remote: "10.0.0.5:4000", xff: []string{"10.0.0.1, 10.0.0.2"}, want: "10.0.0.1"},
+        {name: "unparsable entry stops the walk", proxies: "10.0.0.0/24", remote: "10.0.0.5:4000", xff: []string{"203.0.113.9, unknown, 10.0.0.2"}, want: "10.0.0.2"},
+        {name: "IPv6", proxies: "fd00::/8", remote: "[fd00::1]:4000", xff: []string{"2001:db8::9"}, want: "2001:db8::9"},
+        {name: "host name", proxies: "localhost", remote: "127.0.0.1:4000", xff: []string{"203.0.113.9"}, want: "203.0.113.9"},
+    }
+
+    for _, tt := range tests {
+        t.Run(tt.name, func(t *testing.T) {
+            r := httptest.NewRequest("POST", "/api/v1/auth/login", nil)
+            r.RemoteAddr = tt.remote
+            for _, value := range tt.xff {
+                r.Header.Add("X-Forwarded-For", value)
+            }
+            if got := newTrustedProxies(tt.proxies).clientIP(r); got != tt.want {
+                t.Errorf("clientIP = %q, want %q", got, tt.want)
+            }
+        })
+    }
+}
+
+func TestTrustedProxiesResolveHostsAgain(t *testing.T) {
+    now := time.Date(2026, time.October, 19, 10, 0, 0, 0, time.UTC)
+    p := newTrustedProxies("localhost")
+    p.now = func() time.Time { return now }
+
+    if !p.trusted([]byte{127, 0, 0, 1}) {
+        t.Fatal("localhost is not trusted")
+    }
+    resolvedAt := p.resolvedAt
+
+    now = now.Add(proxyResolveInterval / 2)
+    p.trusted([]byte{127, 0, 0, 1})
+    if !p.resolvedAt.Equal(resolvedAt) {
+        t.Error("resolved the host again within the interval")
+    }
+
+    now = now.Add(proxyResolveInterval)
+    p.trusted([]byte{127, 0, 0, 1})
+    if !p.resolvedAt.Equal(now) {
+        t.Error("did not resolve the host again after the interval")
+    }
+}
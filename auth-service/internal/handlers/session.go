@@ -0,0 +1,92 @@
+package handlers
+
+import (
+    "encoding/json"
+    "errors"
+    "net/http"
+    "strings"
+    "time"
+    "auth-service/internal/jwt"
+    "auth-service/internal/models"
+)
+
+var errUnauthorized = errors.New("invalid or expired token")
+
+// issueToken starts a session for the user and returns its token
+func (h *AuthHandler) issueToken(user *models.User, r *http.Request) (string, error) {
+    sessionID, err := h.db.CreateSession(user.ID, h.clientIP(r), r.UserAgent(), jwt.TokenTTL)
+    if err != nil {
+        return "", err
+    }
+    return h.jwtService.GenerateToken(user, sessionID)
+}
+
+// authenticate returns the claims of the request's bearer token when its
+// session is still active
+func (h *AuthHandler) authenticate(r *http.Request) (*models.Claims, error) {
+    parts := strings.Split(r.Header.Get("Authorization"), " ")
+    if len(parts) != 2 || parts[0] != "Bearer" {
+        return nil, errUnauthorized
+    }
+
+    claims, err := h.jwtService.ValidateToken(parts[1])
+    if err != nil {
+        return nil, errUnauthorized
+    }
+
+    active, err := h.db.IsSessionActive(claims.Id, claims.UserID)
+    if err != nil {
+        return nil, err
+    }
+    if !active {
+        return nil, errUnauthorized
+    }
+    return claims, nil
+}
+
+// currentSession loads the user and claims behind the request's token,
+// writing the error response itself when there is no active session
+func (h *AuthHandler) currentSession(w http.ResponseWriter, r *http.Request) (*models.User, *models.Claims, bool) {
+    claims, err := h.authenticate(r)
+    if err == errUnauthorized {
+        http.Error(w, `{"error": "Invalid or expired token"}`, http.StatusUnauthorized)
+        return nil, nil, false
+    }
+    if err != nil {
+        http.Error(w, `{"error": "`+err.Error()+`"}`, http.StatusInternalServerError)
+        return nil, nil, false
+    }
+
+    user, err := h.db.GetUserByID(claims.UserID)
+    if err != nil {
+        http.Error(w, `{"error": "Invalid or expired token"}`, http.StatusUnauthorized)
+        return nil, nil, false
+    }
+    return user, claims, true
+}
+
+// GetSession answers whether the bearer token's session is still active.
+// The gateway calls it to reject tokens whose session was revoked.
+func (h *AuthHandler) GetSession(w http.ResponseWriter, r *http.Request) {
+    claims, err := h.authenticate(r)
+    if err == errUnauthorized {
+        http.Error(w, `{"error": "Invalid or expired token"}`, http.StatusUnauthorized)
+        return
+    }
+    if err != nil {
+        http.Error(w, `{"error": "`+err.Error()+`"}`, http.StatusInternalServerError)
+        return
+    }
+
+    response := models.SessionResponse{
+        UserID:    claims.UserID,
+        Email:     claims.Email,
+        SessionID: claims.Id,
+        ExpiresAt: time.Unix(claims.ExpiresAt, 0).UTC(),
+    }
+
+    w.Header().Set("Content-Type", "application/json")
+    if err := json.NewEncoder(w).Encode(response); err != nil {
+        http.Error(w, `{"error": "Error encoding response"}`, http.StatusInternalServerError)
+    }
+}
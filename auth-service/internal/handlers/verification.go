@@ -0,0 +1,166 @@
+package handlers
+
+import (
+    "encoding/json"
+    "math"
+    "net/http"
+    "net/mail"
+    "net/url"
+    "os"
+    "strconv"
+    "strings"
+    "time"
+    "auth-service/internal/database"
+    "auth-service/internal/models"
+    "auth-service/internal/notifier"
+)
+
+// verificationConfig reads EMAIL_VERIFICATION_URL, the link emailed with
+// ?token= appended (default the verify endpoint behind the frontend),
+// EMAIL_CHANGE_URL, the same for confirming a new address,
+// EMAIL_VERIFICATION_TTL (default 24h), VERIFICATION_RESEND_INTERVAL
+// (default 1m) and VERIFICATION_RESEND_DAILY_LIMIT (default 5)
+type verificationConfig struct {
+    url       string
+    changeURL string
+    limits    database.VerificationLimits
+}
+
+func verificationConfigFromEnv() verificationConfig {
+    config := verificationConfig{
+        url: os.Getenv("EMAIL_VERIFICATION_URL"),
+        limits: database.VerificationLimits{
+            TTL:        24 * time.Hour,
+            Interval:   time.Minute,
+            DailyLimit: 5,
+        },
+    }
+    if config.url == "" {
+        config.url = "http://localhost/api/v1/auth/verify"
+    }
+    config.changeURL = os.Getenv("EMAIL_CHANGE_URL")
+    if config.changeURL == "" {
+        config.changeURL = "http://localhost/api/v1/auth/email/confirm"
+    }
+    if value, err := time.ParseDuration(os.Getenv("EMAIL_VERIFICATION_TTL")); err == nil && value > 0 {
+        config.limits.TTL = value
+    }
+    if value, err := time.ParseDuration(os.Getenv("VERIFICATION_RESEND_INTERVAL")); err == nil && value >= 0 {
+        config.limits.Interval = value
+    }
+    if value, err := strconv.Atoi(os.Getenv("VERIFICATION_RESEND_DAILY_LIMIT")); err == nil && value >= 0 {
+        config.limits.DailyLimit = value
+    }
+    return config
+}
+
+// validEmail accepts a bare address such as name@example.com
+func validEmail(email string) bool {
+    if len(email) > 254 {
+        return false
+    }
+    address, err := mail.ParseAddress(email)
+    if err != nil || address.Address != email {
+        return false
+    }
+    domain := email[strings.LastIndex(email, "@")+1:]
+    return strings.Contains(domain, ".") && !strings.HasPrefix(domain, ".") && !strings.HasSuffix(domain, ".")
+}
+
+// sendVerificationEmail emails the user a link confirming their current
+// address. It returns how long to wait instead when the resend limits are hit.
+func (h *AuthHandler) sendVerificationEmail(user *models.User) (time.Duration, error) {
+    token, tokenHash := newSecretToken()
+    retryAfter, err := h.db.CreateEmailVerificationToken(user.ID, user.Email, tokenHash, h.verification.limits)
+    if err != nil || retryAfter > 0 {
+        return retryAfter, err
+    }
+
+    h.notifier.Send(notifier.Notification{
+        UserID:   user.ID,
+        Type:     "email",
+        Template: "verify_email",
+        Data: map[string]interface{}{
+            "email":         user.Email,
+            "first_name":    user.FirstName,
+            "verify_url":    h.verification.url + "?token=" + url.QueryEscape(token),
+            "expires_hours": int(math.Ceil(h.verification.limits.TTL.Hours())),
+        },
+        Transactional: true,
+    })
+    return 0, nil
+}
+
+// VerifyEmail confirms an address with the token from a verification email
+func (h *AuthHandler) VerifyEmail(w http.ResponseWriter, r *http.Request) {
+    token := r.URL.Query().Get("token")
+    if token == "" {
+        http.Error(w, `{"error": "Token is required"}`, http.StatusBadRequest)
+        return
+    }
+
+    if _, err := h.db.VerifyEmail(hashToken(token)); err != nil {
+        if err.Error() == "invalid or expired verification token" {
+            http.Error(w, `{"error": "Invalid or expired verification token"}`, http.StatusBadRequest)
+            return
+        }
+        http.Error(w, `{"error": "`+err.Error()+`"}`, http.StatusInternalServerError)
+        return
+    }
+
+    w.Header().Set("Content-Type", "application/json")
+    response := models.MessageResponse{Message: "Email address verified"}
+    if err := json.NewEncoder(w).Encode(response); err != nil {
+        http.Error(w, `{"error": "Error encoding response"}`, http.StatusInternalServerError)
+    }
+}
+
+// ResendVerification emails the signed-in user a new verification link
+func (h *AuthHandler) ResendVerification(w http.ResponseWriter, r *http.Request) {
+    user, _, ok := h.currentSession(w, r)
+    if !ok {
+        return
+    }
+
+    if user.EmailVerified {
+        http.Error(w, `{"error": "Email address is already verified"}`, http.StatusBadRequest)
+        return
+    }
+
+    retryAfter, err := h.sendVerificationEmail(user)
+    if err != nil {
+        http.Error(w, `{"error": "`+err.Error()+`"}`, http.StatusInternalServerError)
+        return
+    }
+    if retryAfter > 0 {
+        w.Header().Set("Retry-After", strconv.Itoa(int(math.Ceil(retryAfter.Seconds()))))
+        http.Error(w, `{"error": "Too many verification emails requested. Try again later."}`, http.StatusTooManyRequests)
+        return
+    }
+
+    w.Header().Set("Content-Type", "application/json")
+    response := models.MessageResponse{Message: "Verification email sent"}
+    if err := json.NewEncoder(w).Encode(response); err != nil {
+        http.Error(w, `{"error": "Error encoding response"}`, http.StatusInternalServerError)
+    }
+}
+
+// RefreshToken issues a new token for the current session carrying the
+// account's current claims, such as email_verified after verification
+func (h *AuthHandler) RefreshToken(w http.ResponseWriter, r *http.Request) {
+    user, claims, ok := h.currentSession(w, r)
+    if !ok {
+        return
+    }
+
+    token, err := h.jwtService.GenerateToken(user, claims.Id)
+    if err != nil {
+        http.Error(w, `{"error": "Failed to generate token"}`, http.StatusInternalServerError)
+        return
+    }
+
+    w.Header().Set("Content-Type", "application/json")
+    if err := json.NewEncoder(w).Encode(models.AuthResponse{User: user, Token: token}); err != nil {
+        http.Error(w, `{"error": "Error encoding response"}`, http.StatusInternalServerError)
+    }
+}
@@ -7,6 +7,9 @@ import (
     "os"
 )
 
+// TokenTTL is how long a token, and the session it belongs to, stays valid
+const TokenTTL = 24 * time.Hour
+
 type JWTService struct {
     secretKey []byte
 }
@@ -21,12 +24,16 @@ func NewJWTService() *JWTService {
     }
 }
 
-func (j *JWTService) GenerateToken(userID string, email string) (string, error) {
+// GenerateToken issues a token for a session; the session ID is the token's
+// jti, so revoking the session invalidates the token
+func (j *JWTService) GenerateToken(user *models.User, sessionID string) (string, error) {
     claims := &models.Claims{
-        UserID: userID,
-        Email:  email,
+        UserID:        user.ID,
+        Email:         user.Email,
+        EmailVerified: user.EmailVerified,
         StandardClaims: jwt.StandardClaims{
-            ExpiresAt: time.Now().Add(24 * time.Hour).Unix(), // Token expires in 24 hours
+            Id:        sessionID,
+            ExpiresAt: time.Now().Add(TokenTTL).Unix(),
             IssuedAt:  time.Now().Unix(),
             Issuer:    "auth-service",
         },
@@ -38,6 +45,9 @@ func (j *JWTService) GenerateToken(userID string, email string) (string, error)
 
 func (j *JWTService) ValidateToken(tokenString string) (*models.Claims, error) {
     token, err := jwt.ParseWithClaims(tokenString, &models.Claims{}, func(token *jwt.Token) (interface{}, error) {
+        if _, ok := token.Method.(*jwt.SigningMethodHMAC); !ok {
+            return nil, jwt.ErrSignatureInvalid
+        }
         return j.secretKey, nil
     })
 
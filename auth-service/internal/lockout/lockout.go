@@ -0,0 +1,293 @@
+package lockout
+
+import (
+	"auth-service/internal/models"
+	"context"
+	"log"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Store keeps failed sign-in counts by key. UpdateLoginAttempts applies
+// update to the key's entry, starting from an empty one, atomically with
+// respect to other updates of the same key. GetLoginAttempts returns nil for
+// a key with no entry.
+type Store interface {
+	GetLoginAttempts(key string) (*models.LoginAttempts, error)
+	UpdateLoginAttempts(key string, update func(*models.LoginAttempts)) (*models.LoginAttempts, error)
+	DeleteLoginAttempts(keys ...string) error
+	// PurgeLoginAttempts removes entries whose last failure and lock both
+	// ended before the given time
+	PurgeLoginAttempts(before time.Time) (int, error)
+}
+
+// NewStoreFromEnv returns postgres unless LOGIN_THROTTLE_STORE is "memory".
+// The in-memory store is per instance, so it only suits a single replica.
+func NewStoreFromEnv(postgres Store) Store {
+	if os.Getenv("LOGIN_THROTTLE_STORE") == "memory" {
+		return NewMemoryStore()
+	}
+	return postgres
+}
+
+// Policy decides how failed sign-ins are slowed down and locked out
+type Policy struct {
+	// AccountMaxFailures and IPMaxFailures are the failures within Window
+	// that lock an account or a client address
+	AccountMaxFailures int
+	IPMaxFailures      int
+	Window             time.Duration
+	// LockoutDuration doubles with each lockout that follows within
+	// LockoutMemory, up to MaxLockout
+	LockoutDuration time.Duration
+	MaxLockout      time.Duration
+	LockoutMemory   time.Duration
+	// From the second failure on, the next attempt waits DelayBase, doubling
+	// with each failure up to MaxDelay
+	DelayBase time.Duration
+	MaxDelay  time.Duration
+}
+
+// PolicyFromEnv reads LOGIN_MAX_FAILURES (default 5), LOGIN_IP_MAX_FAILURES
+// (50), LOGIN_FAILURE_WINDOW (15m), LOGIN_LOCKOUT_DURATION (15m),
+// LOGIN_DELAY_BASE (1s) and LOGIN_DELAY_MAX (30s)
+func PolicyFromEnv() Policy {
+	policy := Policy{
+		AccountMaxFailures: 5,
+		IPMaxFailures:      50,
+		Window:             15 * time.Minute,
+		LockoutDuration:    15 * time.Minute,
+		MaxLockout:         24 * time.Hour,
+		LockoutMemory:      24 * time.Hour,
+		DelayBase:          time.Second,
+		MaxDelay:           30 * time.Second,
+	}
+	if value, err := strconv.Atoi(os.Getenv("LOGIN_MAX_FAILURES")); err == nil && value > 0 {
+		policy.AccountMaxFailures = value
+	}
+	if value, err := strconv.Atoi(os.Getenv("LOGIN_IP_MAX_FAILURES")); err == nil && value > 0 {
+		policy.IPMaxFailures = value
+	}
+	if value, err := time.ParseDuration(os.Getenv("LOGIN_FAILURE_WINDOW")); err == nil && value > 0 {
+		policy.Window = value
+	}
+	if value, err := time.ParseDuration(os.Getenv("LOGIN_LOCKOUT_DURATION")); err == nil && value > 0 {
+		policy.LockoutDuration = value
+	}
+	if value, err := time.ParseDuration(os.Getenv("LOGIN_DELAY_BASE")); err == nil && value >= 0 {
+		policy.DelayBase = value
+	}
+	if value, err := time.ParseDuration(os.Getenv("LOGIN_DELAY_MAX")); err == nil && value >= 0 {
+		policy.MaxDelay = value
+	}
+	return policy
+}
+
+// Result is the outcome of recording a failed sign-in
+type Result struct {
+	// Failures is the account's count including this one
+	Failures int
+	// AccountLocked is set when this failure locked the account
+	AccountLocked bool
+	// RetryAfter is how long until the account and address may try again
+	RetryAfter time.Duration
+}
+
+// Guard throttles sign-in attempts per account and per client address.
+// Accounts are keyed by the email address as typed, whether or not an
+// account exists for it, so throttling looks the same for unknown addresses.
+type Guard struct {
+	store  Store
+	policy Policy
+	now    func() time.Time
+}
+
+func NewGuard(store Store, policy Policy) *Guard {
+	return &Guard{store: store, policy: policy, now: time.Now}
+}
+
+func AccountKey(email string) string {
+	return "account:" + strings.ToLower(strings.TrimSpace(email))
+}
+
+func IPKey(ip string) string {
+	return "ip:" + ip
+}
+
+// Check returns how long the account and address must wait before another
+// attempt; zero allows it
+func (g *Guard) Check(email, ip string) (time.Duration, error) {
+	now := g.now().UTC()
+
+	var wait time.Duration
+	for _, key := range []string{AccountKey(email), IPKey(ip)} {
+		attempts, err := g.store.GetLoginAttempts(key)
+		if err != nil {
+			return 0, err
+		}
+		if d := retryAfter(attempts, now); d > wait {
+			wait = d
+		}
+	}
+	return wait, nil
+}
+
+// Fail records a failed sign-in against the account and the address
+func (g *Guard) Fail(email, ip string) (Result, error) {
+	now := g.now().UTC()
+
+	var result Result
+	account, err := g.store.UpdateLoginAttempts(AccountKey(email), func(a *models.LoginAttempts) {
+		result.AccountLocked = g.recordFailure(a, now, g.policy.AccountMaxFailures)
+		result.Failures = a.Failures
+		if result.AccountLocked {
+			result.Failures = g.policy.AccountMaxFailures
+		}
+	})
+	if err != nil {
+		return result, err
+	}
+	address, err := g.store.UpdateLoginAttempts(IPKey(ip), func(a *models.LoginAttempts) {
+		if g.recordFailure(a, now, g.policy.IPMaxFailures) {
+			log.Printf("🔒 Locked sign-ins from %s after %d failures", ip, g.policy.IPMaxFailures)
+		}
+	})
+	if err != nil {
+		return result, err
+	}
+
+	result.RetryAfter = retryAfter(account, now)
+	if d := retryAfter(address, now); d > result.RetryAfter {
+		result.RetryAfter = d
+	}
+	return result, nil
+}
+
+// Succeed forgets the account's failures. The address keeps its count, so
+// signing in to one account does not reset guesses against others.
+func (g *Guard) Succeed(email string) error {
+	return g.store.DeleteLoginAttempts(AccountKey(email))
+}
+
+// Unlock clears the failures and any lock of an account, an address or both
+func (g *Guard) Unlock(email, ip string) error {
+	var keys []string
+	if email != "" {
+		keys = append(keys, AccountKey(email))
+	}
+	if ip != "" {
+		keys = append(keys, IPKey(ip))
+	}
+	if len(keys) == 0 {
+		return nil
+	}
+	return g.store.DeleteLoginAttempts(keys...)
+}
+
+// Status returns the entries for an account and an address; either may be
+// nil
+func (g *Guard) Status(email, ip string) (*models.LoginAttempts, *models.LoginAttempts, error) {
+	var account, address *models.LoginAttempts
+	var err error
+	if email != "" {
+		if account, err = g.store.GetLoginAttempts(AccountKey(email)); err != nil {
+			return nil, nil, err
+		}
+	}
+	if ip != "" {
+		if address, err = g.store.GetLoginAttempts(IPKey(ip)); err != nil {
+			return nil, nil, err
+		}
+	}
+	return account, address, nil
+}
+
+// Run purges forgotten entries hourly until ctx is cancelled
+func (g *Guard) Run(ctx context.Context) {
+	ticker := time.NewTicker(time.Hour)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+
+		purged, err := g.store.PurgeLoginAttempts(g.now().UTC().Add(-g.policy.LockoutMemory))
+		if err != nil {
+			log.Printf("❌ Failed to purge login attempts: %v", err)
+		} else if purged > 0 {
+			log.Printf("🧹 Purged %d login attempt records", purged)
+		}
+	}
+}
+
+// recordFailure counts a failure and sets the delay or lock that follows it,
+// reporting whether it locked the key
+func (g *Guard) recordFailure(a *models.LoginAttempts, now time.Time, maxFailures int) bool {
+	if a.LastFailureAt != nil && now.Sub(*a.LastFailureAt) > g.policy.Window {
+		a.Failures = 0
+	}
+	if a.LastFailureAt != nil && now.Sub(*a.LastFailureAt) > g.policy.LockoutMemory {
+		a.Lockouts = 0
+	}
+	a.Failures++
+	a.LastFailureAt = &now
+	a.DelayedUntil = nil
+
+	if a.Failures >= maxFailures {
+		a.Failures = 0
+		a.Lockouts++
+		until := now.Add(g.lockoutDuration(a.Lockouts))
+		a.LockedUntil = &until
+		return true
+	}
+
+	if delay := g.delay(a.Failures); delay > 0 {
+		until := now.Add(delay)
+		a.DelayedUntil = &until
+	}
+	return false
+}
+
+func (g *Guard) lockoutDuration(lockouts int) time.Duration {
+	duration := g.policy.LockoutDuration
+	for i := 1; i < lockouts && duration < g.policy.MaxLockout; i++ {
+		duration *= 2
+	}
+	if duration > g.policy.MaxLockout {
+		duration = g.policy.MaxLockout
+	}
+	return duration
+}
+
+// delay lets the first failure through at once, since it is usually a typo
+func (g *Guard) delay(failures int) time.Duration {
+	if failures < 2 || g.policy.DelayBase <= 0 {
+		return 0
+	}
+	delay := g.policy.DelayBase
+	for i := 2; i < failures && delay < g.policy.MaxDelay; i++ {
+		delay *= 2
+	}
+	if delay > g.policy.MaxDelay {
+		delay = g.policy.MaxDelay
+	}
+	return delay
+}
+
+func retryAfter(a *models.LoginAttempts, now time.Time) time.Duration {
+	if a == nil {
+		return 0
+	}
+	var wait time.Duration
+	for _, until := range []*time.Time{a.LockedUntil, a.DelayedUntil} {
+		if until != nil && until.Sub(now) > wait {
+			wait = until.Sub(now)
+		}
+	}
+	return wait
+}
@@ -0,0 +1,260 @@
+package lockout
+
+import (
+	"testing"
+	"time"
+)
+
+var testPolicy = Policy{
+	AccountMaxFailures: 3,
+	IPMaxFailures:      5,
+	Window:             15 * time.Minute,
+	LockoutDuration:    10 * time.Minute,
+	MaxLockout:         30 * time.Minute,
+	LockoutMemory:      24 * time.Hour,
+	DelayBase:          time.Second,
+	MaxDelay:           4 * time.Second,
+}
+
+// testClock is a settable time source for a Guard
+type testClock struct {
+	now time.Time
+}
+
+func (c *testClock) Now() time.Time { return c.now }
+
+func (c *testClock) Advance(d time.Duration) { c.now = c.now.Add(d) }
+
+func newTestGuard(policy Policy) (*Guard, *testClock) {
+	clock := &testClock{now: time.Date(2026, time.October, 19, 10, 0, 0, 0, time.UTC)}
+	guard := NewGuard(NewMemoryStore(), policy)
+	guard.now = clock.Now
+	return guard, clock
+}
+
+func mustFail(t *testing.T, g *Guard, email, ip string) Result {
+	t.Helper()
+	result, err := g.Fail(email, ip)
+	if err != nil {
+		t.Fatalf("Fail returned error: %v", err)
+	}
+	return result
+}
+
+func mustCheck(t *testing.T, g *Guard, email, ip string) time.Duration {
+	t.Helper()
+	wait, err := g.Check(email, ip)
+	if err != nil {
+		t.Fatalf("Check returned error: %v", err)
+	}
+	return wait
+}
+
+func TestFailuresDelayThenLockAccount(t *testing.T) {
+	g, clock := newTestGuard(testPolicy)
+
+	if wait := mustCheck(t, g, "ada@example.test", "198.51.100.7"); wait != 0 {
+		t.Fatalf("fresh account must wait %v", wait)
+	}
+
+	// The first failure is let through at once
+	result := mustFail(t, g, "ada@example.test", "198.51.100.7")
+	if result.Failures != 1 || result.AccountLocked || result.RetryAfter != 0 {
+		t.Errorf("first failure = %+v", result)
+	}
+
+	result = mustFail(t, g, "ada@example.test", "198.51.100.7")
+	if result.Failures != 2 || result.AccountLocked || result.RetryAfter != time.Second {
+		t.Errorf("second failure = %+v, want a 1s delay", result)
+	}
+
+	result = mustFail(t, g, "ada@example.test", "198.51.100.7")
+	if !result.AccountLocked || result.Failures != 3 || result.RetryAfter != 10*time.Minute {
+		t.Errorf("third failure = %+v, want a 10m lock", result)
+	}
+
+	// The lock applies to the account from any address, ignoring case
+	if wait := mustCheck(t, g, " ADA@example.test", "203.0.113.9"); wait != 10*time.Minute {
+		t.Errorf("locked account must wait %v, want 10m", wait)
+	}
+	clock.Advance(10 * time.Minute)
+	if wait := mustCheck(t, g, "ada@example.test", "203.0.113.9"); wait != 0 {
+		t.Errorf("account still waits %v after the lock ended", wait)
+	}
+}
+
+func TestDelayDoublesUpToMax(t *testing.T) {
+	policy := testPolicy
+	policy.AccountMaxFailures = 10
+	policy.IPMaxFailures = 10
+	g, _ := newTestGuard(policy)
+
+	want := []time.Duration{0, time.Second, 2 * time.Second, 4 * time.Second, 4 * time.Second}
+	for i, delay := range want {
+		if result := mustFail(t, g, "ada@example.test", "198.51.100.7"); result.RetryAfter != delay {
+			t.Errorf("failure %d: delay = %v, want %v", i+1, result.RetryAfter, delay)
+		}
+	}
+}
+
+func TestRepeatedLockoutsDoubleUpToMax(t *testing.T) {
+	g, clock := newTestGuard(testPolicy)
+
+	for i, want := range []time.Duration{10 * time.Minute, 20 * time.Minute, 30 * time.Minute, 30 * time.Minute} {
+		// A fresh address each round keeps its own count out of the way
+		ip := "198.51.100." + string(rune('1'+i))
+		var result Result
+		for n := 0; n < testPolicy.AccountMaxFailures; n++ {
+			result = mustFail(t, g, "ada@example.test", ip)
+		}
+		if !result.AccountLocked || result.RetryAfter != want {
+			t.Errorf("lockout %d = %+v, want a %v lock", i+1, result, want)
+		}
+		clock.Advance(result.RetryAfter)
+	}
+}
+
+func TestLockoutsAreForgotten(t *testing.T) {
+	g, clock := newTestGuard(testPolicy)
+
+	for n := 0; n < testPolicy.AccountMaxFailures; n++ {
+		mustFail(t, g, "ada@example.test", "198.51.100.7")
+	}
+	clock.Advance(testPolicy.LockoutMemory + time.Minute)
+
+	var result Result
+	for n := 0; n < testPolicy.AccountMaxFailures; n++ {
+		result = mustFail(t, g, "ada@example.test", "198.51.100.8")
+	}
+	if result.RetryAfter != testPolicy.LockoutDuration {
+		t.Errorf("lock after a quiet day = %v, want the base %v", result.RetryAfter, testPolicy.LockoutDuration)
+	}
+}
+
+func TestFailuresOutsideWindowStartOver(t *testing.T) {
+	g, clock := newTestGuard(testPolicy)
+
+	mustFail(t, g, "ada@example.test", "198.51.100.7")
+	mustFail(t, g, "ada@example.test", "198.51.100.7")
+	clock.Advance(testPolicy.Window + time.Second)
+
+	result := mustFail(t, g, "ada@example.test", "198.51.100.7")
+	if result.Failures != 1 || result.AccountLocked || result.RetryAfter != 0 {
+		t.Errorf("failure after the window = %+v, want a fresh count", result)
+	}
+}
+
+func TestAddressLockCoversEveryAccount(t *testing.T) {
+	g, _ := newTestGuard(testPolicy)
+
+	// Spread over accounts so none of them locks on its own
+	emails := []string{"a@example.test", "b@example.test", "c@example.test", "d@example.test", "e@example.test"}
+	for _, email := range emails {
+		if result := mustFail(t, g, email, "198.51.100.7"); result.AccountLocked {
+			t.Fatalf("account %s locked after one failure", email)
+		}
+	}
+
+	if wait := mustCheck(t, g, "f@example.test", "198.51.100.7"); wait != testPolicy.LockoutDuration {
+		t.Errorf("locked address must wait %v, want %v", wait, testPolicy.LockoutDuration)
+	}
+	if wait := mustCheck(t, g, "f@example.test", "203.0.113.9"); wait != 0 {
+		t.Errorf("another address must wait %v", wait)
+	}
+}
+
+func TestSucceedKeepsAddressCount(t *testing.T) {
+	g, clock := newTestGuard(testPolicy)
+
+	mustFail(t, g, "ada@example.test", "198.51.100.7")
+	mustFail(t, g, "ada@example.test", "198.51.100.7")
+	if err := g.Succeed("ada@example.test"); err != nil {
+		t.Fatalf("Succeed returned error: %v", err)
+	}
+	clock.Advance(time.Minute)
+
+	account, address, err := g.Status("ada@example.test", "198.51.100.7")
+	if err != nil {
+		t.Fatalf("Status returned error: %v", err)
+	}
+	if account != nil {
+		t.Errorf("account failures kept after success: %+v", account)
+	}
+	if address == nil || address.Failures != 2 {
+		t.Errorf("address entry = %+v, want 2 failures", address)
+	}
+
+	// The account starts over, the address does not
+	result := mustFail(t, g, "ada@example.test", "198.51.100.7")
+	if result.Failures != 1 || result.RetryAfter != 2*time.Second {
+		t.Errorf("failure after success = %+v, want 1 account failure and the address's 2s delay", result)
+	}
+}
+
+func TestUnlock(t *testing.T) {
+	g, _ := newTestGuard(testPolicy)
+
+	for n := 0; n < testPolicy.IPMaxFailures; n++ {
+		mustFail(t, g, "ada@example.test", "198.51.100.7")
+	}
+	if wait := mustCheck(t, g, "ada@example.test", "198.51.100.7"); wait == 0 {
+		t.Fatal("account and address are not locked")
+	}
+
+	if err := g.Unlock("ada@example.test", ""); err != nil {
+		t.Fatalf("Unlock returned error: %v", err)
+	}
+	if wait := mustCheck(t, g, "ada@example.test", "203.0.113.9"); wait != 0 {
+		t.Errorf("unlocked account must wait %v", wait)
+	}
+	if wait := mustCheck(t, g, "grace@example.test", "198.51.100.7"); wait == 0 {
+		t.Error("unlocking the account also unlocked the address")
+	}
+
+	if err := g.Unlock("", "198.51.100.7"); err != nil {
+		t.Fatalf("Unlock returned error: %v", err)
+	}
+	if wait := mustCheck(t, g, "grace@example.test", "198.51.100.7"); wait != 0 {
+		t.Errorf("unlocked address must wait %v", wait)
+	}
+}
+
+func TestMemoryStorePurge(t *testing.T) {
+	g, clock := newTestGuard(testPolicy)
+
+	for n := 0; n < testPolicy.AccountMaxFailures; n++ {
+		mustFail(t, g, "ada@example.test", "198.51.100.7")
+	}
+	mustFail(t, g, "grace@example.test", "203.0.113.9")
+	clock.Advance(time.Minute)
+
+	// Only ada's account is still locked; everything else just has old failures
+	if purged, _ := g.store.PurgeLoginAttempts(clock.Now()); purged != 3 {
+		t.Errorf("purged %d entries, want 3", purged)
+	}
+	if account, _, _ := g.Status("ada@example.test", ""); account == nil {
+		t.Error("purged a locked account")
+	}
+
+	clock.Advance(testPolicy.LockoutDuration)
+	if purged, _ := g.store.PurgeLoginAttempts(clock.Now()); purged != 1 {
+		t.Errorf("purged %d entries after the lock ended, want 1", purged)
+	}
+}
+
+func TestPolicyFromEnv(t *testing.T) {
+	t.Setenv("LOGIN_MAX_FAILURES", "7")
+	t.Setenv("LOGIN_IP_MAX_FAILURES", "0")
+	t.Setenv("LOGIN_FAILURE_WINDOW", "1h")
+	t.Setenv("LOGIN_LOCKOUT_DURATION", "not a duration")
+	t.Setenv("LOGIN_DELAY_BASE", "0s")
+	t.Setenv("LOGIN_DELAY_MAX", "")
+
+	policy := PolicyFromEnv()
+	if policy.AccountMaxFailures != 7 || policy.Window != time.Hour || policy.DelayBase != 0 {
+		t.Errorf("set values not applied: %+v", policy)
+	}
+	if policy.IPMaxFailures != 50 || policy.LockoutDuration != 15*time.Minute || policy.MaxDelay != 30*time.Second {
+		t.Errorf("invalid values did not keep the defaults: %+v", policy)
+	}
+}
@@ -0,0 +1,66 @@
+package lockout
+
+import (
+	"auth-service/internal/models"
+	"sync"
+	"time"
+)
+
+// MemoryStore keeps login attempts in process memory
+type MemoryStore struct {
+	mu      sync.Mutex
+	entries map[string]models.LoginAttempts
+}
+
+func NewMemoryStore() *MemoryStore {
+	return &MemoryStore{entries: make(map[string]models.LoginAttempts)}
+}
+
+func (s *MemoryStore) GetLoginAttempts(key string) (*models.LoginAttempts, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	entry, ok := s.entries[key]
+	if !ok {
+		return nil, nil
+	}
+	return &entry, nil
+}
+
+func (s *MemoryStore) UpdateLoginAttempts(key string, update func(*models.LoginAttempts)) (*models.LoginAttempts, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	entry, ok := s.entries[key]
+	if !ok {
+		entry = models.LoginAttempts{Key: key}
+	}
+	update(&entry)
+	s.entries[key] = entry
+	return &entry, nil
+}
+
+func (s *MemoryStore) DeleteLoginAttempts(keys ...string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for _, key := range keys {
+		delete(s.entries, key)
+	}
+	return nil
+}
+
+func (s *MemoryStore) PurgeLoginAttempts(before time.Time) (int, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	purged := 0
+	for key, entry := range s.entries {
+		if entry.LastFailureAt != nil && entry.LastFailureAt.Before(before) &&
+			(entry.LockedUntil == nil || entry.LockedUntil.Before(before)) {
+			delete(s.entries, key)
+			purged++
+		}
+	}
+	return purged, nil
+}
@@ -0,0 +1,165 @@
+package mfa
+
+import (
+	"bytes"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/base32"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"image/png"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/pquerna/otp"
+	"github.com/pquerna/otp/totp"
+)
+
+// period is the TOTP time step; codes from one step either side of the
+// current one are accepted to allow for clock drift
+const period = 30
+
+// Keyring encrypts TOTP secrets at rest and hashes recovery codes. Its key
+// comes from MFA_ENCRYPTION_KEY, or is derived from JWT_SECRET when unset.
+type Keyring struct {
+	aead   cipher.AEAD
+	macKey []byte
+}
+
+func NewKeyring(secret string) (*Keyring, error) {
+	if secret == "" {
+		return nil, errors.New("MFA key is empty")
+	}
+	key := sha256.Sum256([]byte("mfa-encryption:" + secret))
+	block, err := aes.NewCipher(key[:])
+	if err != nil {
+		return nil, err
+	}
+	aead, err := cipher.NewGCM(block)
+	if err != nil {
+		return nil, err
+	}
+	macKey := sha256.Sum256([]byte("mfa-recovery:" + secret))
+	return &Keyring{aead: aead, macKey: macKey[:]}, nil
+}
+
+func NewKeyringFromEnv() (*Keyring, error) {
+	secret := os.Getenv("MFA_ENCRYPTION_KEY")
+	if secret == "" {
+		secret = os.Getenv("JWT_SECRET")
+	}
+	return NewKeyring(secret)
+}
+
+// Seal encrypts a TOTP secret for storage
+func (k *Keyring) Seal(secret string) string {
+	nonce := make([]byte, k.aead.NonceSize())
+	rand.Read(nonce)
+	return base64.StdEncoding.EncodeToString(k.aead.Seal(nonce, nonce, []byte(secret), nil))
+}
+
+// Open decrypts a secret sealed by Seal
+func (k *Keyring) Open(sealed string) (string, error) {
+	data, err := base64.StdEncoding.DecodeString(sealed)
+	if err != nil || len(data) < k.aead.NonceSize() {
+		return "", errors.New("invalid sealed MFA secret")
+	}
+	nonce, ciphertext := data[:k.aead.NonceSize()], data[k.aead.NonceSize():]
+	plaintext, err := k.aead.Open(nil, nonce, ciphertext, nil)
+	if err != nil {
+		return "", errors.New("failed to decrypt MFA secret")
+	}
+	return string(plaintext), nil
+}
+
+// HashRecoveryCode returns the stored form of a recovery code. Codes are
+// compared after normalizing, so case, spaces and dashes do not matter.
+func (k *Keyring) HashRecoveryCode(code string) string {
+	mac := hmac.New(sha256.New, k.macKey)
+	mac.Write([]byte(NormalizeRecoveryCode(code)))
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+// Enrollment is a new TOTP secret with what an authenticator app needs to
+// add it
+type Enrollment struct {
+	Secret string
+	URI    string
+	// QRCode is a PNG of URI as a data: URL
+	QRCode string
+}
+
+// NewEnrollment generates a secret for accountName under issuer
+func NewEnrollment(issuer, accountName string) (*Enrollment, error) {
+	key, err := totp.Generate(totp.GenerateOpts{
+		Issuer:      issuer,
+		AccountName: accountName,
+		Period:      period,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to generate MFA secret: %w", err)
+	}
+
+	image, err := key.Image(256, 256)
+	if err != nil {
+		return nil, fmt.Errorf("failed to render MFA QR code: %w", err)
+	}
+	var buf bytes.Buffer
+	if err := png.Encode(&buf, image); err != nil {
+		return nil, fmt.Errorf("failed to render MFA QR code: %w", err)
+	}
+
+	return &Enrollment{
+		Secret: key.Secret(),
+		URI:    key.URL(),
+		QRCode: "data:image/png;base64," + base64.StdEncoding.EncodeToString(buf.Bytes()),
+	}, nil
+}
+
+// MatchCode checks a TOTP code against secret and returns the time step it
+// belongs to. Callers reject steps at or before the last one used, so a code
+// cannot be replayed.
+func MatchCode(secret, code string, now time.Time) (int64, bool) {
+	code = strings.TrimSpace(code)
+	if len(code) != 6 {
+		return 0, false
+	}
+
+	opts := totp.ValidateOpts{Period: period, Digits: otp.DigitsSix, Algorithm: otp.AlgorithmSHA1}
+	current := now.Unix() / period
+	for _, step := range []int64{current, current - 1, current + 1} {
+		expected, err := totp.GenerateCodeCustom(secret, time.Unix(step*period, 0), opts)
+		if err != nil {
+			return 0, false
+		}
+		if subtle.ConstantTimeCompare([]byte(expected), []byte(code)) == 1 {
+			return step, true
+		}
+	}
+	return 0, false
+}
+
+// GenerateRecoveryCodes returns n single-use codes formatted as xxxxx-xxxxx
+func GenerateRecoveryCodes(n int) []string {
+	encoding := base32.StdEncoding.WithPadding(base32.NoPadding)
+	codes := make([]string, n)
+	for i := range codes {
+		b := make([]byte, 7)
+		rand.Read(b)
+		code := strings.ToLower(encoding.EncodeToString(b))[:10]
+		codes[i] = code[:5] + "-" + code[5:]
+	}
+	return codes
+}
+
+func NormalizeRecoveryCode(code string) string {
+	code = strings.ToLower(code)
+	return strings.NewReplacer(" ", "", "-", "").Replace(code)
+}
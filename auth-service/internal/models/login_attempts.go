@@ -0,0 +1,30 @@
+package models
+
+import "time"
+
+// LoginAttempts tracks failed sign-ins for one key, either an account
+// ("account:<email>") or a client address ("ip:<address>")
+type LoginAttempts struct {
+	Key string `json:"key"`
+	// Failures counts failures since the last success, forgotten once the
+	// failure window passes without another
+	Failures      int        `json:"failures"`
+	Lockouts      int        `json:"lockouts"`
+	LastFailureAt *time.Time `json:"last_failure_at,omitempty"`
+	// DelayedUntil is the progressive delay after the latest failure
+	DelayedUntil *time.Time `json:"delayed_until,omitempty"`
+	LockedUntil  *time.Time `json:"locked_until,omitempty"`
+}
+
+// UnlockRequest names the account, address or both to unlock
+type UnlockRequest struct {
+	Email string `json:"email,omitempty"`
+	IP    string `json:"ip,omitempty"`
+}
+
+// LockoutStatusResponse is the admin view of an account's and an address's
+// sign-in throttling
+type LockoutStatusResponse struct {
+	Account *LoginAttempts `json:"account,omitempty"`
+	IP      *LoginAttempts `json:"ip,omitempty"`
+}
@@ -7,13 +7,15 @@ import (
 )
 
 type User struct {
-	ID        string    `json:"id"`
-	Email     string    `json:"email"`
-	FirstName string    `json:"first_name"`
-	LastName  string    `json:"last_name"`
-	Password  string    `json:"-"` // Hide from JSON
-	CreatedAt time.Time `json:"created_at"`
-	UpdatedAt time.Time `json:"updated_at"`
+	ID            string    `json:"id"`
+	Email         string    `json:"email"`
+	FirstName     string    `json:"first_name"`
+	LastName      string    `json:"last_name"`
+	Password      string    `json:"-"` // Hide from JSON
+	EmailVerified bool      `json:"email_verified"`
+	MFAEnabled    bool      `json:"mfa_enabled"`
+	CreatedAt     time.Time `json:"created_at"`
+	UpdatedAt     time.Time `json:"updated_at"`
 }
 
 type LoginRequest struct {
@@ -34,7 +36,100 @@ type AuthResponse struct {
 }
 
 type Claims struct {
-    UserID string `json:"user_id"`
-    Email  string `json:"email"`
+    UserID        string `json:"user_id"`
+    Email         string `json:"email"`
+    EmailVerified bool   `json:"email_verified"`
     jwt.StandardClaims
-}
\ No newline at end of file
+}
+
+type ForgotPasswordRequest struct {
+	Email string `json:"email"`
+}
+
+type ResetPasswordRequest struct {
+	Token    string `json:"token"`
+	Password string `json:"password"`
+}
+
+type MessageResponse struct {
+	Message string `json:"message"`
+}
+
+// SessionResponse describes the session behind a valid token
+type SessionResponse struct {
+	UserID    string    `json:"user_id"`
+	Email     string    `json:"email"`
+	SessionID string    `json:"session_id"`
+	ExpiresAt time.Time `json:"expires_at"`
+}
+
+// MFAChallengeResponse is the login response for an account with MFA; the
+// challenge token and a code from the authenticator app are exchanged for
+// the real token at /api/v1/auth/login/mfa
+type MFAChallengeResponse struct {
+	MFARequired    bool      `json:"mfa_required"`
+	ChallengeToken string    `json:"challenge_token"`
+	ExpiresAt      time.Time `json:"expires_at"`
+}
+
+// MFALoginRequest carries either a TOTP code or a recovery code
+type MFALoginRequest struct {
+	ChallengeToken string `json:"challenge_token"`
+	Code           string `json:"code,omitempty"`
+	RecoveryCode   string `json:"recovery_code,omitempty"`
+}
+
+type MFAEnrollResponse struct {
+	Secret     string `json:"secret"`
+	OTPAuthURI string `json:"otpauth_uri"`
+	QRCode     string `json:"qr_code"`
+}
+
+type MFAConfirmRequest struct {
+	Code string `json:"code"`
+}
+
+type MFADisableRequest struct {
+	Password     string `json:"password"`
+	Code         string `json:"code,omitempty"`
+	RecoveryCode string `json:"recovery_code,omitempty"`
+}
+
+// RecoveryCodesResponse lists recovery codes; they are shown only once
+type RecoveryCodesResponse struct {
+	RecoveryCodes []string `json:"recovery_codes"`
+}
+
+// PasswordViolation is one password policy rule a password fails
+type PasswordViolation struct {
+	Rule    string `json:"rule"`
+	Message string `json:"message"`
+}
+
+// PasswordPolicyError lists every rule a rejected password fails
+type PasswordPolicyError struct {
+	Error      string              `json:"error"`
+	Violations []PasswordViolation `json:"violations"`
+}
+
+// PasswordPolicyResponse describes the rules new passwords must meet
+type PasswordPolicyResponse struct {
+	MinLength        int  `json:"min_length"`
+	MaxBytes         int  `json:"max_bytes"`
+	RequireUppercase bool `json:"require_uppercase"`
+	RequireLowercase bool `json:"require_lowercase"`
+	RequireDigit     bool `json:"require_digit"`
+	RequireSymbol    bool `json:"require_symbol"`
+	DisallowPersonal bool `json:"disallow_personal_info"`
+	CheckBreached    bool `json:"check_breached"`
+}
+
+type ChangePasswordRequest struct {
+	CurrentPassword string `json:"current_password"`
+	NewPassword     string `json:"new_password"`
+}
+
+type ChangeEmailRequest struct {
+	NewEmail string `json:"new_email"`
+	Password string `json:"password"`
+}
@@ -0,0 +1,153 @@
+package notifier
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"os"
+	"strings"
+	"time"
+)
+
+// Notification is a request to notification-service's from-template API
+type Notification struct {
+	UserID   string                 `json:"user_id"`
+	Type     string                 `json:"type"`
+	Template string                 `json:"template"`
+	Data     map[string]interface{} `json:"data"`
+	// DedupeKey makes retries of one notification create it only once
+	DedupeKey string `json:"dedupe_key"`
+	// Transactional sends the notification regardless of the user's
+	// preferences, digest and quiet hours
+	Transactional bool `json:"transactional,omitempty"`
+}
+
+// Client sends notifications in the background so requests never wait on
+// notification-service. Each notification is retried with exponential
+// backoff while the service is unreachable or failing.
+type Client struct {
+	baseURL      string
+	serviceToken string
+	client       *http.Client
+	queue        chan *Notification
+	workers      int
+	maxAttempts  int
+	backoff      time.Duration
+}
+
+func NewClient(baseURL string) *Client {
+	return &Client{
+		baseURL:     strings.TrimRight(baseURL, "/"),
+		client:      &http.Client{Timeout: 10 * time.Second},
+		queue:       make(chan *Notification, 1000),
+		workers:     4,
+		maxAttempts: 5,
+		backoff:     time.Second,
+	}
+}
+
+// NewClientFromEnv reads NOTIFICATION_SERVICE_URL and SERVICE_TOKEN, which
+// notification-service requires before it mails the address given in a
+// notification's data rather than the one on the user's account
+func NewClientFromEnv() *Client {
+	baseURL := os.Getenv("NOTIFICATION_SERVICE_URL")
+	if baseURL == "" {
+		baseURL = "http://notification-service:8083"
+	}
+	c := NewClient(baseURL)
+	c.serviceToken = os.Getenv("SERVICE_TOKEN")
+	return c
+}
+
+// Send queues a notification without blocking. When the queue is full the
+// notification is dropped and logged.
+func (c *Client) Send(n Notification) {
+	if n.DedupeKey == "" {
+		n.DedupeKey = newDedupeKey()
+	}
+
+	select {
+	case c.queue <- &n:
+	default:
+		log.Printf("⚠️ Notification queue full; dropped %s %s notification for user %s", n.Template, n.Type, n.UserID)
+	}
+}
+
+// Run delivers queued notifications until ctx is cancelled
+func (c *Client) Run(ctx context.Context) {
+	for i := 0; i < c.workers; i++ {
+		go func() {
+			for {
+				select {
+				case <-ctx.Done():
+					return
+				case n := <-c.queue:
+					if err := c.deliver(ctx, n); err != nil {
+						log.Printf("❌ Failed to send %s %s notification to user %s: %v", n.Template, n.Type, n.UserID, err)
+					}
+				}
+			}
+		}()
+	}
+	<-ctx.Done()
+}
+
+func (c *Client) deliver(ctx context.Context, n *Notification) error {
+	body, err := json.Marshal(n)
+	if err != nil {
+		return err
+	}
+
+	backoff := c.backoff
+	for attempt := 1; ; attempt++ {
+		retry, err := c.post(ctx, body)
+		if err == nil {
+			return nil
+		}
+		if !retry || attempt == c.maxAttempts {
+			return fmt.Errorf("giving up after %d attempts: %w", attempt, err)
+		}
+
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(backoff):
+		}
+		backoff *= 2
+	}
+}
+
+// post sends one attempt and reports whether a failure is worth retrying
+func (c *Client) post(ctx context.Context, body []byte) (bool, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, c.baseURL+"/api/v1/notifications/from-template", bytes.NewReader(body))
+	if err != nil {
+		return false, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	if c.serviceToken != "" {
+		req.Header.Set("X-Service-Token", c.serviceToken)
+	}
+
+	resp, err := c.client.Do(req)
+	if err != nil {
+		return true, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode >= 200 && resp.StatusCode < 300 {
+		return true, nil
+	}
+	retry := resp.StatusCode == http.StatusTooManyRequests || resp.StatusCode >= 500
+	return retry, fmt.Errorf("notification service returned status %d", resp.StatusCode)
+}
+
+func newDedupeKey() string {
+	b := make([]byte, 16)
+	rand.Read(b)
+	return "auth-" + hex.EncodeToString(b)
+}
@@ -0,0 +1,108 @@
+package outbox
+
+import (
+	"auth-service/internal/database"
+	"auth-service/internal/events"
+	"auth-service/internal/models"
+	"context"
+	"encoding/json"
+	"log"
+	"os"
+	"time"
+)
+
+// Relay publishes events from the outbox table to the event bus. An event
+// is marked published only after the bus accepts it, so delivery is at
+// least once and consumers ignore repeated event IDs.
+type Relay struct {
+	db        *database.DB
+	bus       events.Bus
+	source    string
+	interval  time.Duration
+	retention time.Duration
+	batchSize int
+}
+
+func NewRelay(db *database.DB, bus events.Bus, source string, interval, retention time.Duration) *Relay {
+	return &Relay{
+		db:        db,
+		bus:       bus,
+		source:    source,
+		interval:  interval,
+		retention: retention,
+		batchSize: 100,
+	}
+}
+
+// NewRelayFromEnv reads OUTBOX_POLL_INTERVAL (default 1s) and
+// OUTBOX_RETENTION, how long published events are kept (default 168h)
+func NewRelayFromEnv(db *database.DB, bus events.Bus, source string) *Relay {
+	interval := time.Second
+	if value, err := time.ParseDuration(os.Getenv("OUTBOX_POLL_INTERVAL")); err == nil && value > 0 {
+		interval = value
+	}
+	retention := 7 * 24 * time.Hour
+	if value, err := time.ParseDuration(os.Getenv("OUTBOX_RETENTION")); err == nil && value > 0 {
+		retention = value
+	}
+	return NewRelay(db, bus, source, interval, retention)
+}
+
+// Run relays the outbox every interval and purges published events hourly
+// until ctx is cancelled
+func (r *Relay) Run(ctx context.Context) {
+	ticker := time.NewTicker(r.interval)
+	defer ticker.Stop()
+	lastPurge := time.Now()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+
+		r.Drain(ctx)
+
+		if time.Since(lastPurge) >= time.Hour {
+			lastPurge = time.Now()
+			purged, err := r.db.PurgePublishedOutbox(r.retention)
+			if err != nil {
+				log.Printf("❌ Failed to purge outbox: %v", err)
+			} else if purged > 0 {
+				log.Printf("🧹 Purged %d published outbox events", purged)
+			}
+		}
+	}
+}
+
+// Drain publishes batches until the outbox is empty or publishing fails
+func (r *Relay) Drain(ctx context.Context) {
+	for ctx.Err() == nil {
+		published, err := r.db.RelayOutbox(r.batchSize, func(event *models.OutboxEvent) error {
+			return r.publish(ctx, event)
+		})
+		if err != nil {
+			log.Printf("❌ Failed to relay outbox events: %v", err)
+			return
+		}
+		if published < r.batchSize {
+			return
+		}
+	}
+}
+
+func (r *Relay) publish(ctx context.Context, event *models.OutboxEvent) error {
+	body, err := json.Marshal(events.Envelope{
+		ID:          event.EventID,
+		Type:        event.Type,
+		Source:      r.source,
+		AggregateID: event.AggregateID,
+		OccurredAt:  event.CreatedAt,
+		Data:        event.Payload,
+	})
+	if err != nil {
+		return err
+	}
+	return r.bus.Publish(ctx, events.Message{ID: event.EventID, Subject: event.Type, Data: body})
+}
@@ -0,0 +1,130 @@
+package passwords
+
+import (
+	"bufio"
+	"crypto/sha1"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"strings"
+)
+
+// prefixLength is the number of hex digits of a SHA-1 that select a range,
+// as in the Pwned Passwords range API
+const prefixLength = 5
+
+// BreachedList holds SHA-1 hashes of passwords known from breaches, grouped
+// by the first five hex digits. A password is looked up by hashing it and
+// searching only the range for its prefix, so the list can equally be a
+// local file or a k-anonymity service that is only ever sent the prefix.
+type BreachedList struct {
+	ranges map[string][]string
+	count  int
+}
+
+// LoadBreachedList reads a list from path. Each line is either a SHA-1 hash
+// in hex, optionally followed by ":count" as in the Pwned Passwords
+// downloads, or a plain password; blank lines and lines starting with # are
+// skipped.
+func LoadBreachedList(path string) (*BreachedList, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open breached password list: %w", err)
+	}
+	defer file.Close()
+
+	list, err := ParseBreachedList(file)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read breached password list %s: %w", path, err)
+	}
+	return list, nil
+}
+
+func ParseBreachedList(r io.Reader) (*BreachedList, error) {
+	list := &BreachedList{ranges: make(map[string][]string)}
+
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		hash := line
+		if i := strings.IndexByte(line, ':'); i == 40 {
+			hash = line[:i]
+		}
+		if !isSHA1Hex(hash) {
+			hash = hashPassword(line)
+		}
+		list.add(strings.ToUpper(hash))
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+
+	for prefix, suffixes := range list.ranges {
+		sort.Strings(suffixes)
+		list.ranges[prefix] = dedupe(suffixes)
+	}
+	list.count = 0
+	for _, suffixes := range list.ranges {
+		list.count += len(suffixes)
+	}
+	return list, nil
+}
+
+// DefaultBreachedList is built from a short list of the most common
+// passwords, used when no list file is configured
+func DefaultBreachedList() *BreachedList {
+	list, _ := ParseBreachedList(strings.NewReader(strings.Join(commonPasswords, "\n")))
+	return list
+}
+
+func (l *BreachedList) add(hash string) {
+	prefix, suffix := hash[:prefixLength], hash[prefixLength:]
+	l.ranges[prefix] = append(l.ranges[prefix], suffix)
+}
+
+// Range returns the sorted hash suffixes under a five-digit prefix
+func (l *BreachedList) Range(prefix string) []string {
+	return l.ranges[strings.ToUpper(prefix)]
+}
+
+// Contains reports whether password is on the list
+func (l *BreachedList) Contains(password string) bool {
+	hash := hashPassword(password)
+	suffixes := l.Range(hash[:prefixLength])
+	suffix := hash[prefixLength:]
+	i := sort.SearchStrings(suffixes, suffix)
+	return i < len(suffixes) && suffixes[i] == suffix
+}
+
+// Len is the number of distinct hashes on the list
+func (l *BreachedList) Len() int {
+	return l.count
+}
+
+func hashPassword(password string) string {
+	sum := sha1.Sum([]byte(password))
+	return strings.ToUpper(hex.EncodeToString(sum[:]))
+}
+
+func isSHA1Hex(s string) bool {
+	if len(s) != 40 {
+		return false
+	}
+	_, err := hex.DecodeString(s)
+	return err == nil
+}
+
+func dedupe(sorted []string) []string {
+	out := sorted[:0]
+	for i, s := range sorted {
+		if i == 0 || s != sorted[i-1] {
+			out = append(out, s)
+		}
+	}
+	return out
+}
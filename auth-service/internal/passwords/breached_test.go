@@ -0,0 +1,86 @@
+package passwords
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// SHA-1 of "password", as it appears in the Pwned Passwords downloads
+const passwordSHA1 = "5BAA61E4C9B93F3F0682250B6CF8331B7EE68FD8"
+
+func TestParseBreachedList(t *testing.T) {
+	input := strings.Join([]string{
+		"# comment",
+		"",
+		passwordSHA1 + ":9545824",
+		strings.ToLower(passwordSHA1),
+		"  hunter2  ",
+		"not:a hash",
+	}, "\n")
+
+	list, err := ParseBreachedList(strings.NewReader(input))
+	if err != nil {
+		t.Fatalf("ParseBreachedList returned error: %v", err)
+	}
+	if list.Len() != 3 {
+		t.Errorf("Len = %d, want 3", list.Len())
+	}
+	for _, password := range []string{"password", "hunter2", "not:a hash"} {
+		if !list.Contains(password) {
+			t.Errorf("list does not contain %q", password)
+		}
+	}
+	for _, password := range []string{"Password", "hunter3", "# comment", ""} {
+		if list.Contains(password) {
+			t.Errorf("list contains %q", password)
+		}
+	}
+}
+
+func TestBreachedListRange(t *testing.T) {
+	list, err := ParseBreachedList(strings.NewReader("password\nhunter2"))
+	if err != nil {
+		t.Fatalf("ParseBreachedList returned error: %v", err)
+	}
+
+	suffixes := list.Range(strings.ToLower(passwordSHA1[:prefixLength]))
+	if len(suffixes) != 1 || suffixes[0] != passwordSHA1[prefixLength:] {
+		t.Errorf("Range = %v, want [%s]", suffixes, passwordSHA1[prefixLength:])
+	}
+	if suffixes := list.Range("00000"); len(suffixes) != 0 {
+		t.Errorf("Range of an unused prefix = %v", suffixes)
+	}
+}
+
+func TestLoadBreachedList(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "breached.txt")
+	if err := os.WriteFile(path, []byte(passwordSHA1+":3\n"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	list, err := LoadBreachedList(path)
+	if err != nil {
+		t.Fatalf("LoadBreachedList returned error: %v", err)
+	}
+	if !list.Contains("password") {
+		t.Error("loaded list does not contain \"password\"")
+	}
+
+	if _, err := LoadBreachedList(filepath.Join(t.TempDir(), "missing.txt")); err == nil {
+		t.Error("LoadBreachedList succeeded for a missing file")
+	}
+}
+
+func TestDefaultBreachedList(t *testing.T) {
+	list := DefaultBreachedList()
+	if list.Len() != len(commonPasswords) {
+		t.Errorf("Len = %d, want one hash per common password (%d)", list.Len(), len(commonPasswords))
+	}
+	for _, password := range commonPasswords {
+		if !list.Contains(password) {
+			t.Errorf("default list does not contain %q", password)
+		}
+	}
+}
@@ -0,0 +1,21 @@
+package passwords
+
+// commonPasswords are among the most used passwords in public breach
+// corpora. Deployments should set PASSWORD_BLOCKLIST_FILE to a fuller list.
+var commonPasswords = []string{
+	"123456", "123456789", "12345678", "1234567890", "12345", "1234567",
+	"password", "password1", "password123", "Password1", "Password123",
+	"Password1!", "Passw0rd", "P@ssw0rd", "P@ssword1", "qwerty", "qwerty123",
+	"Qwerty123", "Qwerty123!", "qwertyuiop", "1q2w3e4r", "1q2w3e4r5t",
+	"1qaz2wsx", "zaq12wsx", "abc123", "Abc12345", "Abcd1234", "abcd1234",
+	"111111", "000000", "123123", "654321", "666666", "121212", "987654321",
+	"iloveyou", "Iloveyou1", "admin", "admin123", "Admin123", "Admin@123",
+	"welcome", "welcome1", "Welcome1", "Welcome123", "Welcome@123", "letmein",
+	"Letmein1", "monkey", "dragon", "football", "baseball", "sunshine",
+	"Sunshine1", "princess", "master", "shadow", "superman", "trustno1",
+	"starwars", "whatever", "freedom", "michael", "jennifer", "charlie",
+	"Summer2024", "Summer2025", "Summer2026", "Winter2024", "Winter2025",
+	"Winter2026", "Spring2025", "Spring2026", "Autumn2025", "Autumn2026",
+	"Changeme1", "changeme", "Changeme123", "Password2024", "Password2025",
+	"Password2026", "Taskmanager1", "TaskManager1", "Taskmanager123",
+}
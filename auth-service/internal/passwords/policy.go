@@ -0,0 +1,170 @@
+package passwords
+
+import (
+	"auth-service/internal/models"
+	"fmt"
+	"log"
+	"os"
+	"strconv"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// maxBytes is the most bcrypt hashes; longer passwords are rejected rather
+// than silently truncated
+const maxBytes = 72
+
+// Policy is the set of rules a new password must meet
+type Policy struct {
+	MinLength        int
+	RequireUppercase bool
+	RequireLowercase bool
+	RequireDigit     bool
+	RequireSymbol    bool
+	// DisallowPersonal rejects passwords containing the email address's
+	// local part or the user's first or last name
+	DisallowPersonal bool
+}
+
+// PolicyFromEnv reads PASSWORD_MIN_LENGTH (default 10) and
+// PASSWORD_REQUIRE_UPPERCASE, PASSWORD_REQUIRE_LOWERCASE,
+// PASSWORD_REQUIRE_DIGIT (default true), PASSWORD_REQUIRE_SYMBOL (default
+// false) and PASSWORD_DISALLOW_PERSONAL (default true)
+func PolicyFromEnv() Policy {
+	policy := Policy{
+		MinLength:        10,
+		RequireUppercase: envBool("PASSWORD_REQUIRE_UPPERCASE", true),
+		RequireLowercase: envBool("PASSWORD_REQUIRE_LOWERCASE", true),
+		RequireDigit:     envBool("PASSWORD_REQUIRE_DIGIT", true),
+		RequireSymbol:    envBool("PASSWORD_REQUIRE_SYMBOL", false),
+		DisallowPersonal: envBool("PASSWORD_DISALLOW_PERSONAL", true),
+	}
+	if value, err := strconv.Atoi(os.Getenv("PASSWORD_MIN_LENGTH")); err == nil && value > 0 {
+		policy.MinLength = value
+	}
+	return policy
+}
+
+func envBool(name string, fallback bool) bool {
+	value, err := strconv.ParseBool(os.Getenv(name))
+	if err != nil {
+		return fallback
+	}
+	return value
+}
+
+// Person is what a password is checked against for personal information
+type Person struct {
+	Email     string
+	FirstName string
+	LastName  string
+}
+
+// Validator checks new passwords against a policy and a breached list
+type Validator struct {
+	policy   Policy
+	breached *BreachedList
+}
+
+func NewValidator(policy Policy, breached *BreachedList) *Validator {
+	return &Validator{policy: policy, breached: breached}
+}
+
+// NewValidatorFromEnv reads the policy from the environment and the breached
+// list from PASSWORD_BLOCKLIST_FILE, falling back to the built-in list of
+// common passwords when it is unset
+func NewValidatorFromEnv() (*Validator, error) {
+	breached := DefaultBreachedList()
+	if path := os.Getenv("PASSWORD_BLOCKLIST_FILE"); path != "" {
+		list, err := LoadBreachedList(path)
+		if err != nil {
+			return nil, err
+		}
+		breached = list
+		log.Printf("🔐 Loaded %d breached password hashes from %s", list.Len(), path)
+	}
+	return NewValidator(PolicyFromEnv(), breached), nil
+}
+
+// Describe returns the policy for clients to show before a password is sent
+func (v *Validator) Describe() models.PasswordPolicyResponse {
+	return models.PasswordPolicyResponse{
+		MinLength:        v.policy.MinLength,
+		MaxBytes:         maxBytes,
+		RequireUppercase: v.policy.RequireUppercase,
+		RequireLowercase: v.policy.RequireLowercase,
+		RequireDigit:     v.policy.RequireDigit,
+		RequireSymbol:    v.policy.RequireSymbol,
+		DisallowPersonal: v.policy.DisallowPersonal,
+		CheckBreached:    v.breached != nil,
+	}
+}
+
+// Validate returns every rule password fails for person, or nil when it
+// meets them all
+func (v *Validator) Validate(password string, person Person) []models.PasswordViolation {
+	var violations []models.PasswordViolation
+	add := func(rule, message string) {
+		violations = append(violations, models.PasswordViolation{Rule: rule, Message: message})
+	}
+
+	if utf8.RuneCountInString(password) < v.policy.MinLength {
+		add("min_length", fmt.Sprintf("Password must be at least %d characters long", v.policy.MinLength))
+	}
+	if len(password) > maxBytes {
+		add("max_length", fmt.Sprintf("Password must be at most %d bytes long", maxBytes))
+	}
+
+	var upper, lower, digit, symbol bool
+	for _, r := range password {
+		switch {
+		case unicode.IsUpper(r):
+			upper = true
+		case unicode.IsLower(r):
+			lower = true
+		case unicode.IsDigit(r):
+			digit = true
+		case unicode.IsPunct(r) || unicode.IsSymbol(r) || unicode.IsSpace(r):
+			symbol = true
+		}
+	}
+	if v.policy.RequireUppercase && !upper {
+		add("uppercase", "Password must contain an uppercase letter")
+	}
+	if v.policy.RequireLowercase && !lower {
+		add("lowercase", "Password must contain a lowercase letter")
+	}
+	if v.policy.RequireDigit && !digit {
+		add("digit", "Password must contain a digit")
+	}
+	if v.policy.RequireSymbol && !symbol {
+		add("symbol", "Password must contain a symbol")
+	}
+
+	if v.policy.DisallowPersonal {
+		lowered := strings.ToLower(password)
+		local := strings.ToLower(person.Email)
+		if at := strings.LastIndex(local, "@"); at >= 0 {
+			local = local[:at]
+		}
+		if containsPart(lowered, local) {
+			add("contains_email", "Password must not contain your email address")
+		}
+		if containsPart(lowered, strings.ToLower(person.FirstName)) || containsPart(lowered, strings.ToLower(person.LastName)) {
+			add("contains_name", "Password must not contain your name")
+		}
+	}
+
+	if v.breached != nil && v.breached.Contains(password) {
+		add("breached", "Password is too common or has appeared in a data breach")
+	}
+	return violations
+}
+
+// containsPart reports whether part, when long enough to mean anything, is
+// in password
+func containsPart(password, part string) bool {
+	part = strings.TrimSpace(part)
+	return utf8.RuneCountInString(part) >= 3 && strings.Contains(password, part)
+}
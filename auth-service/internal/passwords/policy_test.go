@@ -0,0 +1,113 @@
+package passwords
+
+import (
+	"auth-service/internal/models"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+var testPolicy = Policy{
+	MinLength:        10,
+	RequireUppercase: true,
+	RequireLowercase: true,
+	RequireDigit:     true,
+	RequireSymbol:    true,
+	DisallowPersonal: true,
+}
+
+var testPerson = Person{Email: "ada.lovelace@example.test", FirstName: "Ada", LastName: "Lovelace"}
+
+func violatedRules(violations []models.PasswordViolation) []string {
+	var rules []string
+	for _, violation := range violations {
+		rules = append(rules, violation.Rule)
+	}
+	return rules
+}
+
+func TestValidate(t *testing.T) {
+	validator := NewValidator(testPolicy, DefaultBreachedList())
+
+	tests := []struct {
+		name     string
+		password string
+		person   Person
+		want     []string
+	}{
+		{name: "meets every rule", password: "Correct horse 7 battery", person: testPerson},
+		{name: "too short", password: "Ab1!", want: []string{"min_length"}},
+		{name: "length counts characters, not bytes", password: "Ünïcødé1!x"},
+		{name: "too many bytes", password: "A1!" + strings.Repeat("a", 70), want: []string{"max_length"}},
+		{name: "missing character classes", password: "alllowercase", want: []string{"uppercase", "digit", "symbol"}},
+		{name: "only digits", password: "20261019104500", want: []string{"uppercase", "lowercase", "symbol"}},
+		{name: "contains the email address", password: "X1!ada.lovelace", person: testPerson, want: []string{"contains_email", "contains_name"}},
+		{name: "contains the name, any case", password: "LOVELACE-rocks-1", person: testPerson, want: []string{"contains_name"}},
+		{name: "short names are ignored", password: "Always-1234", person: Person{Email: "al@example.test", FirstName: "Al"}},
+		{name: "common password", password: "Welcome@123", want: []string{"breached"}},
+		{name: "empty", password: "", want: []string{"min_length", "uppercase", "lowercase", "digit", "symbol"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := violatedRules(validator.Validate(tt.password, tt.person))
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Validate(%q) = %v, want %v", tt.password, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestValidateOptionalRules(t *testing.T) {
+	validator := NewValidator(Policy{MinLength: 4}, nil)
+
+	if violations := validator.Validate("ada.lovelace", testPerson); violations != nil {
+		t.Errorf("rules that are off were applied: %v", violatedRules(violations))
+	}
+	if violations := validator.Validate("password", testPerson); violations != nil {
+		t.Errorf("checked a breached list that is not configured: %v", violatedRules(violations))
+	}
+}
+
+func TestDescribe(t *testing.T) {
+	want := models.PasswordPolicyResponse{
+		MinLength:        10,
+		MaxBytes:         72,
+		RequireUppercase: true,
+		RequireLowercase: true,
+		RequireDigit:     true,
+		RequireSymbol:    true,
+		DisallowPersonal: true,
+		CheckBreached:    true,
+	}
+	if got := NewValidator(testPolicy, DefaultBreachedList()).Describe(); got != want {
+		t.Errorf("Describe = %+v, want %+v", got, want)
+	}
+	if NewValidator(testPolicy, nil).Describe().CheckBreached {
+		t.Error("Describe reports a breached check without a list")
+	}
+}
+
+func TestPolicyFromEnv(t *testing.T) {
+	for _, name := range []string{"PASSWORD_MIN_LENGTH", "PASSWORD_REQUIRE_UPPERCASE", "PASSWORD_REQUIRE_LOWERCASE", "PASSWORD_REQUIRE_DIGIT", "PASSWORD_REQUIRE_SYMBOL", "PASSWORD_DISALLOW_PERSONAL"} {
+		t.Setenv(name, "")
+	}
+	want := Policy{MinLength: 10, RequireUppercase: true, RequireLowercase: true, RequireDigit: true, DisallowPersonal: true}
+	if got := PolicyFromEnv(); got != want {
+		t.Errorf("default policy = %+v, want %+v", got, want)
+	}
+
+	t.Setenv("PASSWORD_MIN_LENGTH", "14")
+	t.Setenv("PASSWORD_REQUIRE_UPPERCASE", "false")
+	t.Setenv("PASSWORD_REQUIRE_SYMBOL", "1")
+	t.Setenv("PASSWORD_REQUIRE_DIGIT", "maybe")
+	want = Policy{MinLength: 14, RequireLowercase: true, RequireDigit: true, RequireSymbol: true, DisallowPersonal: true}
+	if got := PolicyFromEnv(); got != want {
+		t.Errorf("configured policy = %+v, want %+v", got, want)
+	}
+
+	t.Setenv("PASSWORD_MIN_LENGTH", "0")
+	if got := PolicyFromEnv(); got.MinLength != 10 {
+		t.Errorf("MinLength = %d for 0, want the default 10", got.MinLength)
+	}
+}
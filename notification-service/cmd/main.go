@@ -1,12 +1,22 @@
 package main
 
 import (
+	"context"
 	"encoding/json"
+	"fmt"
 	"log"
 	"net/http"
 	"os"
+	"notification-service/internal/channels"
+	"notification-service/internal/consumers"
 	"notification-service/internal/database"
+	"notification-service/internal/dispatcher"
+	"notification-service/internal/events"
 	"notification-service/internal/handlers"
+	"notification-service/internal/models"
+	"notification-service/internal/retention"
+	"notification-service/internal/stream"
+	"notification-service/internal/templates"
 	"time"
 
 	"github.com/gorilla/mux"
@@ -14,6 +24,17 @@ import (
 
 
 func main() {
+	// `notification-service generate-vapid-keys` prints a key pair for
+	// VAPID_PUBLIC_KEY and VAPID_PRIVATE_KEY
+	if len(os.Args) > 1 && os.Args[1] == "generate-vapid-keys" {
+		publicKey, privateKey, err := channels.GenerateVAPIDKeys()
+		if err != nil {
+			log.Fatal("❌ Failed to generate VAPID keys:", err)
+		}
+		fmt.Printf("VAPID_PUBLIC_KEY=%s\nVAPID_PRIVATE_KEY=%s\n", publicKey, privateKey)
+		return
+	}
+
 	// Initialize database
 	db, err := database.NewPostgresDB()
 	if err != nil {
@@ -26,8 +47,67 @@ func main() {
 		log.Fatal("❌ Notification Service: Database initialization failed:", err)
 	}
 
+	// Seed the built-in notification templates
+	if err := db.EnsureTemplates(templates.Builtin); err != nil {
+		log.Fatal("❌ Notification Service: Template seeding failed:", err)
+	}
+
+	// Deliver pending notifications in the background
+	userServiceURL := os.Getenv("USER_SERVICE_URL")
+	if userServiceURL == "" {
+		userServiceURL = "http://user-service:8081"
+	}
+	dispatchConfig := dispatcher.ConfigFromEnv()
+	emailChannel := channels.NewSMTPChannel(channels.SMTPConfigFromEnv(), channels.NewUserServiceResolver(userServiceURL))
+	notificationDispatcher := dispatcher.NewDispatcher(db, dispatchConfig)
+	notificationDispatcher.Register(models.InAppNotification, channels.InAppChannel{})
+	notificationDispatcher.Register(models.EmailNotification, emailChannel)
+	outbound := channels.OutboundPolicyFromEnv()
+	notificationDispatcher.Register(models.WebhookNotification, channels.NewWebhookChannel(db, outbound))
+
+	pushChannel, err := channels.NewWebPushChannel(channels.VAPIDConfigFromEnv(), db, outbound)
+	if err != nil {
+		log.Fatal("❌ Notification Service: Web push configuration failed:", err)
+	}
+	if pushChannel.PublicKey() == "" {
+		log.Println("⚠️ VAPID_PRIVATE_KEY is not set; push notifications will fail until web push is configured")
+	}
+	notificationDispatcher.Register(models.PushNotification, pushChannel)
+	go notificationDispatcher.Run(context.Background())
+
+	// Send held emails as hourly or daily digests
+	go dispatcher.NewDigestScheduler(db, dispatchConfig, emailChannel).Run(context.Background())
+
+	// Wake streaming clients when their in-app notifications are delivered
+	listener, err := db.NewStreamListener()
+	if err != nil {
+		log.Fatal("❌ Notification Service: Stream listener failed:", err)
+	}
+	hub := stream.NewHub(listener)
+	go hub.Run(context.Background())
+
+	heartbeat := 25 * time.Second
+	if value, err := time.ParseDuration(os.Getenv("STREAM_HEARTBEAT_INTERVAL")); err == nil && value > 0 {
+		heartbeat = value
+	}
+
+	// Expire and purge old notifications, and forget stale idempotency keys
+	go retention.NewJob(db, retention.PolicyFromEnv()).Run(context.Background())
+
+	// Turn task and user events from the other services into notifications
+	bus, err := events.NewBusFromEnv("notification-service")
+	if err != nil {
+		log.Fatal("❌ Notification Service: Event bus connection failed:", err)
+	}
+	defer bus.Close()
+	if err := consumers.NewConsumer(db, bus).Start(); err != nil {
+		log.Fatal("❌ Notification Service: Event subscription failed:", err)
+	}
+
 	// Initialize handlers
-	notificationHandler := handlers.NewNotificationHandler(db)
+	notificationHandler := handlers.NewNotificationHandler(db, outbound)
+	streamHandler := handlers.NewStreamHandler(db, hub, heartbeat)
+	pushHandler := handlers.NewPushHandler(db, pushChannel.PublicKey(), outbound)
 
 	// Setup routes
 	r := mux.NewRouter()
@@ -38,11 +118,39 @@ func main() {
 	
 	// API routes
 	r.HandleFunc("/api/v1/notifications", notificationHandler.CreateNotification).Methods("POST", "OPTIONS")
+	r.HandleFunc("/api/v1/notifications/from-template", notificationHandler.CreateFromTemplate).Methods("POST", "OPTIONS")
+	r.HandleFunc("/api/v1/notifications/push/public-key", pushHandler.GetPublicKey).Methods("GET", "OPTIONS")
 	r.HandleFunc("/api/v1/notifications/{id}", notificationHandler.GetNotification).Methods("GET", "OPTIONS")
 	r.HandleFunc("/api/v1/notifications/{id}", notificationHandler.DeleteNotification).Methods("DELETE", "OPTIONS")
 	r.HandleFunc("/api/v1/notifications/{id}/read", notificationHandler.MarkAsRead).Methods("PUT", "OPTIONS")
 	r.HandleFunc("/api/v1/notifications/{id}/status", notificationHandler.UpdateStatus).Methods("PUT", "OPTIONS")
 	r.HandleFunc("/api/v1/users/{user_id}/notifications", notificationHandler.GetUserNotifications).Methods("GET", "OPTIONS")
+	r.HandleFunc("/api/v1/users/{user_id}/notifications/read-all", notificationHandler.MarkAllAsRead).Methods("PUT", "OPTIONS")
+	r.HandleFunc("/api/v1/users/{user_id}/notifications/read", notificationHandler.MarkManyAsRead).Methods("PUT", "OPTIONS")
+	r.HandleFunc("/api/v1/users/{user_id}/notifications/delete", notificationHandler.DeleteMany).Methods("POST", "OPTIONS")
+	r.HandleFunc("/api/v1/users/{user_id}/webhooks", notificationHandler.CreateWebhook).Methods("POST", "OPTIONS")
+	r.HandleFunc("/api/v1/users/{user_id}/webhooks", notificationHandler.GetWebhooks).Methods("GET", "OPTIONS")
+	r.HandleFunc("/api/v1/users/{user_id}/webhooks/{id}", notificationHandler.UpdateWebhook).Methods("PUT", "OPTIONS")
+	r.HandleFunc("/api/v1/users/{user_id}/webhooks/{id}", notificationHandler.DeleteWebhook).Methods("DELETE", "OPTIONS")
+	r.HandleFunc("/api/v1/users/{user_id}/webhooks/{id}/deliveries", notificationHandler.GetWebhookDeliveries).Methods("GET", "OPTIONS")
+	r.HandleFunc("/api/v1/users/{user_id}/push-subscriptions", pushHandler.Subscribe).Methods("POST", "OPTIONS")
+	r.HandleFunc("/api/v1/users/{user_id}/push-subscriptions", pushHandler.GetSubscriptions).Methods("GET", "OPTIONS")
+	r.HandleFunc("/api/v1/users/{user_id}/push-subscriptions/{id}", pushHandler.Unsubscribe).Methods("DELETE", "OPTIONS")
+	r.HandleFunc("/api/v1/users/{user_id}/notification-preferences", notificationHandler.GetPreferences).Methods("GET", "OPTIONS")
+	r.HandleFunc("/api/v1/users/{user_id}/notification-preferences", notificationHandler.UpdatePreferences).Methods("PUT", "OPTIONS")
+	r.HandleFunc("/api/v1/users/{user_id}/notifications/stream", streamHandler.StreamSSE).Methods("GET")
+	r.HandleFunc("/api/v1/users/{user_id}/notifications/ws", streamHandler.StreamWebSocket).Methods("GET")
+
+	// Admin routes
+	admin := r.PathPrefix("/api/v1/admin/notifications").Subrouter()
+	admin.Use(handlers.AdminMiddleware)
+	admin.HandleFunc("/dead-letter", notificationHandler.GetDeadLetterNotifications).Methods("GET")
+	admin.HandleFunc("/dead-letter/requeue", notificationHandler.RequeueDeadLetters).Methods("POST")
+	admin.HandleFunc("/templates", notificationHandler.GetTemplates).Methods("GET")
+	admin.HandleFunc("/templates/{key}", notificationHandler.GetTemplates).Methods("GET")
+	admin.HandleFunc("/templates/{key}/{locale}", notificationHandler.PutTemplate).Methods("PUT")
+	admin.HandleFunc("/templates/{key}/{locale}", notificationHandler.DeleteTemplate).Methods("DELETE")
+	admin.HandleFunc("/{id}/requeue", notificationHandler.RequeueNotification).Methods("POST")
 	
 	// Enhanced health check
 	r.HandleFunc("/health", func(w http.ResponseWriter, r *http.Request) {
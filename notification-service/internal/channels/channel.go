@@ -0,0 +1,38 @@
+package channels
+
+import (
+	"context"
+	"notification-service/internal/models"
+)
+
+// Channel delivers a notification over one transport. Send returns nil once
+// the notification has been handed off successfully.
+type Channel interface {
+	Send(ctx context.Context, notification *models.Notification) error
+}
+
+// InAppChannel delivers in-app notifications, which are complete as soon as
+// they are stored and readable through the API
+type InAppChannel struct{}
+
+func (InAppChannel) Send(ctx context.Context, notification *models.Notification) error {
+	return nil
+}
+
+// PermanentError marks a delivery failure that retrying cannot fix, such as
+// a malformed address, so the notification is dead-lettered immediately
+type PermanentError struct {
+	Err error
+}
+
+func (e *PermanentError) Error() string {
+	return e.Err.Error()
+}
+
+func (e *PermanentError) Unwrap() error {
+	return e.Err
+}
+
+func Permanent(err error) error {
+	return &PermanentError{Err: err}
+}
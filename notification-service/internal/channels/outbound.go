@@ -0,0 +1,183 @@
+package channels
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"net"
+	"net/http"
+	"net/url"
+	"os"
+	"strconv"
+	"strings"
+	"syscall"
+	"time"
+)
+
+// ErrForbiddenAddress is returned for a URL that resolves to an address the
+// service must not send user-supplied requests to
+var ErrForbiddenAddress = errors.New("address is not publicly routable")
+
+// blockedNetworks are reserved ranges the standard library's address checks
+// do not cover: "this network", carrier-grade NAT and benchmarking
+var blockedNetworks = mustParseCIDRs("0.0.0.0/8", "100.64.0.0/10", "198.18.0.0/15")
+
+// OutboundPolicy restricts where webhooks and push messages are delivered.
+// Their URLs are chosen by users, so without it anyone could make the
+// service post to itself, its neighbours or the cloud metadata endpoint and
+// read the status and timing of each attempt from the delivery log. Addresses are checked
+// when connecting, after DNS resolution, so a host name cannot be pointed
+// at an internal address once it has been registered.
+type OutboundPolicy struct {
+	// AllowHTTP accepts plain http webhook URLs; otherwise only https is
+	// accepted
+	AllowHTTP bool
+	// AllowedNetworks are reachable even though they are loopback, private
+	// or link-local, for receivers running next to the service in
+	// development
+	AllowedNetworks []*net.IPNet
+}
+
+// OutboundPolicyFromEnv reads WEBHOOK_ALLOW_HTTP (default false) and
+// OUTBOUND_ALLOWED_NETWORKS, a comma-separated list of IP addresses and CIDR
+// ranges exempt from the private address check
+func OutboundPolicyFromEnv() OutboundPolicy {
+	policy := OutboundPolicy{}
+	if value, err := strconv.ParseBool(os.Getenv("WEBHOOK_ALLOW_HTTP")); err == nil {
+		policy.AllowHTTP = value
+	}
+	for _, entry := range strings.Split(os.Getenv("OUTBOUND_ALLOWED_NETWORKS"), ",") {
+		entry = strings.TrimSpace(entry)
+		if entry == "" {
+			continue
+		}
+		network, err := parseNetwork(entry)
+		if err != nil {
+			log.Printf("⚠️ Ignoring OUTBOUND_ALLOWED_NETWORKS entry %q: %v", entry, err)
+			continue
+		}
+		policy.AllowedNetworks = append(policy.AllowedNetworks, network)
+	}
+	return policy
+}
+
+// CheckWebhookURL rejects a webhook URL that is not absolute https (or http
+// when allowed) or names a forbidden address directly. Host names are only
+// checked when a delivery connects.
+func (p OutboundPolicy) CheckWebhookURL(raw string) error {
+	return p.checkURL(raw, p.AllowHTTP)
+}
+
+// CheckPushEndpoint is CheckWebhookURL for a push subscription's endpoint,
+// which is always https (RFC 8030)
+func (p OutboundPolicy) CheckPushEndpoint(raw string) error {
+	return p.checkURL(raw, false)
+}
+
+func (p OutboundPolicy) checkURL(raw string, allowHTTP bool) error {
+	u, err := url.Parse(raw)
+	if err != nil || u.Host == "" || u.User != nil {
+		return fmt.Errorf("must be an absolute URL")
+	}
+	if u.Scheme != "https" && !(allowHTTP && u.Scheme == "http") {
+		if allowHTTP {
+			return fmt.Errorf("must be an http or https URL")
+		}
+		return fmt.Errorf("must be an https URL")
+	}
+
+	host := u.Hostname()
+	if strings.EqualFold(host, "localhost") || strings.HasSuffix(strings.ToLower(host), ".localhost") {
+		host = "127.0.0.1"
+	}
+	if ip := net.ParseIP(host); ip != nil && !p.Permits(ip) {
+		return ErrForbiddenAddress
+	}
+	return nil
+}
+
+// Permits reports whether requests may be sent to ip
+func (p OutboundPolicy) Permits(ip net.IP) bool {
+	for _, network := range p.AllowedNetworks {
+		if network.Contains(ip) {
+			return true
+		}
+	}
+	if ip.IsLoopback() || ip.IsPrivate() || ip.IsUnspecified() || ip.IsMulticast() ||
+		ip.IsLinkLocalUnicast() || ip.IsLinkLocalMulticast() || ip.IsInterfaceLocalMulticast() {
+		return false
+	}
+	for _, network := range blockedNetworks {
+		if network.Contains(ip) {
+			return false
+		}
+	}
+	return true
+}
+
+// Client returns an HTTP client that refuses to connect to addresses the
+// policy does not permit and does not follow redirects. It never uses a
+// proxy, which would connect on its behalf without the check.
+func (p OutboundPolicy) Client(timeout time.Duration) *http.Client {
+	dialer := &net.Dialer{
+		Timeout:   timeout,
+		KeepAlive: 30 * time.Second,
+		Control: func(network, address string, _ syscall.RawConn) error {
+			host, _, err := net.SplitHostPort(address)
+			if err != nil {
+				return err
+			}
+			if ip := net.ParseIP(host); ip == nil || !p.Permits(ip) {
+				return fmt.Errorf("%w: %s", ErrForbiddenAddress, host)
+			}
+			return nil
+		},
+	}
+	return &http.Client{
+		Timeout: timeout,
+		Transport: &http.Transport{
+			DialContext: func(ctx context.Context, network, address string) (net.Conn, error) {
+				return dialer.DialContext(ctx, network, address)
+			},
+			ForceAttemptHTTP2:     true,
+			MaxIdleConns:          100,
+			IdleConnTimeout:       90 * time.Second,
+			TLSHandshakeTimeout:   10 * time.Second,
+			ExpectContinueTimeout: time.Second,
+		},
+		// A redirect could send the request somewhere the user never
+		// registered
+		CheckRedirect: func(req *http.Request, via []*http.Request) error {
+			return http.ErrUseLastResponse
+		},
+	}
+}
+
+// parseNetwork reads a CIDR range or a single IP address
+func parseNetwork(entry string) (*net.IPNet, error) {
+	if _, network, err := net.ParseCIDR(entry); err == nil {
+		return network, nil
+	}
+	ip := net.ParseIP(entry)
+	if ip == nil {
+		return nil, fmt.Errorf("not an IP address or CIDR range")
+	}
+	bits := 8 * net.IPv6len
+	if ip.To4() != nil {
+		ip, bits = ip.To4(), 8*net.IPv4len
+	}
+	return &net.IPNet{IP: ip, Mask: net.CIDRMask(bits, bits)}, nil
+}
+
+func mustParseCIDRs(entries ...string) []*net.IPNet {
+	networks := make([]*net.IPNet, len(entries))
+	for i, entry := range entries {
+		_, network, err := net.ParseCIDR(entry)
+		if err != nil {
+			panic(err)
+		}
+		networks[i] = network
+	}
+	return networks
+}
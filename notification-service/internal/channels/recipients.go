@@ -0,0 +1,57 @@
+package channels
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// EmailResolver looks up the address a user's email notifications go to
+type EmailResolver interface {
+	ResolveEmail(ctx context.Context, userID string) (string, error)
+}
+
+// UserServiceResolver resolves addresses through user-service
+type UserServiceResolver struct {
+	baseURL string
+	client  *http.Client
+}
+
+func NewUserServiceResolver(baseURL string) *UserServiceResolver {
+	return &UserServiceResolver{
+		baseURL: strings.TrimRight(baseURL, "/"),
+		client:  &http.Client{Timeout: 5 * time.Second},
+	}
+}
+
+func (r *UserServiceResolver) ResolveEmail(ctx context.Context, userID string) (string, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, r.baseURL+"/api/v1/users/"+userID, nil)
+	if err != nil {
+		return "", err
+	}
+
+	resp, err := r.client.Do(req)
+	if err != nil {
+		return "", fmt.Errorf("failed to look up user %s: %w", userID, err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return "", fmt.Errorf("failed to look up user %s: status %d", userID, resp.StatusCode)
+	}
+
+	var user struct {
+		Email string `json:"email"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&user); err != nil {
+		return "", fmt.Errorf("failed to decode user %s: %w", userID, err)
+	}
+	if user.Email == "" {
+		return "", fmt.Errorf("user %s has no email address", userID)
+	}
+
+	return user.Email, nil
+}
@@ -0,0 +1,225 @@
+package channels
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"crypto/tls"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"mime"
+	"mime/multipart"
+	"mime/quotedprintable"
+	"net"
+	"net/mail"
+	"net/smtp"
+	"net/textproto"
+	"notification-service/internal/models"
+	"os"
+	"strings"
+	"time"
+)
+
+type SMTPConfig struct {
+	Host     string
+	Port     string
+	Username string
+	Password string
+	From     string
+	Timeout  time.Duration
+}
+
+// SMTPConfigFromEnv reads SMTP_HOST, SMTP_PORT, SMTP_USERNAME, SMTP_PASSWORD
+// and SMTP_FROM. The defaults point at a local mail sink such as Mailpit.
+func SMTPConfigFromEnv() SMTPConfig {
+	return SMTPConfig{
+		Host:     getEnv("SMTP_HOST", "localhost"),
+		Port:     getEnv("SMTP_PORT", "1025"),
+		Username: os.Getenv("SMTP_USERNAME"),
+		Password: os.Getenv("SMTP_PASSWORD"),
+		From:     getEnv("SMTP_FROM", "TaskManager <no-reply@taskmanager.local>"),
+		Timeout:  10 * time.Second,
+	}
+}
+
+// SMTPChannel delivers email notifications. The recipient is taken from
+// data.email when present, otherwise from the resolver. The API only keeps
+// data.email on notifications created by trusted services, so users cannot
+// send mail to arbitrary addresses.
+type SMTPChannel struct {
+	config   SMTPConfig
+	resolver EmailResolver
+}
+
+func NewSMTPChannel(config SMTPConfig, resolver EmailResolver) *SMTPChannel {
+	return &SMTPChannel{config: config, resolver: resolver}
+}
+
+func (c *SMTPChannel) Send(ctx context.Context, notification *models.Notification) error {
+	to, err := c.recipient(ctx, notification)
+	if err != nil {
+		return err
+	}
+
+	from, err := mail.ParseAddress(c.config.From)
+	if err != nil {
+		return Permanent(fmt.Errorf("invalid SMTP_FROM address: %w", err))
+	}
+
+	msg, err := buildMessage(from, to, notification)
+	if err != nil {
+		return err
+	}
+
+	return c.deliver(ctx, from.Address, to.Address, msg)
+}
+
+func (c *SMTPChannel) recipient(ctx context.Context, notification *models.Notification) (*mail.Address, error) {
+	address, _ := notification.Data["email"].(string)
+	if address == "" {
+		if c.resolver == nil {
+			return nil, Permanent(fmt.Errorf("no email address for user %s", notification.UserID))
+		}
+		resolved, err := c.resolver.ResolveEmail(ctx, notification.UserID)
+		if err != nil {
+			return nil, err
+		}
+		address = resolved
+	}
+
+	to, err := mail.ParseAddress(address)
+	if err != nil {
+		return nil, Permanent(fmt.Errorf("invalid recipient address %q: %w", address, err))
+	}
+	return to, nil
+}
+
+func (c *SMTPChannel) deliver(ctx context.Context, from, to string, msg []byte) error {
+	addr := net.JoinHostPort(c.config.Host, c.config.Port)
+
+	dialer := &net.Dialer{Timeout: c.config.Timeout}
+	conn, err := dialer.DialContext(ctx, "tcp", addr)
+	if err != nil {
+		return fmt.Errorf("failed to connect to SMTP server: %w", err)
+	}
+	conn.SetDeadline(time.Now().Add(c.config.Timeout))
+
+	client, err := smtp.NewClient(conn, c.config.Host)
+	if err != nil {
+		conn.Close()
+		return fmt.Errorf("failed to start SMTP session: %w", err)
+	}
+	defer client.Close()
+
+	if ok, _ := client.Extension("STARTTLS"); ok {
+		if err := client.StartTLS(&tls.Config{ServerName: c.config.Host}); err != nil {
+			return fmt.Errorf("failed to start TLS: %w", err)
+		}
+	}
+
+	if c.config.Username != "" {
+		auth := smtp.PlainAuth("", c.config.Username, c.config.Password, c.config.Host)
+		if err := client.Auth(auth); err != nil {
+			return fmt.Errorf("SMTP authentication failed: %w", err)
+		}
+	}
+
+	if err := client.Mail(from); err != nil {
+		return fmt.Errorf("SMTP MAIL FROM rejected: %w", err)
+	}
+	if err := client.Rcpt(to); err != nil {
+		return fmt.Errorf("SMTP RCPT TO rejected: %w", err)
+	}
+
+	w, err := client.Data()
+	if err != nil {
+		return fmt.Errorf("SMTP DATA rejected: %w", err)
+	}
+	if _, err := w.Write(msg); err != nil {
+		return fmt.Errorf("failed to write message: %w", err)
+	}
+	if err := w.Close(); err != nil {
+		return fmt.Errorf("SMTP server rejected message: %w", err)
+	}
+
+	return client.Quit()
+}
+
+func buildMessage(from, to *mail.Address, notification *models.Notification) ([]byte, error) {
+	var buf bytes.Buffer
+
+	headers := [][2]string{
+		{"From", from.String()},
+		{"To", to.String()},
+		{"Subject", mime.QEncoding.Encode("utf-8", notification.Title)},
+		{"Date", time.Now().Format(time.RFC1123Z)},
+		{"Message-ID", messageID(from.Address)},
+		{"MIME-Version", "1.0"},
+	}
+	for _, h := range headers {
+		fmt.Fprintf(&buf, "%s: %s\r\n", h[0], h[1])
+	}
+
+	if notification.HTMLMessage == nil {
+		buf.WriteString("Content-Type: text/plain; charset=UTF-8\r\n")
+		buf.WriteString("Content-Transfer-Encoding: quoted-printable\r\n\r\n")
+		if err := writeQuotedPrintable(&buf, notification.Message); err != nil {
+			return nil, err
+		}
+		return buf.Bytes(), nil
+	}
+
+	// Templates with an HTML body are sent as multipart/alternative, plain
+	// text first so clients prefer the HTML part
+	mw := multipart.NewWriter(&buf)
+	fmt.Fprintf(&buf, "Content-Type: multipart/alternative; boundary=%s\r\n\r\n", mw.Boundary())
+
+	parts := [][2]string{
+		{"text/plain; charset=UTF-8", notification.Message},
+		{"text/html; charset=UTF-8", *notification.HTMLMessage},
+	}
+	for _, part := range parts {
+		pw, err := mw.CreatePart(textproto.MIMEHeader{
+			"Content-Type":              {part[0]},
+			"Content-Transfer-Encoding": {"quoted-printable"},
+		})
+		if err != nil {
+			return nil, err
+		}
+		if err := writeQuotedPrintable(pw, part[1]); err != nil {
+			return nil, err
+		}
+	}
+	if err := mw.Close(); err != nil {
+		return nil, err
+	}
+
+	return buf.Bytes(), nil
+}
+
+func writeQuotedPrintable(w io.Writer, text string) error {
+	qp := quotedprintable.NewWriter(w)
+	if _, err := qp.Write([]byte(text)); err != nil {
+		return err
+	}
+	return qp.Close()
+}
+
+func messageID(fromAddress string) string {
+	b := make([]byte, 16)
+	rand.Read(b)
+
+	domain := "localhost"
+	if at := strings.LastIndex(fromAddress, "@"); at >= 0 {
+		domain = fromAddress[at+1:]
+	}
+	return fmt.Sprintf("<%s@%s>", hex.EncodeToString(b), domain)
+}
+
+func getEnv(key, defaultValue string) string {
+	if value := os.Getenv(key); value != "" {
+		return value
+	}
+	return defaultValue
+}
@@ -0,0 +1,280 @@
+package channels
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"io"
+	"mime"
+	"mime/multipart"
+	"mime/quotedprintable"
+	"net"
+	"net/mail"
+	"notification-service/internal/models"
+	"strings"
+	"testing"
+	"time"
+)
+
+// fakeSMTPServer accepts one session and records the envelope and message.
+// A non-empty rejectRcpt is returned instead of 250 to RCPT TO.
+type fakeSMTPServer struct {
+	listener   net.Listener
+	rejectRcpt string
+
+	from string
+	to   []string
+	data string
+	done chan struct{}
+}
+
+func startFakeSMTPServer(t *testing.T, rejectRcpt string) *fakeSMTPServer {
+	t.Helper()
+
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("failed to listen: %v", err)
+	}
+	s := &fakeSMTPServer{listener: listener, rejectRcpt: rejectRcpt, done: make(chan struct{})}
+	t.Cleanup(func() { listener.Close() })
+
+	go s.serve()
+	return s
+}
+
+func (s *fakeSMTPServer) serve() {
+	defer close(s.done)
+
+	conn, err := s.listener.Accept()
+	if err != nil {
+		return
+	}
+	defer conn.Close()
+	conn.SetDeadline(time.Now().Add(5 * time.Second))
+
+	r := bufio.NewReader(conn)
+	reply := func(line string) { io.WriteString(conn, line+"\r\n") }
+
+	reply("220 fake.test ESMTP")
+	for {
+		line, err := r.ReadString('\n')
+		if err != nil {
+			return
+		}
+		line = strings.TrimRight(line, "\r\n")
+		verb := strings.ToUpper(strings.SplitN(line, " ", 2)[0])
+
+		switch verb {
+		case "EHLO", "HELO":
+			reply("250-fake.test")
+			reply("250 8BITMIME")
+		case "MAIL":
+			s.from = addressOf(line)
+			reply("250 OK")
+		case "RCPT":
+			if s.rejectRcpt != "" {
+				reply(s.rejectRcpt)
+				continue
+			}
+			s.to = append(s.to, addressOf(line))
+			reply("250 OK")
+		case "DATA":
+			reply("354 End data with <CR><LF>.<CR><LF>")
+			var data strings.Builder
+			for {
+				line, err := r.ReadString('\n')
+				if err != nil {
+					return
+				}
+				if line == ".\r\n" {
+					break
+				}
+				data.WriteString(strings.TrimPrefix(line, "."))
+			}
+			s.data = data.String()
+			reply("250 OK queued")
+		case "QUIT":
+			reply("221 Bye")
+			return
+		default:
+			reply("250 OK")
+		}
+	}
+}
+
+func (s *fakeSMTPServer) config() SMTPConfig {
+	host, port, _ := net.SplitHostPort(s.listener.Addr().String())
+	return SMTPConfig{
+		Host:    host,
+		Port:    port,
+		From:    "TaskManager <no-reply@taskmanager.test>",
+		Timeout: 5 * time.Second,
+	}
+}
+
+func (s *fakeSMTPServer) message(t *testing.T) *mail.Message {
+	t.Helper()
+	<-s.done
+	msg, err := mail.ReadMessage(strings.NewReader(s.data))
+	if err != nil {
+		t.Fatalf("server received an unparsable message: %v\n%s", err, s.data)
+	}
+	return msg
+}
+
+func addressOf(line string) string {
+	start, end := strings.Index(line, "<"), strings.LastIndex(line, ">")
+	if start < 0 || end < start {
+		return ""
+	}
+	return line[start+1 : end]
+}
+
+type staticResolver map[string]string
+
+func (r staticResolver) ResolveEmail(ctx context.Context, userID string) (string, error) {
+	if email, ok := r[userID]; ok {
+		return email, nil
+	}
+	return "", errors.New("user not found")
+}
+
+func TestSMTPChannelSendsPlainText(t *testing.T) {
+	server := startFakeSMTPServer(t, "")
+	channel := NewSMTPChannel(server.config(), nil)
+
+	notification := &models.Notification{
+		UserID:  "user-1",
+		Title:   "Réunion demain",
+		Message: "See you at 9.\nBring the slides.",
+		Type:    models.EmailNotification,
+		Data:    map[string]interface{}{"email": "Ada <ada@example.test>"},
+	}
+	if err := channel.Send(context.Background(), notification); err != nil {
+		t.Fatalf("Send returned error: %v", err)
+	}
+
+	msg := server.message(t)
+	if server.from != "no-reply@taskmanager.test" {
+		t.Errorf("MAIL FROM = %q", server.from)
+	}
+	if len(server.to) != 1 || server.to[0] != "ada@example.test" {
+		t.Errorf("RCPT TO = %v, want [ada@example.test]", server.to)
+	}
+	if got := msg.Header.Get("To"); got != `"Ada" <ada@example.test>` {
+		t.Errorf("To = %q", got)
+	}
+	subject, err := new(mime.WordDecoder).DecodeHeader(msg.Header.Get("Subject"))
+	if err != nil || subject != notification.Title {
+		t.Errorf("Subject = %q (%v), want %q", subject, err, notification.Title)
+	}
+	if !strings.HasSuffix(msg.Header.Get("Message-ID"), "@taskmanager.test>") {
+		t.Errorf("Message-ID = %q", msg.Header.Get("Message-ID"))
+	}
+	if got := msg.Header.Get("Content-Type"); got != "text/plain; charset=UTF-8" {
+		t.Errorf("Content-Type = %q", got)
+	}
+
+	body, err := io.ReadAll(quotedprintable.NewReader(msg.Body))
+	if err != nil {
+		t.Fatalf("failed to decode body: %v", err)
+	}
+	// The SMTP client ends the data with a line break of its own
+	if got := strings.TrimSuffix(strings.ReplaceAll(string(body), "\r\n", "\n"), "\n"); got != notification.Message {
+		t.Errorf("body = %q, want %q", got, notification.Message)
+	}
+}
+
+func TestSMTPChannelSendsHTMLAsAlternative(t *testing.T) {
+	server := startFakeSMTPServer(t, "")
+	channel := NewSMTPChannel(server.config(), staticResolver{"user-1": "grace@example.test"})
+
+	html := "<p>Your task is <b>overdue</b></p>"
+	notification := &models.Notification{
+		UserID:      "user-1",
+		Title:       "Task overdue",
+		Message:     "Your task is overdue",
+		HTMLMessage: &html,
+		Type:        models.EmailNotification,
+	}
+	if err := channel.Send(context.Background(), notification); err != nil {
+		t.Fatalf("Send returned error: %v", err)
+	}
+
+	msg := server.message(t)
+	if len(server.to) != 1 || server.to[0] != "grace@example.test" {
+		t.Errorf("RCPT TO = %v, want the resolved address", server.to)
+	}
+
+	mediaType, params, err := mime.ParseMediaType(msg.Header.Get("Content-Type"))
+	if err != nil || mediaType != "multipart/alternative" {
+		t.Fatalf("Content-Type = %q (%v)", msg.Header.Get("Content-Type"), err)
+	}
+
+	reader := multipart.NewReader(msg.Body, params["boundary"])
+	want := []struct{ contentType, body string }{
+		{"text/plain; charset=UTF-8", notification.Message},
+		{"text/html; charset=UTF-8", html},
+	}
+	for _, w := range want {
+		part, err := reader.NextRawPart()
+		if err != nil {
+			t.Fatalf("missing %s part: %v", w.contentType, err)
+		}
+		if got := part.Header.Get("Content-Type"); got != w.contentType {
+			t.Errorf("part Content-Type = %q, want %q", got, w.contentType)
+		}
+		body, _ := io.ReadAll(quotedprintable.NewReader(part))
+		if string(body) != w.body {
+			t.Errorf("%s part = %q, want %q", w.contentType, body, w.body)
+		}
+	}
+	if _, err := reader.NextPart(); err != io.EOF {
+		t.Errorf("expected exactly two parts, got more (%v)", err)
+	}
+}
+
+func TestSMTPChannelRecipientErrors(t *testing.T) {
+	tests := []struct {
+		name      string
+		resolver  EmailResolver
+		data      map[string]interface{}
+		permanent bool
+	}{
+		{name: "invalid data.email", data: map[string]interface{}{"email": "not an address"}, permanent: true},
+		{name: "no resolver", permanent: true},
+		{name: "resolver failure", resolver: staticResolver{}, permanent: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			channel := NewSMTPChannel(SMTPConfig{Host: "127.0.0.1", Port: "1", From: "a@example.test", Timeout: time.Second}, tt.resolver)
+			err := channel.Send(context.Background(), &models.Notification{UserID: "user-1", Data: tt.data})
+			if err == nil {
+				t.Fatal("Send succeeded, want an error")
+			}
+			var permanent *PermanentError
+			if errors.As(err, &permanent) != tt.permanent {
+				t.Errorf("permanent = %v, want %v (%v)", !tt.permanent, tt.permanent, err)
+			}
+		})
+	}
+}
+
+func TestSMTPChannelRejectedRecipient(t *testing.T) {
+	server := startFakeSMTPServer(t, "550 5.1.1 No such user")
+	channel := NewSMTPChannel(server.config(), nil)
+
+	err := channel.Send(context.Background(), &models.Notification{
+		UserID: "user-1",
+		Title:  "Hello",
+		Data:   map[string]interface{}{"email": "nobody@example.test"},
+	})
+	if err == nil || !strings.Contains(err.Error(), "RCPT TO rejected") {
+		t.Fatalf("Send error = %v, want RCPT TO rejected", err)
+	}
+	<-server.done
+	if server.data != "" {
+		t.Errorf("message was sent despite the rejected recipient")
+	}
+}
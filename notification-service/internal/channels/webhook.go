@@ -0,0 +1,178 @@
+package channels
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"notification-service/internal/models"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Headers sent with every webhook delivery. The signature is
+// "sha256=" + hex(HMAC-SHA256(secret, timestamp + "." + body)); receivers
+// should recompute it and reject timestamps too far from their own clock.
+const (
+	WebhookIDHeader        = "X-Webhook-Id"
+	WebhookTimestampHeader = "X-Webhook-Timestamp"
+	WebhookSignatureHeader = "X-Webhook-Signature"
+)
+
+// WebhookStore is the persistence the webhook channel needs
+type WebhookStore interface {
+	GetActiveWebhooks(userID string) ([]*models.WebhookEndpoint, error)
+	HasWebhookSucceeded(endpointID, notificationID string) (bool, error)
+	RecordWebhookDelivery(delivery *models.WebhookDelivery) error
+}
+
+// WebhookChannel POSTs webhook notifications to every active endpoint of the
+// user that subscribes to the notification's category. Each attempt is
+// recorded; on a retry, endpoints that already succeeded are skipped.
+type WebhookChannel struct {
+	store    WebhookStore
+	outbound OutboundPolicy
+	client   *http.Client
+	now      func() time.Time
+}
+
+// NewWebhookChannel delivers only to the addresses outbound permits
+func NewWebhookChannel(store WebhookStore, outbound OutboundPolicy) *WebhookChannel {
+	return &WebhookChannel{
+		store:    store,
+		outbound: outbound,
+		client:   outbound.Client(10 * time.Second),
+		now:      time.Now,
+	}
+}
+
+// SignWebhook computes the signature header value for a delivery
+func SignWebhook(secret string, timestamp int64, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(strconv.FormatInt(timestamp, 10)))
+	mac.Write([]byte("."))
+	mac.Write(body)
+	return "sha256=" + hex.EncodeToString(mac.Sum(nil))
+}
+
+// VerifyWebhook checks a signature produced by SignWebhook in constant time
+func VerifyWebhook(secret string, timestamp int64, body []byte, signature string) bool {
+	return hmac.Equal([]byte(SignWebhook(secret, timestamp, body)), []byte(signature))
+}
+
+func (c *WebhookChannel) Send(ctx context.Context, notification *models.Notification) error {
+	endpoints, err := c.store.GetActiveWebhooks(notification.UserID)
+	if err != nil {
+		return err
+	}
+
+	category := notification.Category()
+	body, err := json.Marshal(models.WebhookPayload{
+		ID:           notification.ID,
+		Event:        category,
+		CreatedAt:    notification.CreatedAt,
+		Notification: notification,
+	})
+	if err != nil {
+		return Permanent(fmt.Errorf("failed to encode webhook payload: %w", err))
+	}
+
+	var failures []string
+	retryable := false
+	for _, endpoint := range endpoints {
+		if !endpoint.Wants(category) {
+			continue
+		}
+		done, err := c.store.HasWebhookSucceeded(endpoint.ID, notification.ID)
+		if err != nil {
+			return err
+		}
+		if done {
+			continue
+		}
+
+		if err := c.deliver(ctx, endpoint, notification, body); err != nil {
+			failures = append(failures, fmt.Sprintf("%s: %v", endpoint.URL, err))
+			var permanent *PermanentError
+			if !errors.As(err, &permanent) {
+				retryable = true
+			}
+		}
+	}
+
+	if len(failures) == 0 {
+		return nil
+	}
+	err = fmt.Errorf("webhook delivery failed for %d endpoint(s): %s", len(failures), strings.Join(failures, "; "))
+	if !retryable {
+		return Permanent(err)
+	}
+	return err
+}
+
+func (c *WebhookChannel) deliver(ctx context.Context, endpoint *models.WebhookEndpoint, notification *models.Notification, body []byte) error {
+	timestamp := c.now().Unix()
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, endpoint.URL, bytes.NewReader(body))
+	if err != nil {
+		return Permanent(fmt.Errorf("invalid webhook request: %w", err))
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("User-Agent", "TaskManager-Webhooks/1.0")
+	req.Header.Set(WebhookIDHeader, notification.ID)
+	req.Header.Set(WebhookTimestampHeader, strconv.FormatInt(timestamp, 10))
+	req.Header.Set(WebhookSignatureHeader, SignWebhook(endpoint.Secret, timestamp, body))
+
+	delivery := &models.WebhookDelivery{
+		EndpointID:     endpoint.ID,
+		NotificationID: notification.ID,
+		Attempt:        notification.Attempts + 1,
+	}
+
+	// Endpoints registered before the policy tightened are checked again
+	var resp *http.Response
+	if err = c.outbound.CheckWebhookURL(endpoint.URL); err != nil {
+		err = Permanent(fmt.Errorf("webhook URL %w", err))
+	} else {
+		start := time.Now()
+		resp, err = c.client.Do(req)
+		delivery.DurationMS = time.Since(start).Milliseconds()
+		if errors.Is(err, ErrForbiddenAddress) {
+			err = Permanent(err)
+		}
+	}
+
+	if err == nil {
+		io.Copy(io.Discard, io.LimitReader(resp.Body, 64<<10))
+		resp.Body.Close()
+
+		statusCode := resp.StatusCode
+		delivery.StatusCode = &statusCode
+		delivery.Succeeded = statusCode >= 200 && statusCode < 300
+		if !delivery.Succeeded {
+			err = fmt.Errorf("endpoint returned status %d", statusCode)
+			// Client errors other than timeouts and rate limits will not
+			// change on retry
+			if statusCode >= 400 && statusCode < 500 && statusCode != http.StatusRequestTimeout && statusCode != http.StatusTooManyRequests {
+				err = Permanent(err)
+			}
+		}
+	}
+	if err != nil {
+		message := err.Error()
+		delivery.Error = &message
+	}
+
+	if recordErr := c.store.RecordWebhookDelivery(delivery); recordErr != nil {
+		log.Printf("❌ Failed to record webhook delivery to %s: %v", endpoint.URL, recordErr)
+	}
+	return err
+}
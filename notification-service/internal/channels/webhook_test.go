@@ -0,0 +1,294 @@
+package channels
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"notification-service/internal/models"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// fakeWebhookStore serves a fixed set of endpoints and records deliveries
+type fakeWebhookStore struct {
+	mu         sync.Mutex
+	endpoints  []*models.WebhookEndpoint
+	succeeded  map[string]bool
+	deliveries []*models.WebhookDelivery
+}
+
+func (s *fakeWebhookStore) GetActiveWebhooks(userID string) ([]*models.WebhookEndpoint, error) {
+	return s.endpoints, nil
+}
+
+func (s *fakeWebhookStore) HasWebhookSucceeded(endpointID, notificationID string) (bool, error) {
+	return s.succeeded[endpointID], nil
+}
+
+func (s *fakeWebhookStore) RecordWebhookDelivery(delivery *models.WebhookDelivery) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.deliveries = append(s.deliveries, delivery)
+	return nil
+}
+
+// capturedRequest is what a test endpoint received
+type capturedRequest struct {
+	header http.Header
+	body   []byte
+}
+
+// startWebhookEndpoint answers every delivery with status and passes what it
+// received to the returned channel
+func startWebhookEndpoint(t *testing.T, status int) (*httptest.Server, <-chan capturedRequest) {
+	t.Helper()
+
+	received := make(chan capturedRequest, 4)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		received <- capturedRequest{header: r.Header.Clone(), body: body}
+		w.WriteHeader(status)
+	}))
+	t.Cleanup(server.Close)
+	return server, received
+}
+
+// testOutbound lets deliveries reach the httptest servers on loopback
+var testOutbound = OutboundPolicy{AllowHTTP: true, AllowedNetworks: mustParseCIDRs("127.0.0.0/8")}
+
+func newTestWebhookChannel(store WebhookStore, now time.Time) *WebhookChannel {
+	channel := NewWebhookChannel(store, testOutbound)
+	channel.now = func() time.Time { return now }
+	return channel
+}
+
+func testWebhookNotification() *models.Notification {
+	return &models.Notification{
+		ID:        "4f6b2a0e-9a51-4c8e-8f0a-2d7e5c1b3a90",
+		UserID:    "user-1",
+		Title:     "Task assigned",
+		Message:   "You were assigned a task",
+		Type:      models.WebhookNotification,
+		Data:      map[string]interface{}{"category": "task_assigned"},
+		CreatedAt: time.Date(2026, time.October, 19, 9, 0, 0, 0, time.UTC),
+	}
+}
+
+func TestSignWebhook(t *testing.T) {
+	body := []byte(`{"id":"1"}`)
+	mac := hmac.New(sha256.New, []byte("s3cret"))
+	mac.Write([]byte("1792400000.{\"id\":\"1\"}"))
+	want := "sha256=" + hex.EncodeToString(mac.Sum(nil))
+
+	if got := SignWebhook("s3cret", 1792400000, body); got != want {
+		t.Errorf("SignWebhook = %q, want %q", got, want)
+	}
+	if !VerifyWebhook("s3cret", 1792400000, body, want) {
+		t.Error("VerifyWebhook rejected a valid signature")
+	}
+	for name, check := range map[string]bool{
+		"wrong secret":    VerifyWebhook("other", 1792400000, body, want),
+		"wrong timestamp": VerifyWebhook("s3cret", 1792400001, body, want),
+		"altered body":    VerifyWebhook("s3cret", 1792400000, []byte(`{"id":"2"}`), want),
+	} {
+		if check {
+			t.Errorf("VerifyWebhook accepted a signature with %s", name)
+		}
+	}
+}
+
+func TestWebhookChannelSignsDelivery(t *testing.T) {
+	server, received := startWebhookEndpoint(t, http.StatusNoContent)
+	store := &fakeWebhookStore{endpoints: []*models.WebhookEndpoint{
+		{ID: "endpoint-1", URL: server.URL, Secret: "whsec_test", Active: true},
+	}}
+	now := time.Date(2026, time.October, 19, 10, 0, 0, 0, time.UTC)
+	notification := testWebhookNotification()
+
+	if err := newTestWebhookChannel(store, now).Send(context.Background(), notification); err != nil {
+		t.Fatalf("Send returned error: %v", err)
+	}
+
+	request := <-received
+	if got := request.header.Get("Content-Type"); got != "application/json" {
+		t.Errorf("Content-Type = %q", got)
+	}
+	if got := request.header.Get(WebhookIDHeader); got != notification.ID {
+		t.Errorf("%s = %q, want %q", WebhookIDHeader, got, notification.ID)
+	}
+	timestamp, err := strconv.ParseInt(request.header.Get(WebhookTimestampHeader), 10, 64)
+	if err != nil || timestamp != now.Unix() {
+		t.Errorf("%s = %q, want %d", WebhookTimestampHeader, request.header.Get(WebhookTimestampHeader), now.Unix())
+	}
+	if !VerifyWebhook("whsec_test", timestamp, request.body, request.header.Get(WebhookSignatureHeader)) {
+		t.Errorf("signature %q does not match the body", request.header.Get(WebhookSignatureHeader))
+	}
+
+	var payload models.WebhookPayload
+	if err := json.Unmarshal(request.body, &payload); err != nil {
+		t.Fatalf("payload is not JSON: %v", err)
+	}
+	if payload.ID != notification.ID || payload.Event != "task_assigned" || payload.Notification == nil || payload.Notification.Title != notification.Title {
+		t.Errorf("payload = %+v", payload)
+	}
+
+	if len(store.deliveries) != 1 {
+		t.Fatalf("recorded %d deliveries, want 1", len(store.deliveries))
+	}
+	delivery := store.deliveries[0]
+	if !delivery.Succeeded || delivery.StatusCode == nil || *delivery.StatusCode != http.StatusNoContent || delivery.Error != nil {
+		t.Errorf("delivery = %+v", delivery)
+	}
+	if delivery.EndpointID != "endpoint-1" || delivery.Attempt != 1 {
+		t.Errorf("delivery endpoint/attempt = %s/%d", delivery.EndpointID, delivery.Attempt)
+	}
+}
+
+func TestWebhookChannelStatusHandling(t *testing.T) {
+	tests := []struct {
+		status    int
+		permanent bool
+	}{
+		{status: http.StatusBadRequest, permanent: true},
+		{status: http.StatusUnauthorized, permanent: true},
+		{status: http.StatusNotFound, permanent: true},
+		{status: http.StatusGone, permanent: true},
+		{status: http.StatusRequestTimeout, permanent: false},
+		{status: http.StatusTooManyRequests, permanent: false},
+		{status: http.StatusInternalServerError, permanent: false},
+		{status: http.StatusBadGateway, permanent: false},
+		{status: http.StatusServiceUnavailable, permanent: false},
+		{status: http.StatusFound, permanent: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(strconv.Itoa(tt.status), func(t *testing.T) {
+			server, _ := startWebhookEndpoint(t, tt.status)
+			store := &fakeWebhookStore{endpoints: []*models.WebhookEndpoint{
+				{ID: "endpoint-1", URL: server.URL, Secret: "whsec_test", Active: true},
+			}}
+
+			err := newTestWebhookChannel(store, time.Now()).Send(context.Background(), testWebhookNotification())
+			if err == nil {
+				t.Fatal("Send succeeded, want an error")
+			}
+			var permanent *PermanentError
+			if errors.As(err, &permanent) != tt.permanent {
+				t.Errorf("permanent = %v, want %v (%v)", !tt.permanent, tt.permanent, err)
+			}
+
+			if len(store.deliveries) != 1 {
+				t.Fatalf("recorded %d deliveries, want 1", len(store.deliveries))
+			}
+			delivery := store.deliveries[0]
+			if delivery.Succeeded || delivery.StatusCode == nil || *delivery.StatusCode != tt.status || delivery.Error == nil {
+				t.Errorf("delivery = %+v", delivery)
+			}
+		})
+	}
+}
+
+func TestWebhookChannelUnreachableEndpointIsRetried(t *testing.T) {
+	server := httptest.NewServer(http.NotFoundHandler())
+	url := server.URL
+	server.Close()
+
+	store := &fakeWebhookStore{endpoints: []*models.WebhookEndpoint{
+		{ID: "endpoint-1", URL: url, Secret: "whsec_test", Active: true},
+	}}
+	err := newTestWebhookChannel(store, time.Now()).Send(context.Background(), testWebhookNotification())
+	var permanent *PermanentError
+	if err == nil || errors.As(err, &permanent) {
+		t.Fatalf("Send error = %v, want a retryable error", err)
+	}
+	if len(store.deliveries) != 1 || store.deliveries[0].StatusCode != nil || store.deliveries[0].Error == nil {
+		t.Errorf("deliveries = %+v", store.deliveries)
+	}
+}
+
+func TestWebhookChannelSkipsEndpoints(t *testing.T) {
+	wanted, received := startWebhookEndpoint(t, http.StatusOK)
+	other, otherReceived := startWebhookEndpoint(t, http.StatusOK)
+
+	store := &fakeWebhookStore{
+		endpoints: []*models.WebhookEndpoint{
+			{ID: "subscribed", URL: wanted.URL, Secret: "a", Events: []string{"task_assigned"}, Active: true},
+			{ID: "unsubscribed", URL: other.URL, Secret: "b", Events: []string{"task_completed"}, Active: true},
+			{ID: "already-delivered", URL: other.URL, Secret: "c", Active: true},
+		},
+		succeeded: map[string]bool{"already-delivered": true},
+	}
+
+	if err := newTestWebhookChannel(store, time.Now()).Send(context.Background(), testWebhookNotification()); err != nil {
+		t.Fatalf("Send returned error: %v", err)
+	}
+	if len(received) != 1 {
+		t.Errorf("subscribed endpoint received %d deliveries, want 1", len(received))
+	}
+	if len(otherReceived) != 0 {
+		t.Errorf("skipped endpoints received %d deliveries, want 0", len(otherReceived))
+	}
+}
+
+func TestWebhookChannelRetriesWhenAnyFailureIsRetryable(t *testing.T) {
+	rejecting, _ := startWebhookEndpoint(t, http.StatusBadRequest)
+	failing, _ := startWebhookEndpoint(t, http.StatusInternalServerError)
+
+	store := &fakeWebhookStore{endpoints: []*models.WebhookEndpoint{
+		{ID: "rejecting", URL: rejecting.URL, Secret: "a", Active: true},
+		{ID: "failing", URL: failing.URL, Secret: "b", Active: true},
+	}}
+
+	err := newTestWebhookChannel(store, time.Now()).Send(context.Background(), testWebhookNotification())
+	var permanent *PermanentError
+	if err == nil || errors.As(err, &permanent) {
+		t.Fatalf("Send error = %v, want a retryable error", err)
+	}
+	if len(store.deliveries) != 2 {
+		t.Errorf("recorded %d deliveries, want 2", len(store.deliveries))
+	}
+}
+
+func TestWebhookChannelRefusesInternalAddresses(t *testing.T) {
+	server, received := startWebhookEndpoint(t, http.StatusOK)
+	tests := []struct {
+		name     string
+		outbound OutboundPolicy
+		url      string
+	}{
+		// localhost resolves to loopback, which is only caught when dialing
+		{name: "resolved to loopback", outbound: OutboundPolicy{AllowHTTP: true}, url: strings.Replace(server.URL, "127.0.0.1", "localhost", 1)},
+		{name: "loopback address", outbound: OutboundPolicy{AllowHTTP: true}, url: server.URL},
+		{name: "plain http", outbound: OutboundPolicy{AllowedNetworks: testOutbound.AllowedNetworks}, url: server.URL},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			store := &fakeWebhookStore{endpoints: []*models.WebhookEndpoint{
+				{ID: "endpoint-1", URL: tt.url, Secret: "whsec_test", Active: true},
+			}}
+			channel := NewWebhookChannel(store, tt.outbound)
+
+			err := channel.Send(context.Background(), testWebhookNotification())
+			var permanent *PermanentError
+			if !errors.As(err, &permanent) {
+				t.Fatalf("Send error = %v, want a permanent error", err)
+			}
+			if len(received) != 0 {
+				t.Fatalf("endpoint received %d deliveries, want 0", len(received))
+			}
+			if len(store.deliveries) != 1 || store.deliveries[0].StatusCode != nil || store.deliveries[0].Error == nil {
+				t.Errorf("deliveries = %+v", store.deliveries)
+			}
+		})
+	}
+}
@@ -0,0 +1,404 @@
+package channels
+
+import (
+	"bytes"
+	"context"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/ecdh"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/hkdf"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"net/url"
+	"notification-service/internal/models"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/golang-jwt/jwt/v4"
+)
+
+// Sizes fixed by RFC 8291 and the aes128gcm content coding of RFC 8188
+const (
+	pushRecordSize   = 4096
+	pushSaltSize     = 16
+	pushAuthSize     = 16
+	pushKeySize      = 65
+	pushHeaderSize   = pushSaltSize + 4 + 1 + pushKeySize
+	pushMaxPlaintext = pushRecordSize - pushHeaderSize - aes.BlockSize - 1
+)
+
+type VAPIDConfig struct {
+	PublicKey  string
+	PrivateKey string
+	Subject    string
+	TTL        time.Duration
+}
+
+// VAPIDConfigFromEnv reads VAPID_PUBLIC_KEY, VAPID_PRIVATE_KEY, VAPID_SUBJECT
+// and WEB_PUSH_TTL. Keys are base64url, in the format web-push tooling
+// generates; the public key is derived when only the private key is set.
+func VAPIDConfigFromEnv() VAPIDConfig {
+	ttl := 24 * time.Hour
+	if value, err := time.ParseDuration(os.Getenv("WEB_PUSH_TTL")); err == nil && value >= 0 {
+		ttl = value
+	}
+	return VAPIDConfig{
+		PublicKey:  os.Getenv("VAPID_PUBLIC_KEY"),
+		PrivateKey: os.Getenv("VAPID_PRIVATE_KEY"),
+		Subject:    getEnv("VAPID_SUBJECT", "mailto:admin@taskmanager.local"),
+		TTL:        ttl,
+	}
+}
+
+// GenerateVAPIDKeys creates a new application server key pair
+func GenerateVAPIDKeys() (publicKey, privateKey string, err error) {
+	key, err := ecdh.P256().GenerateKey(rand.Reader)
+	if err != nil {
+		return "", "", err
+	}
+	return base64.RawURLEncoding.EncodeToString(key.PublicKey().Bytes()),
+		base64.RawURLEncoding.EncodeToString(key.Bytes()), nil
+}
+
+// PushStore is the persistence the web push channel needs
+type PushStore interface {
+	GetPushSubscriptions(userID string) ([]*models.PushSubscription, error)
+	RemovePushSubscription(id string) error
+	TouchPushSubscription(id string) error
+}
+
+// WebPushChannel delivers push notifications to every browser a user has
+// subscribed, encrypting each payload for its subscription and signing
+// requests with the VAPID key. Subscriptions the push service reports as
+// gone are removed. A notification counts as delivered once any device
+// accepts it.
+type WebPushChannel struct {
+	store     PushStore
+	key       *ecdsa.PrivateKey
+	publicKey string
+	subject   string
+	ttl       time.Duration
+	outbound  OutboundPolicy
+	client    *http.Client
+	now       func() time.Time
+}
+
+// NewWebPushChannel returns an error only for malformed keys. Without a
+// private key the channel is created disabled and fails every send.
+// Messages only go to push services at addresses outbound permits.
+func NewWebPushChannel(config VAPIDConfig, store PushStore, outbound OutboundPolicy) (*WebPushChannel, error) {
+	c := &WebPushChannel{
+		store:    store,
+		subject:  config.Subject,
+		ttl:      config.TTL,
+		outbound: outbound,
+		client:   outbound.Client(10 * time.Second),
+		now:      time.Now,
+	}
+	if config.PrivateKey == "" {
+		return c, nil
+	}
+
+	raw, err := decodeBase64URL(config.PrivateKey)
+	if err != nil {
+		return nil, fmt.Errorf("invalid VAPID private key: %w", err)
+	}
+	key, err := ecdsa.ParseRawPrivateKey(elliptic.P256(), raw)
+	if err != nil {
+		return nil, fmt.Errorf("invalid VAPID private key: %w", err)
+	}
+	exchangeKey, err := key.ECDH()
+	if err != nil {
+		return nil, fmt.Errorf("invalid VAPID private key: %w", err)
+	}
+
+	publicKey := base64.RawURLEncoding.EncodeToString(exchangeKey.PublicKey().Bytes())
+	if config.PublicKey != "" && strings.TrimRight(config.PublicKey, "=") != publicKey {
+		return nil, fmt.Errorf("VAPID public key does not match the private key")
+	}
+
+	c.key = key
+	c.publicKey = publicKey
+	return c, nil
+}
+
+// PublicKey is the applicationServerKey browsers subscribe with, or "" when
+// web push is not configured
+func (c *WebPushChannel) PublicKey() string {
+	return c.publicKey
+}
+
+// ParsePushKeys decodes and checks the p256dh and auth keys of a browser
+// subscription
+func ParsePushKeys(p256dh, auth string) (*ecdh.PublicKey, []byte, error) {
+	rawKey, err := decodeBase64URL(p256dh)
+	if err != nil || len(rawKey) != pushKeySize {
+		return nil, nil, fmt.Errorf("p256dh must be a base64url uncompressed P-256 public key")
+	}
+	publicKey, err := ecdh.P256().NewPublicKey(rawKey)
+	if err != nil {
+		return nil, nil, fmt.Errorf("p256dh is not a valid P-256 public key")
+	}
+	secret, err := decodeBase64URL(auth)
+	if err != nil || len(secret) != pushAuthSize {
+		return nil, nil, fmt.Errorf("auth must be a base64url 16-byte secret")
+	}
+	return publicKey, secret, nil
+}
+
+func (c *WebPushChannel) Send(ctx context.Context, notification *models.Notification) error {
+	if c.key == nil {
+		return Permanent(errors.New("web push is not configured: set VAPID_PRIVATE_KEY"))
+	}
+
+	subscriptions, err := c.store.GetPushSubscriptions(notification.UserID)
+	if err != nil {
+		return err
+	}
+
+	payload, err := pushPayload(notification)
+	if err != nil {
+		return Permanent(err)
+	}
+
+	delivered := 0
+	retryable := false
+	var failures []string
+	for _, subscription := range subscriptions {
+		gone, err := c.deliver(ctx, subscription, payload)
+		switch {
+		case gone:
+			if err := c.store.RemovePushSubscription(subscription.ID); err != nil {
+				log.Printf("❌ Failed to remove expired push subscription %s: %v", subscription.ID, err)
+			}
+		case err != nil:
+			failures = append(failures, fmt.Sprintf("%s: %v", subscription.ID, err))
+			var permanent *PermanentError
+			if !errors.As(err, &permanent) {
+				retryable = true
+			}
+		default:
+			delivered++
+			if err := c.store.TouchPushSubscription(subscription.ID); err != nil {
+				log.Printf("❌ Failed to update push subscription %s: %v", subscription.ID, err)
+			}
+		}
+	}
+
+	if delivered > 0 {
+		if len(failures) > 0 {
+			log.Printf("⚠️ Push notification %s reached %d device(s) but failed for: %s", notification.ID, delivered, strings.Join(failures, "; "))
+		}
+		return nil
+	}
+	if len(failures) == 0 {
+		return Permanent(fmt.Errorf("user %s has no push subscriptions", notification.UserID))
+	}
+	err = fmt.Errorf("push delivery failed for %d subscription(s): %s", len(failures), strings.Join(failures, "; "))
+	if !retryable {
+		return Permanent(err)
+	}
+	return err
+}
+
+// deliver sends one encrypted push message. gone reports that the push
+// service no longer knows the subscription.
+func (c *WebPushChannel) deliver(ctx context.Context, subscription *models.PushSubscription, payload []byte) (gone bool, err error) {
+	uaPublic, authSecret, err := ParsePushKeys(subscription.P256dh, subscription.Auth)
+	if err != nil {
+		return false, Permanent(err)
+	}
+	body, err := EncryptPushPayload(payload, uaPublic, authSecret)
+	if err != nil {
+		return false, err
+	}
+
+	// Subscriptions stored before endpoints had to be https are checked again
+	if err := c.outbound.CheckPushEndpoint(subscription.Endpoint); err != nil {
+		return false, Permanent(fmt.Errorf("push endpoint %w", err))
+	}
+	endpoint, err := url.Parse(subscription.Endpoint)
+	if err != nil {
+		return false, Permanent(fmt.Errorf("invalid push endpoint"))
+	}
+	token, err := c.vapidToken(endpoint.Scheme + "://" + endpoint.Host)
+	if err != nil {
+		return false, Permanent(fmt.Errorf("failed to sign VAPID token: %w", err))
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, subscription.Endpoint, bytes.NewReader(body))
+	if err != nil {
+		return false, Permanent(fmt.Errorf("invalid push request: %w", err))
+	}
+	req.Header.Set("Content-Type", "application/octet-stream")
+	req.Header.Set("Content-Encoding", "aes128gcm")
+	req.Header.Set("TTL", strconv.Itoa(int(c.ttl.Seconds())))
+	req.Header.Set("Urgency", "normal")
+	req.Header.Set("Authorization", "vapid t="+token+", k="+c.publicKey)
+
+	resp, err := c.client.Do(req)
+	if errors.Is(err, ErrForbiddenAddress) {
+		return false, Permanent(err)
+	}
+	if err != nil {
+		return false, err
+	}
+	io.Copy(io.Discard, io.LimitReader(resp.Body, 64<<10))
+	resp.Body.Close()
+
+	switch {
+	case resp.StatusCode >= 200 && resp.StatusCode < 300:
+		return false, nil
+	case resp.StatusCode == http.StatusNotFound || resp.StatusCode == http.StatusGone:
+		return true, nil
+	case resp.StatusCode == http.StatusTooManyRequests || resp.StatusCode >= 500:
+		return false, fmt.Errorf("push service returned status %d", resp.StatusCode)
+	default:
+		// Rejected payloads and VAPID credentials will not change on retry
+		return false, Permanent(fmt.Errorf("push service returned status %d", resp.StatusCode))
+	}
+}
+
+// vapidToken signs the RFC 8292 JWT for a push service origin
+func (c *WebPushChannel) vapidToken(audience string) (string, error) {
+	claims := jwt.MapClaims{
+		"aud": audience,
+		"exp": c.now().Add(12 * time.Hour).Unix(),
+		"sub": c.subject,
+	}
+	return jwt.NewWithClaims(jwt.SigningMethodES256, claims).SignedString(c.key)
+}
+
+// pushPayload encodes the notification for the service worker, dropping the
+// data and then shortening the body when it would not fit in one record
+func pushPayload(notification *models.Notification) ([]byte, error) {
+	payload := models.PushPayload{
+		ID:        notification.ID,
+		Title:     notification.Title,
+		Body:      notification.Message,
+		Category:  notification.Category(),
+		Data:      notification.Data,
+		CreatedAt: notification.CreatedAt,
+	}
+
+	body, err := json.Marshal(payload)
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode push payload: %w", err)
+	}
+	if len(body) <= pushMaxPlaintext {
+		return body, nil
+	}
+
+	payload.Data = nil
+	for {
+		body, err = json.Marshal(payload)
+		if err != nil {
+			return nil, fmt.Errorf("failed to encode push payload: %w", err)
+		}
+		if len(body) <= pushMaxPlaintext {
+			return body, nil
+		}
+		if payload.Body == "" {
+			return nil, fmt.Errorf("push payload is too large")
+		}
+		excess := len(body) - pushMaxPlaintext + len("…")
+		cut := len(payload.Body) - excess
+		if cut < 0 {
+			cut = 0
+		}
+		for cut > 0 && !utf8.RuneStart(payload.Body[cut]) {
+			cut--
+		}
+		payload.Body = strings.TrimSuffix(payload.Body[:cut], "…") + "…"
+		if cut == 0 {
+			payload.Body = ""
+		}
+	}
+}
+
+// EncryptPushPayload encrypts plaintext for a subscription as a single
+// aes128gcm record, as RFC 8291 requires
+func EncryptPushPayload(plaintext []byte, uaPublic *ecdh.PublicKey, authSecret []byte) ([]byte, error) {
+	asPrivate, err := ecdh.P256().GenerateKey(rand.Reader)
+	if err != nil {
+		return nil, err
+	}
+	salt := make([]byte, pushSaltSize)
+	if _, err := rand.Read(salt); err != nil {
+		return nil, err
+	}
+	return encryptPushPayload(plaintext, uaPublic, authSecret, asPrivate, salt)
+}
+
+func encryptPushPayload(plaintext []byte, uaPublic *ecdh.PublicKey, authSecret []byte, asPrivate *ecdh.PrivateKey, salt []byte) ([]byte, error) {
+	if len(plaintext) > pushMaxPlaintext {
+		return nil, fmt.Errorf("push payload is too large")
+	}
+
+	ecdhSecret, err := asPrivate.ECDH(uaPublic)
+	if err != nil {
+		return nil, err
+	}
+	asPublic := asPrivate.PublicKey().Bytes()
+
+	// Combine the shared secret with the subscription's auth secret
+	keyInfo := "WebPush: info\x00" + string(uaPublic.Bytes()) + string(asPublic)
+	prkKey, err := hkdf.Extract(sha256.New, ecdhSecret, authSecret)
+	if err != nil {
+		return nil, err
+	}
+	ikm, err := hkdf.Expand(sha256.New, prkKey, keyInfo, 32)
+	if err != nil {
+		return nil, err
+	}
+
+	// Derive the content encryption key and nonce for the record
+	prk, err := hkdf.Extract(sha256.New, ikm, salt)
+	if err != nil {
+		return nil, err
+	}
+	cek, err := hkdf.Expand(sha256.New, prk, "Content-Encoding: aes128gcm\x00", 16)
+	if err != nil {
+		return nil, err
+	}
+	nonce, err := hkdf.Expand(sha256.New, prk, "Content-Encoding: nonce\x00", 12)
+	if err != nil {
+		return nil, err
+	}
+
+	block, err := aes.NewCipher(cek)
+	if err != nil {
+		return nil, err
+	}
+	gcm, err := cipher.NewGCM(block)
+	if err != nil {
+		return nil, err
+	}
+
+	header := make([]byte, 0, pushHeaderSize)
+	header = append(header, salt...)
+	header = binary.BigEndian.AppendUint32(header, pushRecordSize)
+	header = append(header, byte(len(asPublic)))
+	header = append(header, asPublic...)
+
+	// 0x02 marks the last (and only) record
+	record := append(append([]byte{}, plaintext...), 0x02)
+	return gcm.Seal(header, nonce, record, nil), nil
+}
+
+func decodeBase64URL(value string) ([]byte, error) {
+	return base64.RawURLEncoding.DecodeString(strings.TrimRight(value, "="))
+}
@@ -0,0 +1,466 @@
+package channels
+
+import (
+	"bytes"
+	"context"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/ecdh"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/hkdf"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"notification-service/internal/models"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/golang-jwt/jwt/v4"
+)
+
+func mustDecode(t *testing.T, value string) []byte {
+	t.Helper()
+	raw, err := decodeBase64URL(value)
+	if err != nil {
+		t.Fatalf("bad base64url %q: %v", value, err)
+	}
+	return raw
+}
+
+// decryptPushPayload is the user agent's side of RFC 8291
+func decryptPushPayload(t *testing.T, body []byte, uaPrivate *ecdh.PrivateKey, authSecret []byte) []byte {
+	t.Helper()
+
+	if len(body) < pushHeaderSize {
+		t.Fatalf("body of %d bytes is shorter than the header", len(body))
+	}
+	salt := body[:pushSaltSize]
+	if rs := binary.BigEndian.Uint32(body[pushSaltSize:]); rs != pushRecordSize {
+		t.Errorf("record size = %d, want %d", rs, pushRecordSize)
+	}
+	if idlen := body[pushSaltSize+4]; idlen != pushKeySize {
+		t.Fatalf("keyid length = %d, want %d", idlen, pushKeySize)
+	}
+	asPublic, err := ecdh.P256().NewPublicKey(body[pushSaltSize+5 : pushHeaderSize])
+	if err != nil {
+		t.Fatalf("keyid is not a P-256 public key: %v", err)
+	}
+
+	ecdhSecret, err := uaPrivate.ECDH(asPublic)
+	if err != nil {
+		t.Fatalf("ECDH failed: %v", err)
+	}
+	prkKey, _ := hkdf.Extract(sha256.New, ecdhSecret, authSecret)
+	ikm, _ := hkdf.Expand(sha256.New, prkKey, "WebPush: info\x00"+string(uaPrivate.PublicKey().Bytes())+string(asPublic.Bytes()), 32)
+	prk, _ := hkdf.Extract(sha256.New, ikm, salt)
+	cek, _ := hkdf.Expand(sha256.New, prk, "Content-Encoding: aes128gcm\x00", 16)
+	nonce, _ := hkdf.Expand(sha256.New, prk, "Content-Encoding: nonce\x00", 12)
+
+	block, _ := aes.NewCipher(cek)
+	gcm, _ := cipher.NewGCM(block)
+	record, err := gcm.Open(nil, nonce, body[pushHeaderSize:], nil)
+	if err != nil {
+		t.Fatalf("failed to decrypt record: %v", err)
+	}
+	if len(record) == 0 || record[len(record)-1] != 0x02 {
+		t.Fatalf("record does not end with the last-record delimiter")
+	}
+	return record[:len(record)-1]
+}
+
+// The test vector of RFC 8291 Appendix A
+func TestEncryptPushPayloadRFC8291Vector(t *testing.T) {
+	uaPublic, authSecret, err := ParsePushKeys(
+		"BCVxsr7N_eNgVRqvHtD0zTZsEc6-VV-JvLexhqUzORcxaOzi6-AYWXvTBHm4bjyPjs7Vd8pZGH6SRpkNtoIAiw4",
+		"BTBZMqHH6r4Tts7J_aSIgg",
+	)
+	if err != nil {
+		t.Fatalf("ParsePushKeys returned error: %v", err)
+	}
+	asPrivate, err := ecdh.P256().NewPrivateKey(mustDecode(t, "yfWPiYE-n46HLnH0KqZOF1fJJU3MYrct3AELtAQ-oRw"))
+	if err != nil {
+		t.Fatalf("bad application server key: %v", err)
+	}
+	plaintext := []byte("When I grow up, I want to be a watermelon")
+	salt := mustDecode(t, "DGv6ra1nlYgDCS1FRnbzlw")
+
+	body, err := encryptPushPayload(plaintext, uaPublic, authSecret, asPrivate, salt)
+	if err != nil {
+		t.Fatalf("encryptPushPayload returned error: %v", err)
+	}
+
+	// The RFC pads nothing, so the only record is the plaintext and its
+	// delimiter; the user agent's key must decrypt it
+	uaPrivate, err := ecdh.P256().NewPrivateKey(mustDecode(t, "q1dXpw3UpT5VOmu_cf_v6ih07Aems3njxI-JWgLcM94"))
+	if err != nil {
+		t.Fatalf("bad user agent key: %v", err)
+	}
+	if got := decryptPushPayload(t, body, uaPrivate, authSecret); !bytes.Equal(got, plaintext) {
+		t.Errorf("decrypted %q, want %q", got, plaintext)
+	}
+
+	want := "DGv6ra1nlYgDCS1FRnbzlwAAEABBBP4z9KsN6nGRTbVYI_c7VJSPQTBtkgcy27mlmlMoZIIgDll6e3vCYLocInmYWAmS6TlzAC8wEqKK6PBru3jl7A_yl95bQpu6cVPTpK4Mqgkf1CXztLVBSt2Ks3oZwbuwXPXLWyouBWLVWGNWQexSgSxsj_Qulcy4a-fN"
+	if got := base64.RawURLEncoding.EncodeToString(body); got != want {
+		t.Errorf("encrypted message = %s, want %s", got, want)
+	}
+}
+
+func TestEncryptPushPayloadIsFreshEachTime(t *testing.T) {
+	uaPrivate, _ := ecdh.P256().GenerateKey(rand.Reader)
+	authSecret := make([]byte, pushAuthSize)
+	rand.Read(authSecret)
+
+	first, err := EncryptPushPayload([]byte("hello"), uaPrivate.PublicKey(), authSecret)
+	if err != nil {
+		t.Fatalf("EncryptPushPayload returned error: %v", err)
+	}
+	second, _ := EncryptPushPayload([]byte("hello"), uaPrivate.PublicKey(), authSecret)
+	if bytes.Equal(first[:pushHeaderSize], second[:pushHeaderSize]) {
+		t.Error("two messages share a salt and server key")
+	}
+	for _, body := range [][]byte{first, second} {
+		if got := decryptPushPayload(t, body, uaPrivate, authSecret); string(got) != "hello" {
+			t.Errorf("decrypted %q, want hello", got)
+		}
+	}
+
+	if _, err := EncryptPushPayload(make([]byte, pushMaxPlaintext+1), uaPrivate.PublicKey(), authSecret); err == nil {
+		t.Error("a payload larger than one record was encrypted")
+	}
+}
+
+func TestPushPayloadFitsOneRecord(t *testing.T) {
+	notification := &models.Notification{
+		ID:      "n-1",
+		Title:   "Long",
+		Message: strings.Repeat("é", pushMaxPlaintext),
+		Data:    map[string]interface{}{"task_id": "t-1"},
+	}
+	body, err := pushPayload(notification)
+	if err != nil {
+		t.Fatalf("pushPayload returned error: %v", err)
+	}
+	if len(body) > pushMaxPlaintext {
+		t.Errorf("payload is %d bytes, want at most %d", len(body), pushMaxPlaintext)
+	}
+
+	var payload models.PushPayload
+	if err := json.Unmarshal(body, &payload); err != nil {
+		t.Fatalf("payload is not JSON: %v", err)
+	}
+	if payload.Data != nil || !strings.HasSuffix(payload.Body, "…") {
+		t.Errorf("payload was not shortened: data=%v body ends %q", payload.Data, payload.Body[len(payload.Body)-8:])
+	}
+}
+
+// fakePushStore serves fixed subscriptions and records what the channel
+// removes and touches
+type fakePushStore struct {
+	mu            sync.Mutex
+	subscriptions []*models.PushSubscription
+	removed       []string
+	touched       []string
+}
+
+func (s *fakePushStore) GetPushSubscriptions(userID string) ([]*models.PushSubscription, error) {
+	return s.subscriptions, nil
+}
+
+func (s *fakePushStore) RemovePushSubscription(id string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.removed = append(s.removed, id)
+	return nil
+}
+
+func (s *fakePushStore) TouchPushSubscription(id string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.touched = append(s.touched, id)
+	return nil
+}
+
+// testBrowser is a subscribed user agent with its private key
+type testBrowser struct {
+	key  *ecdh.PrivateKey
+	auth []byte
+}
+
+func newTestBrowser(t *testing.T) *testBrowser {
+	t.Helper()
+	key, err := ecdh.P256().GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatalf("failed to generate key: %v", err)
+	}
+	auth := make([]byte, pushAuthSize)
+	rand.Read(auth)
+	return &testBrowser{key: key, auth: auth}
+}
+
+func (b *testBrowser) subscription(id, endpoint string) *models.PushSubscription {
+	return &models.PushSubscription{
+		ID:       id,
+		UserID:   "user-1",
+		Endpoint: endpoint,
+		P256dh:   base64.RawURLEncoding.EncodeToString(b.key.PublicKey().Bytes()),
+		Auth:     base64.RawURLEncoding.EncodeToString(b.auth),
+	}
+}
+
+func newTestWebPushChannel(t *testing.T, store PushStore, now time.Time) *WebPushChannel {
+	t.Helper()
+	publicKey, privateKey, err := GenerateVAPIDKeys()
+	if err != nil {
+		t.Fatalf("GenerateVAPIDKeys returned error: %v", err)
+	}
+	channel, err := NewWebPushChannel(VAPIDConfig{
+		PublicKey:  publicKey,
+		PrivateKey: privateKey,
+		Subject:    "mailto:ops@taskmanager.test",
+		TTL:        time.Hour,
+	}, store, testOutbound)
+	if err != nil {
+		t.Fatalf("NewWebPushChannel returned error: %v", err)
+	}
+	trustTestServers(channel.client)
+	if channel.PublicKey() != publicKey {
+		t.Fatalf("PublicKey = %q, want %q", channel.PublicKey(), publicKey)
+	}
+	channel.now = func() time.Time { return now }
+	return channel
+}
+
+// trustTestServers makes client trust the certificate every httptest TLS
+// server presents
+func trustTestServers(client *http.Client) {
+	server := httptest.NewTLSServer(http.NotFoundHandler())
+	defer server.Close()
+	roots := x509.NewCertPool()
+	roots.AddCert(server.Certificate())
+	client.Transport.(*http.Transport).TLSClientConfig = &tls.Config{RootCAs: roots}
+}
+
+func TestWebPushChannelSendsSignedEncryptedMessage(t *testing.T) {
+	type captured struct {
+		header http.Header
+		body   []byte
+	}
+	received := make(chan captured, 1)
+	server := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		received <- captured{header: r.Header.Clone(), body: body}
+		w.WriteHeader(http.StatusCreated)
+	}))
+	defer server.Close()
+
+	browser := newTestBrowser(t)
+	store := &fakePushStore{subscriptions: []*models.PushSubscription{browser.subscription("sub-1", server.URL+"/push/abc")}}
+	now := time.Now().Truncate(time.Second)
+	channel := newTestWebPushChannel(t, store, now)
+
+	notification := &models.Notification{
+		ID:      "n-1",
+		UserID:  "user-1",
+		Title:   "Task assigned",
+		Message: "Review the release notes",
+		Type:    models.PushNotification,
+	}
+	if err := channel.Send(context.Background(), notification); err != nil {
+		t.Fatalf("Send returned error: %v", err)
+	}
+
+	request := <-received
+	for header, want := range map[string]string{
+		"Content-Type":     "application/octet-stream",
+		"Content-Encoding": "aes128gcm",
+		"TTL":              strconv.Itoa(int(time.Hour.Seconds())),
+	} {
+		if got := request.header.Get(header); got != want {
+			t.Errorf("%s = %q, want %q", header, got, want)
+		}
+	}
+
+	// Authorization: vapid t=<jwt>, k=<public key>
+	authorization := request.header.Get("Authorization")
+	if !strings.HasPrefix(authorization, "vapid ") {
+		t.Fatalf("Authorization = %q, want the vapid scheme", authorization)
+	}
+	params := map[string]string{}
+	for _, param := range strings.Split(strings.TrimPrefix(authorization, "vapid "), ", ") {
+		name, value, _ := strings.Cut(param, "=")
+		params[name] = value
+	}
+	if params["k"] != channel.PublicKey() {
+		t.Errorf("k = %q, want the VAPID public key", params["k"])
+	}
+
+	x, y := elliptic.Unmarshal(elliptic.P256(), mustDecode(t, params["k"]))
+	if x == nil {
+		t.Fatalf("k is not an uncompressed P-256 point")
+	}
+	verifyKey := &ecdsa.PublicKey{Curve: elliptic.P256(), X: x, Y: y}
+	claims := jwt.MapClaims{}
+	token, err := jwt.ParseWithClaims(params["t"], claims, func(token *jwt.Token) (interface{}, error) {
+		if token.Method != jwt.SigningMethodES256 {
+			return nil, errors.New("unexpected signing method")
+		}
+		return verifyKey, nil
+	})
+	if err != nil || !token.Valid {
+		t.Fatalf("VAPID token does not verify: %v", err)
+	}
+	if claims["aud"] != server.URL {
+		t.Errorf("aud = %v, want the push service origin %s", claims["aud"], server.URL)
+	}
+	if claims["sub"] != "mailto:ops@taskmanager.test" {
+		t.Errorf("sub = %v", claims["sub"])
+	}
+	if exp, _ := claims["exp"].(float64); int64(exp) != now.Add(12*time.Hour).Unix() {
+		t.Errorf("exp = %v, want 12h from now", claims["exp"])
+	}
+
+	var payload models.PushPayload
+	if err := json.Unmarshal(decryptPushPayload(t, request.body, browser.key, browser.auth), &payload); err != nil {
+		t.Fatalf("decrypted payload is not JSON: %v", err)
+	}
+	if payload.ID != notification.ID || payload.Title != notification.Title || payload.Body != notification.Message {
+		t.Errorf("payload = %+v", payload)
+	}
+	if len(store.touched) != 1 || store.touched[0] != "sub-1" {
+		t.Errorf("touched = %v, want [sub-1]", store.touched)
+	}
+}
+
+func TestWebPushChannelStatusHandling(t *testing.T) {
+	tests := []struct {
+		status    int
+		removed   bool
+		permanent bool
+	}{
+		{status: http.StatusNotFound, removed: true, permanent: true},
+		{status: http.StatusGone, removed: true, permanent: true},
+		{status: http.StatusTooManyRequests, permanent: false},
+		{status: http.StatusServiceUnavailable, permanent: false},
+		{status: http.StatusBadRequest, permanent: true},
+		{status: http.StatusForbidden, permanent: true},
+		{status: http.StatusRequestEntityTooLarge, permanent: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(strconv.Itoa(tt.status), func(t *testing.T) {
+			server := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				w.WriteHeader(tt.status)
+			}))
+			defer server.Close()
+
+			store := &fakePushStore{subscriptions: []*models.PushSubscription{newTestBrowser(t).subscription("sub-1", server.URL)}}
+			err := newTestWebPushChannel(t, store, time.Now()).Send(context.Background(), &models.Notification{ID: "n-1", UserID: "user-1"})
+			if err == nil {
+				t.Fatal("Send succeeded, want an error")
+			}
+			var permanent *PermanentError
+			if errors.As(err, &permanent) != tt.permanent {
+				t.Errorf("permanent = %v, want %v (%v)", !tt.permanent, tt.permanent, err)
+			}
+			if removed := len(store.removed) == 1; removed != tt.removed {
+				t.Errorf("removed = %v, want %v", store.removed, tt.removed)
+			}
+		})
+	}
+}
+
+func TestWebPushChannelDeliveredToAnyDevice(t *testing.T) {
+	accepting := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusCreated)
+	}))
+	defer accepting.Close()
+	failing := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusInternalServerError)
+	}))
+	defer failing.Close()
+
+	store := &fakePushStore{subscriptions: []*models.PushSubscription{
+		newTestBrowser(t).subscription("failing", failing.URL),
+		newTestBrowser(t).subscription("accepting", accepting.URL),
+	}}
+	if err := newTestWebPushChannel(t, store, time.Now()).Send(context.Background(), &models.Notification{ID: "n-1", UserID: "user-1"}); err != nil {
+		t.Errorf("Send returned error: %v", err)
+	}
+}
+
+func TestWebPushChannelRefusesInternalEndpoints(t *testing.T) {
+	received := make(chan struct{}, 2)
+	handler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		received <- struct{}{}
+		w.WriteHeader(http.StatusCreated)
+	})
+	secure := httptest.NewTLSServer(handler)
+	defer secure.Close()
+	plain := httptest.NewServer(handler)
+	defer plain.Close()
+
+	tests := []struct {
+		name     string
+		outbound OutboundPolicy
+		endpoint string
+	}{
+		{name: "loopback", endpoint: secure.URL},
+		// localhost resolves to loopback, which is only caught when dialing
+		{name: "resolved to loopback", endpoint: strings.Replace(secure.URL, "127.0.0.1", "localhost", 1)},
+		{name: "plain http", outbound: testOutbound, endpoint: plain.URL},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			store := &fakePushStore{subscriptions: []*models.PushSubscription{newTestBrowser(t).subscription("sub-1", tt.endpoint)}}
+			channel := newTestWebPushChannel(t, store, time.Now())
+			channel.outbound = tt.outbound
+			channel.client = tt.outbound.Client(10 * time.Second)
+			trustTestServers(channel.client)
+
+			err := channel.Send(context.Background(), &models.Notification{ID: "n-1", UserID: "user-1"})
+			var permanent *PermanentError
+			if !errors.As(err, &permanent) {
+				t.Fatalf("Send error = %v, want a permanent error", err)
+			}
+			if len(received) != 0 {
+				t.Errorf("push service received %d messages, want 0", len(received))
+			}
+		})
+	}
+}
+
+func TestNewWebPushChannelChecksKeys(t *testing.T) {
+	publicKey, privateKey, _ := GenerateVAPIDKeys()
+	otherPublic, _, _ := GenerateVAPIDKeys()
+
+	if _, err := NewWebPushChannel(VAPIDConfig{PrivateKey: privateKey, PublicKey: otherPublic}, nil, OutboundPolicy{}); err == nil {
+		t.Error("mismatched key pair was accepted")
+	}
+	if _, err := NewWebPushChannel(VAPIDConfig{PrivateKey: "not-a-key"}, nil, OutboundPolicy{}); err == nil {
+		t.Error("malformed private key was accepted")
+	}
+
+	channel, err := NewWebPushChannel(VAPIDConfig{PrivateKey: privateKey}, nil, OutboundPolicy{})
+	if err != nil || channel.PublicKey() != publicKey {
+		t.Errorf("derived public key = %q (%v), want %q", channel.PublicKey(), err, publicKey)
+	}
+
+	disabled, _ := NewWebPushChannel(VAPIDConfig{}, nil, OutboundPolicy{})
+	var permanent *PermanentError
+	if err := disabled.Send(context.Background(), &models.Notification{}); !errors.As(err, &permanent) {
+		t.Errorf("unconfigured Send error = %v, want a permanent error", err)
+	}
+}
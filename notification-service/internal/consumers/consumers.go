@@ -0,0 +1,288 @@
+package consumers
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"log"
+	"notification-service/internal/database"
+	"notification-service/internal/events"
+	"notification-service/internal/models"
+	"notification-service/internal/templates"
+	"os"
+	"strings"
+	"time"
+)
+
+// Queue is the queue every notification-service instance subscribes with.
+// The instances share its durable consumers, so each event is handled by one
+// instance and events published while all of them were down are not lost.
+const Queue = "notification-service"
+
+// dedupeTTL is how long an event ID is remembered. Events are delivered at
+// least once; a redelivery inside this window is ignored.
+const dedupeTTL = 7 * 24 * time.Hour
+
+// maxActivityEvents bounds the task events kept on one task_activity
+// notification; older ones are dropped as new activity is merged in
+const maxActivityEvents = 20
+
+// Consumer turns events published by the other services into notifications
+type Consumer struct {
+	db            *database.DB
+	bus           events.Bus
+	activityTypes []models.NotificationType
+}
+
+// NewConsumer reads TASK_ACTIVITY_TYPES, a comma-separated list of channels
+// task activity is sent to watchers on (default "in_app"; add "webhook" to
+// forward task events to user webhooks)
+func NewConsumer(db *database.DB, bus events.Bus) *Consumer {
+	activityTypes := []models.NotificationType{models.InAppNotification}
+	if value := os.Getenv("TASK_ACTIVITY_TYPES"); value != "" {
+		var types []models.NotificationType
+		for _, t := range strings.Split(value, ",") {
+			if t := models.NotificationType(strings.TrimSpace(t)); t.IsValid() {
+				types = append(types, t)
+			}
+		}
+		if len(types) > 0 {
+			activityTypes = types
+		}
+	}
+	return &Consumer{db: db, bus: bus, activityTypes: activityTypes}
+}
+
+// Start subscribes to the events notification-service reacts to
+func (c *Consumer) Start() error {
+	handlers := map[string]func(*events.Envelope) error{
+		events.TaskCreated:   c.taskCreated,
+		events.TaskCompleted: c.taskCompleted,
+		events.TaskActivity:  c.taskActivity,
+		events.UserSignedUp:  c.userSignedUp,
+	}
+	for subject, handle := range handlers {
+		if _, err := c.bus.Subscribe(subject, Queue, envelopeHandler(handle)); err != nil {
+			return fmt.Errorf("failed to subscribe to %s: %w", subject, err)
+		}
+	}
+	return nil
+}
+
+func envelopeHandler(handle func(*events.Envelope) error) events.Handler {
+	return func(ctx context.Context, msg events.Message) error {
+		var envelope events.Envelope
+		if err := json.Unmarshal(msg.Data, &envelope); err != nil || envelope.ID == "" {
+			return fmt.Errorf("invalid event on %s", msg.Subject)
+		}
+		return handle(&envelope)
+	}
+}
+
+// taskEvent is the part of a task-service task the consumers use
+type taskEvent struct {
+	ID         string     `json:"id"`
+	Title      string     `json:"title"`
+	UserID     string     `json:"user_id"`
+	AssigneeID *string    `json:"assignee_id"`
+	DueDate    *time.Time `json:"due_date"`
+}
+
+// taskCreated tells the assignee of a new task, unless they created it
+func (c *Consumer) taskCreated(envelope *events.Envelope) error {
+	var task taskEvent
+	if err := json.Unmarshal(envelope.Data, &task); err != nil {
+		return fmt.Errorf("invalid %s event %s: %w", envelope.Type, envelope.ID, err)
+	}
+	if task.AssigneeID == nil || *task.AssigneeID == "" || *task.AssigneeID == task.UserID {
+		return nil
+	}
+
+	dueDate := ""
+	if task.DueDate != nil {
+		dueDate = task.DueDate.Format("Jan 2, 2006")
+	}
+	return c.notify(envelope, *task.AssigneeID, models.InAppNotification, "task_assigned", map[string]interface{}{
+		"task_id":    task.ID,
+		"task_title": task.Title,
+		"due_date":   dueDate,
+	})
+}
+
+// taskCompleted drops undelivered notifications about a task once it is done
+func (c *Consumer) taskCompleted(envelope *events.Envelope) error {
+	var task taskEvent
+	if err := json.Unmarshal(envelope.Data, &task); err != nil {
+		return fmt.Errorf("invalid %s event %s: %w", envelope.Type, envelope.ID, err)
+	}
+
+	expired, err := c.db.ExpireTaskNotifications(task.ID)
+	if err != nil {
+		return err
+	}
+	if expired > 0 {
+		log.Printf("🧹 Expired %d pending notifications for completed task %s", expired, task.ID)
+	}
+	return nil
+}
+
+// taskActivity tells a task's watchers about a change to it. Activity on a
+// task the watcher has not read about yet is merged into the same
+// notification, which lists the latest events.
+func (c *Consumer) taskActivity(envelope *events.Envelope) error {
+	var activity struct {
+		Type       string   `json:"type"`
+		TaskID     string   `json:"task_id"`
+		ActorID    string   `json:"actor_id"`
+		Recipients []string `json:"recipients"`
+	}
+	if err := json.Unmarshal(envelope.Data, &activity); err != nil || activity.TaskID == "" {
+		return fmt.Errorf("invalid %s event %s", envelope.Type, envelope.ID)
+	}
+	var event map[string]interface{}
+	if err := json.Unmarshal(envelope.Data, &event); err != nil {
+		return fmt.Errorf("invalid %s event %s: %w", envelope.Type, envelope.ID, err)
+	}
+	delete(event, "recipients")
+
+	var actors []string
+	if activity.ActorID != "" {
+		actors = []string{activity.ActorID}
+	}
+	groupKey := "task:" + activity.TaskID
+
+	for _, userID := range activity.Recipients {
+		for _, notificationType := range c.activityTypes {
+			// The events are read and rendered with the group locked, so
+			// activity handled at the same time by another instance is not
+			// dropped from the list
+			build := func(notification *models.Notification, group map[string]interface{}) error {
+				activityEvents := append(previousEvents(group), event)
+				if len(activityEvents) > maxActivityEvents {
+					activityEvents = activityEvents[len(activityEvents)-maxActivityEvents:]
+				}
+
+				rendered, err := c.render(notification.UserID, notification.Type, "task_activity", map[string]interface{}{
+					"action": "task_activity",
+					"events": activityEvents,
+				})
+				if err != nil {
+					return err
+				}
+				rendered.GroupKey = notification.GroupKey
+				rendered.Actors = notification.Actors
+				*notification = *rendered
+				return nil
+			}
+
+			notification := &models.Notification{
+				UserID:   userID,
+				Type:     notificationType,
+				GroupKey: groupKey,
+				Actors:   actors,
+			}
+			if err := c.storeGrouped(envelope, notification, build); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// previousEvents returns the events listed on a task_activity notification
+func previousEvents(data map[string]interface{}) []interface{} {
+	if list, ok := data["events"].([]interface{}); ok {
+		return list
+	}
+	return nil
+}
+
+// userSignedUp welcomes a new user in the app and by email
+func (c *Consumer) userSignedUp(envelope *events.Envelope) error {
+	var user struct {
+		UserID    string `json:"user_id"`
+		Email     string `json:"email"`
+		FirstName string `json:"first_name"`
+	}
+	if err := json.Unmarshal(envelope.Data, &user); err != nil || user.UserID == "" {
+		return fmt.Errorf("invalid %s event %s", envelope.Type, envelope.ID)
+	}
+
+	if err := c.notify(envelope, user.UserID, models.InAppNotification, "welcome", map[string]interface{}{
+		"first_name": user.FirstName,
+	}); err != nil {
+		return err
+	}
+	return c.notify(envelope, user.UserID, models.EmailNotification, "welcome", map[string]interface{}{
+		"first_name": user.FirstName,
+		"email":      user.Email,
+	})
+}
+
+// notify renders a template for the user and stores the notification once
+// per event, recipient and channel
+func (c *Consumer) notify(envelope *events.Envelope, userID string, notificationType models.NotificationType, templateKey string, data map[string]interface{}) error {
+	notification, err := c.render(userID, notificationType, templateKey, data)
+	if err != nil {
+		return err
+	}
+	return c.store(envelope, notification)
+}
+
+// render builds a pending notification for the user from a template in
+// their preferred locale
+func (c *Consumer) render(userID string, notificationType models.NotificationType, templateKey string, data map[string]interface{}) (*models.Notification, error) {
+	prefs, err := c.db.GetPreferences(userID)
+	if err != nil {
+		return nil, err
+	}
+	template, err := c.db.GetTemplate(templateKey, templates.LocaleCandidates(prefs.Locale))
+	if err != nil {
+		return nil, fmt.Errorf("failed to load template %s: %w", templateKey, err)
+	}
+	rendered, err := templates.Render(template, data)
+	if err != nil {
+		return nil, fmt.Errorf("failed to render template %s: %w", templateKey, err)
+	}
+
+	return &models.Notification{
+		UserID:      userID,
+		Title:       truncate(rendered.Title, 255),
+		Message:     rendered.Message,
+		Type:        notificationType,
+		Status:      models.StatusPending,
+		Data:        data,
+		Template:    template.Key,
+		HTMLMessage: rendered.HTML,
+	}, nil
+}
+
+// store saves the notification once per event, recipient and channel
+func (c *Consumer) store(envelope *events.Envelope, notification *models.Notification) error {
+	return c.storeGrouped(envelope, notification, nil)
+}
+
+// storeGrouped is store for a notification that build fills in from the
+// group it joins
+func (c *Consumer) storeGrouped(envelope *events.Envelope, notification *models.Notification, build database.GroupBuilder) error {
+	hash := sha256.Sum256(envelope.Data)
+	key := envelope.ID + ":" + notification.UserID + ":" + string(notification.Type)
+	_, replayed, err := c.db.CreateGroupedNotificationOnce("event", key, hex.EncodeToString(hash[:]), dedupeTTL, notification, build)
+	if err != nil {
+		return err
+	}
+	if replayed {
+		log.Printf("↩️ Ignored duplicate %s event %s", envelope.Type, envelope.ID)
+	}
+	return nil
+}
+
+func truncate(s string, max int) string {
+	runes := []rune(s)
+	if len(runes) <= max {
+		return s
+	}
+	return string(runes[:max])
+}
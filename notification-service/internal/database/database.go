@@ -10,12 +10,14 @@ import (
 	"os"
     "regexp"
     "strings"
-	_ "github.com/lib/pq"
+
+	"github.com/lib/pq"
 )
 
 
 type DB struct {
     *sql.DB
+    connStr string
 }
 
 func NewPostgresDB() (*DB, error) {
@@ -50,7 +52,7 @@ func NewPostgresDB() (*DB, error) {
     }
 
     log.Println("✅ Service: Connected to PostgreSQL successfully")
-    return &DB{db}, nil
+    return &DB{DB: db, connStr: connStr}, nil
 }
 
 // Helper function to mask password in logs
@@ -80,6 +82,158 @@ func (db *DB) Init() error {
 	CREATE INDEX IF NOT EXISTS idx_notifications_status ON notifications(status);
 	CREATE INDEX IF NOT EXISTS idx_notifications_created_at ON notifications(created_at DESC);
 	CREATE INDEX IF NOT EXISTS idx_notifications_type ON notifications(type);
+
+	-- Migration: delivery time recorded by the dispatcher
+	ALTER TABLE notifications ADD COLUMN IF NOT EXISTS sent_at TIMESTAMP NULL;
+	CREATE INDEX IF NOT EXISTS idx_notifications_pending ON notifications(created_at) WHERE status = 'pending';
+
+	-- Migration: retry bookkeeping and the dead_letter status
+	ALTER TABLE notifications ADD COLUMN IF NOT EXISTS attempts INT NOT NULL DEFAULT 0;
+	ALTER TABLE notifications ADD COLUMN IF NOT EXISTS next_attempt_at TIMESTAMP NULL;
+	ALTER TABLE notifications ADD COLUMN IF NOT EXISTS last_error TEXT NULL;
+	ALTER TABLE notifications DROP CONSTRAINT IF EXISTS notifications_status_check;
+	ALTER TABLE notifications ADD CONSTRAINT notifications_status_check
+		CHECK (status IN ('pending', 'sent', 'failed', 'read', 'dead_letter'));
+
+	-- Migration: delivery order of in-app notifications for streaming clients
+	CREATE SEQUENCE IF NOT EXISTS notification_stream_seq;
+	ALTER TABLE notifications ADD COLUMN IF NOT EXISTS stream_seq BIGINT NULL;
+	CREATE INDEX IF NOT EXISTS idx_notifications_stream ON notifications(user_id, stream_seq) WHERE stream_seq IS NOT NULL;
+
+	-- Migration: notifications rendered from templates
+	ALTER TABLE notifications ADD COLUMN IF NOT EXISTS template VARCHAR(100) NULL;
+	ALTER TABLE notifications ADD COLUMN IF NOT EXISTS html_message TEXT NULL;
+
+	CREATE TABLE IF NOT EXISTS notification_templates (
+		key VARCHAR(100) NOT NULL,
+		locale VARCHAR(20) NOT NULL,
+		title_template TEXT NOT NULL,
+		body_template TEXT NOT NULL,
+		html_template TEXT NULL,
+		variables TEXT[] NOT NULL DEFAULT '{}',
+		created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+		updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+		PRIMARY KEY (key, locale)
+	);
+
+	-- Migration: per-user preferences and the suppressed status
+	CREATE TABLE IF NOT EXISTS notification_preferences (
+		user_id UUID PRIMARY KEY,
+		timezone VARCHAR(64) NOT NULL DEFAULT 'UTC',
+		channels JSONB NOT NULL DEFAULT '{}'::jsonb,
+		categories JSONB NOT NULL DEFAULT '{}'::jsonb,
+		quiet_start VARCHAR(5) NULL,
+		quiet_end VARCHAR(5) NULL,
+		digest VARCHAR(10) NOT NULL DEFAULT 'off' CHECK (digest IN ('off', 'hourly', 'daily')),
+		updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+	);
+	ALTER TABLE notifications DROP CONSTRAINT IF EXISTS notifications_status_check;
+	ALTER TABLE notifications ADD CONSTRAINT notifications_status_check
+		CHECK (status IN ('pending', 'sent', 'failed', 'read', 'dead_letter', 'suppressed'));
+
+	-- Migration: emails held for the user's digest
+	ALTER TABLE notifications ADD COLUMN IF NOT EXISTS digest_at TIMESTAMP NULL;
+	CREATE INDEX IF NOT EXISTS idx_notifications_digest ON notifications(digest_at) WHERE status = 'pending' AND digest_at IS NOT NULL;
+
+	-- Migration: idempotency keys for notification creation
+	CREATE TABLE IF NOT EXISTS idempotency_keys (
+		scope VARCHAR(50) NOT NULL,
+		key VARCHAR(255) NOT NULL,
+		request_hash CHAR(64) NOT NULL,
+		notification_id UUID NOT NULL,
+		response JSONB NOT NULL,
+		created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+		expires_at TIMESTAMP NOT NULL,
+		PRIMARY KEY (scope, key)
+	);
+	CREATE INDEX IF NOT EXISTS idx_idempotency_keys_expires_at ON idempotency_keys(expires_at);
+
+	-- Migration: scheduled and expiring notifications
+	ALTER TABLE notifications ADD COLUMN IF NOT EXISTS send_at TIMESTAMP NULL;
+	ALTER TABLE notifications ADD COLUMN IF NOT EXISTS expires_at TIMESTAMP NULL;
+	CREATE INDEX IF NOT EXISTS idx_notifications_expires_at ON notifications(expires_at) WHERE expires_at IS NOT NULL;
+	ALTER TABLE notifications DROP CONSTRAINT IF EXISTS notifications_status_check;
+	ALTER TABLE notifications ADD CONSTRAINT notifications_status_check
+		CHECK (status IN ('pending', 'sent', 'failed', 'read', 'dead_letter', 'suppressed', 'expired'));
+
+	-- Migration: webhook channel
+	ALTER TABLE notifications DROP CONSTRAINT IF EXISTS notifications_type_check;
+	ALTER TABLE notifications ADD CONSTRAINT notifications_type_check
+		CHECK (type IN ('email', 'in_app', 'push', 'webhook'));
+
+	CREATE TABLE IF NOT EXISTS webhook_endpoints (
+		id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+		user_id UUID NOT NULL,
+		url TEXT NOT NULL,
+		secret VARCHAR(128) NOT NULL,
+		events TEXT[] NOT NULL DEFAULT '{}',
+		active BOOLEAN NOT NULL DEFAULT TRUE,
+		created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+		updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+	);
+	CREATE INDEX IF NOT EXISTS idx_webhook_endpoints_user_id ON webhook_endpoints(user_id);
+
+	CREATE TABLE IF NOT EXISTS webhook_deliveries (
+		id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+		endpoint_id UUID NOT NULL REFERENCES webhook_endpoints(id) ON DELETE CASCADE,
+		notification_id UUID NOT NULL,
+		attempt INT NOT NULL,
+		status_code INT NULL,
+		error TEXT NULL,
+		succeeded BOOLEAN NOT NULL,
+		duration_ms BIGINT NOT NULL,
+		created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+	);
+	CREATE INDEX IF NOT EXISTS idx_webhook_deliveries_endpoint ON webhook_deliveries(endpoint_id, created_at DESC);
+	CREATE INDEX IF NOT EXISTS idx_webhook_deliveries_notification ON webhook_deliveries(notification_id) WHERE succeeded;
+
+	CREATE TABLE IF NOT EXISTS push_subscriptions (
+		id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+		user_id UUID NOT NULL,
+		endpoint TEXT NOT NULL UNIQUE,
+		p256dh VARCHAR(128) NOT NULL,
+		auth VARCHAR(64) NOT NULL,
+		user_agent TEXT NOT NULL DEFAULT '',
+		created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+		updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+		last_used_at TIMESTAMP NULL
+	);
+	CREATE INDEX IF NOT EXISTS idx_push_subscriptions_user_id ON push_subscriptions(user_id);
+
+	-- Migration: notification grouping
+	ALTER TABLE notifications ADD COLUMN IF NOT EXISTS group_key VARCHAR(255) NULL;
+	ALTER TABLE notifications ADD COLUMN IF NOT EXISTS group_count INT NOT NULL DEFAULT 1;
+	ALTER TABLE notifications ADD COLUMN IF NOT EXISTS actors TEXT[] NOT NULL DEFAULT '{}';
+	CREATE INDEX IF NOT EXISTS idx_notifications_group ON notifications(user_id, group_key) WHERE group_key IS NOT NULL AND read_at IS NULL;
+
+	-- Migration: dispatcher claims are leased rather than held in a transaction
+	ALTER TABLE notifications ADD COLUMN IF NOT EXISTS locked_until TIMESTAMP NULL;
+	ALTER TABLE notifications DROP CONSTRAINT IF EXISTS notifications_status_check;
+	ALTER TABLE notifications ADD CONSTRAINT notifications_status_check
+		CHECK (status IN ('pending', 'sending', 'sent', 'failed', 'read', 'dead_letter', 'suppressed', 'expired'));
+	CREATE INDEX IF NOT EXISTS idx_notifications_sending ON notifications(locked_until) WHERE status = 'sending';
+
+	-- Migration: stream positions come from a per-user counter. The counter
+	-- row stays locked until the transaction taking a position commits, so a
+	-- user's positions become visible in order and a client resuming after
+	-- the highest one it saw never skips a notification committed late.
+	-- Counters start after the user's highest position from the old sequence.
+	CREATE TABLE IF NOT EXISTS notification_stream_counters (
+		user_id UUID PRIMARY KEY,
+		last_seq BIGINT NOT NULL
+	);
+	CREATE OR REPLACE FUNCTION next_stream_seq(p_user_id UUID) RETURNS BIGINT AS $$
+		INSERT INTO notification_stream_counters AS c (user_id, last_seq)
+		VALUES (p_user_id, (SELECT COALESCE(MAX(stream_seq), 0) FROM notifications WHERE user_id = p_user_id) + 1)
+		ON CONFLICT (user_id) DO UPDATE SET last_seq = c.last_seq + 1
+		RETURNING last_seq
+	$$ LANGUAGE sql;
+
+	-- Migration: transactional notifications skip preferences, digest and quiet hours
+	ALTER TABLE notifications ADD COLUMN IF NOT EXISTS transactional BOOLEAN NOT NULL DEFAULT FALSE;
+
+	-- Migration: the language of notifications the service renders itself
+	ALTER TABLE notification_preferences ADD COLUMN IF NOT EXISTS locale VARCHAR(20) NOT NULL DEFAULT '';
 	`
 
 	_, err := db.Exec(query)
@@ -91,12 +245,39 @@ func (db *DB) Init() error {
 	return nil
 }
 
+// CreateNotification stores a new notification, or merges it into the unread
+// notification with the same group key
 func (db *DB) CreateNotification(notification *models.Notification) error {
-	query := `
-	INSERT INTO notifications (user_id, title, message, type, status, data)
-	VALUES ($1, $2, $3, $4, $5, $6)
-	RETURNING id, created_at, updated_at`
+	if notification.GroupKey == "" {
+		return createNotification(db, notification)
+	}
+
+	tx, err := db.Begin()
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	if err := createNotification(tx, notification); err != nil {
+		return err
+	}
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit notification: %w", err)
+	}
+	return nil
+}
 
+// queryRower is satisfied by both *DB and *sql.Tx
+type queryRower interface {
+	QueryRow(query string, args ...interface{}) *sql.Row
+	Exec(query string, args ...interface{}) (sql.Result, error)
+}
+
+// createNotification inserts the notification. A grouped notification must
+// be created inside a transaction: the group is locked for the rest of it so
+// concurrent notifications for the same group are merged one after another
+// rather than each inserting a row.
+func createNotification(q queryRower, notification *models.Notification) error {
 	// Handle JSON data properly - use json.RawMessage or empty object
 	var jsonData interface{}
 	if notification.Data != nil && len(notification.Data) > 0 {
@@ -110,15 +291,38 @@ func (db *DB) CreateNotification(notification *models.Notification) error {
 		// Use empty JSON object
 		jsonData = []byte("{}")
 	}
+	if notification.Actors == nil {
+		notification.Actors = []string{}
+	}
+
+	if notification.GroupKey != "" {
+		merged, err := mergeIntoGroup(q, notification, jsonData)
+		if err != nil || merged {
+			return err
+		}
+	}
+
+	query := `
+	INSERT INTO notifications (user_id, title, message, type, status, data, template, html_message,
+		send_at, expires_at, next_attempt_at, group_key, actors, transactional)
+	VALUES ($1, $2, $3, $4, $5, $6, NULLIF($7, ''), $8, $9, $10, $9, NULLIF($11, ''), $12, $13)
+	RETURNING id, created_at, updated_at, group_count`
 
-	err := db.QueryRow(query,
+	err := q.QueryRow(query,
 		notification.UserID,
 		notification.Title,
 		notification.Message,
 		notification.Type,
 		notification.Status,
 		jsonData,
-	).Scan(&notification.ID, &notification.CreatedAt, &notification.UpdatedAt)
+		notification.Template,
+		notification.HTMLMessage,
+		notification.SendAt,
+		notification.ExpiresAt,
+		notification.GroupKey,
+		pq.Array(notification.Actors),
+		notification.Transactional,
+	).Scan(&notification.ID, &notification.CreatedAt, &notification.UpdatedAt, &notification.GroupCount)
 
 	if err != nil {
 		return fmt.Errorf("failed to create notification: %w", err)
@@ -126,14 +330,123 @@ func (db *DB) CreateNotification(notification *models.Notification) error {
 
 	return nil
 }
-func (db *DB) GetNotificationByID(id string) (*models.Notification, error) {
-	var notification models.Notification
+
+// mergeIntoGroup folds the notification into the newest unread notification
+// of its group that can still change: one waiting for delivery, or an in-app
+// one already delivered. The group takes the new title, message and data,
+// counts one more notification and gains any new actors. created_at becomes
+// the time of the latest notification, so the group moves to the top of the
+// user's list. A delivered in-app notification also takes the user's next
+// stream position, the same way a new delivery would, so connected clients
+// and clients resuming from an earlier position see the update. On a merge
+// the notification is replaced by the updated group.
+func mergeIntoGroup(q queryRower, notification *models.Notification, jsonData interface{}) (bool, error) {
+	if err := lockGroup(q, notification); err != nil {
+		return false, err
+	}
+
+	query := `
+	UPDATE notifications
+	SET title = $1, message = $2, data = $3, template = NULLIF($4, ''), html_message = $5,
+		expires_at = $6, group_count = group_count + 1,
+		actors = array_cat(actors, ARRAY(SELECT unnest($7::text[]) EXCEPT SELECT unnest(actors))),
+		created_at = CURRENT_TIMESTAMP, updated_at = CURRENT_TIMESTAMP,
+		stream_seq = CASE WHEN status = 'sent' THEN next_stream_seq(user_id) ELSE stream_seq END
+	WHERE id = (
+		SELECT id FROM notifications
+		WHERE user_id = $8 AND type = $9 AND group_key = $10 AND read_at IS NULL
+			AND (status = 'pending' OR (type = 'in_app' AND status = 'sent'))
+			AND (send_at IS NULL OR send_at <= CURRENT_TIMESTAMP)
+			AND (expires_at IS NULL OR expires_at > CURRENT_TIMESTAMP)
+		ORDER BY created_at DESC
+		LIMIT 1
+	)
+	RETURNING ` + notificationColumns
+
+	merged, err := scanNotification(q.QueryRow(query,
+		notification.Title,
+		notification.Message,
+		jsonData,
+		notification.Template,
+		notification.HTMLMessage,
+		notification.ExpiresAt,
+		pq.Array(notification.Actors),
+		notification.UserID,
+		notification.Type,
+		notification.GroupKey,
+	))
+	if err == sql.ErrNoRows {
+		return false, nil
+	}
+	if err != nil {
+		return false, fmt.Errorf("failed to merge notification into group: %w", err)
+	}
+
+	if merged.Status == models.StatusSent {
+		if _, err := q.Exec(`SELECT pg_notify($1, $2)`, StreamChannel, merged.UserID); err != nil {
+			return false, fmt.Errorf("failed to signal grouped notification: %w", err)
+		}
+	}
+
+	*notification = *merged
+	return true, nil
+}
+
+// lockGroup locks the notification's group until the transaction ends. The
+// lock is taken again by every notification joining the group in the same
+// transaction, which Postgres allows.
+func lockGroup(q queryRower, notification *models.Notification) error {
+	_, err := q.Exec(`SELECT pg_advisory_xact_lock(hashtext($1::text || ':' || $2::text || ':' || $3::text))`,
+		notification.UserID, notification.Type, notification.GroupKey)
+	if err != nil {
+		return fmt.Errorf("failed to lock notification group: %w", err)
+	}
+	return nil
+}
+
+// groupData returns the data of the unread notification a new notification
+// in the group would be merged into, or nil when there is none
+func groupData(q queryRower, userID string, notificationType models.NotificationType, groupKey string) (map[string]interface{}, error) {
+	query := `
+	SELECT data FROM notifications
+	WHERE user_id = $1 AND type = $2 AND group_key = $3 AND read_at IS NULL
+		AND (status = 'pending' OR (type = 'in_app' AND status = 'sent'))
+		AND (send_at IS NULL OR send_at <= CURRENT_TIMESTAMP)
+		AND (expires_at IS NULL OR expires_at > CURRENT_TIMESTAMP)
+	ORDER BY created_at DESC
+	LIMIT 1`
+
 	var dataBytes []byte
+	err := q.QueryRow(query, userID, notificationType, groupKey).Scan(&dataBytes)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get notification group: %w", err)
+	}
+
+	data := make(map[string]interface{})
+	if err := json.Unmarshal(dataBytes, &data); err != nil {
+		return nil, fmt.Errorf("failed to decode notification group data: %w", err)
+	}
+	return data, nil
+}
+
+const notificationColumns = `id, user_id, title, message, type, status, data, created_at, updated_at, read_at,
+	sent_at, attempts, next_attempt_at, last_error, stream_seq, COALESCE(template, ''), html_message,
+	send_at, expires_at, COALESCE(group_key, ''), group_count, actors, locked_until, transactional`
+
+// rowScanner is satisfied by both *sql.Row and *sql.Rows
+type rowScanner interface {
+	Scan(dest ...interface{}) error
+}
 
-	query := `SELECT id, user_id, title, message, type, status, data, created_at, updated_at, read_at 
-			  FROM notifications WHERE id = $1`
+// scanNotification reads the notificationColumns of a row, followed by any extra columns
+func scanNotification(row rowScanner, extra ...interface{}) (*models.Notification, error) {
+	var notification models.Notification
+	var dataBytes []byte
 
-	err := db.QueryRow(query, id).Scan(
+	dest := []interface{}{
 		&notification.ID,
 		&notification.UserID,
 		&notification.Title,
@@ -144,13 +457,23 @@ func (db *DB) GetNotificationByID(id string) (*models.Notification, error) {
 		&notification.CreatedAt,
 		&notification.UpdatedAt,
 		&notification.ReadAt,
-	)
-
-	if err == sql.ErrNoRows {
-		return nil, fmt.Errorf("notification not found")
+		&notification.SentAt,
+		&notification.Attempts,
+		&notification.NextAttemptAt,
+		&notification.LastError,
+		&notification.StreamSeq,
+		&notification.Template,
+		&notification.HTMLMessage,
+		&notification.SendAt,
+		&notification.ExpiresAt,
+		&notification.GroupKey,
+		&notification.GroupCount,
+		pq.Array(&notification.Actors),
+		&notification.LockedUntil,
+		&notification.Transactional,
 	}
-	if err != nil {
-		return nil, fmt.Errorf("failed to get notification: %w", err)
+	if err := row.Scan(append(dest, extra...)...); err != nil {
+		return nil, err
 	}
 
 	// Unmarshal JSON data - handle empty case
@@ -162,64 +485,147 @@ func (db *DB) GetNotificationByID(id string) (*models.Notification, error) {
 	} else {
 		notification.Data = make(map[string]interface{})
 	}
+	if notification.Actors == nil {
+		notification.Actors = []string{}
+	}
 
 	return &notification, nil
 }
 
-func (db *DB) GetNotificationsByUserID(userID string, limit, offset int) ([]*models.Notification, error) {
-	query := `SELECT id, user_id, title, message, type, status, data, created_at, updated_at, read_at 
-			  FROM notifications WHERE user_id = $1 
-			  ORDER BY created_at DESC 
-			  LIMIT $2 OFFSET $3`
+func (db *DB) GetNotificationByID(id string) (*models.Notification, error) {
+	query := `SELECT ` + notificationColumns + ` FROM notifications WHERE id = $1`
+
+	notification, err := scanNotification(db.QueryRow(query, id))
+	if err == sql.ErrNoRows {
+		return nil, fmt.Errorf("notification not found")
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get notification: %w", err)
+	}
+
+	return notification, nil
+}
+
+// GetNotificationsByUserID returns a page of the user's notifications, newest
+// first, with the total and unread counts for the whole filtered list. Pages
+// continue from cursor when it is set, otherwise from offset.
+func (db *DB) GetNotificationsByUserID(userID string, filter models.NotificationFilter, cursor *models.NotificationCursor, limit, offset int) ([]*models.Notification, int, int, error) {
+	// Scheduled notifications appear once due and expired ones disappear
+	conditions := []string{
+		"user_id = $1",
+		"(send_at IS NULL OR send_at <= CURRENT_TIMESTAMP)",
+		"(expires_at IS NULL OR expires_at > CURRENT_TIMESTAMP)",
+	}
+	args := []interface{}{userID}
+	arg := func(v interface{}) string {
+		args = append(args, v)
+		return fmt.Sprintf("$%d", len(args))
+	}
+
+	if len(filter.Statuses) > 0 {
+		statuses := make([]string, len(filter.Statuses))
+		for i, status := range filter.Statuses {
+			statuses[i] = string(status)
+		}
+		conditions = append(conditions, "status = ANY("+arg(pq.Array(statuses))+")")
+	}
+	if len(filter.Types) > 0 {
+		types := make([]string, len(filter.Types))
+		for i, t := range filter.Types {
+			types[i] = string(t)
+		}
+		conditions = append(conditions, "type = ANY("+arg(pq.Array(types))+")")
+	}
+	if filter.From != nil {
+		conditions = append(conditions, "created_at >= "+arg(*filter.From))
+	}
+	if filter.To != nil {
+		conditions = append(conditions, "created_at <= "+arg(*filter.To))
+	}
+	if filter.UnreadOnly {
+		conditions = append(conditions, "read_at IS NULL")
+	}
+
+	where := strings.Join(conditions, " AND ")
+
+	var total, unread int
+	err := db.QueryRow(`SELECT COUNT(*), COUNT(*) FILTER (WHERE read_at IS NULL)
+		FROM notifications WHERE `+where, args...).Scan(&total, &unread)
+	if err != nil {
+		return nil, 0, 0, fmt.Errorf("failed to count notifications: %w", err)
+	}
+
+	if cursor != nil {
+		where += " AND (created_at, id) < (" + arg(cursor.CreatedAt) + ", " + arg(cursor.ID) + ")"
+		offset = 0
+	}
 
-	rows, err := db.Query(query, userID, limit, offset)
+	query := `SELECT ` + notificationColumns + `
+			  FROM notifications WHERE ` + where + `
+			  ORDER BY created_at DESC, id DESC
+			  LIMIT ` + arg(limit) + ` OFFSET ` + arg(offset)
+
+	rows, err := db.Query(query, args...)
 	if err != nil {
-		return nil, fmt.Errorf("failed to query notifications: %w", err)
+		return nil, 0, 0, fmt.Errorf("failed to query notifications: %w", err)
 	}
 	defer rows.Close()
 
-	var notifications []*models.Notification
+	notifications := []*models.Notification{}
 	for rows.Next() {
-		var notification models.Notification
-		var dataBytes []byte
-
-		err := rows.Scan(
-			&notification.ID,
-			&notification.UserID,
-			&notification.Title,
-			&notification.Message,
-			&notification.Type,
-			&notification.Status,
-			&dataBytes,
-			&notification.CreatedAt,
-			&notification.UpdatedAt,
-			&notification.ReadAt,
-		)
+		notification, err := scanNotification(rows)
 		if err != nil {
-			return nil, fmt.Errorf("failed to scan notification: %w", err)
+			return nil, 0, 0, fmt.Errorf("failed to scan notification: %w", err)
 		}
 
-		// Unmarshal JSON data - handle empty case
-		if len(dataBytes) > 0 && string(dataBytes) != "null" {
-			if err := json.Unmarshal(dataBytes, &notification.Data); err != nil {
-				notification.Data = make(map[string]interface{})
-			}
-		} else {
-			notification.Data = make(map[string]interface{})
-		}
+		notifications = append(notifications, notification)
+	}
 
-		notifications = append(notifications, &notification)
+	return notifications, total, unread, rows.Err()
+}
+
+// MarkNotificationsAsRead marks the user's unread notifications as read, only
+// those in ids when given. Notifications still waiting for delivery, pending
+// or claimed for sending, keep their status so the dispatcher sends them.
+func (db *DB) MarkNotificationsAsRead(userID string, ids []string) (int64, error) {
+	query := `UPDATE notifications
+			  SET read_at = $1, updated_at = $1,
+				status = CASE WHEN status = 'sent' THEN 'read' ELSE status END
+			  WHERE user_id = $2 AND read_at IS NULL
+				AND ($3::uuid[] IS NULL OR id = ANY($3::uuid[]))`
+
+	var idArray interface{}
+	if len(ids) > 0 {
+		idArray = pq.Array(ids)
+	}
+
+	result, err := db.Exec(query, time.Now(), userID, idArray)
+	if err != nil {
+		return 0, fmt.Errorf("failed to mark notifications as read: %w", err)
 	}
+	return result.RowsAffected()
+}
 
-	return notifications, nil
+// DeleteNotifications deletes the listed notifications belonging to the user
+func (db *DB) DeleteNotifications(userID string, ids []string) (int64, error) {
+	result, err := db.Exec(`DELETE FROM notifications WHERE user_id = $1 AND id = ANY($2::uuid[])`,
+		userID, pq.Array(ids))
+	if err != nil {
+		return 0, fmt.Errorf("failed to delete notifications: %w", err)
+	}
+	return result.RowsAffected()
 }
 
+// MarkNotificationAsRead marks one notification as read. Like the bulk
+// variant it leaves notifications still waiting for delivery to the
+// dispatcher, which records them as read once they are sent.
 func (db *DB) MarkNotificationAsRead(id string) error {
 	query := `UPDATE notifications 
-			  SET status = $1, read_at = $2, updated_at = $2 
-			  WHERE id = $3 AND read_at IS NULL`
+			  SET read_at = $1, updated_at = $1,
+				status = CASE WHEN status = 'sent' THEN 'read' ELSE status END
+			  WHERE id = $2 AND read_at IS NULL`
 
-	result, err := db.Exec(query, models.StatusRead, time.Now(), id)
+	result, err := db.Exec(query, time.Now(), id)
 	if err != nil {
 		return fmt.Errorf("failed to mark notification as read: %w", err)
 	}
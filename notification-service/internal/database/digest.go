@@ -0,0 +1,92 @@
+package database
+
+import (
+	"fmt"
+	"notification-service/internal/models"
+	"time"
+
+	"github.com/lib/pq"
+)
+
+// GetDueDigestUsers returns users with held emails whose digest time has come
+func (db *DB) GetDueDigestUsers(limit int) ([]string, error) {
+	query := `SELECT DISTINCT user_id FROM notifications
+			  WHERE status = $1 AND digest_at <= CURRENT_TIMESTAMP
+				AND (expires_at IS NULL OR expires_at > CURRENT_TIMESTAMP)
+			  LIMIT $2`
+
+	rows, err := db.Query(query, models.StatusPending, limit)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query digest users: %w", err)
+	}
+	defer rows.Close()
+
+	var users []string
+	for rows.Next() {
+		var userID string
+		if err := rows.Scan(&userID); err != nil {
+			return nil, fmt.Errorf("failed to scan digest user: %w", err)
+		}
+		users = append(users, userID)
+	}
+	return users, rows.Err()
+}
+
+// SendDigest claims the user's due held emails, up to limit, and hands them
+// to send as one batch. Like DispatchPending the claim is a lease committed
+// before sending; the outcome send returns is then recorded for every
+// notification in one short transaction, so a digest is either sent as a
+// whole or retried as a whole.
+func (db *DB) SendDigest(userID string, limit int, lease time.Duration, send func([]*models.Notification) models.DeliveryOutcome) (int, error) {
+	query := `UPDATE notifications
+			  SET status = $1, locked_until = CURRENT_TIMESTAMP + make_interval(secs => $5)
+			  WHERE id IN (
+				SELECT id FROM notifications
+				WHERE user_id = $3 AND digest_at <= CURRENT_TIMESTAMP
+				  AND (expires_at IS NULL OR expires_at > CURRENT_TIMESTAMP)
+				  AND (status = $2 OR (status = $1 AND locked_until <= CURRENT_TIMESTAMP))
+				ORDER BY created_at
+				LIMIT $4
+				FOR UPDATE SKIP LOCKED
+			  )
+			  RETURNING ` + notificationColumns
+
+	batch, err := db.claim(query, models.StatusSending, models.StatusPending, userID, limit, lease.Seconds())
+	if err != nil {
+		return 0, fmt.Errorf("failed to claim digest notifications: %w", err)
+	}
+	if len(batch) == 0 {
+		return 0, nil
+	}
+
+	outcome := send(batch)
+
+	var lastError interface{}
+	if outcome.Error != "" {
+		lastError = outcome.Error
+	}
+	attempted := 1
+	if outcome.Deferred {
+		attempted = 0
+	}
+
+	ids := make([]string, len(batch))
+	for i, notification := range batch {
+		ids[i] = notification.ID
+	}
+
+	// A retried digest keeps its notifications held, just for later. The
+	// batch was claimed under one lease; notifications whose claim was taken
+	// over since are left to the new claimant.
+	_, err = db.Exec(`UPDATE notifications
+		SET status = CASE WHEN $1 = 'sent' AND read_at IS NOT NULL THEN 'read' ELSE $1 END,
+			attempts = attempts + $5, digest_at = COALESCE($2, digest_at),
+			last_error = COALESCE($3, last_error), updated_at = CURRENT_TIMESTAMP, locked_until = NULL,
+			sent_at = CASE WHEN $1 = 'sent' THEN CURRENT_TIMESTAMP ELSE sent_at END
+		WHERE id = ANY($4::uuid[]) AND status = $6 AND locked_until = $7`,
+		outcome.Status, outcome.NextAttemptAt, lastError, pq.Array(ids), attempted, models.StatusSending, batch[0].LockedUntil)
+	if err != nil {
+		return 0, fmt.Errorf("failed to record digest for %s: %w", userID, err)
+	}
+	return len(batch), nil
+}
@@ -0,0 +1,173 @@
+package database
+
+import (
+	"fmt"
+	"notification-service/internal/models"
+	"sort"
+	"time"
+
+	"github.com/lib/pq"
+)
+
+// DispatchPending claims up to limit pending notifications whose next attempt
+// is due and hands each to deliver, recording the outcome it returns along
+// with the attempt.
+//
+// Claimed notifications move to the sending status under a lease that ends
+// after lease; the claim commits before anything is sent, so no transaction
+// or row lock is held across the network. Each outcome is then recorded in
+// its own short transaction. Several dispatchers can run side by side without
+// claiming the same notification, and a dispatcher that dies mid-batch leaves
+// its claims to be taken over once their lease runs out.
+func (db *DB) DispatchPending(limit int, lease time.Duration, deliver func(*models.Notification) models.DeliveryOutcome) (int, error) {
+	query := `UPDATE notifications
+			  SET status = $1, locked_until = CURRENT_TIMESTAMP + make_interval(secs => $4)
+			  WHERE id IN (
+				SELECT id FROM notifications
+				WHERE digest_at IS NULL
+				  AND (expires_at IS NULL OR expires_at > CURRENT_TIMESTAMP)
+				  AND ((status = $2 AND (next_attempt_at IS NULL OR next_attempt_at <= CURRENT_TIMESTAMP))
+					OR (status = $1 AND locked_until <= CURRENT_TIMESTAMP))
+				ORDER BY created_at
+				LIMIT $3
+				FOR UPDATE SKIP LOCKED
+			  )
+			  RETURNING ` + notificationColumns
+
+	batch, err := db.claim(query, models.StatusSending, models.StatusPending, limit, lease.Seconds())
+	if err != nil {
+		return 0, err
+	}
+
+	// A failure to record one outcome does not hold up the rest of the batch;
+	// that notification is retried once its lease runs out
+	var recordErr error
+	for _, notification := range batch {
+		if err := db.recordDelivery(notification, deliver(notification)); err != nil && recordErr == nil {
+			recordErr = err
+		}
+	}
+
+	return len(batch), recordErr
+}
+
+// claim runs a claiming UPDATE ... RETURNING and reads the claimed
+// notifications, oldest first
+func (db *DB) claim(query string, args ...interface{}) ([]*models.Notification, error) {
+	rows, err := db.Query(query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to claim notifications: %w", err)
+	}
+	defer rows.Close()
+
+	var batch []*models.Notification
+	for rows.Next() {
+		notification, err := scanNotification(rows)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan notification: %w", err)
+		}
+		batch = append(batch, notification)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to claim notifications: %w", err)
+	}
+
+	sort.Slice(batch, func(i, j int) bool { return batch[i].CreatedAt.Before(batch[j].CreatedAt) })
+	return batch, nil
+}
+
+// recordDelivery stores the outcome of one claimed notification and releases
+// its lease. A notification whose claim was taken over after its lease ran
+// out is left to the new claimant. One the user read while it waited is
+// recorded as read once it is sent.
+func (db *DB) recordDelivery(notification *models.Notification, outcome models.DeliveryOutcome) error {
+	var lastError interface{}
+	if outcome.Error != "" {
+		lastError = outcome.Error
+	}
+
+	attempted := 1
+	if outcome.Deferred {
+		attempted = 0
+	}
+
+	tx, err := db.Begin()
+	if err != nil {
+		return fmt.Errorf("failed to record delivery of %s: %w", notification.ID, err)
+	}
+	defer tx.Rollback()
+
+	result, err := tx.Exec(`UPDATE notifications
+		SET status = CASE WHEN $1 = 'sent' AND read_at IS NOT NULL THEN 'read' ELSE $1 END,
+			attempts = attempts + $5, next_attempt_at = $2, digest_at = $6,
+			last_error = COALESCE($3, last_error), updated_at = CURRENT_TIMESTAMP, locked_until = NULL,
+			sent_at = CASE WHEN $1 = 'sent' THEN CURRENT_TIMESTAMP ELSE sent_at END,
+			stream_seq = CASE WHEN $1 = 'sent' AND type = 'in_app' THEN next_stream_seq(user_id) ELSE stream_seq END
+		WHERE id = $4 AND status = $7 AND locked_until = $8`,
+		outcome.Status, outcome.NextAttemptAt, lastError, notification.ID, attempted, outcome.DigestAt,
+		models.StatusSending, notification.LockedUntil)
+	if err != nil {
+		return fmt.Errorf("failed to record delivery of %s: %w", notification.ID, err)
+	}
+	if recorded, _ := result.RowsAffected(); recorded == 0 {
+		return nil
+	}
+
+	// Postgres holds the notify until commit, so listeners never see a
+	// notification before it is readable
+	if outcome.Status == models.StatusSent && notification.Type == models.InAppNotification {
+		if _, err := tx.Exec(`SELECT pg_notify($1, $2)`, StreamChannel, notification.UserID); err != nil {
+			return fmt.Errorf("failed to signal delivery of %s: %w", notification.ID, err)
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("failed to record delivery of %s: %w", notification.ID, err)
+	}
+	return nil
+}
+
+func (db *DB) GetDeadLetterNotifications(limit, offset int) ([]*models.Notification, int, error) {
+	query := `SELECT ` + notificationColumns + `, COUNT(*) OVER ()
+			  FROM notifications WHERE status = $1
+			  ORDER BY updated_at DESC
+			  LIMIT $2 OFFSET $3`
+
+	rows, err := db.Query(query, models.StatusDeadLetter, limit, offset)
+	if err != nil {
+		return nil, 0, fmt.Errorf("failed to query dead-lettered notifications: %w", err)
+	}
+	defer rows.Close()
+
+	notifications := []*models.Notification{}
+	total := 0
+	for rows.Next() {
+		notification, err := scanNotification(rows, &total)
+		if err != nil {
+			return nil, 0, fmt.Errorf("failed to scan notification: %w", err)
+		}
+		notifications = append(notifications, notification)
+	}
+
+	return notifications, total, rows.Err()
+}
+
+// RequeueDeadLetters moves dead-lettered notifications back to pending with a
+// fresh attempt budget. With no IDs every dead-lettered notification is requeued.
+func (db *DB) RequeueDeadLetters(ids []string) (int64, error) {
+	query := `UPDATE notifications
+			  SET status = $1, attempts = 0, next_attempt_at = NULL, digest_at = NULL, updated_at = CURRENT_TIMESTAMP
+			  WHERE status = $2 AND ($3::uuid[] IS NULL OR id = ANY($3::uuid[]))`
+
+	var idArray interface{}
+	if len(ids) > 0 {
+		idArray = pq.Array(ids)
+	}
+
+	result, err := db.Exec(query, models.StatusPending, models.StatusDeadLetter, idArray)
+	if err != nil {
+		return 0, fmt.Errorf("failed to requeue notifications: %w", err)
+	}
+
+	return result.RowsAffected()
+}
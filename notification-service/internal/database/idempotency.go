@@ -0,0 +1,143 @@
+package database
+
+import (
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"notification-service/internal/models"
+	"time"
+)
+
+// ErrIdempotencyConflict means the key was already used for a different request
+var ErrIdempotencyConflict = errors.New("idempotency key was already used with a different request")
+
+// CreateNotificationOnce creates the notification unless key has been seen in
+// scope for the same user before. A repeat of the same request returns the
+// notification as it was first created, with replayed set; a different
+// request under the same key fails with ErrIdempotencyConflict.
+//
+// The key is stored in the same transaction as the notification. A
+// concurrent request with the same key waits on the key's unique index until
+// the first commits, then discards its own notification and replays the
+// first, so only one notification is ever created.
+func (db *DB) CreateNotificationOnce(scope, key, requestHash string, ttl time.Duration, notification *models.Notification) (*models.Notification, bool, error) {
+	return db.CreateGroupedNotificationOnce(scope, key, requestHash, ttl, notification, nil)
+}
+
+// GroupBuilder fills in a grouped notification from the data of the unread
+// notification it will be merged into, which is nil when it starts the group
+type GroupBuilder func(notification *models.Notification, group map[string]interface{}) error
+
+// CreateGroupedNotificationOnce is CreateNotificationOnce for a notification
+// whose content depends on the group it joins, such as a list of the events
+// merged so far. The notification needs its user, type and group key; build
+// fills in the rest while the group is locked, and the lock is held until
+// the notification is stored, so concurrent notifications for the group are
+// each built on the other's data rather than overwriting it.
+func (db *DB) CreateGroupedNotificationOnce(scope, key, requestHash string, ttl time.Duration, notification *models.Notification, build GroupBuilder) (*models.Notification, bool, error) {
+	// Keys are chosen by callers, so two users picking the same key must not
+	// be served each other's notification
+	scope = scope + ":" + notification.UserID
+
+	tx, err := db.Begin()
+	if err != nil {
+		return nil, false, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	// An expired key is free to be used again
+	_, err = tx.Exec(`DELETE FROM idempotency_keys
+		WHERE scope = $1 AND key = $2 AND expires_at <= CURRENT_TIMESTAMP`, scope, key)
+	if err != nil {
+		return nil, false, fmt.Errorf("failed to expire idempotency key: %w", err)
+	}
+
+	original, err := replayIdempotent(tx, scope, key, requestHash, " FOR UPDATE")
+	if err != sql.ErrNoRows {
+		return original, err == nil, err
+	}
+
+	if build != nil {
+		if err := lockGroup(tx, notification); err != nil {
+			return nil, false, err
+		}
+		group, err := groupData(tx, notification.UserID, notification.Type, notification.GroupKey)
+		if err != nil {
+			return nil, false, err
+		}
+		if err := build(notification, group); err != nil {
+			return nil, false, err
+		}
+	}
+
+	if err := createNotification(tx, notification); err != nil {
+		return nil, false, err
+	}
+
+	response, err := json.Marshal(notification)
+	if err != nil {
+		return nil, false, fmt.Errorf("failed to encode response: %w", err)
+	}
+
+	result, err := tx.Exec(`INSERT INTO idempotency_keys
+		(scope, key, request_hash, notification_id, response, expires_at)
+		VALUES ($1, $2, $3, $4, $5, $6)
+		ON CONFLICT (scope, key) DO NOTHING`,
+		scope, key, requestHash, notification.ID, response, time.Now().Add(ttl))
+	if err != nil {
+		return nil, false, fmt.Errorf("failed to store idempotency key: %w", err)
+	}
+	inserted, err := result.RowsAffected()
+	if err != nil {
+		return nil, false, fmt.Errorf("failed to store idempotency key: %w", err)
+	}
+	if inserted == 0 {
+		// A concurrent request committed the key first. Drop this
+		// notification and serve the stored one.
+		tx.Rollback()
+		original, err := replayIdempotent(db, scope, key, requestHash, "")
+		if err == sql.ErrNoRows {
+			return nil, false, fmt.Errorf("idempotency key was released by a concurrent request")
+		}
+		return original, err == nil, err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, false, fmt.Errorf("failed to commit notification: %w", err)
+	}
+	return notification, false, nil
+}
+
+// replayIdempotent returns the notification stored under the key, or
+// sql.ErrNoRows when the key is unused
+func replayIdempotent(q queryRower, scope, key, requestHash, lock string) (*models.Notification, error) {
+	var storedHash string
+	var response []byte
+	err := q.QueryRow(`SELECT request_hash, response FROM idempotency_keys
+		WHERE scope = $1 AND key = $2`+lock, scope, key).Scan(&storedHash, &response)
+	if err == sql.ErrNoRows {
+		return nil, err
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to look up idempotency key: %w", err)
+	}
+	if storedHash != requestHash {
+		return nil, ErrIdempotencyConflict
+	}
+
+	var original models.Notification
+	if err := json.Unmarshal(response, &original); err != nil {
+		return nil, fmt.Errorf("failed to decode stored response: %w", err)
+	}
+	return &original, nil
+}
+
+// PurgeExpiredIdempotencyKeys deletes keys past their TTL
+func (db *DB) PurgeExpiredIdempotencyKeys() (int64, error) {
+	result, err := db.Exec(`DELETE FROM idempotency_keys WHERE expires_at <= CURRENT_TIMESTAMP`)
+	if err != nil {
+		return 0, fmt.Errorf("failed to purge idempotency keys: %w", err)
+	}
+	return result.RowsAffected()
+}
@@ -0,0 +1,85 @@
+package database
+
+import (
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"notification-service/internal/models"
+)
+
+// GetPreferences returns the user's saved preferences, or the defaults when
+// there are none
+func (db *DB) GetPreferences(userID string) (*models.NotificationPreferences, error) {
+	query := `SELECT timezone, channels, categories, quiet_start, quiet_end, digest, locale, updated_at
+			  FROM notification_preferences WHERE user_id = $1`
+
+	prefs := models.DefaultPreferences(userID)
+	var channels, categories []byte
+	var quietStart, quietEnd sql.NullString
+
+	err := db.QueryRow(query, userID).Scan(
+		&prefs.Timezone,
+		&channels,
+		&categories,
+		&quietStart,
+		&quietEnd,
+		&prefs.Digest,
+		&prefs.Locale,
+		&prefs.UpdatedAt,
+	)
+	if err == sql.ErrNoRows {
+		return prefs, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get preferences: %w", err)
+	}
+
+	if err := json.Unmarshal(channels, &prefs.Channels); err != nil {
+		return nil, fmt.Errorf("failed to decode channel preferences: %w", err)
+	}
+	if err := json.Unmarshal(categories, &prefs.Categories); err != nil {
+		return nil, fmt.Errorf("failed to decode category preferences: %w", err)
+	}
+	if quietStart.Valid && quietEnd.Valid {
+		prefs.QuietHours = &models.QuietHours{Start: quietStart.String, End: quietEnd.String}
+	}
+
+	return prefs, nil
+}
+
+func (db *DB) SavePreferences(prefs *models.NotificationPreferences) error {
+	channels, err := json.Marshal(prefs.Channels)
+	if err != nil {
+		return fmt.Errorf("failed to encode channel preferences: %w", err)
+	}
+	categories, err := json.Marshal(prefs.Categories)
+	if err != nil {
+		return fmt.Errorf("failed to encode category preferences: %w", err)
+	}
+
+	var quietStart, quietEnd interface{}
+	if prefs.QuietHours != nil {
+		quietStart, quietEnd = prefs.QuietHours.Start, prefs.QuietHours.End
+	}
+
+	query := `INSERT INTO notification_preferences
+			  (user_id, timezone, channels, categories, quiet_start, quiet_end, digest, locale)
+			  VALUES ($1, $2, $3, $4, $5, $6, $7, $8)
+			  ON CONFLICT (user_id) DO UPDATE SET
+				timezone = EXCLUDED.timezone,
+				channels = EXCLUDED.channels,
+				categories = EXCLUDED.categories,
+				quiet_start = EXCLUDED.quiet_start,
+				quiet_end = EXCLUDED.quiet_end,
+				digest = EXCLUDED.digest,
+				locale = EXCLUDED.locale,
+				updated_at = CURRENT_TIMESTAMP
+			  RETURNING updated_at`
+
+	err = db.QueryRow(query, prefs.UserID, prefs.Timezone, channels, categories,
+		quietStart, quietEnd, prefs.Digest, prefs.Locale).Scan(&prefs.UpdatedAt)
+	if err != nil {
+		return fmt.Errorf("failed to save preferences: %w", err)
+	}
+	return nil
+}
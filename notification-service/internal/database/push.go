@@ -0,0 +1,92 @@
+package database
+
+import (
+	"fmt"
+	"notification-service/internal/models"
+)
+
+const pushSubscriptionColumns = `id, user_id, endpoint, p256dh, auth, user_agent, created_at, updated_at, last_used_at`
+
+// SavePushSubscription registers a browser push endpoint. Endpoints are
+// unique, so a browser that subscribes again (or is signed into by another
+// user) updates the existing row rather than adding a duplicate.
+func (db *DB) SavePushSubscription(subscription *models.PushSubscription) error {
+	query := `INSERT INTO push_subscriptions (user_id, endpoint, p256dh, auth, user_agent)
+			  VALUES ($1, $2, $3, $4, $5)
+			  ON CONFLICT (endpoint) DO UPDATE
+			  SET user_id = EXCLUDED.user_id, p256dh = EXCLUDED.p256dh, auth = EXCLUDED.auth,
+			      user_agent = EXCLUDED.user_agent, updated_at = CURRENT_TIMESTAMP
+			  RETURNING id, created_at, updated_at, last_used_at`
+
+	err := db.QueryRow(query, subscription.UserID, subscription.Endpoint, subscription.P256dh, subscription.Auth, subscription.UserAgent).
+		Scan(&subscription.ID, &subscription.CreatedAt, &subscription.UpdatedAt, &subscription.LastUsedAt)
+	if err != nil {
+		return fmt.Errorf("failed to save push subscription: %w", err)
+	}
+	return nil
+}
+
+func (db *DB) GetPushSubscriptions(userID string) ([]*models.PushSubscription, error) {
+	query := `SELECT ` + pushSubscriptionColumns + ` FROM push_subscriptions
+			  WHERE user_id = $1 ORDER BY created_at`
+
+	rows, err := db.Query(query, userID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query push subscriptions: %w", err)
+	}
+	defer rows.Close()
+
+	subscriptions := []*models.PushSubscription{}
+	for rows.Next() {
+		var subscription models.PushSubscription
+		err := rows.Scan(
+			&subscription.ID,
+			&subscription.UserID,
+			&subscription.Endpoint,
+			&subscription.P256dh,
+			&subscription.Auth,
+			&subscription.UserAgent,
+			&subscription.CreatedAt,
+			&subscription.UpdatedAt,
+			&subscription.LastUsedAt,
+		)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan push subscription: %w", err)
+		}
+		subscriptions = append(subscriptions, &subscription)
+	}
+	return subscriptions, rows.Err()
+}
+
+func (db *DB) DeletePushSubscription(userID, id string) error {
+	result, err := db.Exec(`DELETE FROM push_subscriptions WHERE id = $1 AND user_id = $2`, id, userID)
+	if err != nil {
+		return fmt.Errorf("failed to delete push subscription: %w", err)
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("failed to get rows affected: %w", err)
+	}
+	if rowsAffected == 0 {
+		return fmt.Errorf("push subscription not found")
+	}
+	return nil
+}
+
+// RemovePushSubscription drops a subscription the push service reported as
+// expired or unsubscribed
+func (db *DB) RemovePushSubscription(id string) error {
+	if _, err := db.Exec(`DELETE FROM push_subscriptions WHERE id = $1`, id); err != nil {
+		return fmt.Errorf("failed to remove push subscription: %w", err)
+	}
+	return nil
+}
+
+func (db *DB) TouchPushSubscription(id string) error {
+	_, err := db.Exec(`UPDATE push_subscriptions SET last_used_at = CURRENT_TIMESTAMP WHERE id = $1`, id)
+	if err != nil {
+		return fmt.Errorf("failed to update push subscription: %w", err)
+	}
+	return nil
+}
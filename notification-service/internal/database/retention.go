@@ -0,0 +1,47 @@
+package database
+
+import (
+	"fmt"
+	"notification-service/internal/models"
+	"time"
+)
+
+// ExpireUndelivered moves notifications still waiting for delivery past
+// their expires_at to the expired status
+func (db *DB) ExpireUndelivered() (int64, error) {
+	result, err := db.Exec(`UPDATE notifications
+		SET status = $1, updated_at = CURRENT_TIMESTAMP
+		WHERE status = $2 AND expires_at <= CURRENT_TIMESTAMP`,
+		models.StatusExpired, models.StatusPending)
+	if err != nil {
+		return 0, fmt.Errorf("failed to expire notifications: %w", err)
+	}
+	return result.RowsAffected()
+}
+
+// ExpireTaskNotifications expires the notifications about a task that are
+// still waiting for delivery, such as reminders scheduled before it was done
+func (db *DB) ExpireTaskNotifications(taskID string) (int64, error) {
+	result, err := db.Exec(`UPDATE notifications
+		SET status = $1, updated_at = CURRENT_TIMESTAMP
+		WHERE status = $2 AND data->>'task_id' = $3`,
+		models.StatusExpired, models.StatusPending, taskID)
+	if err != nil {
+		return 0, fmt.Errorf("failed to expire task notifications: %w", err)
+	}
+	return result.RowsAffected()
+}
+
+// PurgeNotifications deletes up to limit notifications in status last
+// updated before cutoff. It deletes in bounded batches so a large purge does
+// not hold locks on the table for long.
+func (db *DB) PurgeNotifications(status models.NotificationStatus, cutoff time.Time, limit int) (int64, error) {
+	result, err := db.Exec(`DELETE FROM notifications WHERE id IN (
+			SELECT id FROM notifications
+			WHERE status = $1 AND updated_at < $2
+			LIMIT $3)`, status, cutoff, limit)
+	if err != nil {
+		return 0, fmt.Errorf("failed to purge %s notifications: %w", status, err)
+	}
+	return result.RowsAffected()
+}
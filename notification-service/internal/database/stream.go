@@ -0,0 +1,70 @@
+package database
+
+import (
+	"fmt"
+	"log"
+	"notification-service/internal/models"
+	"time"
+
+	"github.com/lib/pq"
+)
+
+// StreamChannel is the Postgres NOTIFY channel carrying the user ID of every
+// in-app notification the dispatcher delivers
+const StreamChannel = "in_app_notifications"
+
+// NewStreamListener opens a dedicated connection listening on StreamChannel.
+// The listener reconnects on its own and sends a nil notification after each
+// reconnect, since anything signalled in between was missed.
+func (db *DB) NewStreamListener() (*pq.Listener, error) {
+	listener := pq.NewListener(db.connStr, 10*time.Second, time.Minute, func(event pq.ListenerEventType, err error) {
+		if err != nil {
+			log.Printf("❌ Stream listener: %v", err)
+		}
+	})
+
+	if err := listener.Listen(StreamChannel); err != nil {
+		listener.Close()
+		return nil, fmt.Errorf("failed to listen on %s: %w", StreamChannel, err)
+	}
+	return listener, nil
+}
+
+// GetStreamNotifications returns the user's delivered in-app notifications
+// after the given stream position, oldest first
+func (db *DB) GetStreamNotifications(userID string, afterSeq int64, limit int) ([]*models.Notification, error) {
+	query := `SELECT ` + notificationColumns + `
+			  FROM notifications
+			  WHERE user_id = $1 AND stream_seq > $2
+				AND (expires_at IS NULL OR expires_at > CURRENT_TIMESTAMP)
+			  ORDER BY stream_seq
+			  LIMIT $3`
+
+	rows, err := db.Query(query, userID, afterSeq, limit)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query stream notifications: %w", err)
+	}
+	defer rows.Close()
+
+	var notifications []*models.Notification
+	for rows.Next() {
+		notification, err := scanNotification(rows)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan notification: %w", err)
+		}
+		notifications = append(notifications, notification)
+	}
+
+	return notifications, rows.Err()
+}
+
+// GetLatestStreamSeq returns the user's current stream position, which is
+// where a client without a Last-Event-ID starts
+func (db *DB) GetLatestStreamSeq(userID string) (int64, error) {
+	var seq int64
+	err := db.QueryRow(`SELECT COALESCE(MAX(stream_seq), 0) FROM notifications WHERE user_id = $1`, userID).Scan(&seq)
+	if err != nil {
+		return 0, fmt.Errorf("failed to get stream position: %w", err)
+	}
+	return seq, nil
+}
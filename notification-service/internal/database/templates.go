@@ -0,0 +1,128 @@
+package database
+
+import (
+	"database/sql"
+	"fmt"
+	"notification-service/internal/models"
+
+	"github.com/lib/pq"
+)
+
+const templateColumns = `key, locale, title_template, body_template, html_template, variables, created_at, updated_at`
+
+func scanTemplate(row rowScanner) (*models.NotificationTemplate, error) {
+	var template models.NotificationTemplate
+	err := row.Scan(
+		&template.Key,
+		&template.Locale,
+		&template.TitleTemplate,
+		&template.BodyTemplate,
+		&template.HTMLTemplate,
+		pq.Array(&template.Variables),
+		&template.CreatedAt,
+		&template.UpdatedAt,
+	)
+	if err != nil {
+		return nil, err
+	}
+	if template.Variables == nil {
+		template.Variables = []string{}
+	}
+	return &template, nil
+}
+
+// EnsureTemplates inserts built-in templates that do not exist yet, leaving
+// any edited through the admin API untouched
+func (db *DB) EnsureTemplates(templates []*models.NotificationTemplate) error {
+	for _, template := range templates {
+		_, err := db.Exec(`INSERT INTO notification_templates
+			(key, locale, title_template, body_template, html_template, variables)
+			VALUES ($1, $2, $3, $4, $5, $6)
+			ON CONFLICT (key, locale) DO NOTHING`,
+			template.Key, template.Locale, template.TitleTemplate, template.BodyTemplate,
+			template.HTMLTemplate, pq.Array(template.Variables))
+		if err != nil {
+			return fmt.Errorf("failed to seed template %s/%s: %w", template.Key, template.Locale, err)
+		}
+	}
+	return nil
+}
+
+func (db *DB) UpsertTemplate(template *models.NotificationTemplate) error {
+	query := `INSERT INTO notification_templates
+			  (key, locale, title_template, body_template, html_template, variables)
+			  VALUES ($1, $2, $3, $4, $5, $6)
+			  ON CONFLICT (key, locale) DO UPDATE SET
+				title_template = EXCLUDED.title_template,
+				body_template = EXCLUDED.body_template,
+				html_template = EXCLUDED.html_template,
+				variables = EXCLUDED.variables,
+				updated_at = CURRENT_TIMESTAMP
+			  RETURNING created_at, updated_at`
+
+	err := db.QueryRow(query, template.Key, template.Locale, template.TitleTemplate, template.BodyTemplate,
+		template.HTMLTemplate, pq.Array(template.Variables)).Scan(&template.CreatedAt, &template.UpdatedAt)
+	if err != nil {
+		return fmt.Errorf("failed to save template: %w", err)
+	}
+	return nil
+}
+
+// GetTemplate returns the template for key in the first of locales that has
+// one, so callers pass locales from most to least specific
+func (db *DB) GetTemplate(key string, locales []string) (*models.NotificationTemplate, error) {
+	query := `SELECT ` + templateColumns + `
+			  FROM notification_templates
+			  WHERE key = $1 AND locale = ANY($2)
+			  ORDER BY array_position($2, locale::text)
+			  LIMIT 1`
+
+	template, err := scanTemplate(db.QueryRow(query, key, pq.Array(locales)))
+	if err == sql.ErrNoRows {
+		return nil, fmt.Errorf("template not found")
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get template: %w", err)
+	}
+	return template, nil
+}
+
+// GetTemplates lists every template, or only the locales of key when set
+func (db *DB) GetTemplates(key string) ([]*models.NotificationTemplate, error) {
+	query := `SELECT ` + templateColumns + `
+			  FROM notification_templates
+			  WHERE $1 = '' OR key = $1
+			  ORDER BY key, locale`
+
+	rows, err := db.Query(query, key)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query templates: %w", err)
+	}
+	defer rows.Close()
+
+	templates := []*models.NotificationTemplate{}
+	for rows.Next() {
+		template, err := scanTemplate(rows)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan template: %w", err)
+		}
+		templates = append(templates, template)
+	}
+	return templates, rows.Err()
+}
+
+func (db *DB) DeleteTemplate(key, locale string) error {
+	result, err := db.Exec(`DELETE FROM notification_templates WHERE key = $1 AND locale = $2`, key, locale)
+	if err != nil {
+		return fmt.Errorf("failed to delete template: %w", err)
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("failed to get rows affected: %w", err)
+	}
+	if rowsAffected == 0 {
+		return fmt.Errorf("template not found")
+	}
+	return nil
+}
@@ -0,0 +1,174 @@
+package database
+
+import (
+	"database/sql"
+	"fmt"
+	"notification-service/internal/models"
+
+	"github.com/lib/pq"
+)
+
+const webhookColumns = `id, user_id, url, secret, events, active, created_at, updated_at`
+
+func scanWebhook(row rowScanner) (*models.WebhookEndpoint, error) {
+	var endpoint models.WebhookEndpoint
+	err := row.Scan(
+		&endpoint.ID,
+		&endpoint.UserID,
+		&endpoint.URL,
+		&endpoint.Secret,
+		pq.Array(&endpoint.Events),
+		&endpoint.Active,
+		&endpoint.CreatedAt,
+		&endpoint.UpdatedAt,
+	)
+	if err != nil {
+		return nil, err
+	}
+	if endpoint.Events == nil {
+		endpoint.Events = []string{}
+	}
+	return &endpoint, nil
+}
+
+func (db *DB) CreateWebhook(endpoint *models.WebhookEndpoint) error {
+	query := `INSERT INTO webhook_endpoints (user_id, url, secret, events, active)
+			  VALUES ($1, $2, $3, $4, $5)
+			  RETURNING id, created_at, updated_at`
+
+	err := db.QueryRow(query, endpoint.UserID, endpoint.URL, endpoint.Secret, pq.Array(endpoint.Events), endpoint.Active).
+		Scan(&endpoint.ID, &endpoint.CreatedAt, &endpoint.UpdatedAt)
+	if err != nil {
+		return fmt.Errorf("failed to create webhook: %w", err)
+	}
+	return nil
+}
+
+func (db *DB) GetWebhook(userID, id string) (*models.WebhookEndpoint, error) {
+	query := `SELECT ` + webhookColumns + ` FROM webhook_endpoints WHERE id = $1 AND user_id = $2`
+
+	endpoint, err := scanWebhook(db.QueryRow(query, id, userID))
+	if err == sql.ErrNoRows {
+		return nil, fmt.Errorf("webhook not found")
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get webhook: %w", err)
+	}
+	return endpoint, nil
+}
+
+func (db *DB) GetWebhooks(userID string) ([]*models.WebhookEndpoint, error) {
+	return db.queryWebhooks(`SELECT `+webhookColumns+` FROM webhook_endpoints
+		WHERE user_id = $1 ORDER BY created_at`, userID)
+}
+
+// GetActiveWebhooks returns the endpoints webhook notifications for the user
+// are delivered to
+func (db *DB) GetActiveWebhooks(userID string) ([]*models.WebhookEndpoint, error) {
+	return db.queryWebhooks(`SELECT `+webhookColumns+` FROM webhook_endpoints
+		WHERE user_id = $1 AND active ORDER BY created_at`, userID)
+}
+
+func (db *DB) queryWebhooks(query string, args ...interface{}) ([]*models.WebhookEndpoint, error) {
+	rows, err := db.Query(query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query webhooks: %w", err)
+	}
+	defer rows.Close()
+
+	endpoints := []*models.WebhookEndpoint{}
+	for rows.Next() {
+		endpoint, err := scanWebhook(rows)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan webhook: %w", err)
+		}
+		endpoints = append(endpoints, endpoint)
+	}
+	return endpoints, rows.Err()
+}
+
+func (db *DB) UpdateWebhook(endpoint *models.WebhookEndpoint) error {
+	query := `UPDATE webhook_endpoints
+			  SET url = $1, events = $2, active = $3, updated_at = CURRENT_TIMESTAMP
+			  WHERE id = $4 AND user_id = $5
+			  RETURNING updated_at`
+
+	err := db.QueryRow(query, endpoint.URL, pq.Array(endpoint.Events), endpoint.Active, endpoint.ID, endpoint.UserID).
+		Scan(&endpoint.UpdatedAt)
+	if err == sql.ErrNoRows {
+		return fmt.Errorf("webhook not found")
+	}
+	if err != nil {
+		return fmt.Errorf("failed to update webhook: %w", err)
+	}
+	return nil
+}
+
+func (db *DB) DeleteWebhook(userID, id string) error {
+	result, err := db.Exec(`DELETE FROM webhook_endpoints WHERE id = $1 AND user_id = $2`, id, userID)
+	if err != nil {
+		return fmt.Errorf("failed to delete webhook: %w", err)
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("failed to get rows affected: %w", err)
+	}
+	if rowsAffected == 0 {
+		return fmt.Errorf("webhook not found")
+	}
+	return nil
+}
+
+// HasWebhookSucceeded reports whether the notification already reached the
+// endpoint, so a retry only goes to endpoints that failed
+func (db *DB) HasWebhookSucceeded(endpointID, notificationID string) (bool, error) {
+	var exists bool
+	err := db.QueryRow(`SELECT EXISTS (SELECT 1 FROM webhook_deliveries
+		WHERE endpoint_id = $1 AND notification_id = $2 AND succeeded)`, endpointID, notificationID).Scan(&exists)
+	if err != nil {
+		return false, fmt.Errorf("failed to check webhook deliveries: %w", err)
+	}
+	return exists, nil
+}
+
+func (db *DB) RecordWebhookDelivery(delivery *models.WebhookDelivery) error {
+	query := `INSERT INTO webhook_deliveries
+			  (endpoint_id, notification_id, attempt, status_code, error, succeeded, duration_ms)
+			  VALUES ($1, $2, $3, $4, $5, $6, $7)
+			  RETURNING id, created_at`
+
+	err := db.QueryRow(query, delivery.EndpointID, delivery.NotificationID, delivery.Attempt,
+		delivery.StatusCode, delivery.Error, delivery.Succeeded, delivery.DurationMS).
+		Scan(&delivery.ID, &delivery.CreatedAt)
+	if err != nil {
+		return fmt.Errorf("failed to record webhook delivery: %w", err)
+	}
+	return nil
+}
+
+// GetWebhookDeliveries returns the endpoint's most recent delivery attempts
+func (db *DB) GetWebhookDeliveries(endpointID string, limit, offset int) ([]*models.WebhookDelivery, error) {
+	query := `SELECT id, endpoint_id, notification_id, attempt, status_code, error, succeeded, duration_ms, created_at
+			  FROM webhook_deliveries WHERE endpoint_id = $1
+			  ORDER BY created_at DESC
+			  LIMIT $2 OFFSET $3`
+
+	rows, err := db.Query(query, endpointID, limit, offset)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query webhook deliveries: %w", err)
+	}
+	defer rows.Close()
+
+	deliveries := []*models.WebhookDelivery{}
+	for rows.Next() {
+		var delivery models.WebhookDelivery
+		err := rows.Scan(&delivery.ID, &delivery.EndpointID, &delivery.NotificationID, &delivery.Attempt,
+			&delivery.StatusCode, &delivery.Error, &delivery.Succeeded, &delivery.DurationMS, &delivery.CreatedAt)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan webhook delivery: %w", err)
+		}
+		deliveries = append(deliveries, &delivery)
+	}
+	return deliveries, rows.Err()
+}
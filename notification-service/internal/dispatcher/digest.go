@@ -0,0 +1,200 @@
+package dispatcher
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"notification-service/internal/channels"
+	"notification-service/internal/database"
+	"notification-service/internal/models"
+	"notification-service/internal/templates"
+	"os"
+	"time"
+)
+
+// digestTemplate renders the combined message
+const digestTemplate = "email_digest"
+
+// digestLimit caps how many notifications go into one digest; anything over
+// it goes out in the next one
+const digestLimit = 200
+
+// DigestScheduler sends the emails the dispatcher held for digest users as a
+// single message per user once their digest time comes
+type DigestScheduler struct {
+	db       *database.DB
+	config   Config
+	channel  channels.Channel
+	interval time.Duration
+}
+
+// NewDigestScheduler sends digests through channel, checking for due digests
+// every DIGEST_INTERVAL (default 1m)
+func NewDigestScheduler(db *database.DB, config Config, channel channels.Channel) *DigestScheduler {
+	interval := time.Minute
+	if value, err := time.ParseDuration(os.Getenv("DIGEST_INTERVAL")); err == nil && value > 0 {
+		interval = value
+	}
+	return &DigestScheduler{db: db, config: config, channel: channel, interval: interval}
+}
+
+// Run sends due digests until ctx is cancelled
+func (s *DigestScheduler) Run(ctx context.Context) {
+	log.Printf("📬 Digest scheduler started (interval %s)", s.interval)
+
+	ticker := time.NewTicker(s.interval)
+	defer ticker.Stop()
+
+	for {
+		if err := s.SendDue(ctx); err != nil {
+			log.Printf("❌ Digest run failed: %v", err)
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// SendDue sends one digest to every user whose digest time has come
+func (s *DigestScheduler) SendDue(ctx context.Context) error {
+	users, err := s.db.GetDueDigestUsers(s.config.BatchSize)
+	if err != nil {
+		return err
+	}
+
+	for _, userID := range users {
+		prefs, err := s.db.GetPreferences(userID)
+		if err != nil {
+			log.Printf("❌ Failed to load preferences for digest of %s: %v", userID, err)
+			continue
+		}
+
+		// A digest is a single send, so its claim only has to outlast one
+		// send timeout
+		count, err := s.db.SendDigest(userID, digestLimit, s.config.SendTimeout+time.Minute, func(batch []*models.Notification) models.DeliveryOutcome {
+			return s.send(ctx, userID, prefs, batch)
+		})
+		if err != nil {
+			log.Printf("❌ Digest for %s failed: %v", userID, err)
+			continue
+		}
+		if count > 0 {
+			log.Printf("📬 Processed digest of %d notification(s) for %s", count, userID)
+		}
+	}
+	return nil
+}
+
+func (s *DigestScheduler) send(ctx context.Context, userID string, prefs *models.NotificationPreferences, batch []*models.Notification) models.DeliveryOutcome {
+	// Digests due during quiet hours wait for them to end
+	if until := prefs.QuietUntil(time.Now()); until != nil {
+		return models.DeliveryOutcome{Status: models.StatusPending, NextAttemptAt: until, Deferred: true}
+	}
+
+	digest, err := s.render(userID, prefs, batch)
+	if err == nil {
+		sendCtx, cancel := context.WithTimeout(ctx, s.config.SendTimeout)
+		err = s.channel.Send(sendCtx, digest)
+		cancel()
+	}
+	if err == nil {
+		return models.DeliveryOutcome{Status: models.StatusSent}
+	}
+
+	attempt := 0
+	for _, notification := range batch {
+		if notification.Attempts > attempt {
+			attempt = notification.Attempts
+		}
+	}
+	attempt++
+
+	var permanent *channels.PermanentError
+	if errors.As(err, &permanent) || attempt >= s.config.MaxAttempts {
+		log.Printf("💀 Digest of %d notification(s) for %s dead-lettered after %d attempt(s): %v", len(batch), userID, attempt, err)
+		return models.DeliveryOutcome{Status: models.StatusDeadLetter, Error: err.Error()}
+	}
+
+	next := time.Now().Add(s.config.backoff(attempt))
+	log.Printf("❌ Digest for %s failed (attempt %d/%d), retrying at %s: %v",
+		userID, attempt, s.config.MaxAttempts, next.Format(time.RFC3339), err)
+	return models.DeliveryOutcome{Status: models.StatusPending, NextAttemptAt: &next, Error: err.Error()}
+}
+
+// digestLocale is the user's preferred locale, or failing that the locale
+// the newest notification in the batch was requested in
+func digestLocale(prefs *models.NotificationPreferences, batch []*models.Notification) string {
+	if prefs.Locale != "" {
+		return prefs.Locale
+	}
+	for i := len(batch) - 1; i >= 0; i-- {
+		if locale, ok := batch[i].Data["locale"].(string); ok && locale != "" {
+			return locale
+		}
+	}
+	return ""
+}
+
+// render combines the batch into one email notification. It is only handed
+// to the channel, never stored; the constituents record the delivery.
+func (s *DigestScheduler) render(userID string, prefs *models.NotificationPreferences, batch []*models.Notification) (*models.Notification, error) {
+	template, err := s.db.GetTemplate(digestTemplate, templates.LocaleCandidates(digestLocale(prefs, batch)))
+	if err != nil {
+		return nil, fmt.Errorf("failed to load digest template: %w", err)
+	}
+
+	loc, err := time.LoadLocation(prefs.Timezone)
+	if err != nil {
+		loc = time.UTC
+	}
+
+	items := make([]interface{}, len(batch))
+	for i, notification := range batch {
+		items[i] = map[string]interface{}{
+			"title":      notification.Title,
+			"message":    notification.Message,
+			"created_at": notification.CreatedAt.In(loc).Format("Jan 2, 15:04"),
+		}
+	}
+	data := map[string]interface{}{
+		"items":  items,
+		"count":  len(batch),
+		"period": string(prefs.Digest),
+	}
+
+	rendered, err := templates.Render(template, data)
+	if err != nil {
+		return nil, channels.Permanent(err)
+	}
+
+	// An address override is honoured when every constituent agrees on it
+	digestData := map[string]interface{}{"category": "digest"}
+	if email, ok := batch[0].Data["email"].(string); ok {
+		shared := true
+		for _, notification := range batch[1:] {
+			if other, _ := notification.Data["email"].(string); other != email {
+				shared = false
+				break
+			}
+		}
+		if shared {
+			digestData["email"] = email
+		}
+	}
+
+	return &models.Notification{
+		UserID:      userID,
+		Title:       rendered.Title,
+		Message:     rendered.Message,
+		HTMLMessage: rendered.HTML,
+		Type:        models.EmailNotification,
+		Status:      models.StatusPending,
+		Data:        digestData,
+		Template:    template.Key,
+		CreatedAt:   time.Now(),
+	}, nil
+}
@@ -0,0 +1,205 @@
+package dispatcher
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"math/rand"
+	"notification-service/internal/channels"
+	"notification-service/internal/database"
+	"notification-service/internal/models"
+	"os"
+	"strconv"
+	"time"
+)
+
+type Config struct {
+	Interval    time.Duration
+	BatchSize   int
+	SendTimeout time.Duration
+
+	// MaxAttempts is how many deliveries are tried before dead-lettering
+	MaxAttempts int
+	BackoffBase time.Duration
+	BackoffMax  time.Duration
+}
+
+// ConfigFromEnv reads DISPATCH_INTERVAL, DISPATCH_BATCH_SIZE,
+// DISPATCH_MAX_ATTEMPTS, DISPATCH_BACKOFF_BASE and DISPATCH_BACKOFF_MAX
+func ConfigFromEnv() Config {
+	config := Config{
+		Interval:    5 * time.Second,
+		BatchSize:   50,
+		SendTimeout: 30 * time.Second,
+		MaxAttempts: 5,
+		BackoffBase: 30 * time.Second,
+		BackoffMax:  time.Hour,
+	}
+	if value, err := time.ParseDuration(os.Getenv("DISPATCH_INTERVAL")); err == nil && value > 0 {
+		config.Interval = value
+	}
+	if value, err := strconv.Atoi(os.Getenv("DISPATCH_BATCH_SIZE")); err == nil && value > 0 {
+		config.BatchSize = value
+	}
+	if value, err := strconv.Atoi(os.Getenv("DISPATCH_MAX_ATTEMPTS")); err == nil && value > 0 {
+		config.MaxAttempts = value
+	}
+	if value, err := time.ParseDuration(os.Getenv("DISPATCH_BACKOFF_BASE")); err == nil && value > 0 {
+		config.BackoffBase = value
+	}
+	if value, err := time.ParseDuration(os.Getenv("DISPATCH_BACKOFF_MAX")); err == nil && value > 0 {
+		config.BackoffMax = value
+	}
+	return config
+}
+
+// Dispatcher moves pending notifications to sent by routing each one to the
+// Channel registered for its type, retrying failures with exponential backoff
+// until they are dead-lettered
+type Dispatcher struct {
+	db       *database.DB
+	config   Config
+	channels map[models.NotificationType]channels.Channel
+}
+
+func NewDispatcher(db *database.DB, config Config) *Dispatcher {
+	return &Dispatcher{
+		db:       db,
+		config:   config,
+		channels: make(map[models.NotificationType]channels.Channel),
+	}
+}
+
+// Register routes notifications of type t to channel
+func (d *Dispatcher) Register(t models.NotificationType, channel channels.Channel) {
+	d.channels[t] = channel
+}
+
+// Run dispatches until ctx is cancelled. A full batch is followed immediately
+// by the next one, so a backlog drains without waiting for the interval.
+func (d *Dispatcher) Run(ctx context.Context) {
+	log.Printf("📨 Notification dispatcher started (interval %s, batch %d)", d.config.Interval, d.config.BatchSize)
+
+	for {
+		count, err := d.DispatchOnce(ctx)
+		if err != nil {
+			log.Printf("❌ Dispatch failed: %v", err)
+		}
+		if err == nil && count == d.config.BatchSize {
+			continue
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(d.config.Interval):
+		}
+	}
+}
+
+// DispatchOnce delivers a single batch and returns how many were processed
+func (d *Dispatcher) DispatchOnce(ctx context.Context) (int, error) {
+	preferences := make(map[string]*models.NotificationPreferences)
+
+	return d.db.DispatchPending(d.config.BatchSize, d.config.lease(), func(notification *models.Notification) models.DeliveryOutcome {
+		// Transactional notifications, such as password resets, go out
+		// regardless of preferences: the user is waiting for them or must not
+		// miss them
+		if !notification.Transactional {
+			prefs, ok := preferences[notification.UserID]
+			if !ok {
+				var err error
+				if prefs, err = d.db.GetPreferences(notification.UserID); err != nil {
+					return d.failure(notification, err)
+				}
+				preferences[notification.UserID] = prefs
+			}
+
+			if outcome, held := d.hold(notification, prefs); held {
+				return outcome
+			}
+		}
+
+		err := d.deliver(ctx, notification)
+		if err == nil {
+			return models.DeliveryOutcome{Status: models.StatusSent}
+		}
+		return d.failure(notification, err)
+	})
+}
+
+// hold applies the user's preferences: notifications they opted out of are
+// suppressed, emails of digest users are held for the digest scheduler, and
+// email or push arriving during quiet hours is deferred until the quiet hours
+// end. In-app notifications are never deferred since they do not interrupt
+// anyone.
+func (d *Dispatcher) hold(notification *models.Notification, prefs *models.NotificationPreferences) (models.DeliveryOutcome, bool) {
+	if !prefs.Allows(notification.Category(), notification.Type) {
+		return models.DeliveryOutcome{Status: models.StatusSuppressed}, true
+	}
+
+	if notification.Type == models.EmailNotification {
+		if digestAt := prefs.NextDigest(time.Now()); digestAt != nil {
+			return models.DeliveryOutcome{Status: models.StatusPending, DigestAt: digestAt, Deferred: true}, true
+		}
+	}
+
+	if notification.Type == models.InAppNotification {
+		return models.DeliveryOutcome{}, false
+	}
+	if until := prefs.QuietUntil(time.Now()); until != nil {
+		return models.DeliveryOutcome{Status: models.StatusPending, NextAttemptAt: until, Deferred: true}, true
+	}
+	return models.DeliveryOutcome{}, false
+}
+
+// failure schedules another attempt, or dead-letters the notification once
+// it is out of attempts or the error is permanent
+func (d *Dispatcher) failure(notification *models.Notification, err error) models.DeliveryOutcome {
+	attempt := notification.Attempts + 1
+
+	var permanent *channels.PermanentError
+	if errors.As(err, &permanent) || attempt >= d.config.MaxAttempts {
+		log.Printf("💀 Notification %s dead-lettered after %d attempt(s): %v", notification.ID, attempt, err)
+		return models.DeliveryOutcome{Status: models.StatusDeadLetter, Error: err.Error()}
+	}
+
+	next := time.Now().Add(d.config.backoff(attempt))
+	log.Printf("❌ Delivery of %s notification %s failed (attempt %d/%d), retrying at %s: %v",
+		notification.Type, notification.ID, attempt, d.config.MaxAttempts, next.Format(time.RFC3339), err)
+	return models.DeliveryOutcome{Status: models.StatusPending, NextAttemptAt: &next, Error: err.Error()}
+}
+
+// lease is how long a dispatcher keeps its claim on a batch: long enough for
+// every notification in it to use its full send timeout, plus some slack
+func (c Config) lease() time.Duration {
+	return time.Duration(c.BatchSize)*c.SendTimeout + time.Minute
+}
+
+// backoff doubles the delay with every attempt up to BackoffMax, then picks a
+// random point in its upper half so retries from a failed batch spread out
+func (c Config) backoff(attempt int) time.Duration {
+	delay := c.BackoffBase
+	for i := 1; i < attempt && delay < c.BackoffMax; i++ {
+		delay *= 2
+	}
+	if delay > c.BackoffMax {
+		delay = c.BackoffMax
+	}
+
+	half := delay / 2
+	return half + time.Duration(rand.Int63n(int64(half)+1))
+}
+
+func (d *Dispatcher) deliver(ctx context.Context, notification *models.Notification) error {
+	channel, ok := d.channels[notification.Type]
+	if !ok {
+		return channels.Permanent(fmt.Errorf("no channel registered for type %s", notification.Type))
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, d.config.SendTimeout)
+	defer cancel()
+
+	return channel.Send(ctx, notification)
+}
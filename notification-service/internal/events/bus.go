@@ -0,0 +1,93 @@
+package events
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"log"
+	"os"
+	"strings"
+	"time"
+)
+
+// Event types shared by the services. The type doubles as the bus subject.
+const (
+	TaskCreated   = "task.created"
+	TaskCompleted = "task.completed"
+	TaskActivity  = "task.activity"
+	UserSignedUp  = "user.signed_up"
+)
+
+// Envelope is the JSON body of every event on the bus. ID is unique per
+// event and stays the same when the event is redelivered, so consumers use it
+// to ignore duplicates.
+type Envelope struct {
+	ID          string          `json:"id"`
+	Type        string          `json:"type"`
+	Source      string          `json:"source"`
+	AggregateID string          `json:"aggregate_id"`
+	OccurredAt  time.Time       `json:"occurred_at"`
+	Data        json.RawMessage `json:"data"`
+}
+
+// Message is one message as carried on the bus. ID, when set, is the event
+// ID, which the bus uses to drop a message published twice.
+type Message struct {
+	ID      string
+	Subject string
+	Data    []byte
+}
+
+// Handler processes a message delivered to a subscription
+type Handler func(ctx context.Context, msg Message) error
+
+type Subscription interface {
+	Unsubscribe() error
+}
+
+// Bus carries events between services. Publish returns once the bus has
+// stored the message. Subjects are dot-separated; a subscription may use
+// "*" to match one token and a trailing ">" to match the rest, and
+// subscribers sharing a queue name split the messages between them. A
+// message whose handler fails is delivered again.
+type Bus interface {
+	Publish(ctx context.Context, msg Message) error
+	Subscribe(subject, queue string, handler Handler) (Subscription, error)
+	Close() error
+}
+
+// NewBusFromEnv connects to NATS at NATS_URL. An in-process bus, which only
+// reaches subscribers in the same service, must be asked for with
+// EVENT_BUS=memory and is meant for local development; without either,
+// startup fails rather than silently dropping events meant for other
+// services. name identifies the connection to the server.
+func NewBusFromEnv(name string) (Bus, error) {
+	if os.Getenv("EVENT_BUS") == "memory" {
+		log.Printf("⚠️ Using the in-process event bus; events will not reach other services")
+		return NewMemoryBus(), nil
+	}
+	url := os.Getenv("NATS_URL")
+	if url == "" {
+		return nil, errors.New("NATS_URL is not set (set EVENT_BUS=memory to run without NATS in development)")
+	}
+	return DialNATS(url, name)
+}
+
+// MatchSubject reports whether subject matches a subscription pattern
+func MatchSubject(pattern, subject string) bool {
+	patternTokens := strings.Split(pattern, ".")
+	subjectTokens := strings.Split(subject, ".")
+
+	for i, token := range patternTokens {
+		if token == ">" {
+			return i < len(subjectTokens)
+		}
+		if i >= len(subjectTokens) {
+			return false
+		}
+		if token != "*" && token != subjectTokens[i] {
+			return false
+		}
+	}
+	return len(patternTokens) == len(subjectTokens)
+}
@@ -0,0 +1,213 @@
+package events
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/nats-io/nats.go"
+	"github.com/nats-io/nats.go/jetstream"
+)
+
+// StreamName is the JetStream stream every service publishes its events to.
+// It keeps events for as long as consumers remember event IDs, so a consumer
+// that was down catches up on what it missed.
+const StreamName = "EVENTS"
+
+// streamSubjects are the subjects the stream captures, one per publishing
+// service
+var streamSubjects = []string{"task.>", "user.>"}
+
+const (
+	streamMaxAge = 7 * 24 * time.Hour
+
+	// A message is redelivered when its handler fails or does not ack within
+	// ackWait, backing off up to maxRedeliveryDelay, and is given up on after
+	// maxDeliver attempts
+	ackWait            = 30 * time.Second
+	maxDeliver         = 20
+	maxRedeliveryDelay = time.Minute
+)
+
+// NATSBus carries events on NATS JetStream. Publishes return once the stream
+// has stored the message, and each queue is a durable consumer that acks a
+// message only after its handler succeeds, so events published while a
+// subscriber is down are delivered when it comes back.
+type NATSBus struct {
+	conn *nats.Conn
+	js   jetstream.JetStream
+
+	mu       sync.Mutex
+	consumes []jetstream.ConsumeContext
+}
+
+// DialNATS connects to the server at url, reconnecting for as long as the
+// process runs, and creates or updates the event stream
+func DialNATS(url, name string) (*NATSBus, error) {
+	conn, err := nats.Connect(url,
+		nats.Name(name),
+		nats.MaxReconnects(-1),
+		nats.DisconnectErrHandler(func(_ *nats.Conn, err error) {
+			if err != nil {
+				log.Printf("⚠️ NATS connection lost: %v", err)
+			}
+		}),
+		nats.ReconnectHandler(func(conn *nats.Conn) {
+			log.Printf("✅ Reconnected to NATS at %s", conn.ConnectedUrlRedacted())
+		}),
+	)
+	if err != nil {
+		return nil, fmt.Errorf("failed to connect to NATS: %w", err)
+	}
+
+	js, err := jetstream.New(conn)
+	if err != nil {
+		conn.Close()
+		return nil, fmt.Errorf("failed to open JetStream: %w", err)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	_, err = js.CreateOrUpdateStream(ctx, jetstream.StreamConfig{
+		Name:     StreamName,
+		Subjects: streamSubjects,
+		Storage:  jetstream.FileStorage,
+		MaxAge:   streamMaxAge,
+	})
+	if err != nil {
+		conn.Close()
+		return nil, fmt.Errorf("failed to create stream %s (is JetStream enabled on the server?): %w", StreamName, err)
+	}
+
+	return &NATSBus{conn: conn, js: js}, nil
+}
+
+// Publish stores the message in the stream. The stream drops a repeat of the
+// same message ID within its duplicate window, so a publish retried after a
+// lost ack is not stored twice.
+func (b *NATSBus) Publish(ctx context.Context, msg Message) error {
+	var opts []jetstream.PublishOpt
+	if msg.ID != "" {
+		opts = append(opts, jetstream.WithMsgID(msg.ID))
+	}
+	if _, err := b.js.Publish(ctx, msg.Subject, msg.Data, opts...); err != nil {
+		return fmt.Errorf("failed to publish to %s: %w", msg.Subject, err)
+	}
+	return nil
+}
+
+// Subscribe consumes the messages on subject. Subscribers sharing a queue
+// share one durable consumer, which starts from the oldest message in the
+// stream the first time it is created. Without a queue the subscription
+// only sees messages published after it and nothing is redelivered.
+func (b *NATSBus) Subscribe(subject, queue string, handler Handler) (Subscription, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	var consumer jetstream.Consumer
+	var err error
+	if queue == "" {
+		consumer, err = b.js.OrderedConsumer(ctx, StreamName, jetstream.OrderedConsumerConfig{
+			FilterSubjects: []string{subject},
+			DeliverPolicy:  jetstream.DeliverNewPolicy,
+		})
+	} else {
+		consumer, err = b.js.CreateOrUpdateConsumer(ctx, StreamName, jetstream.ConsumerConfig{
+			Durable:       durableName(queue, subject),
+			FilterSubject: subject,
+			DeliverPolicy: jetstream.DeliverAllPolicy,
+			AckPolicy:     jetstream.AckExplicitPolicy,
+			AckWait:       ackWait,
+			MaxDeliver:    maxDeliver,
+		})
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to create consumer for %s: %w", subject, err)
+	}
+
+	consume, err := consumer.Consume(func(msg jetstream.Msg) {
+		b.handle(msg, queue != "", handler)
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to consume %s: %w", subject, err)
+	}
+
+	b.mu.Lock()
+	b.consumes = append(b.consumes, consume)
+	b.mu.Unlock()
+	return natsSubscription{consume}, nil
+}
+
+// handle runs the handler and acks the message, or asks for it again later
+// when the handler fails
+func (b *NATSBus) handle(msg jetstream.Msg, acked bool, handler Handler) {
+	err := handler(context.Background(), Message{
+		ID:      msg.Headers().Get(jetstream.MsgIDHeader),
+		Subject: msg.Subject(),
+		Data:    msg.Data(),
+	})
+	if !acked {
+		if err != nil {
+			log.Printf("❌ Failed to handle %s: %v", msg.Subject(), err)
+		}
+		return
+	}
+	if err == nil {
+		if err := msg.Ack(); err != nil {
+			log.Printf("⚠️ Failed to ack %s: %v", msg.Subject(), err)
+		}
+		return
+	}
+
+	attempt := uint64(1)
+	if meta, metaErr := msg.Metadata(); metaErr == nil {
+		attempt = meta.NumDelivered
+	}
+	if attempt >= maxDeliver {
+		log.Printf("❌ Giving up on %s after %d attempts: %v", msg.Subject(), attempt, err)
+		msg.Term()
+		return
+	}
+	log.Printf("❌ Failed to handle %s (attempt %d): %v", msg.Subject(), attempt, err)
+	msg.NakWithDelay(redeliveryDelay(attempt))
+}
+
+// Close stops consuming and drains the connection, letting handlers that are
+// running finish
+func (b *NATSBus) Close() error {
+	b.mu.Lock()
+	for _, consume := range b.consumes {
+		consume.Stop()
+	}
+	b.consumes = nil
+	b.mu.Unlock()
+	return b.conn.Drain()
+}
+
+type natsSubscription struct {
+	consume jetstream.ConsumeContext
+}
+
+func (s natsSubscription) Unsubscribe() error {
+	s.consume.Stop()
+	return nil
+}
+
+// redeliveryDelay backs off linearly, a few seconds per failed attempt
+func redeliveryDelay(attempt uint64) time.Duration {
+	delay := time.Duration(attempt) * 5 * time.Second
+	if delay > maxRedeliveryDelay {
+		return maxRedeliveryDelay
+	}
+	return delay
+}
+
+// durableName derives the consumer name of a queue's subscription to subject.
+// Consumer names cannot contain the subject separators and wildcards.
+func durableName(queue, subject string) string {
+	name := strings.NewReplacer(".", "_", "*", "any", ">", "all").Replace(subject)
+	return queue + "_" + name
+}
@@ -0,0 +1,133 @@
+package handlers
+
+import (
+	"crypto/subtle"
+	"encoding/json"
+	"net/http"
+	"notification-service/internal/models"
+	"os"
+	"strconv"
+
+	"github.com/gorilla/mux"
+)
+
+// AdminMiddleware guards operator endpoints with the X-Admin-Token header.
+// The endpoints stay disabled when ADMIN_TOKEN is not configured.
+func AdminMiddleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		token := os.Getenv("ADMIN_TOKEN")
+		if token == "" {
+			http.Error(w, `{"error": "Admin endpoints are disabled"}`, http.StatusForbidden)
+			return
+		}
+		if subtle.ConstantTimeCompare([]byte(r.Header.Get("X-Admin-Token")), []byte(token)) != 1 {
+			http.Error(w, `{"error": "Invalid admin token"}`, http.StatusUnauthorized)
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+// trustedService reports whether the request comes from another service
+// holding SERVICE_TOKEN, sent as X-Service-Token, rather than from a user
+// through the gateway. No request is trusted when SERVICE_TOKEN is not
+// configured.
+func trustedService(r *http.Request) bool {
+	token := os.Getenv("SERVICE_TOKEN")
+	if token == "" {
+		return false
+	}
+	return subtle.ConstantTimeCompare([]byte(r.Header.Get("X-Service-Token")), []byte(token)) == 1
+}
+
+func (h *NotificationHandler) GetDeadLetterNotifications(w http.ResponseWriter, r *http.Request) {
+	limit := 50
+	offset := 0
+
+	if l, err := strconv.Atoi(r.URL.Query().Get("limit")); err == nil && l > 0 {
+		limit = l
+	}
+	if limit > 200 {
+		limit = 200
+	}
+	if o, err := strconv.Atoi(r.URL.Query().Get("offset")); err == nil && o >= 0 {
+		offset = o
+	}
+
+	notifications, total, err := h.db.GetDeadLetterNotifications(limit, offset)
+	if err != nil {
+		http.Error(w, `{"error": "`+err.Error()+`"}`, http.StatusInternalServerError)
+		return
+	}
+
+	response := models.DeadLetterResponse{
+		Notifications: notifications,
+		Total:         total,
+		Limit:         limit,
+		Offset:        offset,
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(response); err != nil {
+		http.Error(w, `{"error": "Error encoding response"}`, http.StatusInternalServerError)
+	}
+}
+
+func (h *NotificationHandler) RequeueNotification(w http.ResponseWriter, r *http.Request) {
+	notificationID := mux.Vars(r)["id"]
+	if !uuidPattern.MatchString(notificationID) {
+		http.Error(w, `{"error": "Invalid notification id"}`, http.StatusBadRequest)
+		return
+	}
+
+	requeued, err := h.db.RequeueDeadLetters([]string{notificationID})
+	if err != nil {
+		http.Error(w, `{"error": "`+err.Error()+`"}`, http.StatusInternalServerError)
+		return
+	}
+	if requeued == 0 {
+		http.Error(w, `{"error": "dead-lettered notification not found"}`, http.StatusNotFound)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(models.RequeueResponse{Requeued: requeued}); err != nil {
+		http.Error(w, `{"error": "Error encoding response"}`, http.StatusInternalServerError)
+	}
+}
+
+// RequeueDeadLetters requeues the listed notifications, or every
+// dead-lettered notification when the body says "all": true
+func (h *NotificationHandler) RequeueDeadLetters(w http.ResponseWriter, r *http.Request) {
+	var req models.RequeueRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, `{"error": "Invalid request body"}`, http.StatusBadRequest)
+		return
+	}
+
+	switch {
+	case req.All && len(req.IDs) > 0:
+		http.Error(w, `{"error": "Pass either ids or all, not both"}`, http.StatusBadRequest)
+		return
+	case !req.All && len(req.IDs) == 0:
+		http.Error(w, `{"error": "ids is required; pass \"all\": true to requeue every dead-lettered notification"}`, http.StatusBadRequest)
+		return
+	}
+	for _, id := range req.IDs {
+		if !uuidPattern.MatchString(id) {
+			http.Error(w, `{"error": "Invalid notification id: `+jsonEscape(id)+`"}`, http.StatusBadRequest)
+			return
+		}
+	}
+
+	requeued, err := h.db.RequeueDeadLetters(req.IDs)
+	if err != nil {
+		http.Error(w, `{"error": "`+err.Error()+`"}`, http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(models.RequeueResponse{Requeued: requeued}); err != nil {
+		http.Error(w, `{"error": "Error encoding response"}`, http.StatusInternalServerError)
+	}
+}
@@ -0,0 +1,194 @@
+package handlers
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"notification-service/internal/models"
+	"strings"
+	"time"
+
+	"github.com/gorilla/mux"
+)
+
+// maxBulkIDs bounds the ID list of a bulk request
+const maxBulkIDs = 500
+
+// MarkAllAsRead serves PUT /users/{user_id}/notifications/read-all
+func (h *NotificationHandler) MarkAllAsRead(w http.ResponseWriter, r *http.Request) {
+	userID := mux.Vars(r)["user_id"]
+	if !uuidPattern.MatchString(userID) {
+		http.Error(w, `{"error": "Invalid user_id"}`, http.StatusBadRequest)
+		return
+	}
+	if actingForOther(r, userID) {
+		http.Error(w, `{"error": "Cannot update another user's notifications"}`, http.StatusForbidden)
+		return
+	}
+
+	affected, err := h.db.MarkNotificationsAsRead(userID, nil)
+	if err != nil {
+		http.Error(w, `{"error": "`+err.Error()+`"}`, http.StatusInternalServerError)
+		return
+	}
+
+	writeBulkResponse(w, affected)
+}
+
+// MarkManyAsRead serves PUT /users/{user_id}/notifications/read with an ID list
+func (h *NotificationHandler) MarkManyAsRead(w http.ResponseWriter, r *http.Request) {
+	userID, ids, ok := decodeBulkRequest(w, r)
+	if !ok {
+		return
+	}
+
+	affected, err := h.db.MarkNotificationsAsRead(userID, ids)
+	if err != nil {
+		http.Error(w, `{"error": "`+err.Error()+`"}`, http.StatusInternalServerError)
+		return
+	}
+
+	writeBulkResponse(w, affected)
+}
+
+// DeleteMany serves POST /users/{user_id}/notifications/delete with an ID list
+func (h *NotificationHandler) DeleteMany(w http.ResponseWriter, r *http.Request) {
+	userID, ids, ok := decodeBulkRequest(w, r)
+	if !ok {
+		return
+	}
+
+	affected, err := h.db.DeleteNotifications(userID, ids)
+	if err != nil {
+		http.Error(w, `{"error": "`+err.Error()+`"}`, http.StatusInternalServerError)
+		return
+	}
+
+	writeBulkResponse(w, affected)
+}
+
+// decodeBulkRequest validates the user and ID list of a bulk request. IDs
+// belonging to other users are ignored by the queries, not rejected.
+func decodeBulkRequest(w http.ResponseWriter, r *http.Request) (string, []string, bool) {
+	userID := mux.Vars(r)["user_id"]
+	if !uuidPattern.MatchString(userID) {
+		http.Error(w, `{"error": "Invalid user_id"}`, http.StatusBadRequest)
+		return "", nil, false
+	}
+	if actingForOther(r, userID) {
+		http.Error(w, `{"error": "Cannot update another user's notifications"}`, http.StatusForbidden)
+		return "", nil, false
+	}
+
+	var req models.BulkNotificationsRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, `{"error": "Invalid request body"}`, http.StatusBadRequest)
+		return "", nil, false
+	}
+	if len(req.IDs) == 0 || len(req.IDs) > maxBulkIDs {
+		http.Error(w, fmt.Sprintf(`{"error": "ids must list between 1 and %d notifications"}`, maxBulkIDs), http.StatusBadRequest)
+		return "", nil, false
+	}
+	for _, id := range req.IDs {
+		if !uuidPattern.MatchString(id) {
+			http.Error(w, `{"error": "Invalid notification id `+jsonEscape(id)+`"}`, http.StatusBadRequest)
+			return "", nil, false
+		}
+	}
+
+	return userID, req.IDs, true
+}
+
+func writeBulkResponse(w http.ResponseWriter, affected int64) {
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(models.BulkNotificationsResponse{Affected: affected}); err != nil {
+		http.Error(w, `{"error": "Error encoding response"}`, http.StatusInternalServerError)
+	}
+}
+
+func parseNotificationFilter(query url.Values) (models.NotificationFilter, error) {
+	var filter models.NotificationFilter
+
+	for _, value := range splitList(query.Get("status")) {
+		status := models.NotificationStatus(value)
+		switch status {
+		case models.StatusPending, models.StatusSending, models.StatusSent, models.StatusFailed, models.StatusRead,
+			models.StatusDeadLetter, models.StatusSuppressed, models.StatusExpired:
+		default:
+			return filter, fmt.Errorf("invalid status %q", value)
+		}
+		filter.Statuses = append(filter.Statuses, status)
+	}
+
+	for _, value := range splitList(query.Get("type")) {
+		t := models.NotificationType(value)
+		if !t.IsValid() {
+			return filter, fmt.Errorf("invalid type %q", value)
+		}
+		filter.Types = append(filter.Types, t)
+	}
+
+	var err error
+	if filter.From, err = parseFilterTime(query.Get("from"), false); err != nil {
+		return filter, fmt.Errorf("invalid from: %w", err)
+	}
+	if filter.To, err = parseFilterTime(query.Get("to"), true); err != nil {
+		return filter, fmt.Errorf("invalid to: %w", err)
+	}
+
+	filter.UnreadOnly = query.Get("unread") == "true"
+	return filter, nil
+}
+
+// parseFilterTime accepts RFC 3339 or a plain date, which as an upper bound
+// covers the whole day
+func parseFilterTime(value string, endOfDay bool) (*time.Time, error) {
+	if value == "" {
+		return nil, nil
+	}
+	if t, err := time.Parse(time.RFC3339, value); err == nil {
+		t = t.UTC()
+		return &t, nil
+	}
+	t, err := time.Parse("2006-01-02", value)
+	if err != nil {
+		return nil, fmt.Errorf("expected RFC 3339 or YYYY-MM-DD")
+	}
+	if endOfDay {
+		t = t.Add(24*time.Hour - time.Microsecond)
+	}
+	return &t, nil
+}
+
+func splitList(value string) []string {
+	var items []string
+	for _, item := range strings.Split(value, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			items = append(items, item)
+		}
+	}
+	return items
+}
+
+func encodeCursor(cursor models.NotificationCursor) string {
+	raw := cursor.CreatedAt.UTC().Format(time.RFC3339Nano) + "|" + cursor.ID
+	return base64.RawURLEncoding.EncodeToString([]byte(raw))
+}
+
+func decodeCursor(value string) (*models.NotificationCursor, error) {
+	raw, err := base64.RawURLEncoding.DecodeString(value)
+	if err != nil {
+		return nil, err
+	}
+	parts := strings.SplitN(string(raw), "|", 2)
+	if len(parts) != 2 || !uuidPattern.MatchString(parts[1]) {
+		return nil, fmt.Errorf("malformed cursor")
+	}
+	createdAt, err := time.Parse(time.RFC3339Nano, parts[0])
+	if err != nil {
+		return nil, err
+	}
+	return &models.NotificationCursor{CreatedAt: createdAt, ID: parts[1]}, nil
+}
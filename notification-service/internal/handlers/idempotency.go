@@ -0,0 +1,88 @@
+package handlers
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"notification-service/internal/database"
+	"notification-service/internal/models"
+	"os"
+	"time"
+)
+
+const maxIdempotencyKeyLength = 255
+
+// idempotencyTTL is how long a key is remembered, IDEMPOTENCY_TTL or 24h
+func idempotencyTTL() time.Duration {
+	if value, err := time.ParseDuration(os.Getenv("IDEMPOTENCY_TTL")); err == nil && value > 0 {
+		return value
+	}
+	return 24 * time.Hour
+}
+
+// idempotencyKey prefers the Idempotency-Key header over the body's dedupe_key
+func idempotencyKey(r *http.Request, dedupeKey string) string {
+	if key := r.Header.Get("Idempotency-Key"); key != "" {
+		return key
+	}
+	return dedupeKey
+}
+
+// createNotification stores the notification and writes the 201 response.
+// With an idempotency key a repeated request gets the original response and
+// a key reused for a different request gets 409. scope keeps keys of
+// different endpoints apart; req is the decoded request being fingerprinted.
+func (h *NotificationHandler) createNotification(w http.ResponseWriter, r *http.Request, scope, key string, req interface{}, notification *models.Notification) {
+	if key == "" {
+		if err := h.db.CreateNotification(notification); err != nil {
+			http.Error(w, `{"error": "`+err.Error()+`"}`, http.StatusInternalServerError)
+			return
+		}
+		writeCreated(w, notification)
+		return
+	}
+
+	if len(key) > maxIdempotencyKeyLength {
+		http.Error(w, `{"error": "Idempotency key must be at most 255 characters"}`, http.StatusBadRequest)
+		return
+	}
+
+	body, err := json.Marshal(req)
+	if err != nil {
+		http.Error(w, `{"error": "Invalid request body"}`, http.StatusBadRequest)
+		return
+	}
+	hash := sha256.Sum256(body)
+
+	created, replayed, err := h.db.CreateNotificationOnce(scope, key, hex.EncodeToString(hash[:]), idempotencyTTL(), notification)
+	if errors.Is(err, database.ErrIdempotencyConflict) {
+		http.Error(w, `{"error": "`+err.Error()+`"}`, http.StatusConflict)
+		return
+	}
+	if err != nil {
+		http.Error(w, `{"error": "`+err.Error()+`"}`, http.StatusInternalServerError)
+		return
+	}
+
+	if replayed {
+		w.Header().Set("Idempotent-Replayed", "true")
+	}
+	writeCreated(w, created)
+}
+
+// writeCreated responds 201 for a new notification and 200 when it was merged
+// into an existing group
+func writeCreated(w http.ResponseWriter, notification *models.Notification) {
+	status := http.StatusCreated
+	if notification.GroupCount > 1 {
+		status = http.StatusOK
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	if err := json.NewEncoder(w).Encode(models.NotificationResponse{Notification: notification}); err != nil {
+		http.Error(w, `{"error": "Error encoding response"}`, http.StatusInternalServerError)
+	}
+}
@@ -3,6 +3,7 @@ package handlers
 import (
 	"encoding/json"
 	"net/http"
+	"notification-service/internal/channels"
 	"notification-service/internal/database"
 	"notification-service/internal/models"
 	"strconv"
@@ -11,11 +12,13 @@ import (
 )
 
 type NotificationHandler struct {
-	db *database.DB
+	db       *database.DB
+	outbound channels.OutboundPolicy
 }
 
-func NewNotificationHandler(db *database.DB) *NotificationHandler {
-	return &NotificationHandler{db: db}
+// NewNotificationHandler accepts only webhook URLs outbound permits
+func NewNotificationHandler(db *database.DB, outbound channels.OutboundPolicy) *NotificationHandler {
+	return &NotificationHandler{db: db, outbound: outbound}
 }
 
 func (h *NotificationHandler) CreateNotification(w http.ResponseWriter, r *http.Request) {
@@ -30,10 +33,24 @@ func (h *NotificationHandler) CreateNotification(w http.ResponseWriter, r *http.
 		http.Error(w, `{"error": "user_id, title, and message are required"}`, http.StatusBadRequest)
 		return
 	}
+	if actingForOther(r, req.UserID) {
+		http.Error(w, `{"error": "Cannot create notifications for another user"}`, http.StatusForbidden)
+		return
+	}
 
 	// Validate notification type
-	if req.Type != models.EmailNotification && req.Type != models.InAppNotification && req.Type != models.PushNotification {
-		http.Error(w, `{"error": "Invalid notification type. Must be email, in_app, or push"}`, http.StatusBadRequest)
+	if !req.Type.IsValid() {
+		http.Error(w, `{"error": "Invalid notification type. Must be email, in_app, push, or webhook"}`, http.StatusBadRequest)
+		return
+	}
+
+	if err := validateSchedule(req.SendAt, req.ExpiresAt); err != nil {
+		http.Error(w, `{"error": "`+err.Error()+`"}`, http.StatusBadRequest)
+		return
+	}
+
+	if err := validateGroup(req.GroupKey, req.Actors); err != nil {
+		http.Error(w, `{"error": "`+err.Error()+`"}`, http.StatusBadRequest)
 		return
 	}
 
@@ -41,6 +58,15 @@ func (h *NotificationHandler) CreateNotification(w http.ResponseWriter, r *http.
 	if req.Data == nil {
 		req.Data = make(map[string]interface{})
 	}
+	// Only a trusted service may choose where an email is sent; anyone else's
+	// email goes to the address on the user's account
+	if !trustedService(r) {
+		delete(req.Data, "email")
+	}
+	if req.Transactional && !trustedService(r) {
+		http.Error(w, `{"error": "Only trusted services can send transactional notifications"}`, http.StatusForbidden)
+		return
+	}
 
 	notification := &models.Notification{
 		UserID:  req.UserID,
@@ -49,18 +75,16 @@ func (h *NotificationHandler) CreateNotification(w http.ResponseWriter, r *http.
 		Type:    req.Type,
 		Status:  models.StatusPending,
 		Data:    req.Data, // This will now be {} instead of nil
-	}
 
-	if err := h.db.CreateNotification(notification); err != nil {
-		http.Error(w, `{"error": "`+err.Error()+`"}`, http.StatusInternalServerError)
-		return
-	}
+		SendAt:    utc(req.SendAt),
+		ExpiresAt: utc(req.ExpiresAt),
+		GroupKey:  req.GroupKey,
+		Actors:    req.Actors,
 
-	w.Header().Set("Content-Type", "application/json")
-	w.WriteHeader(http.StatusCreated)
-	if err := json.NewEncoder(w).Encode(models.NotificationResponse{Notification: notification}); err != nil {
-		http.Error(w, `{"error": "Error encoding response"}`, http.StatusInternalServerError)
+		Transactional: req.Transactional,
 	}
+
+	h.createNotification(w, r, "create", idempotencyKey(r, req.DedupeKey), req, notification)
 }
 
 func (h *NotificationHandler) GetNotification(w http.ResponseWriter, r *http.Request) {
@@ -79,13 +103,24 @@ func (h *NotificationHandler) GetNotification(w http.ResponseWriter, r *http.Req
 	}
 }
 
+// GetUserNotifications lists a user's notifications, newest first. Filters:
+// status and type (comma separated), from and to (RFC 3339 or YYYY-MM-DD on
+// created_at) and unread=true. Pass next_cursor back as cursor for the next
+// page; limit/offset paging is still accepted without a cursor.
 func (h *NotificationHandler) GetUserNotifications(w http.ResponseWriter, r *http.Request) {
 	vars := mux.Vars(r)
 	userID := vars["user_id"]
 
+	if actingForOther(r, userID) {
+		http.Error(w, `{"error": "Cannot list another user's notifications"}`, http.StatusForbidden)
+		return
+	}
+
+	query := r.URL.Query()
+
 	// Get pagination parameters
-	limitStr := r.URL.Query().Get("limit")
-	offsetStr := r.URL.Query().Get("offset")
+	limitStr := query.Get("limit")
+	offsetStr := query.Get("offset")
 
 	limit := 50 // default limit
 	offset := 0 // default offset
@@ -95,6 +130,9 @@ func (h *NotificationHandler) GetUserNotifications(w http.ResponseWriter, r *htt
 			limit = l
 		}
 	}
+	if limit > 200 {
+		limit = 200
+	}
 
 	if offsetStr != "" {
 		if o, err := strconv.Atoi(offsetStr); err == nil && o >= 0 {
@@ -102,7 +140,21 @@ func (h *NotificationHandler) GetUserNotifications(w http.ResponseWriter, r *htt
 		}
 	}
 
-	notifications, err := h.db.GetNotificationsByUserID(userID, limit, offset)
+	filter, err := parseNotificationFilter(query)
+	if err != nil {
+		http.Error(w, `{"error": "`+jsonEscape(err.Error())+`"}`, http.StatusBadRequest)
+		return
+	}
+
+	var cursor *models.NotificationCursor
+	if value := query.Get("cursor"); value != "" {
+		if cursor, err = decodeCursor(value); err != nil {
+			http.Error(w, `{"error": "Invalid cursor"}`, http.StatusBadRequest)
+			return
+		}
+	}
+
+	notifications, total, unread, err := h.db.GetNotificationsByUserID(userID, filter, cursor, limit, offset)
 	if err != nil {
 		http.Error(w, `{"error": "`+err.Error()+`"}`, http.StatusInternalServerError)
 		return
@@ -110,7 +162,12 @@ func (h *NotificationHandler) GetUserNotifications(w http.ResponseWriter, r *htt
 
 	response := models.NotificationsResponse{
 		Notifications: notifications,
-		Total:         len(notifications),
+		Total:         total,
+		UnreadCount:   unread,
+	}
+	if len(notifications) == limit {
+		last := notifications[len(notifications)-1]
+		response.NextCursor = encodeCursor(models.NotificationCursor{CreatedAt: last.CreatedAt, ID: last.ID})
 	}
 
 	w.Header().Set("Content-Type", "application/json")
@@ -0,0 +1,131 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+	"notification-service/internal/models"
+	"notification-service/internal/templates"
+	"regexp"
+	"time"
+
+	"github.com/gorilla/mux"
+)
+
+// localePattern accepts normalized language tags such as "es" or "pt-BR"
+var localePattern = regexp.MustCompile(`^[a-z]{2,3}(-[A-Za-z0-9]{2,8}){0,2}$`)
+
+// actingForOther reports whether the authenticated user the gateway forwards
+// in X-User-ID differs from userID. Direct service calls carry no header.
+func actingForOther(r *http.Request, userID string) bool {
+	actor := r.Header.Get("X-User-ID")
+	return actor != "" && actor != userID
+}
+
+func (h *NotificationHandler) GetPreferences(w http.ResponseWriter, r *http.Request) {
+	userID := mux.Vars(r)["user_id"]
+	if !uuidPattern.MatchString(userID) {
+		http.Error(w, `{"error": "Invalid user_id"}`, http.StatusBadRequest)
+		return
+	}
+	if actingForOther(r, userID) {
+		http.Error(w, `{"error": "Cannot read another user's preferences"}`, http.StatusForbidden)
+		return
+	}
+
+	prefs, err := h.db.GetPreferences(userID)
+	if err != nil {
+		http.Error(w, `{"error": "`+err.Error()+`"}`, http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(models.PreferencesResponse{Preferences: prefs}); err != nil {
+		http.Error(w, `{"error": "Error encoding response"}`, http.StatusInternalServerError)
+	}
+}
+
+// UpdatePreferences replaces the user's preferences with the request body
+func (h *NotificationHandler) UpdatePreferences(w http.ResponseWriter, r *http.Request) {
+	userID := mux.Vars(r)["user_id"]
+	if !uuidPattern.MatchString(userID) {
+		http.Error(w, `{"error": "Invalid user_id"}`, http.StatusBadRequest)
+		return
+	}
+	if actingForOther(r, userID) {
+		http.Error(w, `{"error": "Cannot change another user's preferences"}`, http.StatusForbidden)
+		return
+	}
+
+	var req models.UpdatePreferencesRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, `{"error": "Invalid request body"}`, http.StatusBadRequest)
+		return
+	}
+
+	prefs := models.DefaultPreferences(userID)
+	if req.Timezone != "" {
+		if _, err := time.LoadLocation(req.Timezone); err != nil {
+			http.Error(w, `{"error": "Invalid timezone"}`, http.StatusBadRequest)
+			return
+		}
+		prefs.Timezone = req.Timezone
+	}
+	if req.Locale != "" {
+		locale := templates.NormalizeLocale(req.Locale)
+		if !localePattern.MatchString(locale) {
+			http.Error(w, `{"error": "Invalid locale"}`, http.StatusBadRequest)
+			return
+		}
+		prefs.Locale = locale
+	}
+	if req.Digest != "" {
+		if !req.Digest.IsValid() {
+			http.Error(w, `{"error": "Invalid digest. Must be off, hourly, or daily"}`, http.StatusBadRequest)
+			return
+		}
+		prefs.Digest = req.Digest
+	}
+
+	for channel := range req.Channels {
+		if !channel.IsValid() {
+			http.Error(w, `{"error": "Invalid channel `+jsonEscape(string(channel))+`. Must be email, in_app, push, or webhook"}`, http.StatusBadRequest)
+			return
+		}
+	}
+	for _, channels := range req.Categories {
+		for channel := range channels {
+			if !channel.IsValid() {
+				http.Error(w, `{"error": "Invalid channel `+jsonEscape(string(channel))+`. Must be email, in_app, push, or webhook"}`, http.StatusBadRequest)
+				return
+			}
+		}
+	}
+	if req.Channels != nil {
+		prefs.Channels = req.Channels
+	}
+	if req.Categories != nil {
+		prefs.Categories = req.Categories
+	}
+
+	if req.QuietHours != nil {
+		if err := models.ValidateClock(req.QuietHours.Start); err != nil {
+			http.Error(w, `{"error": "Invalid quiet_hours.start: `+jsonEscape(err.Error())+`"}`, http.StatusBadRequest)
+			return
+		}
+		if err := models.ValidateClock(req.QuietHours.End); err != nil {
+			http.Error(w, `{"error": "Invalid quiet_hours.end: `+jsonEscape(err.Error())+`"}`, http.StatusBadRequest)
+			return
+		}
+		prefs.QuietHours = req.QuietHours
+	}
+
+	if err := h.db.SavePreferences(prefs); err != nil {
+		http.Error(w, `{"error": "`+err.Error()+`"}`, http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(models.PreferencesResponse{Preferences: prefs}); err != nil {
+		http.Error(w, `{"error": "Error encoding response"}`, http.StatusInternalServerError)
+	}
+}
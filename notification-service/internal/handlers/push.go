@@ -0,0 +1,131 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+	"notification-service/internal/channels"
+	"notification-service/internal/database"
+	"notification-service/internal/models"
+
+	"github.com/gorilla/mux"
+)
+
+// PushHandler manages browser push subscriptions and hands out the VAPID
+// public key clients need to create them
+type PushHandler struct {
+	db        *database.DB
+	publicKey string
+	outbound  channels.OutboundPolicy
+}
+
+// NewPushHandler accepts only subscription endpoints outbound permits
+func NewPushHandler(db *database.DB, publicKey string, outbound channels.OutboundPolicy) *PushHandler {
+	return &PushHandler{db: db, publicKey: publicKey, outbound: outbound}
+}
+
+// pushUser validates the user_id of a push subscription route. On failure
+// the error response has been written.
+func pushUser(w http.ResponseWriter, r *http.Request) (string, bool) {
+	userID := mux.Vars(r)["user_id"]
+	if !uuidPattern.MatchString(userID) {
+		http.Error(w, `{"error": "Invalid user_id"}`, http.StatusBadRequest)
+		return "", false
+	}
+	if actingForOther(r, userID) {
+		http.Error(w, `{"error": "Cannot manage another user's push subscriptions"}`, http.StatusForbidden)
+		return "", false
+	}
+	return userID, true
+}
+
+// GetPublicKey returns the applicationServerKey for PushManager.subscribe
+func (h *PushHandler) GetPublicKey(w http.ResponseWriter, r *http.Request) {
+	if h.publicKey == "" {
+		http.Error(w, `{"error": "Web push is not configured"}`, http.StatusServiceUnavailable)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(map[string]string{"public_key": h.publicKey}); err != nil {
+		http.Error(w, `{"error": "Error encoding response"}`, http.StatusInternalServerError)
+	}
+}
+
+// Subscribe registers the device a PushSubscription came from. Posting the
+// same endpoint again refreshes its keys.
+func (h *PushHandler) Subscribe(w http.ResponseWriter, r *http.Request) {
+	userID, ok := pushUser(w, r)
+	if !ok {
+		return
+	}
+
+	var req models.CreatePushSubscriptionRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, `{"error": "Invalid request body"}`, http.StatusBadRequest)
+		return
+	}
+	if err := h.outbound.CheckPushEndpoint(req.Endpoint); err != nil {
+		http.Error(w, `{"error": "endpoint `+err.Error()+`"}`, http.StatusBadRequest)
+		return
+	}
+	if _, _, err := channels.ParsePushKeys(req.Keys.P256dh, req.Keys.Auth); err != nil {
+		http.Error(w, `{"error": "`+err.Error()+`"}`, http.StatusBadRequest)
+		return
+	}
+
+	userAgent := req.UserAgent
+	if userAgent == "" {
+		userAgent = r.UserAgent()
+	}
+
+	subscription := &models.PushSubscription{
+		UserID:    userID,
+		Endpoint:  req.Endpoint,
+		P256dh:    req.Keys.P256dh,
+		Auth:      req.Keys.Auth,
+		UserAgent: truncate(userAgent, 512),
+	}
+	if err := h.db.SavePushSubscription(subscription); err != nil {
+		http.Error(w, `{"error": "`+err.Error()+`"}`, http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	if err := json.NewEncoder(w).Encode(models.PushSubscriptionResponse{Subscription: subscription}); err != nil {
+		http.Error(w, `{"error": "Error encoding response"}`, http.StatusInternalServerError)
+	}
+}
+
+func (h *PushHandler) GetSubscriptions(w http.ResponseWriter, r *http.Request) {
+	userID, ok := pushUser(w, r)
+	if !ok {
+		return
+	}
+
+	subscriptions, err := h.db.GetPushSubscriptions(userID)
+	if err != nil {
+		http.Error(w, `{"error": "`+err.Error()+`"}`, http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(models.PushSubscriptionsResponse{Subscriptions: subscriptions, Total: len(subscriptions)}); err != nil {
+		http.Error(w, `{"error": "Error encoding response"}`, http.StatusInternalServerError)
+	}
+}
+
+func (h *PushHandler) Unsubscribe(w http.ResponseWriter, r *http.Request) {
+	userID, ok := pushUser(w, r)
+	if !ok {
+		return
+	}
+
+	if err := h.db.DeletePushSubscription(userID, mux.Vars(r)["id"]); err != nil {
+		http.Error(w, `{"error": "`+err.Error()+`"}`, http.StatusNotFound)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusNoContent)
+}
@@ -0,0 +1,215 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"notification-service/internal/database"
+	"notification-service/internal/models"
+	"notification-service/internal/stream"
+	"regexp"
+	"strconv"
+	"time"
+
+	"github.com/gorilla/mux"
+	"github.com/gorilla/websocket"
+)
+
+// streamBatchSize bounds how many notifications are read per query while
+// catching a connection up
+const streamBatchSize = 100
+
+var uuidPattern = regexp.MustCompile(`^[0-9a-fA-F]{8}-[0-9a-fA-F]{4}-[0-9a-fA-F]{4}-[0-9a-fA-F]{4}-[0-9a-fA-F]{12}$`)
+
+// Origins are not checked here: the API gateway authenticates the connection
+// and rewrites Host, so a same-origin check would reject every browser.
+var upgrader = websocket.Upgrader{
+	ReadBufferSize:  1024,
+	WriteBufferSize: 1024,
+	CheckOrigin:     func(r *http.Request) bool { return true },
+}
+
+// StreamHandler pushes delivered in-app notifications to connected clients
+// over Server-Sent Events or WebSocket
+type StreamHandler struct {
+	db        *database.DB
+	hub       *stream.Hub
+	heartbeat time.Duration
+}
+
+func NewStreamHandler(db *database.DB, hub *stream.Hub, heartbeat time.Duration) *StreamHandler {
+	return &StreamHandler{db: db, hub: hub, heartbeat: heartbeat}
+}
+
+// StreamSSE serves GET /users/{user_id}/notifications/stream as an event
+// stream. Each event's id is the notification's stream_seq, so a reconnecting
+// EventSource resumes from its Last-Event-ID without gaps.
+func (h *StreamHandler) StreamSSE(w http.ResponseWriter, r *http.Request) {
+	userID, cursor, ok := h.open(w, r)
+	if !ok {
+		return
+	}
+
+	rc := http.NewResponseController(w)
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.Header().Set("Connection", "keep-alive")
+	w.Header().Set("X-Accel-Buffering", "no")
+	w.WriteHeader(http.StatusOK)
+
+	fmt.Fprint(w, "retry: 5000\n\n")
+	if err := rc.Flush(); err != nil {
+		log.Printf("❌ Streaming not supported for %s: %v", userID, err)
+		return
+	}
+
+	send := func(notification *models.Notification) error {
+		data, err := json.Marshal(notification)
+		if err != nil {
+			return err
+		}
+		fmt.Fprintf(w, "id: %d\nevent: notification\ndata: %s\n\n", *notification.StreamSeq, data)
+		return rc.Flush()
+	}
+	heartbeat := func() error {
+		fmt.Fprint(w, ": heartbeat\n\n")
+		return rc.Flush()
+	}
+
+	if err := h.follow(r.Context(), userID, cursor, send, heartbeat); err != nil {
+		log.Printf("❌ Notification stream for %s ended: %v", userID, err)
+	}
+}
+
+// StreamWebSocket serves GET /users/{user_id}/notifications/ws. Browsers
+// cannot set Last-Event-ID on a WebSocket, so resuming uses ?last_event_id=
+// with the id of the last StreamEvent received.
+func (h *StreamHandler) StreamWebSocket(w http.ResponseWriter, r *http.Request) {
+	userID, cursor, ok := h.open(w, r)
+	if !ok {
+		return
+	}
+
+	conn, err := upgrader.Upgrade(w, r, nil)
+	if err != nil {
+		// Upgrade has already replied with an error status
+		return
+	}
+	defer conn.Close()
+
+	ctx, cancel := context.WithCancel(r.Context())
+	defer cancel()
+
+	// Clients only send control frames; reading processes their pongs and
+	// notices a closed or silent connection
+	conn.SetReadLimit(512)
+	conn.SetReadDeadline(time.Now().Add(2 * h.heartbeat))
+	conn.SetPongHandler(func(string) error {
+		return conn.SetReadDeadline(time.Now().Add(2 * h.heartbeat))
+	})
+	go func() {
+		defer cancel()
+		for {
+			if _, _, err := conn.NextReader(); err != nil {
+				return
+			}
+		}
+	}()
+
+	send := func(notification *models.Notification) error {
+		conn.SetWriteDeadline(time.Now().Add(10 * time.Second))
+		return conn.WriteJSON(models.StreamEvent{
+			ID:           *notification.StreamSeq,
+			Type:         "notification",
+			Notification: notification,
+		})
+	}
+	heartbeat := func() error {
+		return conn.WriteControl(websocket.PingMessage, nil, time.Now().Add(10*time.Second))
+	}
+
+	if err := h.follow(ctx, userID, cursor, send, heartbeat); err != nil {
+		log.Printf("❌ Notification socket for %s ended: %v", userID, err)
+	}
+	conn.WriteControl(websocket.CloseMessage,
+		websocket.FormatCloseMessage(websocket.CloseNormalClosure, ""), time.Now().Add(time.Second))
+}
+
+// open validates the request and works out where the stream starts. On
+// failure the error response has been written.
+func (h *StreamHandler) open(w http.ResponseWriter, r *http.Request) (string, int64, bool) {
+	userID := mux.Vars(r)["user_id"]
+	if !uuidPattern.MatchString(userID) {
+		http.Error(w, `{"error": "Invalid user_id"}`, http.StatusBadRequest)
+		return "", 0, false
+	}
+
+	// A stream carries every notification the user receives, so it is never
+	// opened for someone else
+	if actingForOther(r, userID) {
+		http.Error(w, `{"error": "Cannot stream another user's notifications"}`, http.StatusForbidden)
+		return "", 0, false
+	}
+
+	lastEventID := r.Header.Get("Last-Event-ID")
+	if lastEventID == "" {
+		lastEventID = r.URL.Query().Get("last_event_id")
+	}
+	if lastEventID != "" {
+		cursor, err := strconv.ParseInt(lastEventID, 10, 64)
+		if err != nil || cursor < 0 {
+			http.Error(w, `{"error": "Invalid Last-Event-ID"}`, http.StatusBadRequest)
+			return "", 0, false
+		}
+		return userID, cursor, true
+	}
+
+	cursor, err := h.db.GetLatestStreamSeq(userID)
+	if err != nil {
+		http.Error(w, `{"error": "`+err.Error()+`"}`, http.StatusInternalServerError)
+		return "", 0, false
+	}
+	return userID, cursor, true
+}
+
+// follow sends everything after cursor, then waits for the hub to signal new
+// deliveries. Heartbeats keep proxies from closing an idle connection and
+// also trigger a catch-up read, covering any signal lost on the way.
+func (h *StreamHandler) follow(ctx context.Context, userID string, cursor int64,
+	send func(*models.Notification) error, heartbeat func() error) error {
+	wake, unsubscribe := h.hub.Subscribe(userID)
+	defer unsubscribe()
+
+	ticker := time.NewTicker(h.heartbeat)
+	defer ticker.Stop()
+
+	for {
+		for {
+			batch, err := h.db.GetStreamNotifications(userID, cursor, streamBatchSize)
+			if err != nil {
+				return err
+			}
+			for _, notification := range batch {
+				if err := send(notification); err != nil {
+					return err
+				}
+				cursor = *notification.StreamSeq
+			}
+			if len(batch) < streamBatchSize {
+				break
+			}
+		}
+
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-wake:
+		case <-ticker.C:
+			if err := heartbeat(); err != nil {
+				return err
+			}
+		}
+	}
+}
@@ -0,0 +1,225 @@
+package handlers
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"notification-service/internal/models"
+	"notification-service/internal/templates"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/gorilla/mux"
+)
+
+// CreateFromTemplate renders the named template in the best available locale
+// and stores the result as a new notification
+func (h *NotificationHandler) CreateFromTemplate(w http.ResponseWriter, r *http.Request) {
+	var req models.CreateFromTemplateRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, `{"error": "Invalid request body"}`, http.StatusBadRequest)
+		return
+	}
+
+	if req.UserID == "" || req.Template == "" {
+		http.Error(w, `{"error": "user_id and template are required"}`, http.StatusBadRequest)
+		return
+	}
+	if actingForOther(r, req.UserID) {
+		http.Error(w, `{"error": "Cannot create notifications for another user"}`, http.StatusForbidden)
+		return
+	}
+	if !req.Type.IsValid() {
+		http.Error(w, `{"error": "Invalid notification type. Must be email, in_app, push, or webhook"}`, http.StatusBadRequest)
+		return
+	}
+	if err := validateSchedule(req.SendAt, req.ExpiresAt); err != nil {
+		http.Error(w, `{"error": "`+err.Error()+`"}`, http.StatusBadRequest)
+		return
+	}
+	if err := validateGroup(req.GroupKey, req.Actors); err != nil {
+		http.Error(w, `{"error": "`+err.Error()+`"}`, http.StatusBadRequest)
+		return
+	}
+	if req.Data == nil {
+		req.Data = make(map[string]interface{})
+	}
+	// Only a trusted service may choose where an email is sent; anyone else's
+	// email goes to the address on the user's account
+	if !trustedService(r) {
+		delete(req.Data, "email")
+	}
+	if req.Transactional && !trustedService(r) {
+		http.Error(w, `{"error": "Only trusted services can send transactional notifications"}`, http.StatusForbidden)
+		return
+	}
+
+	template, err := h.db.GetTemplate(req.Template, templates.LocaleCandidates(req.Locale))
+	if err != nil {
+		status := http.StatusInternalServerError
+		if err.Error() == "template not found" {
+			status = http.StatusNotFound
+		}
+		http.Error(w, `{"error": "`+err.Error()+`"}`, status)
+		return
+	}
+
+	if missing := templates.MissingVariables(template, req.Data); len(missing) > 0 {
+		http.Error(w, `{"error": "Missing template variables: `+strings.Join(missing, ", ")+`"}`, http.StatusBadRequest)
+		return
+	}
+
+	rendered, err := templates.Render(template, req.Data)
+	if err != nil {
+		http.Error(w, `{"error": "`+jsonEscape(err.Error())+`"}`, http.StatusBadRequest)
+		return
+	}
+
+	// Remember the requested locale so an email held for the user's digest is
+	// summarised in the same language
+	if _, ok := req.Data["locale"]; !ok && req.Locale != "" {
+		req.Data["locale"] = templates.NormalizeLocale(req.Locale)
+	}
+
+	notification := &models.Notification{
+		UserID:      req.UserID,
+		Title:       truncate(rendered.Title, 255),
+		Message:     rendered.Message,
+		Type:        req.Type,
+		Status:      models.StatusPending,
+		Data:        req.Data,
+		Template:    template.Key,
+		HTMLMessage: rendered.HTML,
+		SendAt:      utc(req.SendAt),
+		ExpiresAt:   utc(req.ExpiresAt),
+		GroupKey:    req.GroupKey,
+		Actors:      req.Actors,
+
+		Transactional: req.Transactional,
+	}
+
+	h.createNotification(w, r, "from-template", idempotencyKey(r, req.DedupeKey), req, notification)
+}
+
+func (h *NotificationHandler) GetTemplates(w http.ResponseWriter, r *http.Request) {
+	list, err := h.db.GetTemplates(mux.Vars(r)["key"])
+	if err != nil {
+		http.Error(w, `{"error": "`+err.Error()+`"}`, http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(models.TemplatesResponse{Templates: list, Total: len(list)}); err != nil {
+		http.Error(w, `{"error": "Error encoding response"}`, http.StatusInternalServerError)
+	}
+}
+
+// PutTemplate creates or replaces one locale of a template
+func (h *NotificationHandler) PutTemplate(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+
+	var req models.UpsertTemplateRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, `{"error": "Invalid request body"}`, http.StatusBadRequest)
+		return
+	}
+	if strings.TrimSpace(req.TitleTemplate) == "" || strings.TrimSpace(req.BodyTemplate) == "" {
+		http.Error(w, `{"error": "title_template and body_template are required"}`, http.StatusBadRequest)
+		return
+	}
+
+	template := &models.NotificationTemplate{
+		Key:           vars["key"],
+		Locale:        templates.NormalizeLocale(vars["locale"]),
+		TitleTemplate: req.TitleTemplate,
+		BodyTemplate:  req.BodyTemplate,
+		HTMLTemplate:  req.HTMLTemplate,
+		Variables:     req.Variables,
+	}
+	if template.Variables == nil {
+		template.Variables = []string{}
+	}
+
+	if err := templates.Validate(template); err != nil {
+		http.Error(w, `{"error": "`+jsonEscape(err.Error())+`"}`, http.StatusBadRequest)
+		return
+	}
+
+	if err := h.db.UpsertTemplate(template); err != nil {
+		http.Error(w, `{"error": "`+err.Error()+`"}`, http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(models.TemplateResponse{Template: template}); err != nil {
+		http.Error(w, `{"error": "Error encoding response"}`, http.StatusInternalServerError)
+	}
+}
+
+func (h *NotificationHandler) DeleteTemplate(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+
+	if err := h.db.DeleteTemplate(vars["key"], templates.NormalizeLocale(vars["locale"])); err != nil {
+		http.Error(w, `{"error": "`+err.Error()+`"}`, http.StatusNotFound)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// validateSchedule rejects an expiry that is already past or comes before
+// the scheduled send time
+func validateSchedule(sendAt, expiresAt *time.Time) error {
+	if expiresAt == nil {
+		return nil
+	}
+	if !expiresAt.After(time.Now()) {
+		return fmt.Errorf("expires_at must be in the future")
+	}
+	if sendAt != nil && !expiresAt.After(*sendAt) {
+		return fmt.Errorf("expires_at must be after send_at")
+	}
+	return nil
+}
+
+// validateGroup bounds the group key and actors of a grouped notification
+func validateGroup(groupKey string, actors []string) error {
+	if len(groupKey) > 255 {
+		return fmt.Errorf("group_key must be at most 255 characters")
+	}
+	if len(actors) > 50 {
+		return fmt.Errorf("at most 50 actors are allowed")
+	}
+	for _, actor := range actors {
+		if actor == "" || len(actor) > 255 {
+			return fmt.Errorf("actors must be non-empty and at most 255 characters")
+		}
+	}
+	return nil
+}
+
+// utc normalises a request time for the TIMESTAMP columns, which drop offsets
+func utc(t *time.Time) *time.Time {
+	if t == nil {
+		return nil
+	}
+	u := t.UTC()
+	return &u
+}
+
+// jsonEscape makes an error message safe to embed in the hand-written JSON
+// error bodies used throughout the handlers
+func jsonEscape(s string) string {
+	b, _ := json.Marshal(s)
+	return string(b[1 : len(b)-1])
+}
+
+func truncate(s string, max int) string {
+	if utf8.RuneCountInString(s) <= max {
+		return s
+	}
+	runes := []rune(s)
+	return string(runes[:max-3]) + "..."
+}
@@ -0,0 +1,193 @@
+package handlers
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"net/http"
+	"notification-service/internal/models"
+	"strconv"
+
+	"github.com/gorilla/mux"
+)
+
+// webhookUser validates the user_id of a webhook route. On failure the error
+// response has been written.
+func webhookUser(w http.ResponseWriter, r *http.Request) (string, bool) {
+	userID := mux.Vars(r)["user_id"]
+	if !uuidPattern.MatchString(userID) {
+		http.Error(w, `{"error": "Invalid user_id"}`, http.StatusBadRequest)
+		return "", false
+	}
+	if actingForOther(r, userID) {
+		http.Error(w, `{"error": "Cannot manage another user's webhooks"}`, http.StatusForbidden)
+		return "", false
+	}
+	return userID, true
+}
+
+// CreateWebhook registers an endpoint. The signing secret is generated when
+// not supplied and is only ever returned here.
+func (h *NotificationHandler) CreateWebhook(w http.ResponseWriter, r *http.Request) {
+	userID, ok := webhookUser(w, r)
+	if !ok {
+		return
+	}
+
+	var req models.CreateWebhookRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, `{"error": "Invalid request body"}`, http.StatusBadRequest)
+		return
+	}
+	if err := h.outbound.CheckWebhookURL(req.URL); err != nil {
+		http.Error(w, `{"error": "url `+err.Error()+`"}`, http.StatusBadRequest)
+		return
+	}
+	if req.Secret != "" && (len(req.Secret) < 16 || len(req.Secret) > 128) {
+		http.Error(w, `{"error": "secret must be between 16 and 128 characters"}`, http.StatusBadRequest)
+		return
+	}
+
+	if req.Secret == "" {
+		b := make([]byte, 32)
+		if _, err := rand.Read(b); err != nil {
+			http.Error(w, `{"error": "Failed to generate secret"}`, http.StatusInternalServerError)
+			return
+		}
+		req.Secret = "whsec_" + hex.EncodeToString(b)
+	}
+	if req.Events == nil {
+		req.Events = []string{}
+	}
+
+	endpoint := &models.WebhookEndpoint{
+		UserID: userID,
+		URL:    req.URL,
+		Secret: req.Secret,
+		Events: req.Events,
+		Active: true,
+	}
+	if err := h.db.CreateWebhook(endpoint); err != nil {
+		http.Error(w, `{"error": "`+err.Error()+`"}`, http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	if err := json.NewEncoder(w).Encode(models.WebhookResponse{Webhook: endpoint}); err != nil {
+		http.Error(w, `{"error": "Error encoding response"}`, http.StatusInternalServerError)
+	}
+}
+
+func (h *NotificationHandler) GetWebhooks(w http.ResponseWriter, r *http.Request) {
+	userID, ok := webhookUser(w, r)
+	if !ok {
+		return
+	}
+
+	endpoints, err := h.db.GetWebhooks(userID)
+	if err != nil {
+		http.Error(w, `{"error": "`+err.Error()+`"}`, http.StatusInternalServerError)
+		return
+	}
+	for _, endpoint := range endpoints {
+		endpoint.Secret = ""
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(models.WebhooksResponse{Webhooks: endpoints, Total: len(endpoints)}); err != nil {
+		http.Error(w, `{"error": "Error encoding response"}`, http.StatusInternalServerError)
+	}
+}
+
+func (h *NotificationHandler) UpdateWebhook(w http.ResponseWriter, r *http.Request) {
+	userID, ok := webhookUser(w, r)
+	if !ok {
+		return
+	}
+
+	endpoint, err := h.db.GetWebhook(userID, mux.Vars(r)["id"])
+	if err != nil {
+		http.Error(w, `{"error": "`+err.Error()+`"}`, http.StatusNotFound)
+		return
+	}
+
+	var req models.UpdateWebhookRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, `{"error": "Invalid request body"}`, http.StatusBadRequest)
+		return
+	}
+	if req.URL != nil {
+		if err := h.outbound.CheckWebhookURL(*req.URL); err != nil {
+			http.Error(w, `{"error": "url `+err.Error()+`"}`, http.StatusBadRequest)
+			return
+		}
+		endpoint.URL = *req.URL
+	}
+	if req.Events != nil {
+		endpoint.Events = req.Events
+	}
+	if req.Active != nil {
+		endpoint.Active = *req.Active
+	}
+
+	if err := h.db.UpdateWebhook(endpoint); err != nil {
+		http.Error(w, `{"error": "`+err.Error()+`"}`, http.StatusInternalServerError)
+		return
+	}
+	endpoint.Secret = ""
+
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(models.WebhookResponse{Webhook: endpoint}); err != nil {
+		http.Error(w, `{"error": "Error encoding response"}`, http.StatusInternalServerError)
+	}
+}
+
+func (h *NotificationHandler) DeleteWebhook(w http.ResponseWriter, r *http.Request) {
+	userID, ok := webhookUser(w, r)
+	if !ok {
+		return
+	}
+
+	if err := h.db.DeleteWebhook(userID, mux.Vars(r)["id"]); err != nil {
+		http.Error(w, `{"error": "`+err.Error()+`"}`, http.StatusNotFound)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// GetWebhookDeliveries lists recent delivery attempts to one endpoint
+func (h *NotificationHandler) GetWebhookDeliveries(w http.ResponseWriter, r *http.Request) {
+	userID, ok := webhookUser(w, r)
+	if !ok {
+		return
+	}
+
+	endpoint, err := h.db.GetWebhook(userID, mux.Vars(r)["id"])
+	if err != nil {
+		http.Error(w, `{"error": "`+err.Error()+`"}`, http.StatusNotFound)
+		return
+	}
+
+	limit := 50
+	offset := 0
+	if l, err := strconv.Atoi(r.URL.Query().Get("limit")); err == nil && l > 0 && l <= 200 {
+		limit = l
+	}
+	if o, err := strconv.Atoi(r.URL.Query().Get("offset")); err == nil && o >= 0 {
+		offset = o
+	}
+
+	deliveries, err := h.db.GetWebhookDeliveries(endpoint.ID, limit, offset)
+	if err != nil {
+		http.Error(w, `{"error": "`+err.Error()+`"}`, http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(models.WebhookDeliveriesResponse{Deliveries: deliveries, Total: len(deliveries)}); err != nil {
+		http.Error(w, `{"error": "Error encoding response"}`, http.StatusInternalServerError)
+	}
+}
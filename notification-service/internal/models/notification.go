@@ -11,8 +11,18 @@ const (
 	EmailNotification NotificationType = "email"
 	InAppNotification NotificationType = "in_app"
 	PushNotification  NotificationType = "push"
+	// WebhookNotification is POSTed to the user's registered webhook endpoints
+	WebhookNotification NotificationType = "webhook"
 )
 
+func (t NotificationType) IsValid() bool {
+	switch t {
+	case EmailNotification, InAppNotification, PushNotification, WebhookNotification:
+		return true
+	}
+	return false
+}
+
 type NotificationStatus string
 
 const (
@@ -20,6 +30,15 @@ const (
 	StatusSent    NotificationStatus = "sent"
 	StatusFailed  NotificationStatus = "failed"
 	StatusRead    NotificationStatus = "read"
+	// StatusDeadLetter marks notifications that exhausted their delivery attempts
+	StatusDeadLetter NotificationStatus = "dead_letter"
+	// StatusSuppressed marks notifications the user opted out of
+	StatusSuppressed NotificationStatus = "suppressed"
+	// StatusExpired marks notifications that expired before delivery
+	StatusExpired NotificationStatus = "expired"
+	// StatusSending marks notifications a dispatcher has claimed and is
+	// delivering
+	StatusSending NotificationStatus = "sending"
 )
 
 type Notification struct {
@@ -33,6 +52,61 @@ type Notification struct {
 	CreatedAt time.Time              `json:"created_at"`
 	UpdatedAt time.Time              `json:"updated_at"`
 	ReadAt    *time.Time             `json:"read_at,omitempty"`
+	SentAt    *time.Time             `json:"sent_at,omitempty"`
+
+	Attempts      int        `json:"attempts"`
+	NextAttemptAt *time.Time `json:"next_attempt_at,omitempty"`
+	LastError     *string    `json:"last_error,omitempty"`
+	// LockedUntil is when a dispatcher's claim on a sending notification lapses
+	LockedUntil *time.Time `json:"locked_until,omitempty"`
+
+	// StreamSeq orders delivered in-app notifications for streaming clients
+	StreamSeq *int64 `json:"stream_seq,omitempty"`
+
+	// Template is the key the notification was rendered from, if any
+	Template    string  `json:"template,omitempty"`
+	HTMLMessage *string `json:"html_message,omitempty"`
+
+	// SendAt delays delivery; after ExpiresAt an undelivered notification is
+	// dropped and a delivered one no longer listed
+	SendAt    *time.Time `json:"send_at,omitempty"`
+	ExpiresAt *time.Time `json:"expires_at,omitempty"`
+
+	// GroupKey collapses notifications about the same thing: a new one with
+	// the key of an unread notification updates it instead of adding a row.
+	// GroupCount is how many notifications the item stands for and Actors the
+	// distinct users who caused them.
+	GroupKey   string   `json:"group_key,omitempty"`
+	GroupCount int      `json:"group_count"`
+	Actors     []string `json:"actors"`
+
+	// Transactional notifications, such as password resets and security
+	// alerts, bypass the user's preferences, digest and quiet hours
+	Transactional bool `json:"transactional,omitempty"`
+}
+
+// Category groups notifications for user preferences: data.category when the
+// sender sets one, otherwise the template the notification was rendered from
+func (n *Notification) Category() string {
+	if category, ok := n.Data["category"].(string); ok && category != "" {
+		return category
+	}
+	if n.Template != "" {
+		return n.Template
+	}
+	return "general"
+}
+
+// DeliveryOutcome is what the dispatcher decided after one delivery attempt.
+// Deferred outcomes were held back rather than tried, so they do not use up
+// an attempt. DigestAt hands the notification to the digest scheduler, which
+// sends it with the user's other held emails at that time.
+type DeliveryOutcome struct {
+	Status        NotificationStatus
+	NextAttemptAt *time.Time
+	Error         string
+	Deferred      bool
+	DigestAt      *time.Time
 }
 
 type CreateNotificationRequest struct {
@@ -41,15 +115,83 @@ type CreateNotificationRequest struct {
 	Message string                 `json:"message"`
 	Type    NotificationType       `json:"type"`
 	Data    map[string]interface{} `json:"data,omitempty"`
+
+	SendAt    *time.Time `json:"send_at,omitempty"`
+	ExpiresAt *time.Time `json:"expires_at,omitempty"`
+
+	GroupKey string   `json:"group_key,omitempty"`
+	Actors   []string `json:"actors,omitempty"`
+
+	// DedupeKey is an alternative to the Idempotency-Key header
+	DedupeKey string `json:"dedupe_key,omitempty"`
+
+	// Transactional marks mail the user must receive, such as password
+	// resets; only trusted services may set it
+	Transactional bool `json:"transactional,omitempty"`
 }
 
 type NotificationResponse struct {
 	Notification *Notification `json:"notification"`
 }
 
+// NotificationFilter narrows a user's notification list. Empty fields match
+// everything; From and To bound created_at.
+type NotificationFilter struct {
+	Statuses   []NotificationStatus
+	Types      []NotificationType
+	From       *time.Time
+	To         *time.Time
+	UnreadOnly bool
+}
+
+// NotificationCursor marks the last notification of a page; the next page
+// starts after it in created_at, id order
+type NotificationCursor struct {
+	CreatedAt time.Time
+	ID        string
+}
+
+// NotificationsResponse is one page of a user's notifications. Total and
+// UnreadCount cover every notification matching the filter, not just the page.
 type NotificationsResponse struct {
 	Notifications []*Notification `json:"notifications"`
 	Total         int             `json:"total"`
+	UnreadCount   int             `json:"unread_count"`
+	NextCursor    string          `json:"next_cursor,omitempty"`
+}
+
+type BulkNotificationsRequest struct {
+	IDs []string `json:"ids"`
+}
+
+type BulkNotificationsResponse struct {
+	Affected int64 `json:"affected"`
+}
+
+type DeadLetterResponse struct {
+	Notifications []*Notification `json:"notifications"`
+	Total         int             `json:"total"`
+	Limit         int             `json:"limit"`
+	Offset        int             `json:"offset"`
+}
+
+// RequeueRequest names the notifications to requeue; requeueing every
+// dead-lettered notification takes an explicit All
+type RequeueRequest struct {
+	IDs []string `json:"ids,omitempty"`
+	All bool     `json:"all,omitempty"`
+}
+
+type RequeueResponse struct {
+	Requeued int64 `json:"requeued"`
+}
+
+// StreamEvent is the WebSocket frame for a delivered in-app notification;
+// ID is the value a client passes back as last_event_id to resume
+type StreamEvent struct {
+	ID           int64         `json:"id"`
+	Type         string        `json:"type"`
+	Notification *Notification `json:"notification"`
 }
 
 type MarkAsReadRequest struct {
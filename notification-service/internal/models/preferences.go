@@ -0,0 +1,165 @@
+package models
+
+import (
+	"fmt"
+	"time"
+)
+
+type DigestMode string
+
+const (
+	DigestOff    DigestMode = "off"
+	DigestHourly DigestMode = "hourly"
+	DigestDaily  DigestMode = "daily"
+)
+
+func (m DigestMode) IsValid() bool {
+	switch m {
+	case DigestOff, DigestHourly, DigestDaily:
+		return true
+	}
+	return false
+}
+
+// QuietHours is a daily window, in the user's timezone, during which email
+// and push notifications are held back. End before Start spans midnight.
+type QuietHours struct {
+	Start string `json:"start"`
+	End   string `json:"end"`
+}
+
+// NotificationPreferences controls which notifications a user receives and
+// when. Channels opts in or out of whole channels; Categories overrides that
+// per category and channel. Anything not listed is enabled.
+type NotificationPreferences struct {
+	UserID     string                               `json:"user_id"`
+	Timezone   string                               `json:"timezone"`
+	Channels   map[NotificationType]bool            `json:"channels"`
+	Categories map[string]map[NotificationType]bool `json:"categories"`
+	QuietHours *QuietHours                          `json:"quiet_hours,omitempty"`
+	Digest     DigestMode                           `json:"digest"`
+	// Locale picks the template language of notifications rendered for the
+	// user by the service itself, such as digests
+	Locale    string     `json:"locale,omitempty"`
+	UpdatedAt *time.Time `json:"updated_at,omitempty"`
+}
+
+// DefaultPreferences applies to users who never saved any
+func DefaultPreferences(userID string) *NotificationPreferences {
+	return &NotificationPreferences{
+		UserID:     userID,
+		Timezone:   "UTC",
+		Channels:   map[NotificationType]bool{},
+		Categories: map[string]map[NotificationType]bool{},
+		Digest:     DigestOff,
+	}
+}
+
+type UpdatePreferencesRequest struct {
+	Timezone   string                               `json:"timezone"`
+	Channels   map[NotificationType]bool            `json:"channels"`
+	Categories map[string]map[NotificationType]bool `json:"categories"`
+	QuietHours *QuietHours                          `json:"quiet_hours"`
+	Digest     DigestMode                           `json:"digest"`
+	Locale     string                               `json:"locale"`
+}
+
+type PreferencesResponse struct {
+	Preferences *NotificationPreferences `json:"preferences"`
+}
+
+// Allows reports whether the user wants notifications of category over channel
+func (p *NotificationPreferences) Allows(category string, channel NotificationType) bool {
+	if enabled, ok := p.Categories[category][channel]; ok {
+		return enabled
+	}
+	if enabled, ok := p.Channels[channel]; ok {
+		return enabled
+	}
+	return true
+}
+
+// QuietUntil returns when the quiet hours containing now end, or nil when
+// now is outside quiet hours
+func (p *NotificationPreferences) QuietUntil(now time.Time) *time.Time {
+	if p.QuietHours == nil {
+		return nil
+	}
+	loc, err := time.LoadLocation(p.Timezone)
+	if err != nil {
+		loc = time.UTC
+	}
+	start, err := parseClock(p.QuietHours.Start)
+	if err != nil {
+		return nil
+	}
+	end, err := parseClock(p.QuietHours.End)
+	if err != nil || start == end {
+		return nil
+	}
+
+	local := now.In(loc)
+	at := func(days int, minutes int) time.Time {
+		return time.Date(local.Year(), local.Month(), local.Day()+days, minutes/60, minutes%60, 0, 0, loc)
+	}
+	minute := local.Hour()*60 + local.Minute()
+
+	var until time.Time
+	switch {
+	case start < end && minute >= start && minute < end:
+		until = at(0, end)
+	case start > end && minute >= start:
+		until = at(1, end)
+	case start > end && minute < end:
+		until = at(0, end)
+	default:
+		return nil
+	}
+	return &until
+}
+
+// digestHour is the local time of day daily digests go out
+const digestHour = 8
+
+// NextDigest returns when a notification arriving at now goes out in the
+// user's digest: the start of the next local hour, or the next 08:00 local
+// time. It is nil when digests are off.
+func (p *NotificationPreferences) NextDigest(now time.Time) *time.Time {
+	loc, err := time.LoadLocation(p.Timezone)
+	if err != nil {
+		loc = time.UTC
+	}
+	local := now.In(loc)
+
+	var next time.Time
+	switch p.Digest {
+	case DigestHourly:
+		// Back to the start of the local hour, which is not a whole UTC
+		// hour in zones such as Asia/Kolkata, then an hour on
+		sinceHour := time.Duration(local.Minute())*time.Minute +
+			time.Duration(local.Second())*time.Second + time.Duration(local.Nanosecond())
+		next = local.Add(time.Hour - sinceHour)
+	case DigestDaily:
+		next = time.Date(local.Year(), local.Month(), local.Day(), digestHour, 0, 0, 0, loc)
+		if !next.After(local) {
+			next = time.Date(local.Year(), local.Month(), local.Day()+1, digestHour, 0, 0, 0, loc)
+		}
+	default:
+		return nil
+	}
+	return &next
+}
+
+// ValidateClock checks an "HH:MM" time of day
+func ValidateClock(value string) error {
+	_, err := parseClock(value)
+	return err
+}
+
+func parseClock(value string) (int, error) {
+	t, err := time.Parse("15:04", value)
+	if err != nil {
+		return 0, fmt.Errorf("invalid time %q, expected HH:MM", value)
+	}
+	return t.Hour()*60 + t.Minute(), nil
+}
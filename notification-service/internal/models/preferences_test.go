@@ -0,0 +1,95 @@
+package models
+
+import (
+	"testing"
+	"time"
+)
+
+func utc(year int, month time.Month, day, hour, minute int) *time.Time {
+	t := time.Date(year, month, day, hour, minute, 0, 0, time.UTC)
+	return &t
+}
+
+func TestQuietUntil(t *testing.T) {
+	overnight := &QuietHours{Start: "22:00", End: "07:00"}
+
+	tests := []struct {
+		name     string
+		timezone string
+		quiet    *QuietHours
+		now      time.Time
+		want     *time.Time
+	}{
+		{name: "no quiet hours", timezone: "UTC", now: *utc(2026, time.October, 19, 23, 0)},
+		{name: "inside a daytime window", timezone: "UTC", quiet: &QuietHours{Start: "13:00", End: "14:00"}, now: *utc(2026, time.October, 19, 13, 30), want: utc(2026, time.October, 19, 14, 0)},
+		{name: "end of a daytime window", timezone: "UTC", quiet: &QuietHours{Start: "13:00", End: "14:00"}, now: *utc(2026, time.October, 19, 14, 0)},
+		{name: "overnight before midnight", timezone: "UTC", quiet: overnight, now: *utc(2026, time.October, 19, 23, 0), want: utc(2026, time.October, 20, 7, 0)},
+		{name: "overnight at the start", timezone: "UTC", quiet: overnight, now: *utc(2026, time.October, 19, 22, 0), want: utc(2026, time.October, 20, 7, 0)},
+		{name: "overnight after midnight", timezone: "UTC", quiet: overnight, now: *utc(2026, time.October, 20, 6, 59), want: utc(2026, time.October, 20, 7, 0)},
+		{name: "outside an overnight window", timezone: "UTC", quiet: overnight, now: *utc(2026, time.October, 20, 12, 0)},
+		{name: "start equals end", timezone: "UTC", quiet: &QuietHours{Start: "09:00", End: "09:00"}, now: *utc(2026, time.October, 19, 9, 0)},
+		{name: "invalid clock", timezone: "UTC", quiet: &QuietHours{Start: "25:00", End: "07:00"}, now: *utc(2026, time.October, 19, 23, 0)},
+		{name: "invalid timezone falls back to UTC", timezone: "Mars/Olympus_Mons", quiet: overnight, now: *utc(2026, time.October, 19, 23, 0), want: utc(2026, time.October, 20, 7, 0)},
+		// 23:00 EDT
+		{name: "user's timezone", timezone: "America/New_York", quiet: overnight, now: *utc(2026, time.October, 20, 3, 0), want: utc(2026, time.October, 20, 11, 0)},
+		// 23:00 EDT; the night the clocks go back ends at 07:00 EST
+		{name: "night the clocks go back", timezone: "America/New_York", quiet: overnight, now: *utc(2026, time.November, 1, 3, 0), want: utc(2026, time.November, 1, 12, 0)},
+		// 23:00 CET; the night the clocks go forward ends at 07:00 CEST
+		{name: "night the clocks go forward", timezone: "Europe/Berlin", quiet: overnight, now: *utc(2027, time.March, 27, 22, 0), want: utc(2027, time.March, 28, 5, 0)},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			prefs := DefaultPreferences("user-1")
+			prefs.Timezone = tt.timezone
+			prefs.QuietHours = tt.quiet
+
+			got := prefs.QuietUntil(tt.now)
+			if (got == nil) != (tt.want == nil) || (got != nil && !got.Equal(*tt.want)) {
+				t.Errorf("QuietUntil(%v) = %v, want %v", tt.now, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestNextDigest(t *testing.T) {
+	tests := []struct {
+		name     string
+		timezone string
+		digest   DigestMode
+		now      time.Time
+		want     *time.Time
+	}{
+		{name: "digest off", timezone: "UTC", digest: DigestOff, now: *utc(2026, time.October, 19, 10, 15)},
+		{name: "hourly", timezone: "UTC", digest: DigestHourly, now: *utc(2026, time.October, 19, 10, 15), want: utc(2026, time.October, 19, 11, 0)},
+		{name: "hourly on the hour", timezone: "UTC", digest: DigestHourly, now: *utc(2026, time.October, 19, 10, 0), want: utc(2026, time.October, 19, 11, 0)},
+		// 15:45 IST goes out at 16:00 IST, not 16:30
+		{name: "hourly with a half-hour offset", timezone: "Asia/Kolkata", digest: DigestHourly, now: *utc(2026, time.October, 19, 10, 15), want: utc(2026, time.October, 19, 10, 30)},
+		// 17:00 NPT goes out at 18:00 NPT
+		{name: "hourly with a 45-minute offset", timezone: "Asia/Kathmandu", digest: DigestHourly, now: *utc(2026, time.October, 19, 11, 15), want: utc(2026, time.October, 19, 12, 15)},
+		// 01:30 EDT goes out at the repeated 01:00, now EST
+		{name: "hourly when the clocks go back", timezone: "America/New_York", digest: DigestHourly, now: *utc(2026, time.November, 1, 5, 30), want: utc(2026, time.November, 1, 6, 0)},
+		// 01:30 CET goes out at 03:00 CEST, as 02:00 never happens
+		{name: "hourly when the clocks go forward", timezone: "Europe/Berlin", digest: DigestHourly, now: *utc(2027, time.March, 28, 0, 30), want: utc(2027, time.March, 28, 1, 0)},
+		{name: "daily before 08:00", timezone: "UTC", digest: DigestDaily, now: *utc(2026, time.October, 19, 7, 0), want: utc(2026, time.October, 19, 8, 0)},
+		{name: "daily at 08:00", timezone: "UTC", digest: DigestDaily, now: *utc(2026, time.October, 19, 8, 0), want: utc(2026, time.October, 20, 8, 0)},
+		{name: "daily after 08:00", timezone: "UTC", digest: DigestDaily, now: *utc(2026, time.October, 19, 9, 0), want: utc(2026, time.October, 20, 8, 0)},
+		{name: "daily in the user's timezone", timezone: "Asia/Kolkata", digest: DigestDaily, now: *utc(2026, time.October, 19, 3, 0), want: utc(2026, time.October, 20, 2, 30)},
+		{name: "daily when the clocks go back", timezone: "America/New_York", digest: DigestDaily, now: *utc(2026, time.October, 31, 12, 0), want: utc(2026, time.November, 1, 13, 0)},
+		{name: "daily when the clocks go forward", timezone: "Europe/Berlin", digest: DigestDaily, now: *utc(2027, time.March, 27, 8, 0), want: utc(2027, time.March, 28, 6, 0)},
+		{name: "invalid timezone falls back to UTC", timezone: "Mars/Olympus_Mons", digest: DigestDaily, now: *utc(2026, time.October, 19, 9, 0), want: utc(2026, time.October, 20, 8, 0)},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			prefs := DefaultPreferences("user-1")
+			prefs.Timezone = tt.timezone
+			prefs.Digest = tt.digest
+
+			got := prefs.NextDigest(tt.now)
+			if (got == nil) != (tt.want == nil) || (got != nil && !got.Equal(*tt.want)) {
+				t.Errorf("NextDigest(%v) = %v, want %v", tt.now, got, tt.want)
+			}
+		})
+	}
+}
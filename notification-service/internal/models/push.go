@@ -0,0 +1,51 @@
+package models
+
+import "time"
+
+// PushSubscription is a browser push endpoint registered by one of a user's
+// devices. P256dh and Auth are the base64url keys from the browser's
+// PushSubscription and are used to encrypt every payload sent to it.
+type PushSubscription struct {
+	ID         string     `json:"id"`
+	UserID     string     `json:"user_id"`
+	Endpoint   string     `json:"endpoint"`
+	P256dh     string     `json:"-"`
+	Auth       string     `json:"-"`
+	UserAgent  string     `json:"user_agent,omitempty"`
+	CreatedAt  time.Time  `json:"created_at"`
+	UpdatedAt  time.Time  `json:"updated_at"`
+	LastUsedAt *time.Time `json:"last_used_at,omitempty"`
+}
+
+// PushSubscriptionKeys matches the keys object of PushSubscription.toJSON()
+type PushSubscriptionKeys struct {
+	P256dh string `json:"p256dh"`
+	Auth   string `json:"auth"`
+}
+
+// CreatePushSubscriptionRequest accepts the JSON a browser produces for
+// PushSubscription.toJSON(), so clients can post it unchanged
+type CreatePushSubscriptionRequest struct {
+	Endpoint  string               `json:"endpoint"`
+	Keys      PushSubscriptionKeys `json:"keys"`
+	UserAgent string               `json:"user_agent,omitempty"`
+}
+
+type PushSubscriptionResponse struct {
+	Subscription *PushSubscription `json:"subscription"`
+}
+
+type PushSubscriptionsResponse struct {
+	Subscriptions []*PushSubscription `json:"subscriptions"`
+	Total         int                 `json:"total"`
+}
+
+// PushPayload is the JSON a service worker receives in its push event
+type PushPayload struct {
+	ID        string                 `json:"id"`
+	Title     string                 `json:"title"`
+	Body      string                 `json:"body"`
+	Category  string                 `json:"category"`
+	Data      map[string]interface{} `json:"data,omitempty"`
+	CreatedAt time.Time              `json:"created_at"`
+}
@@ -0,0 +1,56 @@
+package models
+
+import "time"
+
+// NotificationTemplate renders the title and message of one notification
+// kind in one locale. Title and body are Go text/templates, HTML is an
+// optional html/template used for email; all three see the request's data.
+type NotificationTemplate struct {
+	Key           string    `json:"key"`
+	Locale        string    `json:"locale"`
+	TitleTemplate string    `json:"title_template"`
+	BodyTemplate  string    `json:"body_template"`
+	HTMLTemplate  *string   `json:"html_template,omitempty"`
+	Variables     []string  `json:"variables"`
+	CreatedAt     time.Time `json:"created_at"`
+	UpdatedAt     time.Time `json:"updated_at"`
+}
+
+type UpsertTemplateRequest struct {
+	TitleTemplate string   `json:"title_template"`
+	BodyTemplate  string   `json:"body_template"`
+	HTMLTemplate  *string  `json:"html_template,omitempty"`
+	Variables     []string `json:"variables"`
+}
+
+type TemplateResponse struct {
+	Template *NotificationTemplate `json:"template"`
+}
+
+type TemplatesResponse struct {
+	Templates []*NotificationTemplate `json:"templates"`
+	Total     int                     `json:"total"`
+}
+
+// CreateFromTemplateRequest creates a notification by rendering a template.
+// Data supplies the template variables and is stored on the notification.
+type CreateFromTemplateRequest struct {
+	UserID   string                 `json:"user_id"`
+	Template string                 `json:"template"`
+	Locale   string                 `json:"locale,omitempty"`
+	Type     NotificationType       `json:"type"`
+	Data     map[string]interface{} `json:"data,omitempty"`
+
+	SendAt    *time.Time `json:"send_at,omitempty"`
+	ExpiresAt *time.Time `json:"expires_at,omitempty"`
+
+	GroupKey string   `json:"group_key,omitempty"`
+	Actors   []string `json:"actors,omitempty"`
+
+	// DedupeKey is an alternative to the Idempotency-Key header
+	DedupeKey string `json:"dedupe_key,omitempty"`
+
+	// Transactional marks mail the user must receive, such as password
+	// resets; only trusted services may set it
+	Transactional bool `json:"transactional,omitempty"`
+}
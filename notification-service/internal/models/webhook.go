@@ -0,0 +1,77 @@
+package models
+
+import "time"
+
+// WebhookEndpoint is a URL a user registered to receive webhook
+// notifications. Events lists the notification categories it wants; an
+// empty list receives every category.
+type WebhookEndpoint struct {
+	ID        string    `json:"id"`
+	UserID    string    `json:"user_id"`
+	URL       string    `json:"url"`
+	Secret    string    `json:"secret,omitempty"`
+	Events    []string  `json:"events"`
+	Active    bool      `json:"active"`
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+// Wants reports whether the endpoint subscribes to category
+func (e *WebhookEndpoint) Wants(category string) bool {
+	if len(e.Events) == 0 {
+		return true
+	}
+	for _, event := range e.Events {
+		if event == category || event == "*" {
+			return true
+		}
+	}
+	return false
+}
+
+// WebhookDelivery records one attempt to deliver a notification to an endpoint
+type WebhookDelivery struct {
+	ID             string    `json:"id"`
+	EndpointID     string    `json:"endpoint_id"`
+	NotificationID string    `json:"notification_id"`
+	Attempt        int       `json:"attempt"`
+	StatusCode     *int      `json:"status_code,omitempty"`
+	Error          *string   `json:"error,omitempty"`
+	Succeeded      bool      `json:"succeeded"`
+	DurationMS     int64     `json:"duration_ms"`
+	CreatedAt      time.Time `json:"created_at"`
+}
+
+// WebhookPayload is the JSON body POSTed to webhook endpoints
+type WebhookPayload struct {
+	ID           string        `json:"id"`
+	Event        string        `json:"event"`
+	CreatedAt    time.Time     `json:"created_at"`
+	Notification *Notification `json:"notification"`
+}
+
+type CreateWebhookRequest struct {
+	URL    string   `json:"url"`
+	Secret string   `json:"secret,omitempty"`
+	Events []string `json:"events"`
+}
+
+type UpdateWebhookRequest struct {
+	URL    *string  `json:"url,omitempty"`
+	Events []string `json:"events,omitempty"`
+	Active *bool    `json:"active,omitempty"`
+}
+
+type WebhookResponse struct {
+	Webhook *WebhookEndpoint `json:"webhook"`
+}
+
+type WebhooksResponse struct {
+	Webhooks []*WebhookEndpoint `json:"webhooks"`
+	Total    int                `json:"total"`
+}
+
+type WebhookDeliveriesResponse struct {
+	Deliveries []*WebhookDelivery `json:"deliveries"`
+	Total      int                `json:"total"`
+}
@@ -0,0 +1,112 @@
+package retention
+
+import (
+	"context"
+	"log"
+	"notification-service/internal/database"
+	"notification-service/internal/models"
+	"os"
+	"strconv"
+	"time"
+)
+
+// Policy says how long finished notifications are kept, per status. A zero
+// duration keeps them forever.
+type Policy struct {
+	Interval  time.Duration
+	BatchSize int
+	Keep      map[models.NotificationStatus]time.Duration
+}
+
+// PolicyFromEnv reads RETENTION_INTERVAL (default 1h) and the number of days
+// to keep notifications in each status: RETENTION_READ_DAYS (90),
+// RETENTION_EXPIRED_DAYS (7), RETENTION_SUPPRESSED_DAYS (30) and
+// RETENTION_DEAD_LETTER_DAYS (0, kept until requeued or deleted)
+func PolicyFromEnv() Policy {
+	policy := Policy{
+		Interval:  time.Hour,
+		BatchSize: 1000,
+		Keep: map[models.NotificationStatus]time.Duration{
+			models.StatusRead:       days("RETENTION_READ_DAYS", 90),
+			models.StatusExpired:    days("RETENTION_EXPIRED_DAYS", 7),
+			models.StatusSuppressed: days("RETENTION_SUPPRESSED_DAYS", 30),
+			models.StatusDeadLetter: days("RETENTION_DEAD_LETTER_DAYS", 0),
+		},
+	}
+	if value, err := time.ParseDuration(os.Getenv("RETENTION_INTERVAL")); err == nil && value > 0 {
+		policy.Interval = value
+	}
+	return policy
+}
+
+func days(key string, defaultDays int) time.Duration {
+	n := defaultDays
+	if value, err := strconv.Atoi(os.Getenv(key)); err == nil && value >= 0 {
+		n = value
+	}
+	return time.Duration(n) * 24 * time.Hour
+}
+
+// Job expires overdue notifications and purges old ones by policy. It also
+// clears idempotency keys past their TTL.
+type Job struct {
+	db     *database.DB
+	policy Policy
+}
+
+func NewJob(db *database.DB, policy Policy) *Job {
+	return &Job{db: db, policy: policy}
+}
+
+// Run applies the policy every interval until ctx is cancelled
+func (j *Job) Run(ctx context.Context) {
+	ticker := time.NewTicker(j.policy.Interval)
+	defer ticker.Stop()
+
+	for {
+		j.RunOnce()
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+func (j *Job) RunOnce() {
+	if expired, err := j.db.ExpireUndelivered(); err != nil {
+		log.Printf("❌ Retention: %v", err)
+	} else if expired > 0 {
+		log.Printf("⌛ Expired %d undelivered notifications", expired)
+	}
+
+	for status, keep := range j.policy.Keep {
+		if keep <= 0 {
+			continue
+		}
+		cutoff := time.Now().UTC().Add(-keep)
+
+		var total int64
+		for {
+			purged, err := j.db.PurgeNotifications(status, cutoff, j.policy.BatchSize)
+			if err != nil {
+				log.Printf("❌ Retention: %v", err)
+				break
+			}
+			total += purged
+			if purged < int64(j.policy.BatchSize) {
+				break
+			}
+		}
+		if total > 0 {
+			log.Printf("🧹 Purged %d %s notifications older than %s", total, status, keep)
+		}
+	}
+
+	if purged, err := j.db.PurgeExpiredIdempotencyKeys(); err != nil {
+		log.Printf("❌ Idempotency key purge failed: %v", err)
+	} else if purged > 0 {
+		log.Printf("🧹 Purged %d expired idempotency keys", purged)
+	}
+}
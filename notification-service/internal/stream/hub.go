@@ -0,0 +1,104 @@
+package stream
+
+import (
+	"context"
+	"log"
+	"sync"
+	"time"
+
+	"github.com/lib/pq"
+)
+
+// Hub wakes streaming connections when one of their user's in-app
+// notifications is delivered. Signals come from Postgres NOTIFY, so a
+// notification dispatched by any instance reaches clients on every instance.
+// A wake carries no payload; the connection reads what is new from the
+// database, which keeps replay and live delivery on the same path.
+type Hub struct {
+	listener *pq.Listener
+
+	mu          sync.Mutex
+	subscribers map[string]map[chan struct{}]struct{}
+}
+
+func NewHub(listener *pq.Listener) *Hub {
+	return &Hub{
+		listener:    listener,
+		subscribers: make(map[string]map[chan struct{}]struct{}),
+	}
+}
+
+// Subscribe registers a connection for userID. The returned channel receives
+// a value whenever there may be something new; call cancel when done.
+func (h *Hub) Subscribe(userID string) (<-chan struct{}, func()) {
+	wake := make(chan struct{}, 1)
+
+	h.mu.Lock()
+	if h.subscribers[userID] == nil {
+		h.subscribers[userID] = make(map[chan struct{}]struct{})
+	}
+	h.subscribers[userID][wake] = struct{}{}
+	h.mu.Unlock()
+
+	cancel := func() {
+		h.mu.Lock()
+		delete(h.subscribers[userID], wake)
+		if len(h.subscribers[userID]) == 0 {
+			delete(h.subscribers, userID)
+		}
+		h.mu.Unlock()
+	}
+	return wake, cancel
+}
+
+// Run forwards listener signals to subscribers until ctx is cancelled
+func (h *Hub) Run(ctx context.Context) {
+	defer h.listener.Close()
+
+	ticker := time.NewTicker(90 * time.Second)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case notification := <-h.listener.Notify:
+			if notification == nil {
+				// Reconnected; signals may have been lost
+				h.wakeAll()
+				continue
+			}
+			h.wake(notification.Extra)
+		case <-ticker.C:
+			if err := h.listener.Ping(); err != nil {
+				log.Printf("❌ Stream listener ping failed: %v", err)
+			}
+		}
+	}
+}
+
+func (h *Hub) wake(userID string) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	for wake := range h.subscribers[userID] {
+		signal(wake)
+	}
+}
+
+func (h *Hub) wakeAll() {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	for _, subscribers := range h.subscribers {
+		for wake := range subscribers {
+			signal(wake)
+		}
+	}
+}
+
+// signal never blocks; a pending wake already covers the new notification
+func signal(wake chan struct{}) {
+	select {
+	case wake <- struct{}{}:
+	default:
+	}
+}
@@ -0,0 +1,412 @@
+package templates
+
+import "notification-service/internal/models"
+
+// Builtin templates are seeded on startup when missing. Edits made through
+// the admin API are kept across restarts.
+var Builtin = []*models.NotificationTemplate{
+	{
+		Key:    "task_activity",
+		Locale: "en",
+		TitleTemplate: `
+{{- if gt (len .events) 1}}{{len .events}} updates on "{{(index .events 0).task_title}}"
+{{- else}}{{with index .events 0}}
+	{{- if eq .type "status_changed"}}"{{.task_title}}" is now {{.detail}}
+	{{- else if eq .type "commented"}}New comment on "{{.task_title}}"
+	{{- else if eq .type "overdue"}}"{{.task_title}}" is overdue
+	{{- else}}"{{.task_title}}" was updated{{end}}
+{{- end}}{{end}}`,
+		BodyTemplate: `
+{{- range .events}}
+• {{if eq .type "status_changed"}}"{{.task_title}}" is now {{.detail}}
+	{{- else if eq .type "commented"}}New comment on "{{.task_title}}": {{.detail}}
+	{{- else if eq .type "overdue"}}"{{.task_title}}" is overdue
+	{{- else}}"{{.task_title}}" was updated{{end}}
+{{- end}}`,
+		Variables: []string{"events"},
+	},
+	{
+		Key:    "task_activity",
+		Locale: "es",
+		TitleTemplate: `
+{{- if gt (len .events) 1}}{{len .events}} novedades en "{{(index .events 0).task_title}}"
+{{- else}}{{with index .events 0}}
+	{{- if eq .type "status_changed"}}"{{.task_title}}" ahora está {{.detail}}
+	{{- else if eq .type "commented"}}Nuevo comentario en "{{.task_title}}"
+	{{- else if eq .type "overdue"}}"{{.task_title}}" está vencida
+	{{- else}}"{{.task_title}}" se actualizó{{end}}
+{{- end}}{{end}}`,
+		BodyTemplate: `
+{{- range .events}}
+• {{if eq .type "status_changed"}}"{{.task_title}}" ahora está {{.detail}}
+	{{- else if eq .type "commented"}}Nuevo comentario en "{{.task_title}}": {{.detail}}
+	{{- else if eq .type "overdue"}}"{{.task_title}}" está vencida
+	{{- else}}"{{.task_title}}" se actualizó{{end}}
+{{- end}}`,
+		Variables: []string{"events"},
+	},
+	{
+		Key:    "email_digest",
+		Locale: "en",
+		TitleTemplate: `
+{{- if eq .period "daily"}}Your daily digest
+{{- else if eq .period "hourly"}}Your hourly digest
+{{- else}}Your digest{{end}}: {{.count}} notification{{if ne .count 1}}s{{end}}`,
+		BodyTemplate: `
+{{- range .items}}
+{{.title}} ({{.created_at}})
+{{.message}}
+{{end}}`,
+		HTMLTemplate: strPtr(`<h2>You have {{.count}} new notification{{if ne .count 1}}s{{end}}</h2>
+{{range .items}}<div style="margin-bottom:16px">
+<strong>{{.title}}</strong> <span style="color:#888">{{.created_at}}</span>
+<p style="white-space:pre-line;margin:4px 0">{{.message}}</p>
+</div>
+{{end}}`),
+		Variables: []string{"items", "count", "period"},
+	},
+	{
+		Key:    "email_digest",
+		Locale: "es",
+		TitleTemplate: `
+{{- if eq .period "daily"}}Tu resumen diario
+{{- else if eq .period "hourly"}}Tu resumen de la hora
+{{- else}}Tu resumen{{end}}: {{.count}} notificaci{{if ne .count 1}}ones{{else}}ón{{end}}`,
+		BodyTemplate: `
+{{- range .items}}
+{{.title}} ({{.created_at}})
+{{.message}}
+{{end}}`,
+		HTMLTemplate: strPtr(`<h2>Tienes {{.count}} notificaci{{if ne .count 1}}ones nuevas{{else}}ón nueva{{end}}</h2>
+{{range .items}}<div style="margin-bottom:16px">
+<strong>{{.title}}</strong> <span style="color:#888">{{.created_at}}</span>
+<p style="white-space:pre-line;margin:4px 0">{{.message}}</p>
+</div>
+{{end}}`),
+		Variables: []string{"items", "count", "period"},
+	},
+	{
+		Key:           "task_assigned",
+		Locale:        "en",
+		TitleTemplate: `You were assigned "{{.task_title}}"`,
+		BodyTemplate:  `"{{.task_title}}" was assigned to you{{if .due_date}} and is due {{.due_date}}{{end}}.`,
+		Variables:     []string{"task_title", "due_date"},
+	},
+	{
+		Key:           "task_assigned",
+		Locale:        "es",
+		TitleTemplate: `Se te asignó "{{.task_title}}"`,
+		BodyTemplate:  `Se te asignó "{{.task_title}}"{{if .due_date}}, con vencimiento el {{.due_date}}{{end}}.`,
+		Variables:     []string{"task_title", "due_date"},
+	},
+	{
+		Key:           "welcome",
+		Locale:        "en",
+		TitleTemplate: `Welcome to TaskManager, {{.first_name}}!`,
+		BodyTemplate: `Hi {{.first_name}},
+
+Your account is ready. Create your first task, invite teammates to watch it, and we'll keep you posted as things change.`,
+		HTMLTemplate: strPtr(`<h2>Welcome to TaskManager, {{.first_name}}!</h2>
+<p>Your account is ready. Create your first task, invite teammates to watch it, and we'll keep you posted as things change.</p>`),
+		Variables: []string{"first_name"},
+	},
+	{
+		Key:           "welcome",
+		Locale:        "es",
+		TitleTemplate: `¡Te damos la bienvenida a TaskManager, {{.first_name}}!`,
+		BodyTemplate: `Hola {{.first_name}}:
+
+Tu cuenta está lista. Crea tu primera tarea, invita a tu equipo a seguirla y te avisaremos cuando haya cambios.`,
+		HTMLTemplate: strPtr(`<h2>¡Te damos la bienvenida a TaskManager, {{.first_name}}!</h2>
+<p>Tu cuenta está lista. Crea tu primera tarea, invita a tu equipo a seguirla y te avisaremos cuando haya cambios.</p>`),
+		Variables: []string{"first_name"},
+	},
+	{
+		Key:           "security_new_login",
+		Locale:        "en",
+		TitleTemplate: `New sign-in to your account`,
+		BodyTemplate: `Your account was signed in to from a {{if .new_device}}new device{{else}}new location{{end}} on {{.time}}.
+
+Device: {{.device}}
+IP address: {{.ip}}
+
+If this was you, there's nothing to do. If not, change your password right away.`,
+		HTMLTemplate: strPtr(`<h2>New sign-in to your account</h2>
+<p>Your account was signed in to from a {{if .new_device}}new device{{else}}new location{{end}} on {{.time}}.</p>
+<p>Device: {{.device}}<br>IP address: {{.ip}}</p>
+<p>If this was you, there's nothing to do. If not, change your password right away.</p>`),
+		Variables: []string{"device", "ip", "time", "new_device"},
+	},
+	{
+		Key:           "security_new_login",
+		Locale:        "es",
+		TitleTemplate: `Nuevo inicio de sesión en tu cuenta`,
+		BodyTemplate: `Se inició sesión en tu cuenta desde {{if .new_device}}un dispositivo nuevo{{else}}una ubicación nueva{{end}} el {{.time}}.
+
+Dispositivo: {{.device}}
+Dirección IP: {{.ip}}
+
+Si fuiste tú, no tienes que hacer nada. Si no, cambia tu contraseña de inmediato.`,
+		HTMLTemplate: strPtr(`<h2>Nuevo inicio de sesión en tu cuenta</h2>
+<p>Se inició sesión en tu cuenta desde {{if .new_device}}un dispositivo nuevo{{else}}una ubicación nueva{{end}} el {{.time}}.</p>
+<p>Dispositivo: {{.device}}<br>Dirección IP: {{.ip}}</p>
+<p>Si fuiste tú, no tienes que hacer nada. Si no, cambia tu contraseña de inmediato.</p>`),
+		Variables: []string{"device", "ip", "time", "new_device"},
+	},
+	{
+		Key:           "security_failed_logins",
+		Locale:        "en",
+		TitleTemplate: `Failed sign-in attempts on your account`,
+		BodyTemplate: `There were {{.attempts}} failed attempts to sign in to your account, most recently from {{.ip}} on {{.time}}.
+
+If this wasn't you, someone may be trying to guess your password. Consider changing it to something long and unique.`,
+		HTMLTemplate: strPtr(`<h2>Failed sign-in attempts on your account</h2>
+<p>There were {{.attempts}} failed attempts to sign in to your account, most recently from {{.ip}} on {{.time}}.</p>
+<p>If this wasn't you, someone may be trying to guess your password. Consider changing it to something long and unique.</p>`),
+		Variables: []string{"attempts", "ip", "time"},
+	},
+	{
+		Key:           "security_failed_logins",
+		Locale:        "es",
+		TitleTemplate: `Intentos fallidos de inicio de sesión en tu cuenta`,
+		BodyTemplate: `Hubo {{.attempts}} intentos fallidos de iniciar sesión en tu cuenta, el último desde {{.ip}} el {{.time}}.
+
+Si no fuiste tú, alguien podría estar intentando adivinar tu contraseña. Considera cambiarla por una larga y única.`,
+		HTMLTemplate: strPtr(`<h2>Intentos fallidos de inicio de sesión en tu cuenta</h2>
+<p>Hubo {{.attempts}} intentos fallidos de iniciar sesión en tu cuenta, el último desde {{.ip}} el {{.time}}.</p>
+<p>Si no fuiste tú, alguien podría estar intentando adivinar tu contraseña. Considera cambiarla por una larga y única.</p>`),
+		Variables: []string{"attempts", "ip", "time"},
+	},
+	{
+		Key:           "security_account_locked",
+		Locale:        "en",
+		TitleTemplate: `Sign-in to your account was locked`,
+		BodyTemplate: `After {{.attempts}} failed attempts to sign in, most recently from {{.ip}} on {{.time}}, sign-in to your account is locked for {{.minutes}} minutes.
+
+If this wasn't you, someone may be trying to guess your password. Resetting your password lifts the lock right away.`,
+		HTMLTemplate: strPtr(`<h2>Sign-in to your account was locked</h2>
+<p>After {{.attempts}} failed attempts to sign in, most recently from {{.ip}} on {{.time}}, sign-in to your account is locked for {{.minutes}} minutes.</p>
+<p>If this wasn't you, someone may be trying to guess your password. Resetting your password lifts the lock right away.</p>`),
+		Variables: []string{"attempts", "ip", "time", "minutes"},
+	},
+	{
+		Key:           "security_account_locked",
+		Locale:        "es",
+		TitleTemplate: `Se bloqueó el inicio de sesión en tu cuenta`,
+		BodyTemplate: `Tras {{.attempts}} intentos fallidos de iniciar sesión, el último desde {{.ip}} el {{.time}}, el inicio de sesión en tu cuenta queda bloqueado durante {{.minutes}} minutos.
+
+Si no fuiste tú, alguien podría estar intentando adivinar tu contraseña. Restablecer tu contraseña levanta el bloqueo de inmediato.`,
+		HTMLTemplate: strPtr(`<h2>Se bloqueó el inicio de sesión en tu cuenta</h2>
+<p>Tras {{.attempts}} intentos fallidos de iniciar sesión, el último desde {{.ip}} el {{.time}}, el inicio de sesión en tu cuenta queda bloqueado durante {{.minutes}} minutos.</p>
+<p>Si no fuiste tú, alguien podría estar intentando adivinar tu contraseña. Restablecer tu contraseña levanta el bloqueo de inmediato.</p>`),
+		Variables: []string{"attempts", "ip", "time", "minutes"},
+	},
+	{
+		Key:           "password_reset",
+		Locale:        "en",
+		TitleTemplate: `Reset your TaskManager password`,
+		BodyTemplate: `Hi {{.first_name}},
+
+We received a request to reset your password. Open this link to choose a new one:
+
+{{.reset_url}}
+
+The link works once and expires in {{.expires_minutes}} minutes. If you didn't ask for this, you can ignore this email; your password won't change.`,
+		HTMLTemplate: strPtr(`<h2>Reset your password</h2>
+<p>Hi {{.first_name}},</p>
+<p>We received a request to reset your password. Use the button below to choose a new one.</p>
+<p><a href="{{.reset_url}}" style="display:inline-block;padding:10px 16px;background:#2563eb;color:#fff;text-decoration:none;border-radius:4px">Reset password</a></p>
+<p>The link works once and expires in {{.expires_minutes}} minutes. If you didn't ask for this, you can ignore this email; your password won't change.</p>`),
+		Variables: []string{"first_name", "reset_url", "expires_minutes"},
+	},
+	{
+		Key:           "password_reset",
+		Locale:        "es",
+		TitleTemplate: `Restablece tu contraseña de TaskManager`,
+		BodyTemplate: `Hola {{.first_name}}:
+
+Recibimos una solicitud para restablecer tu contraseña. Abre este enlace para elegir una nueva:
+
+{{.reset_url}}
+
+El enlace solo se puede usar una vez y caduca en {{.expires_minutes}} minutos. Si no lo solicitaste, ignora este correo; tu contraseña no cambiará.`,
+		HTMLTemplate: strPtr(`<h2>Restablece tu contraseña</h2>
+<p>Hola {{.first_name}}:</p>
+<p>Recibimos una solicitud para restablecer tu contraseña. Usa el botón de abajo para elegir una nueva.</p>
+<p><a href="{{.reset_url}}" style="display:inline-block;padding:10px 16px;background:#2563eb;color:#fff;text-decoration:none;border-radius:4px">Restablecer contraseña</a></p>
+<p>El enlace solo se puede usar una vez y caduca en {{.expires_minutes}} minutos. Si no lo solicitaste, ignora este correo; tu contraseña no cambiará.</p>`),
+		Variables: []string{"first_name", "reset_url", "expires_minutes"},
+	},
+	{
+		Key:           "security_password_changed",
+		Locale:        "en",
+		TitleTemplate: `Your password was changed`,
+		BodyTemplate: `The password for your account was changed on {{.time}} from {{.ip}}, and you were signed out of your other sessions.
+
+If you didn't do this, reset your password right away.`,
+		HTMLTemplate: strPtr(`<h2>Your password was changed</h2>
+<p>The password for your account was changed on {{.time}} from {{.ip}}, and you were signed out of your other sessions.</p>
+<p>If you didn't do this, reset your password right away.</p>`),
+		Variables: []string{"ip", "time"},
+	},
+	{
+		Key:           "security_password_changed",
+		Locale:        "es",
+		TitleTemplate: `Se cambió tu contraseña`,
+		BodyTemplate: `La contraseña de tu cuenta se cambió el {{.time}} desde {{.ip}} y se cerraron tus otras sesiones.
+
+Si no fuiste tú, restablece tu contraseña de inmediato.`,
+		HTMLTemplate: strPtr(`<h2>Se cambió tu contraseña</h2>
+<p>La contraseña de tu cuenta se cambió el {{.time}} desde {{.ip}} y se cerraron tus otras sesiones.</p>
+<p>Si no fuiste tú, restablece tu contraseña de inmediato.</p>`),
+		Variables: []string{"ip", "time"},
+	},
+	{
+		Key:           "security_mfa_changed",
+		Locale:        "en",
+		TitleTemplate: `Two-factor authentication {{if .enabled}}enabled{{else}}disabled{{end}}`,
+		BodyTemplate: `Two-factor authentication was {{if .enabled}}turned on{{else}}turned off{{end}} for your account on {{.time}} from {{.ip}}.
+
+If you didn't do this, reset your password right away.`,
+		HTMLTemplate: strPtr(`<h2>Two-factor authentication {{if .enabled}}enabled{{else}}disabled{{end}}</h2>
+<p>Two-factor authentication was {{if .enabled}}turned on{{else}}turned off{{end}} for your account on {{.time}} from {{.ip}}.</p>
+<p>If you didn't do this, reset your password right away.</p>`),
+		Variables: []string{"enabled", "ip", "time"},
+	},
+	{
+		Key:           "security_mfa_changed",
+		Locale:        "es",
+		TitleTemplate: `Verificación en dos pasos {{if .enabled}}activada{{else}}desactivada{{end}}`,
+		BodyTemplate: `La verificación en dos pasos de tu cuenta se {{if .enabled}}activó{{else}}desactivó{{end}} el {{.time}} desde {{.ip}}.
+
+Si no fuiste tú, restablece tu contraseña de inmediato.`,
+		HTMLTemplate: strPtr(`<h2>Verificación en dos pasos {{if .enabled}}activada{{else}}desactivada{{end}}</h2>
+<p>La verificación en dos pasos de tu cuenta se {{if .enabled}}activó{{else}}desactivó{{end}} el {{.time}} desde {{.ip}}.</p>
+<p>Si no fuiste tú, restablece tu contraseña de inmediato.</p>`),
+		Variables: []string{"enabled", "ip", "time"},
+	},
+	{
+		Key:           "verify_email_change",
+		Locale:        "en",
+		TitleTemplate: `Confirm your new email address`,
+		BodyTemplate: `Hi {{.first_name}},
+
+Someone asked to change the email address of a TaskManager account to {{.new_email}}. To confirm, open this link:
+
+{{.verify_url}}
+
+The link expires in {{.expires_hours}} hours. If you didn't ask for this, you can ignore this email.`,
+		HTMLTemplate: strPtr(`<h2>Confirm your new email address</h2>
+<p>Hi {{.first_name}},</p>
+<p>Someone asked to change the email address of a TaskManager account to {{.new_email}}.</p>
+<p><a href="{{.verify_url}}" style="display:inline-block;padding:10px 16px;background:#2563eb;color:#fff;text-decoration:none;border-radius:4px">Confirm new address</a></p>
+<p>The link expires in {{.expires_hours}} hours. If you didn't ask for this, you can ignore this email.</p>`),
+		Variables: []string{"first_name", "new_email", "verify_url", "expires_hours"},
+	},
+	{
+		Key:           "verify_email_change",
+		Locale:        "es",
+		TitleTemplate: `Confirma tu nueva dirección de correo`,
+		BodyTemplate: `Hola {{.first_name}}:
+
+Alguien pidió cambiar la dirección de correo de una cuenta de TaskManager a {{.new_email}}. Para confirmarlo, abre este enlace:
+
+{{.verify_url}}
+
+El enlace caduca en {{.expires_hours}} horas. Si no lo pediste, ignora este correo.`,
+		HTMLTemplate: strPtr(`<h2>Confirma tu nueva dirección de correo</h2>
+<p>Hola {{.first_name}}:</p>
+<p>Alguien pidió cambiar la dirección de correo de una cuenta de TaskManager a {{.new_email}}.</p>
+<p><a href="{{.verify_url}}" style="display:inline-block;padding:10px 16px;background:#2563eb;color:#fff;text-decoration:none;border-radius:4px">Confirmar nueva dirección</a></p>
+<p>El enlace caduca en {{.expires_hours}} horas. Si no lo pediste, ignora este correo.</p>`),
+		Variables: []string{"first_name", "new_email", "verify_url", "expires_hours"},
+	},
+	{
+		Key:           "security_email_change_requested",
+		Locale:        "en",
+		TitleTemplate: `Email change requested`,
+		BodyTemplate: `On {{.time}}, a request from {{.ip}} asked to change your account's email address to {{.new_email}}. The change happens only once the new address is confirmed.
+
+If you didn't do this, change your password right away.`,
+		HTMLTemplate: strPtr(`<h2>Email change requested</h2>
+<p>On {{.time}}, a request from {{.ip}} asked to change your account's email address to {{.new_email}}. The change happens only once the new address is confirmed.</p>
+<p>If you didn't do this, change your password right away.</p>`),
+		Variables: []string{"new_email", "ip", "time"},
+	},
+	{
+		Key:           "security_email_change_requested",
+		Locale:        "es",
+		TitleTemplate: `Se solicitó un cambio de correo`,
+		BodyTemplate: `El {{.time}}, una solicitud desde {{.ip}} pidió cambiar la dirección de correo de tu cuenta a {{.new_email}}. El cambio solo se hará cuando se confirme la nueva dirección.
+
+Si no fuiste tú, cambia tu contraseña de inmediato.`,
+		HTMLTemplate: strPtr(`<h2>Se solicitó un cambio de correo</h2>
+<p>El {{.time}}, una solicitud desde {{.ip}} pidió cambiar la dirección de correo de tu cuenta a {{.new_email}}. El cambio solo se hará cuando se confirme la nueva dirección.</p>
+<p>Si no fuiste tú, cambia tu contraseña de inmediato.</p>`),
+		Variables: []string{"new_email", "ip", "time"},
+	},
+	{
+		Key:           "security_email_changed",
+		Locale:        "en",
+		TitleTemplate: `Your email address was changed`,
+		BodyTemplate: `The email address of your account was changed from {{.old_email}} to {{.new_email}} on {{.time}}.
+
+If you didn't do this, contact support right away.`,
+		HTMLTemplate: strPtr(`<h2>Your email address was changed</h2>
+<p>The email address of your account was changed from {{.old_email}} to {{.new_email}} on {{.time}}.</p>
+<p>If you didn't do this, contact support right away.</p>`),
+		Variables: []string{"old_email", "new_email", "ip", "time"},
+	},
+	{
+		Key:           "security_email_changed",
+		Locale:        "es",
+		TitleTemplate: `Se cambió tu dirección de correo`,
+		BodyTemplate: `La dirección de correo de tu cuenta se cambió de {{.old_email}} a {{.new_email}} el {{.time}}.
+
+Si no fuiste tú, contacta con soporte de inmediato.`,
+		HTMLTemplate: strPtr(`<h2>Se cambió tu dirección de correo</h2>
+<p>La dirección de correo de tu cuenta se cambió de {{.old_email}} a {{.new_email}} el {{.time}}.</p>
+<p>Si no fuiste tú, contacta con soporte de inmediato.</p>`),
+		Variables: []string{"old_email", "new_email", "ip", "time"},
+	},
+	{
+		Key:           "verify_email",
+		Locale:        "en",
+		TitleTemplate: `Confirm your email address`,
+		BodyTemplate: `Hi {{.first_name}},
+
+Please confirm that this is your email address by opening this link:
+
+{{.verify_url}}
+
+The link expires in {{.expires_hours}} hours. If you didn't create a TaskManager account, you can ignore this email.`,
+		HTMLTemplate: strPtr(`<h2>Confirm your email address</h2>
+<p>Hi {{.first_name}},</p>
+<p>Please confirm that this is your email address.</p>
+<p><a href="{{.verify_url}}" style="display:inline-block;padding:10px 16px;background:#2563eb;color:#fff;text-decoration:none;border-radius:4px">Verify email</a></p>
+<p>The link expires in {{.expires_hours}} hours. If you didn't create a TaskManager account, you can ignore this email.</p>`),
+		Variables: []string{"first_name", "verify_url", "expires_hours"},
+	},
+	{
+		Key:           "verify_email",
+		Locale:        "es",
+		TitleTemplate: `Confirma tu dirección de correo`,
+		BodyTemplate: `Hola {{.first_name}}:
+
+Confirma que esta es tu dirección de correo abriendo este enlace:
+
+{{.verify_url}}
+
+El enlace caduca en {{.expires_hours}} horas. Si no creaste una cuenta de TaskManager, ignora este correo.`,
+		HTMLTemplate: strPtr(`<h2>Confirma tu dirección de correo</h2>
+<p>Hola {{.first_name}}:</p>
+<p>Confirma que esta es tu dirección de correo.</p>
+<p><a href="{{.verify_url}}" style="display:inline-block;padding:10px 16px;background:#2563eb;color:#fff;text-decoration:none;border-radius:4px">Verificar correo</a></p>
+<p>El enlace caduca en {{.expires_hours}} horas. Si no creaste una cuenta de TaskManager, ignora este correo.</p>`),
+		Variables: []string{"first_name", "verify_url", "expires_hours"},
+	},
+}
+
+func strPtr(s string) *string {
+	return &s
+}
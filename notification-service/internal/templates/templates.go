@@ -0,0 +1,150 @@
+package templates
+
+import (
+	"bytes"
+	"fmt"
+	htmltemplate "html/template"
+	"notification-service/internal/models"
+	"os"
+	"strings"
+	"text/template"
+)
+
+// DefaultLocale is the last fallback when resolving a template. It can be
+// changed with DEFAULT_LOCALE.
+func DefaultLocale() string {
+	if locale := os.Getenv("DEFAULT_LOCALE"); locale != "" {
+		return NormalizeLocale(locale)
+	}
+	return "en"
+}
+
+// NormalizeLocale turns "PT_br" into "pt-BR"
+func NormalizeLocale(locale string) string {
+	parts := strings.Split(strings.ReplaceAll(strings.TrimSpace(locale), "_", "-"), "-")
+	parts[0] = strings.ToLower(parts[0])
+	for i := 1; i < len(parts); i++ {
+		if len(parts[i]) == 2 {
+			parts[i] = strings.ToUpper(parts[i])
+		}
+	}
+	return strings.Join(parts, "-")
+}
+
+// LocaleCandidates lists the locales to try for a requested locale, most
+// specific first: "pt-BR" gives pt-BR, pt, then the default locale
+func LocaleCandidates(locale string) []string {
+	var candidates []string
+	add := func(l string) {
+		for _, c := range candidates {
+			if c == l {
+				return
+			}
+		}
+		candidates = append(candidates, l)
+	}
+
+	if locale = NormalizeLocale(locale); locale != "" {
+		parts := strings.Split(locale, "-")
+		for i := len(parts); i > 0; i-- {
+			add(strings.Join(parts[:i], "-"))
+		}
+	}
+	add(DefaultLocale())
+	return candidates
+}
+
+// Rendered is the output of a template for one set of data
+type Rendered struct {
+	Title   string
+	Message string
+	HTML    *string
+}
+
+// Validate checks that every part of the template parses
+func Validate(t *models.NotificationTemplate) error {
+	if _, err := parseText("title", t.TitleTemplate); err != nil {
+		return err
+	}
+	if _, err := parseText("body", t.BodyTemplate); err != nil {
+		return err
+	}
+	if t.HTMLTemplate != nil {
+		if _, err := parseHTML(*t.HTMLTemplate); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// MissingVariables returns the declared variables absent from data
+func MissingVariables(t *models.NotificationTemplate, data map[string]interface{}) []string {
+	var missing []string
+	for _, name := range t.Variables {
+		if _, ok := data[name]; !ok {
+			missing = append(missing, name)
+		}
+	}
+	return missing
+}
+
+// Render executes the template against data. A variable referenced by the
+// template but missing from data is an error rather than "<no value>".
+func Render(t *models.NotificationTemplate, data map[string]interface{}) (*Rendered, error) {
+	title, err := executeText("title", t.TitleTemplate, data)
+	if err != nil {
+		return nil, err
+	}
+	message, err := executeText("body", t.BodyTemplate, data)
+	if err != nil {
+		return nil, err
+	}
+
+	rendered := &Rendered{
+		Title:   strings.Join(strings.Fields(title), " "),
+		Message: strings.TrimSpace(message),
+	}
+
+	if t.HTMLTemplate != nil {
+		tmpl, err := parseHTML(*t.HTMLTemplate)
+		if err != nil {
+			return nil, err
+		}
+		var buf bytes.Buffer
+		if err := tmpl.Execute(&buf, data); err != nil {
+			return nil, fmt.Errorf("failed to render html: %w", err)
+		}
+		html := buf.String()
+		rendered.HTML = &html
+	}
+
+	return rendered, nil
+}
+
+func parseText(name, text string) (*template.Template, error) {
+	tmpl, err := template.New(name).Option("missingkey=error").Parse(text)
+	if err != nil {
+		return nil, fmt.Errorf("invalid %s template: %w", name, err)
+	}
+	return tmpl, nil
+}
+
+func parseHTML(text string) (*htmltemplate.Template, error) {
+	tmpl, err := htmltemplate.New("html").Option("missingkey=error").Parse(text)
+	if err != nil {
+		return nil, fmt.Errorf("invalid html template: %w", err)
+	}
+	return tmpl, nil
+}
+
+func executeText(name, text string, data map[string]interface{}) (string, error) {
+	tmpl, err := parseText(name, text)
+	if err != nil {
+		return "", err
+	}
+	var buf bytes.Buffer
+	if err := tmpl.Execute(&buf, data); err != nil {
+		return "", fmt.Errorf("failed to render %s: %w", name, err)
+	}
+	return buf.String(), nil
+}
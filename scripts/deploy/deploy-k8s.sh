@@ -8,6 +8,11 @@ echo "Deploying Task Manager to AKS..."
 echo "Applying PostgreSQL configuration..."
 kubectl apply -f ../../k8s/config/
 
+# Deploy the event bus before the services that publish and consume events
+echo "Deploying nats..."
+kubectl apply -f ../../k8s/manifests/nats/
+kubectl rollout status --timeout=300s statefulset/nats
+
 # Deploy services in order (database-dependent first)
 echo "Deploying user-service..."
 kubectl apply -f ../../k8s/manifests/user-service/
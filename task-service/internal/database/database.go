@@ -1,193 +1,413 @@
-package database
-
-import (
-    "database/sql"
-    "fmt"
-    "log"
-    "os"
-    "regexp"
-    "strings"
-    "time"
-    "taskservice/internal/models"
-    _ "github.com/lib/pq"
-)
-
-type DB struct {
-    *sql.DB
-}
-
-func NewPostgresDB() (*DB, error) {
-    // Get database URL from environment variable
-    connStr := os.Getenv("DATABASE_URL")
-    if connStr == "" {
-        // Fallback to localhost (for development outside Docker)
-        connStr = "host=localhost port=5432 user=postgres password=password dbname=taskmanager sslmode=disable"
-    } else {
-        // Log that we're using the environment variable (for debugging)
-        log.Printf("🔗 Using DATABASE_URL from environment: %s", maskPassword(connStr))
-    }
-    
-    db, err := sql.Open("postgres", connStr)
-    if err != nil {
-        return nil, fmt.Errorf("failed to open database: %w", err)
-    }
-
-    // Add connection retry logic
-    var pingErr error
-    for i := 0; i < 10; i++ {
-        pingErr = db.Ping()
-        if pingErr == nil {
-            break
-        }
-        log.Printf("⏳ Database not ready yet (attempt %d/10): %v", i+1, pingErr)
-        time.Sleep(2 * time.Second)
-    }
-
-    if pingErr != nil {
-        return nil, fmt.Errorf("failed to ping database after retries: %w", pingErr)
-    }
-
-    log.Println("✅ Service: Connected to PostgreSQL successfully")
-    return &DB{db}, nil
-}
-
-// Helper function to mask password in logs
-func maskPassword(connStr string) string {
-    if strings.Contains(connStr, "password=") {
-        return regexp.MustCompile(`password=[^&]+`).ReplaceAllString(connStr, "password=***")
-    }
-    return connStr
-}
-func (db *DB) Init() error {
-    query := `
-    CREATE TABLE IF NOT EXISTS tasks (
-        id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
-        title VARCHAR(255) NOT NULL,
-        description TEXT,
-        status VARCHAR(20) DEFAULT 'pending' CHECK (status IN ('pending', 'in_progress', 'completed')),
-        user_id UUID NOT NULL,
-        due_date TIMESTAMP,
-        created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
-        updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
-    );
-
-    CREATE INDEX IF NOT EXISTS idx_tasks_user_id ON tasks(user_id);
-    CREATE INDEX IF NOT EXISTS idx_tasks_status ON tasks(status);
-    CREATE INDEX IF NOT EXISTS idx_tasks_due_date ON tasks(due_date);
-    `
-
-    _, err := db.Exec(query)
-    if err != nil {
-        return fmt.Errorf("failed to initialize tasks schema: %w", err)
-    }
-
-    log.Println("✅ Task Service: Database schema initialized successfully")
-    return nil
-}
-
-func (db *DB) CreateTask(task *models.Task) error {
-    query := `
-    INSERT INTO tasks (title, description, user_id, due_date)
-    VALUES ($1, $2, $3, $4)
-    RETURNING id, status, created_at, updated_at`
-
-    err := db.QueryRow(query, task.Title, task.Description, task.UserID, task.DueDate).Scan(
-        &task.ID, &task.Status, &task.CreatedAt, &task.UpdatedAt)
-    
-    if err != nil {
-        return fmt.Errorf("failed to create task: %w", err)
-    }
-    
-    return nil
-}
-
-func (db *DB) GetTaskByID(id string) (*models.Task, error) {
-    var task models.Task
-    
-    query := `SELECT id, title, description, status, user_id, due_date, created_at, updated_at 
-              FROM tasks WHERE id = $1`
-    
-    err := db.QueryRow(query, id).Scan(
-        &task.ID, &task.Title, &task.Description, &task.Status, &task.UserID,
-        &task.DueDate, &task.CreatedAt, &task.UpdatedAt)
-    
-    if err == sql.ErrNoRows {
-        return nil, fmt.Errorf("task not found")
-    }
-    if err != nil {
-        return nil, fmt.Errorf("failed to get task: %w", err)
-    }
-    
-    return &task, nil
-}
-
-func (db *DB) GetTasksByUserID(userID string) ([]*models.Task, error) {
-    query := `SELECT id, title, description, status, user_id, due_date, created_at, updated_at 
-              FROM tasks WHERE user_id = $1 ORDER BY created_at DESC`
-    
-    rows, err := db.Query(query, userID)
-    if err != nil {
-        return nil, fmt.Errorf("failed to query tasks: %w", err)
-    }
-    defer rows.Close()
-
-    var tasks []*models.Task
-    for rows.Next() {
-        var task models.Task
-        err := rows.Scan(
-            &task.ID, &task.Title, &task.Description, &task.Status, &task.UserID,
-            &task.DueDate, &task.CreatedAt, &task.UpdatedAt)
-        if err != nil {
-            return nil, fmt.Errorf("failed to scan task: %w", err)
-        }
-        tasks = append(tasks, &task)
-    }
-
-    return tasks, nil
-}
-
-func (db *DB) UpdateTask(id string, req *models.UpdateTaskRequest) (*models.Task, error) {
-    query := `
-    UPDATE tasks 
-    SET title = COALESCE($1, title),
-        description = COALESCE($2, description),
-        status = COALESCE($3, status),
-        due_date = $4,
-        updated_at = CURRENT_TIMESTAMP
-    WHERE id = $5
-    RETURNING id, title, description, status, user_id, due_date, created_at, updated_at`
-
-    var task models.Task
-    err := db.QueryRow(query, req.Title, req.Description, req.Status, req.DueDate, id).Scan(
-        &task.ID, &task.Title, &task.Description, &task.Status, &task.UserID,
-        &task.DueDate, &task.CreatedAt, &task.UpdatedAt)
-    
-    if err == sql.ErrNoRows {
-        return nil, fmt.Errorf("task not found")
-    }
-    if err != nil {
-        return nil, fmt.Errorf("failed to update task: %w", err)
-    }
-    
-    return &task, nil
-}
-
-func (db *DB) DeleteTask(id string) error {
-    query := `DELETE FROM tasks WHERE id = $1`
-    
-    result, err := db.Exec(query, id)
-    if err != nil {
-        return fmt.Errorf("failed to delete task: %w", err)
-    }
-    
-    rows, _ := result.RowsAffected()
-    if rows == 0 {
-        return fmt.Errorf("task not found")
-    }
-    
-    return nil
-}
-
-func (db *DB) Close() error {
-    return db.DB.Close()
-}
+package database
+
+import (
+    "database/sql"
+    "fmt"
+    "log"
+    "os"
+    "regexp"
+    "strings"
+    "time"
+    "taskservice/internal/events"
+    "taskservice/internal/models"
+    "github.com/lib/pq"
+)
+
+type DB struct {
+    *sql.DB
+}
+
+func NewPostgresDB() (*DB, error) {
+    // Get database URL from environment variable
+    connStr := os.Getenv("DATABASE_URL")
+    if connStr == "" {
+        // Fallback to localhost (for development outside Docker)
+        connStr = "host=localhost port=5432 user=postgres password=password dbname=taskmanager sslmode=disable"
+    } else {
+        // Log that we're using the environment variable (for debugging)
+        log.Printf("🔗 Using DATABASE_URL from environment: %s", maskPassword(connStr))
+    }
+    
+    db, err := sql.Open("postgres", connStr)
+    if err != nil {
+        return nil, fmt.Errorf("failed to open database: %w", err)
+    }
+
+    // Add connection retry logic
+    var pingErr error
+    for i := 0; i < 10; i++ {
+        pingErr = db.Ping()
+        if pingErr == nil {
+            break
+        }
+        log.Printf("⏳ Database not ready yet (attempt %d/10): %v", i+1, pingErr)
+        time.Sleep(2 * time.Second)
+    }
+
+    if pingErr != nil {
+        return nil, fmt.Errorf("failed to ping database after retries: %w", pingErr)
+    }
+
+    log.Println("✅ Service: Connected to PostgreSQL successfully")
+    return &DB{db}, nil
+}
+
+// Helper function to mask password in logs
+func maskPassword(connStr string) string {
+    if strings.Contains(connStr, "password=") {
+        return regexp.MustCompile(`password=[^&]+`).ReplaceAllString(connStr, "password=***")
+    }
+    return connStr
+}
+func (db *DB) Init() error {
+    query := `
+    CREATE TABLE IF NOT EXISTS tasks (
+        id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+        title VARCHAR(255) NOT NULL,
+        description TEXT,
+        status VARCHAR(20) DEFAULT 'pending' CHECK (status IN ('pending', 'in_progress', 'completed')),
+        user_id UUID NOT NULL,
+        due_date TIMESTAMP,
+        created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+        updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+    );
+
+    CREATE INDEX IF NOT EXISTS idx_tasks_user_id ON tasks(user_id);
+    CREATE INDEX IF NOT EXISTS idx_tasks_status ON tasks(status);
+    CREATE INDEX IF NOT EXISTS idx_tasks_due_date ON tasks(due_date);
+
+    -- Migration: external_id identifies tasks brought in through import
+    ALTER TABLE tasks ADD COLUMN IF NOT EXISTS external_id VARCHAR(255);
+    CREATE UNIQUE INDEX IF NOT EXISTS idx_tasks_user_external_id
+        ON tasks(user_id, external_id) WHERE external_id IS NOT NULL;
+
+    -- Migration: project grouping and completion time for statistics
+    ALTER TABLE tasks ADD COLUMN IF NOT EXISTS project VARCHAR(100) NOT NULL DEFAULT '';
+    ALTER TABLE tasks ADD COLUMN IF NOT EXISTS completed_at TIMESTAMP;
+    UPDATE tasks SET completed_at = updated_at WHERE status = 'completed' AND completed_at IS NULL;
+    CREATE INDEX IF NOT EXISTS idx_tasks_user_completed_at ON tasks(user_id, completed_at);
+
+    -- Migration: labels and assignee for filtering
+    ALTER TABLE tasks ADD COLUMN IF NOT EXISTS labels TEXT[] NOT NULL DEFAULT '{}';
+    ALTER TABLE tasks ADD COLUMN IF NOT EXISTS assignee_id UUID;
+    CREATE INDEX IF NOT EXISTS idx_tasks_labels ON tasks USING GIN (labels);
+    CREATE INDEX IF NOT EXISTS idx_tasks_assignee_id ON tasks(assignee_id);
+
+    CREATE TABLE IF NOT EXISTS saved_views (
+        id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+        user_id UUID NOT NULL,
+        name VARCHAR(100) NOT NULL,
+        filter JSONB NOT NULL DEFAULT '{}'::jsonb,
+        sort_field VARCHAR(20) NOT NULL DEFAULT 'created_at',
+        sort_direction VARCHAR(4) NOT NULL DEFAULT 'desc',
+        created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+        updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+        UNIQUE (user_id, name)
+    );
+
+    -- Watchers are notified when a task changes; overdue_notified_at marks
+    -- tasks whose overdue notification has already gone out
+    -- Tasks already past due when the column is added were due before
+    -- watching existed, so they are marked as reported rather than announced
+    -- all at once
+    DO $$
+    BEGIN
+        IF NOT EXISTS (SELECT 1 FROM information_schema.columns
+                       WHERE table_name = 'tasks' AND column_name = 'overdue_notified_at') THEN
+            ALTER TABLE tasks ADD COLUMN overdue_notified_at TIMESTAMP;
+            UPDATE tasks SET overdue_notified_at = CURRENT_TIMESTAMP
+            WHERE due_date < CURRENT_TIMESTAMP AND status <> 'completed';
+        END IF;
+    END $$;
+
+    CREATE TABLE IF NOT EXISTS task_watchers (
+        task_id UUID NOT NULL REFERENCES tasks(id) ON DELETE CASCADE,
+        user_id UUID NOT NULL,
+        created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+        PRIMARY KEY (task_id, user_id)
+    );
+    CREATE INDEX IF NOT EXISTS idx_task_watchers_user_id ON task_watchers(user_id);
+
+    CREATE TABLE IF NOT EXISTS task_comments (
+        id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+        task_id UUID NOT NULL REFERENCES tasks(id) ON DELETE CASCADE,
+        user_id UUID NOT NULL,
+        body TEXT NOT NULL,
+        created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+    );
+    CREATE INDEX IF NOT EXISTS idx_task_comments_task_id ON task_comments(task_id, created_at);
+
+    -- Migration: priority and RRULE recurrence
+    ALTER TABLE tasks ADD COLUMN IF NOT EXISTS priority VARCHAR(10) NOT NULL DEFAULT 'medium'
+        CHECK (priority IN ('low', 'medium', 'high', 'urgent'));
+    ALTER TABLE tasks ADD COLUMN IF NOT EXISTS recurrence VARCHAR(255) NOT NULL DEFAULT '';
+
+    -- Transactional outbox: events are written with the change that caused
+    -- them and published to the event bus by the outbox relay
+    CREATE TABLE IF NOT EXISTS outbox_events (
+        id BIGSERIAL PRIMARY KEY,
+        event_id UUID NOT NULL DEFAULT gen_random_uuid(),
+        event_type VARCHAR(100) NOT NULL,
+        aggregate_id VARCHAR(255) NOT NULL,
+        payload JSONB NOT NULL,
+        created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+        published_at TIMESTAMP,
+        attempts INTEGER NOT NULL DEFAULT 0,
+        last_error TEXT
+    );
+    CREATE INDEX IF NOT EXISTS idx_outbox_events_unpublished ON outbox_events(id) WHERE published_at IS NULL;
+    CREATE INDEX IF NOT EXISTS idx_outbox_events_published_at ON outbox_events(published_at) WHERE published_at IS NOT NULL;
+    `
+
+    _, err := db.Exec(query)
+    if err != nil {
+        return fmt.Errorf("failed to initialize tasks schema: %w", err)
+    }
+
+    log.Println("✅ Task Service: Database schema initialized successfully")
+    return nil
+}
+
+const taskColumns = `id, title, description, status, user_id, due_date, external_id, project, labels, assignee_id, priority, recurrence, completed_at, created_at, updated_at`
+
+// rowScanner is satisfied by both *sql.Row and *sql.Rows
+type rowScanner interface {
+    Scan(dest ...interface{}) error
+}
+
+// scanTask reads the taskColumns of a row, followed by any extra columns
+func scanTask(row rowScanner, extra ...interface{}) (*models.Task, error) {
+    var task models.Task
+    dest := []interface{}{
+        &task.ID, &task.Title, &task.Description, &task.Status, &task.UserID,
+        &task.DueDate, &task.ExternalID, &task.Project,
+        pq.Array(&task.Labels), &task.AssigneeID, &task.Priority, &task.Recurrence,
+        &task.CompletedAt, &task.CreatedAt, &task.UpdatedAt,
+    }
+    if err := row.Scan(append(dest, extra...)...); err != nil {
+        return nil, err
+    }
+    return &task, nil
+}
+
+func (db *DB) CreateTask(task *models.Task) error {
+    if task.Status == "" {
+        task.Status = models.StatusPending
+    }
+
+    // The owner watches every new task
+    query := `
+    WITH created AS (
+        INSERT INTO tasks (title, description, status, user_id, due_date, external_id, project, labels, assignee_id,
+                           priority, recurrence, completed_at)
+        VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, CASE WHEN $3 = 'completed' THEN CURRENT_TIMESTAMP END)
+        RETURNING id, user_id, status, completed_at, created_at, updated_at
+    ), watcher AS (
+        INSERT INTO task_watchers (task_id, user_id) SELECT id, user_id FROM created
+    )
+    SELECT id, status, completed_at, created_at, updated_at FROM created`
+
+    if task.Labels == nil {
+        task.Labels = []string{}
+    }
+    if task.Priority == "" {
+        task.Priority = models.PriorityMedium
+    }
+
+    // The task and its task.created event are committed together
+    tx, err := db.Begin()
+    if err != nil {
+        return fmt.Errorf("failed to create task: %w", err)
+    }
+    defer tx.Rollback()
+
+    err = tx.QueryRow(query, task.Title, task.Description, task.Status, task.UserID, task.DueDate,
+        task.ExternalID, task.Project, pq.Array(task.Labels), task.AssigneeID, task.Priority, task.Recurrence).Scan(
+        &task.ID, &task.Status, &task.CompletedAt, &task.CreatedAt, &task.UpdatedAt)
+    
+    if err != nil {
+        return fmt.Errorf("failed to create task: %w", err)
+    }
+
+    if err := addOutboxEvent(tx, events.TaskCreated, task.ID, task); err != nil {
+        return err
+    }
+
+    if err := tx.Commit(); err != nil {
+        return fmt.Errorf("failed to create task: %w", err)
+    }
+    
+    return nil
+}
+
+func (db *DB) GetTaskByID(id string) (*models.Task, error) {
+    query := `SELECT ` + taskColumns + ` FROM tasks WHERE id = $1`
+    
+    task, err := scanTask(db.QueryRow(query, id))
+    if err == sql.ErrNoRows {
+        return nil, fmt.Errorf("task not found")
+    }
+    if err != nil {
+        return nil, fmt.Errorf("failed to get task: %w", err)
+    }
+    
+    return task, nil
+}
+
+func (db *DB) GetTasksByUserID(userID string) ([]*models.Task, error) {
+    var tasks []*models.Task
+    err := db.StreamTasksByUserID(userID, func(task *models.Task) error {
+        tasks = append(tasks, task)
+        return nil
+    })
+    if err != nil {
+        return nil, err
+    }
+
+    return tasks, nil
+}
+
+// StreamTasksByUserID calls fn for each of the user's tasks as rows are read,
+// so large exports never hold the full result set in memory
+func (db *DB) StreamTasksByUserID(userID string, fn func(*models.Task) error) error {
+    query := `SELECT ` + taskColumns + `
+              FROM tasks WHERE user_id = $1 ORDER BY created_at DESC`
+    
+    rows, err := db.Query(query, userID)
+    if err != nil {
+        return fmt.Errorf("failed to query tasks: %w", err)
+    }
+    defer rows.Close()
+
+    for rows.Next() {
+        task, err := scanTask(rows)
+        if err != nil {
+            return fmt.Errorf("failed to scan task: %w", err)
+        }
+        if err := fn(task); err != nil {
+            return err
+        }
+    }
+
+    return rows.Err()
+}
+
+// GetExternalIDs returns the IDs an imported row may refer to an existing
+// task by, mapped to task IDs: external IDs of imported tasks, and the IDs of
+// the user's own tasks, which an export of them carries
+func (db *DB) GetExternalIDs(userID string) (map[string]string, error) {
+    query := `SELECT id, COALESCE(external_id, '') FROM tasks WHERE user_id = $1`
+
+    rows, err := db.Query(query, userID)
+    if err != nil {
+        return nil, fmt.Errorf("failed to query external ids: %w", err)
+    }
+    defer rows.Close()
+
+    ids := make(map[string]string)
+    for rows.Next() {
+        var taskID, externalID string
+        if err := rows.Scan(&taskID, &externalID); err != nil {
+            return nil, fmt.Errorf("failed to scan external id: %w", err)
+        }
+        ids[taskID] = taskID
+        if externalID != "" {
+            ids[externalID] = taskID
+        }
+    }
+
+    return ids, rows.Err()
+}
+
+// UpdateTask applies the changes made by actorID. A status change is recorded
+// as activity for the task's watchers in the same transaction.
+func (db *DB) UpdateTask(id string, req *models.UpdateTaskRequest, actorID string) (*models.Task, error) {
+    query := `
+    UPDATE tasks 
+    SET title = COALESCE($1, title),
+        description = COALESCE($2, description),
+        status = COALESCE($3, status),
+        due_date = $4,
+        overdue_notified_at = CASE WHEN $4 IS DISTINCT FROM due_date THEN NULL ELSE overdue_notified_at END,
+        project = COALESCE($5, project),
+        labels = COALESCE($6, labels),
+        assignee_id = CASE WHEN $7::text IS NULL THEN assignee_id ELSE NULLIF($7, '')::uuid END,
+        priority = COALESCE(NULLIF($8, ''), priority),
+        recurrence = COALESCE($9, recurrence),
+        completed_at = CASE WHEN COALESCE($3, status) = 'completed'
+                            THEN COALESCE(completed_at, CURRENT_TIMESTAMP) END,
+        updated_at = CURRENT_TIMESTAMP
+    WHERE id = $10
+    RETURNING ` + taskColumns
+
+    var labels interface{}
+    if req.Labels != nil {
+        labels = pq.Array(req.Labels)
+    }
+
+    tx, err := db.Begin()
+    if err != nil {
+        return nil, fmt.Errorf("failed to update task: %w", err)
+    }
+    defer tx.Rollback()
+
+    // Lock the row so the status read here is the one the update replaces
+    var previousStatus models.TaskStatus
+    err = tx.QueryRow(`SELECT status FROM tasks WHERE id = $1 FOR UPDATE`, id).Scan(&previousStatus)
+    if err == sql.ErrNoRows {
+        return nil, fmt.Errorf("task not found")
+    }
+    if err != nil {
+        return nil, fmt.Errorf("failed to update task: %w", err)
+    }
+
+    task, err := scanTask(tx.QueryRow(query, req.Title, req.Description, req.Status, req.DueDate, req.Project,
+        labels, req.AssigneeID, req.Priority, req.Recurrence, id))
+    if err != nil {
+        return nil, fmt.Errorf("failed to update task: %w", err)
+    }
+
+    if task.Status == models.StatusCompleted && previousStatus != models.StatusCompleted {
+        if err := addOutboxEvent(tx, events.TaskCompleted, task.ID, task); err != nil {
+            return nil, err
+        }
+    }
+    if task.Status != previousStatus {
+        err := addTaskActivity(tx, &models.TaskActivity{
+            Type:      models.ActivityStatusChanged,
+            TaskID:    task.ID,
+            TaskTitle: task.Title,
+            ActorID:   actorID,
+            Detail:    string(task.Status),
+        })
+        if err != nil {
+            return nil, err
+        }
+    }
+
+    if err := tx.Commit(); err != nil {
+        return nil, fmt.Errorf("failed to update task: %w", err)
+    }
+    
+    return task, nil
+}
+
+func (db *DB) DeleteTask(id string) error {
+    query := `DELETE FROM tasks WHERE id = $1`
+    
+    result, err := db.Exec(query, id)
+    if err != nil {
+        return fmt.Errorf("failed to delete task: %w", err)
+    }
+    
+    rows, _ := result.RowsAffected()
+    if rows == 0 {
+        return fmt.Errorf("task not found")
+    }
+    
+    return nil
+}
+
+func (db *DB) Close() error {
+    return db.DB.Close()
+}
@@ -0,0 +1,137 @@
+package database
+
+import (
+    "database/sql"
+    "fmt"
+    "time"
+    "taskservice/internal/models"
+)
+
+const statsDateLayout = "2006-01-02"
+
+// GetTaskStats aggregates a user's tasks in SQL. from and to are inclusive
+// calendar days bounding the completion, lead time and burndown series.
+func (db *DB) GetTaskStats(userID string, from, to time.Time) (*models.TaskStatsResponse, error) {
+    fromDay := from.Format(statsDateLayout)
+    toDay := to.Format(statsDateLayout)
+
+    stats := &models.TaskStatsResponse{
+        From:             fromDay,
+        To:               toDay,
+        ByStatus:         make(map[models.TaskStatus]int),
+        CompletedPerDay:  []*models.DailyCount{},
+        CompletedPerWeek: []*models.WeeklyCount{},
+        Burndown:         []*models.ProjectBurndown{},
+    }
+
+    summaryQuery := `
+    SELECT COUNT(*),
+           COUNT(*) FILTER (WHERE status = 'pending'),
+           COUNT(*) FILTER (WHERE status = 'in_progress'),
+           COUNT(*) FILTER (WHERE status = 'completed'),
+           COUNT(*) FILTER (WHERE status <> 'completed' AND due_date < CURRENT_TIMESTAMP),
+           AVG(EXTRACT(EPOCH FROM completed_at - created_at) / 3600) FILTER (
+               WHERE completed_at >= $2::timestamp AND completed_at < $3::timestamp + interval '1 day')
+    FROM tasks WHERE user_id = $1`
+
+    var pending, inProgress, completed int
+    var avgLeadTime sql.NullFloat64
+    err := db.QueryRow(summaryQuery, userID, fromDay, toDay).Scan(
+        &stats.Total, &pending, &inProgress, &completed, &stats.Overdue, &avgLeadTime)
+    if err != nil {
+        return nil, fmt.Errorf("failed to query task summary: %w", err)
+    }
+    stats.ByStatus[models.StatusPending] = pending
+    stats.ByStatus[models.StatusInProgress] = inProgress
+    stats.ByStatus[models.StatusCompleted] = completed
+    if avgLeadTime.Valid {
+        stats.AvgLeadTimeHours = &avgLeadTime.Float64
+    }
+
+    perDayQuery := `
+    SELECT to_char(d, 'YYYY-MM-DD'), COUNT(t.id)
+    FROM generate_series($2::timestamp, $3::timestamp, interval '1 day') AS d
+    LEFT JOIN tasks t ON t.user_id = $1
+        AND t.completed_at >= d AND t.completed_at < d + interval '1 day'
+    GROUP BY d ORDER BY d`
+
+    rows, err := db.Query(perDayQuery, userID, fromDay, toDay)
+    if err != nil {
+        return nil, fmt.Errorf("failed to query daily completions: %w", err)
+    }
+    for rows.Next() {
+        var day models.DailyCount
+        if err := rows.Scan(&day.Date, &day.Count); err != nil {
+            rows.Close()
+            return nil, fmt.Errorf("failed to scan daily completions: %w", err)
+        }
+        stats.CompletedPerDay = append(stats.CompletedPerDay, &day)
+    }
+    err = rows.Err()
+    rows.Close()
+    if err != nil {
+        return nil, fmt.Errorf("failed to read daily completions: %w", err)
+    }
+
+    // Weeks start on Monday; the first and last week are clipped to the range
+    perWeekQuery := `
+    SELECT to_char(w, 'YYYY-MM-DD'), COUNT(t.id)
+    FROM generate_series(date_trunc('week', $2::timestamp), $3::timestamp, interval '1 week') AS w
+    LEFT JOIN tasks t ON t.user_id = $1
+        AND t.completed_at >= GREATEST(w, $2::timestamp)
+        AND t.completed_at < LEAST(w + interval '1 week', $3::timestamp + interval '1 day')
+    GROUP BY w ORDER BY w`
+
+    rows, err = db.Query(perWeekQuery, userID, fromDay, toDay)
+    if err != nil {
+        return nil, fmt.Errorf("failed to query weekly completions: %w", err)
+    }
+    for rows.Next() {
+        var week models.WeeklyCount
+        if err := rows.Scan(&week.WeekStart, &week.Count); err != nil {
+            rows.Close()
+            return nil, fmt.Errorf("failed to scan weekly completions: %w", err)
+        }
+        stats.CompletedPerWeek = append(stats.CompletedPerWeek, &week)
+    }
+    err = rows.Err()
+    rows.Close()
+    if err != nil {
+        return nil, fmt.Errorf("failed to read weekly completions: %w", err)
+    }
+
+    // Remaining work at the end of each day: created by then and not yet completed
+    burndownQuery := `
+    SELECT t.project, to_char(d, 'YYYY-MM-DD'),
+           COUNT(*) FILTER (WHERE t.created_at < d + interval '1 day'
+                            AND (t.completed_at IS NULL OR t.completed_at >= d + interval '1 day'))
+    FROM tasks t
+    CROSS JOIN generate_series($2::timestamp, $3::timestamp, interval '1 day') AS d
+    WHERE t.user_id = $1
+      AND t.created_at < $3::timestamp + interval '1 day'
+      AND (t.completed_at IS NULL OR t.completed_at >= $2::timestamp)
+    GROUP BY t.project, d
+    ORDER BY t.project, d`
+
+    rows, err = db.Query(burndownQuery, userID, fromDay, toDay)
+    if err != nil {
+        return nil, fmt.Errorf("failed to query burndown: %w", err)
+    }
+    defer rows.Close()
+
+    var current *models.ProjectBurndown
+    for rows.Next() {
+        var project string
+        var point models.BurndownPoint
+        if err := rows.Scan(&project, &point.Date, &point.Remaining); err != nil {
+            return nil, fmt.Errorf("failed to scan burndown: %w", err)
+        }
+        if current == nil || current.Project != project {
+            current = &models.ProjectBurndown{Project: project}
+            stats.Burndown = append(stats.Burndown, current)
+        }
+        current.Points = append(current.Points, &point)
+    }
+
+    return stats, rows.Err()
+}
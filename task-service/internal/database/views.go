@@ -0,0 +1,226 @@
+package database
+
+import (
+    "database/sql"
+    "encoding/json"
+    "fmt"
+    "strings"
+    "time"
+    "taskservice/internal/models"
+
+    "github.com/lib/pq"
+)
+
+// sortColumns whitelists the columns a saved view may order by
+var sortColumns = map[string]string{
+    "created_at": "created_at",
+    "updated_at": "updated_at",
+    "due_date":   "due_date",
+    "title":      "title",
+    "status":     "status",
+}
+
+func IsValidSortField(field string) bool {
+    _, ok := sortColumns[field]
+    return ok
+}
+
+const savedViewColumns = `id, user_id, name, filter, sort_field, sort_direction, created_at, updated_at`
+
+func scanSavedView(row rowScanner) (*models.SavedView, error) {
+    var view models.SavedView
+    var filterBytes []byte
+    err := row.Scan(&view.ID, &view.UserID, &view.Name, &filterBytes,
+        &view.Sort.Field, &view.Sort.Direction, &view.CreatedAt, &view.UpdatedAt)
+    if err != nil {
+        return nil, err
+    }
+    if err := json.Unmarshal(filterBytes, &view.Filter); err != nil {
+        return nil, fmt.Errorf("failed to decode view filter: %w", err)
+    }
+    return &view, nil
+}
+
+func (db *DB) CreateSavedView(view *models.SavedView) error {
+    filterBytes, err := json.Marshal(view.Filter)
+    if err != nil {
+        return fmt.Errorf("failed to marshal view filter: %w", err)
+    }
+
+    query := `
+    INSERT INTO saved_views (user_id, name, filter, sort_field, sort_direction)
+    VALUES ($1, $2, $3, $4, $5)
+    RETURNING id, created_at, updated_at`
+
+    err = db.QueryRow(query, view.UserID, view.Name, filterBytes, view.Sort.Field, view.Sort.Direction).Scan(
+        &view.ID, &view.CreatedAt, &view.UpdatedAt)
+    if isUniqueViolation(err) {
+        return fmt.Errorf("a view with this name already exists")
+    }
+    if err != nil {
+        return fmt.Errorf("failed to create view: %w", err)
+    }
+
+    return nil
+}
+
+func (db *DB) GetSavedView(userID, id string) (*models.SavedView, error) {
+    query := `SELECT ` + savedViewColumns + ` FROM saved_views WHERE id = $1 AND user_id = $2`
+
+    view, err := scanSavedView(db.QueryRow(query, id, userID))
+    if err == sql.ErrNoRows {
+        return nil, fmt.Errorf("view not found")
+    }
+    if err != nil {
+        return nil, fmt.Errorf("failed to get view: %w", err)
+    }
+
+    return view, nil
+}
+
+func (db *DB) GetSavedViewsByUserID(userID string) ([]*models.SavedView, error) {
+    query := `SELECT ` + savedViewColumns + ` FROM saved_views WHERE user_id = $1 ORDER BY name`
+
+    rows, err := db.Query(query, userID)
+    if err != nil {
+        return nil, fmt.Errorf("failed to query views: %w", err)
+    }
+    defer rows.Close()
+
+    views := []*models.SavedView{}
+    for rows.Next() {
+        view, err := scanSavedView(rows)
+        if err != nil {
+            return nil, fmt.Errorf("failed to scan view: %w", err)
+        }
+        views = append(views, view)
+    }
+
+    return views, rows.Err()
+}
+
+func (db *DB) UpdateSavedView(view *models.SavedView) error {
+    filterBytes, err := json.Marshal(view.Filter)
+    if err != nil {
+        return fmt.Errorf("failed to marshal view filter: %w", err)
+    }
+
+    query := `
+    UPDATE saved_views
+    SET name = $1, filter = $2, sort_field = $3, sort_direction = $4, updated_at = CURRENT_TIMESTAMP
+    WHERE id = $5 AND user_id = $6
+    RETURNING created_at, updated_at`
+
+    err = db.QueryRow(query, view.Name, filterBytes, view.Sort.Field, view.Sort.Direction, view.ID, view.UserID).Scan(
+        &view.CreatedAt, &view.UpdatedAt)
+    if err == sql.ErrNoRows {
+        return fmt.Errorf("view not found")
+    }
+    if isUniqueViolation(err) {
+        return fmt.Errorf("a view with this name already exists")
+    }
+    if err != nil {
+        return fmt.Errorf("failed to update view: %w", err)
+    }
+
+    return nil
+}
+
+func (db *DB) DeleteSavedView(userID, id string) error {
+    result, err := db.Exec(`DELETE FROM saved_views WHERE id = $1 AND user_id = $2`, id, userID)
+    if err != nil {
+        return fmt.Errorf("failed to delete view: %w", err)
+    }
+
+    rows, _ := result.RowsAffected()
+    if rows == 0 {
+        return fmt.Errorf("view not found")
+    }
+
+    return nil
+}
+
+// FindTasks returns one page of the tasks a user owns or is assigned to that
+// match filter, along with the total number of matches
+func (db *DB) FindTasks(userID string, filter models.TaskFilter, sort models.TaskSort, limit, offset int) ([]*models.Task, int, error) {
+    conditions := []string{"(user_id = $1 OR assignee_id = $1)"}
+    args := []interface{}{userID}
+    arg := func(v interface{}) string {
+        args = append(args, v)
+        return fmt.Sprintf("$%d", len(args))
+    }
+
+    if len(filter.Statuses) > 0 {
+        statuses := make([]string, len(filter.Statuses))
+        for i, status := range filter.Statuses {
+            statuses[i] = string(status)
+        }
+        conditions = append(conditions, "status = ANY("+arg(pq.Array(statuses))+")")
+    }
+    if len(filter.Labels) > 0 {
+        conditions = append(conditions, "labels @> "+arg(pq.Array(filter.Labels))+"::text[]")
+    }
+    if filter.DueFrom != nil {
+        conditions = append(conditions, "due_date >= "+arg(*filter.DueFrom))
+    }
+    if filter.DueTo != nil {
+        conditions = append(conditions, "due_date <= "+arg(*filter.DueTo))
+    }
+    if filter.DueWithinDays != nil {
+        now := time.Now()
+        conditions = append(conditions, "due_date >= "+arg(now))
+        conditions = append(conditions, "due_date <= "+arg(now.AddDate(0, 0, *filter.DueWithinDays)))
+    }
+    if filter.AssigneeID != "" {
+        conditions = append(conditions, "assignee_id = "+arg(filter.AssigneeID))
+    }
+
+    column, ok := sortColumns[sort.Field]
+    if !ok {
+        column = "created_at"
+    }
+    direction := "DESC"
+    if sort.Direction == models.SortAsc {
+        direction = "ASC"
+    }
+
+    query := `SELECT ` + taskColumns + `, COUNT(*) OVER ()
+              FROM tasks WHERE ` + strings.Join(conditions, " AND ") + `
+              ORDER BY ` + column + ` ` + direction + ` NULLS LAST, id
+              LIMIT ` + arg(limit) + ` OFFSET ` + arg(offset)
+
+    rows, err := db.Query(query, args...)
+    if err != nil {
+        return nil, 0, fmt.Errorf("failed to query tasks: %w", err)
+    }
+    defer rows.Close()
+
+    tasks := []*models.Task{}
+    total := 0
+    for rows.Next() {
+        task, err := scanTask(rows, &total)
+        if err != nil {
+            return nil, 0, fmt.Errorf("failed to scan task: %w", err)
+        }
+        tasks = append(tasks, task)
+    }
+    if err := rows.Err(); err != nil {
+        return nil, 0, fmt.Errorf("failed to read tasks: %w", err)
+    }
+
+    // A page past the end has no rows to carry the window count
+    if len(tasks) == 0 && offset > 0 {
+        err := db.QueryRow(`SELECT COUNT(*) FROM tasks WHERE `+strings.Join(conditions, " AND "),
+            args[:len(args)-2]...).Scan(&total)
+        if err != nil {
+            return nil, 0, fmt.Errorf("failed to count tasks: %w", err)
+        }
+    }
+
+    return tasks, total, nil
+}
+
+func isUniqueViolation(err error) bool {
+    pqErr, ok := err.(*pq.Error)
+    return ok && pqErr.Code == "23505"
+}
@@ -0,0 +1,209 @@
+package database
+
+import (
+    "database/sql"
+    "fmt"
+    "time"
+    "taskservice/internal/events"
+    "taskservice/internal/models"
+)
+
+func (db *DB) AddWatcher(taskID, userID string) (*models.Watcher, error) {
+    query := `
+    INSERT INTO task_watchers (task_id, user_id)
+    SELECT id, $2 FROM tasks WHERE id = $1
+    ON CONFLICT (task_id, user_id) DO UPDATE SET task_id = EXCLUDED.task_id
+    RETURNING task_id, user_id, created_at`
+
+    var watcher models.Watcher
+    err := db.QueryRow(query, taskID, userID).Scan(&watcher.TaskID, &watcher.UserID, &watcher.CreatedAt)
+    if err != nil {
+        if err == sql.ErrNoRows {
+            return nil, fmt.Errorf("task not found")
+        }
+        return nil, fmt.Errorf("failed to add watcher: %w", err)
+    }
+
+    return &watcher, nil
+}
+
+func (db *DB) RemoveWatcher(taskID, userID string) error {
+    result, err := db.Exec(`DELETE FROM task_watchers WHERE task_id = $1 AND user_id = $2`, taskID, userID)
+    if err != nil {
+        return fmt.Errorf("failed to remove watcher: %w", err)
+    }
+
+    rows, _ := result.RowsAffected()
+    if rows == 0 {
+        return fmt.Errorf("watcher not found")
+    }
+
+    return nil
+}
+
+func (db *DB) GetWatchers(taskID string) ([]*models.Watcher, error) {
+    query := `SELECT task_id, user_id, created_at FROM task_watchers WHERE task_id = $1 ORDER BY created_at`
+
+    rows, err := db.Query(query, taskID)
+    if err != nil {
+        return nil, fmt.Errorf("failed to query watchers: %w", err)
+    }
+    defer rows.Close()
+
+    watchers := []*models.Watcher{}
+    for rows.Next() {
+        var watcher models.Watcher
+        if err := rows.Scan(&watcher.TaskID, &watcher.UserID, &watcher.CreatedAt); err != nil {
+            return nil, fmt.Errorf("failed to scan watcher: %w", err)
+        }
+        watchers = append(watchers, &watcher)
+    }
+
+    return watchers, rows.Err()
+}
+
+// CreateComment stores the comment and, in the same transaction, the activity
+// event that tells the task's other watchers about it
+func (db *DB) CreateComment(comment *models.Comment) error {
+    query := `
+    INSERT INTO task_comments (task_id, user_id, body)
+    SELECT id, $2, $3 FROM tasks WHERE id = $1
+    RETURNING id, created_at, (SELECT title FROM tasks WHERE id = $1)`
+
+    tx, err := db.Begin()
+    if err != nil {
+        return fmt.Errorf("failed to create comment: %w", err)
+    }
+    defer tx.Rollback()
+
+    var taskTitle string
+    err = tx.QueryRow(query, comment.TaskID, comment.UserID, comment.Body).Scan(&comment.ID, &comment.CreatedAt, &taskTitle)
+    if err != nil {
+        if err == sql.ErrNoRows {
+            return fmt.Errorf("task not found")
+        }
+        return fmt.Errorf("failed to create comment: %w", err)
+    }
+
+    err = addTaskActivity(tx, &models.TaskActivity{
+        Type:       models.ActivityCommented,
+        TaskID:     comment.TaskID,
+        TaskTitle:  taskTitle,
+        ActorID:    comment.UserID,
+        Detail:     comment.Body,
+        OccurredAt: comment.CreatedAt,
+    })
+    if err != nil {
+        return err
+    }
+
+    if err := tx.Commit(); err != nil {
+        return fmt.Errorf("failed to create comment: %w", err)
+    }
+    return nil
+}
+
+func (db *DB) GetComments(taskID string) ([]*models.Comment, error) {
+    query := `SELECT id, task_id, user_id, body, created_at FROM task_comments
+              WHERE task_id = $1 ORDER BY created_at`
+
+    rows, err := db.Query(query, taskID)
+    if err != nil {
+        return nil, fmt.Errorf("failed to query comments: %w", err)
+    }
+    defer rows.Close()
+
+    comments := []*models.Comment{}
+    for rows.Next() {
+        var comment models.Comment
+        if err := rows.Scan(&comment.ID, &comment.TaskID, &comment.UserID, &comment.Body, &comment.CreatedAt); err != nil {
+            return nil, fmt.Errorf("failed to scan comment: %w", err)
+        }
+        comments = append(comments, &comment)
+    }
+
+    return comments, rows.Err()
+}
+
+// ClaimOverdueTasks marks tasks that have become overdue since the last check
+// and records an overdue activity event for each, so each one is reported
+// exactly once per due date
+func (db *DB) ClaimOverdueTasks() ([]*models.Task, error) {
+    query := `
+    UPDATE tasks SET overdue_notified_at = CURRENT_TIMESTAMP
+    WHERE due_date < CURRENT_TIMESTAMP AND status <> 'completed' AND overdue_notified_at IS NULL
+    RETURNING ` + taskColumns
+
+    tx, err := db.Begin()
+    if err != nil {
+        return nil, fmt.Errorf("failed to claim overdue tasks: %w", err)
+    }
+    defer tx.Rollback()
+
+    rows, err := tx.Query(query)
+    if err != nil {
+        return nil, fmt.Errorf("failed to claim overdue tasks: %w", err)
+    }
+
+    var tasks []*models.Task
+    for rows.Next() {
+        task, err := scanTask(rows)
+        if err != nil {
+            rows.Close()
+            return nil, fmt.Errorf("failed to scan task: %w", err)
+        }
+        tasks = append(tasks, task)
+    }
+    rows.Close()
+    if err := rows.Err(); err != nil {
+        return nil, fmt.Errorf("failed to claim overdue tasks: %w", err)
+    }
+
+    for _, task := range tasks {
+        err := addTaskActivity(tx, &models.TaskActivity{
+            Type:      models.ActivityOverdue,
+            TaskID:    task.ID,
+            TaskTitle: task.Title,
+        })
+        if err != nil {
+            return nil, err
+        }
+    }
+
+    if err := tx.Commit(); err != nil {
+        return nil, fmt.Errorf("failed to claim overdue tasks: %w", err)
+    }
+    return tasks, nil
+}
+
+// addTaskActivity addresses the activity to the task's watchers, except the
+// user who caused it, and records it in the outbox. Nothing is recorded when
+// nobody else watches the task.
+func addTaskActivity(tx *sql.Tx, activity *models.TaskActivity) error {
+    rows, err := tx.Query(`SELECT user_id FROM task_watchers WHERE task_id = $1 AND user_id::text <> $2 ORDER BY created_at`,
+        activity.TaskID, activity.ActorID)
+    if err != nil {
+        return fmt.Errorf("failed to query watchers: %w", err)
+    }
+    defer rows.Close()
+
+    activity.Recipients = nil
+    for rows.Next() {
+        var userID string
+        if err := rows.Scan(&userID); err != nil {
+            return fmt.Errorf("failed to scan watcher: %w", err)
+        }
+        activity.Recipients = append(activity.Recipients, userID)
+    }
+    if err := rows.Err(); err != nil {
+        return fmt.Errorf("failed to query watchers: %w", err)
+    }
+    if len(activity.Recipients) == 0 {
+        return nil
+    }
+
+    if activity.OccurredAt.IsZero() {
+        activity.OccurredAt = time.Now()
+    }
+    return addOutboxEvent(tx, events.TaskActivity, activity.TaskID, activity)
+}
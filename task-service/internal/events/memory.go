@@ -0,0 +1,102 @@
+package events
+
+import (
+    "context"
+    "errors"
+    "fmt"
+    "sync"
+)
+
+// MemoryBus delivers messages to subscribers in the same process. Handlers
+// run synchronously inside Publish and their errors are returned to the
+// publisher, so an outbox relay keeps an event until it has been handled.
+type MemoryBus struct {
+    mu     sync.Mutex
+    subs   []*memorySubscription
+    next   map[string]int
+    closed bool
+}
+
+type memorySubscription struct {
+    bus     *MemoryBus
+    subject string
+    queue   string
+    handler Handler
+}
+
+func NewMemoryBus() *MemoryBus {
+    return &MemoryBus{next: make(map[string]int)}
+}
+
+func (b *MemoryBus) Publish(ctx context.Context, msg Message) error {
+    b.mu.Lock()
+    if b.closed {
+        b.mu.Unlock()
+        return errors.New("bus is closed")
+    }
+
+    // Every plain subscriber gets the message; each queue group gets it once,
+    // round robin between its members
+    var targets []*memorySubscription
+    groups := make(map[string][]*memorySubscription)
+    var order []string
+    for _, sub := range b.subs {
+        if !MatchSubject(sub.subject, msg.Subject) {
+            continue
+        }
+        if sub.queue == "" {
+            targets = append(targets, sub)
+            continue
+        }
+        if _, ok := groups[sub.queue]; !ok {
+            order = append(order, sub.queue)
+        }
+        groups[sub.queue] = append(groups[sub.queue], sub)
+    }
+    for _, queue := range order {
+        members := groups[queue]
+        targets = append(targets, members[b.next[queue]%len(members)])
+        b.next[queue]++
+    }
+    b.mu.Unlock()
+
+    var errs []error
+    for _, sub := range targets {
+        if err := sub.handler(ctx, msg); err != nil {
+            errs = append(errs, fmt.Errorf("%s: %w", sub.subject, err))
+        }
+    }
+    return errors.Join(errs...)
+}
+
+func (b *MemoryBus) Subscribe(subject, queue string, handler Handler) (Subscription, error) {
+    b.mu.Lock()
+    defer b.mu.Unlock()
+    if b.closed {
+        return nil, errors.New("bus is closed")
+    }
+
+    sub := &memorySubscription{bus: b, subject: subject, queue: queue, handler: handler}
+    b.subs = append(b.subs, sub)
+    return sub, nil
+}
+
+func (b *MemoryBus) Close() error {
+    b.mu.Lock()
+    defer b.mu.Unlock()
+    b.closed = true
+    b.subs = nil
+    return nil
+}
+
+func (s *memorySubscription) Unsubscribe() error {
+    s.bus.mu.Lock()
+    defer s.bus.mu.Unlock()
+    for i, sub := range s.bus.subs {
+        if sub == s {
+            s.bus.subs = append(s.bus.subs[:i], s.bus.subs[i+1:]...)
+            break
+        }
+    }
+    return nil
+}
@@ -0,0 +1,407 @@
+package handlers
+
+import (
+    "encoding/csv"
+    "encoding/json"
+    "fmt"
+    "io"
+    "log"
+    "mime"
+    "net/http"
+    "strings"
+    "time"
+    "taskservice/internal/models"
+
+    "github.com/gorilla/mux"
+)
+
+const (
+    maxImportBytes = 10 << 20
+    maxImportRows  = 5000
+)
+
+var exportColumns = []string{
+    "id", "external_id", "title", "description", "status",
+    "user_id", "due_date", "project", "labels", "assignee_id",
+    "priority", "recurrence", "completed_at", "created_at", "updated_at",
+}
+
+// ExportTasks streams all of a user's tasks as CSV or JSON
+func (h *TaskHandler) ExportTasks(w http.ResponseWriter, r *http.Request) {
+    userID := mux.Vars(r)["user_id"]
+
+    format := r.URL.Query().Get("format")
+    if format == "" {
+        format = "json"
+    }
+
+    var err error
+    switch format {
+    case "csv":
+        w.Header().Set("Content-Type", "text/csv; charset=utf-8")
+        w.Header().Set("Content-Disposition", `attachment; filename="tasks.csv"`)
+        err = h.exportCSV(w, userID)
+    case "json":
+        w.Header().Set("Content-Type", "application/json")
+        w.Header().Set("Content-Disposition", `attachment; filename="tasks.json"`)
+        err = h.exportJSON(w, userID)
+    default:
+        http.Error(w, `{"error": "Invalid format. Must be csv or json"}`, http.StatusBadRequest)
+        return
+    }
+
+    // Headers are already sent once streaming starts, so a failure can only be logged
+    if err != nil {
+        log.Printf("❌ Task export failed for user %s: %v", userID, err)
+    }
+}
+
+func (h *TaskHandler) exportCSV(w io.Writer, userID string) error {
+    cw := csv.NewWriter(w)
+    if err := cw.Write(exportColumns); err != nil {
+        return err
+    }
+
+    err := h.db.StreamTasksByUserID(userID, func(task *models.Task) error {
+        return cw.Write([]string{
+            task.ID,
+            stringValue(task.ExternalID),
+            task.Title,
+            task.Description,
+            string(task.Status),
+            task.UserID,
+            formatTime(task.DueDate),
+            task.Project,
+            strings.Join(task.Labels, ","),
+            stringValue(task.AssigneeID),
+            string(task.Priority),
+            task.Recurrence,
+            formatTime(task.CompletedAt),
+            task.CreatedAt.Format(time.RFC3339),
+            task.UpdatedAt.Format(time.RFC3339),
+        })
+    })
+    if err != nil {
+        return err
+    }
+
+    cw.Flush()
+    return cw.Error()
+}
+
+func (h *TaskHandler) exportJSON(w io.Writer, userID string) error {
+    if _, err := io.WriteString(w, "["); err != nil {
+        return err
+    }
+
+    enc := json.NewEncoder(w)
+    first := true
+    err := h.db.StreamTasksByUserID(userID, func(task *models.Task) error {
+        if !first {
+            if _, err := io.WriteString(w, ","); err != nil {
+                return err
+            }
+        }
+        first = false
+        return enc.Encode(task)
+    })
+    if err != nil {
+        return err
+    }
+
+    _, err = io.WriteString(w, "]\n")
+    return err
+}
+
+// ImportTasks creates tasks for a user from a CSV or JSON file. Every row is
+// validated and reported individually; rows whose external_id already exists,
+// or whose exported id is one of the user's tasks, are skipped. With
+// dry_run=true nothing is written.
+func (h *TaskHandler) ImportTasks(w http.ResponseWriter, r *http.Request) {
+    userID := mux.Vars(r)["user_id"]
+    dryRun := r.URL.Query().Get("dry_run") == "true"
+
+    format := r.URL.Query().Get("format")
+    if format == "" {
+        format = importFormatFromContentType(r.Header.Get("Content-Type"))
+    }
+
+    body := http.MaxBytesReader(w, r.Body, maxImportBytes)
+
+    var records []models.ImportTaskRecord
+    var err error
+    switch format {
+    case "csv":
+        records, err = parseCSVImport(body)
+    case "json":
+        records, err = parseJSONImport(body)
+    default:
+        http.Error(w, `{"error": "Invalid format. Must be csv or json"}`, http.StatusBadRequest)
+        return
+    }
+    if err != nil {
+        http.Error(w, `{"error": "`+jsonEscape(err.Error())+`"}`, http.StatusBadRequest)
+        return
+    }
+
+    if len(records) > maxImportRows {
+        http.Error(w, fmt.Sprintf(`{"error": "Too many rows, at most %d are allowed"}`, maxImportRows), http.StatusRequestEntityTooLarge)
+        return
+    }
+
+    existing, err := h.db.GetExternalIDs(userID)
+    if err != nil {
+        http.Error(w, `{"error": "`+err.Error()+`"}`, http.StatusInternalServerError)
+        return
+    }
+
+    response := models.ImportResponse{
+        DryRun: dryRun,
+        Total:  len(records),
+        Rows:   make([]*models.ImportRowResult, 0, len(records)),
+    }
+
+    for i, record := range records {
+        result := &models.ImportRowResult{Row: i + 1, ExternalID: record.ExternalID}
+        response.Rows = append(response.Rows, result)
+
+        task, errs := taskFromImportRecord(userID, record)
+        if len(errs) > 0 {
+            result.Status = models.ImportRowInvalid
+            result.Errors = errs
+            response.Invalid++
+            continue
+        }
+
+        if record.ExternalID != "" {
+            if taskID, ok := existing[record.ExternalID]; ok {
+                result.Status = models.ImportRowDuplicate
+                result.TaskID = taskID
+                response.Duplicates++
+                continue
+            }
+        }
+
+        if dryRun {
+            result.Status = models.ImportRowWouldCreate
+        } else {
+            if err := h.db.CreateTask(task); err != nil {
+                result.Status = models.ImportRowFailed
+                result.Errors = []string{err.Error()}
+                response.Failed++
+                continue
+            }
+            result.Status = models.ImportRowCreated
+            result.TaskID = task.ID
+            response.Created++
+        }
+
+        // Later rows repeating this external_id are duplicates of this one
+        if record.ExternalID != "" {
+            existing[record.ExternalID] = task.ID
+        }
+    }
+
+    w.Header().Set("Content-Type", "application/json")
+    if err := json.NewEncoder(w).Encode(response); err != nil {
+        http.Error(w, `{"error": "Error encoding response"}`, http.StatusInternalServerError)
+    }
+}
+
+func taskFromImportRecord(userID string, record models.ImportTaskRecord) (*models.Task, []string) {
+    var errs []string
+
+    title := strings.TrimSpace(record.Title)
+    if title == "" {
+        errs = append(errs, "title is required")
+    } else if len(title) > 255 {
+        errs = append(errs, "title must be at most 255 characters")
+    }
+
+    if len(record.ExternalID) > 255 {
+        errs = append(errs, "external_id must be at most 255 characters")
+    }
+
+    if record.AssigneeID != "" && !uuidPattern.MatchString(record.AssigneeID) {
+        errs = append(errs, "assignee_id must be a UUID")
+    }
+
+    if record.Priority != "" && !record.Priority.IsValid() {
+        errs = append(errs, fmt.Sprintf("invalid priority %q, must be low, medium, high, or urgent", record.Priority))
+    }
+
+    if len(record.Project) > 100 {
+        errs = append(errs, "project must be at most 100 characters")
+    }
+
+    status := record.Status
+    if status == "" {
+        status = models.StatusPending
+    } else if !status.IsValid() {
+        errs = append(errs, fmt.Sprintf("invalid status %q, must be pending, in_progress, or completed", status))
+    }
+
+    var dueDate *time.Time
+    if record.DueDate != "" {
+        parsed, err := parseImportDate(record.DueDate)
+        if err != nil {
+            errs = append(errs, fmt.Sprintf("invalid due_date %q, use RFC 3339 or YYYY-MM-DD", record.DueDate))
+        } else {
+            dueDate = &parsed
+        }
+    }
+
+    if len(errs) > 0 {
+        return nil, errs
+    }
+
+    task := &models.Task{
+        Title:       title,
+        Description: record.Description,
+        Status:      status,
+        UserID:      userID,
+        DueDate:     dueDate,
+        Project:     record.Project,
+        Labels:      record.Labels,
+        Priority:    record.Priority,
+        Recurrence:  record.Recurrence,
+    }
+    if record.AssigneeID != "" {
+        assigneeID := record.AssigneeID
+        task.AssigneeID = &assigneeID
+    }
+    if record.ExternalID != "" {
+        externalID := record.ExternalID
+        task.ExternalID = &externalID
+    }
+    return task, nil
+}
+
+func parseCSVImport(r io.Reader) ([]models.ImportTaskRecord, error) {
+    cr := csv.NewReader(r)
+    cr.FieldsPerRecord = -1
+    cr.TrimLeadingSpace = true
+
+    header, err := cr.Read()
+    if err == io.EOF {
+        return nil, fmt.Errorf("CSV file is empty")
+    }
+    if err != nil {
+        return nil, fmt.Errorf("failed to read CSV header: %v", err)
+    }
+
+    columns := make(map[string]int, len(header))
+    for i, name := range header {
+        columns[strings.ToLower(strings.TrimSpace(name))] = i
+    }
+    if _, ok := columns["title"]; !ok {
+        return nil, fmt.Errorf("CSV header must include a title column")
+    }
+
+    field := func(row []string, name string) string {
+        if i, ok := columns[name]; ok && i < len(row) {
+            return strings.TrimSpace(row[i])
+        }
+        return ""
+    }
+
+    var records []models.ImportTaskRecord
+    for {
+        row, err := cr.Read()
+        if err == io.EOF {
+            break
+        }
+        if err != nil {
+            return nil, fmt.Errorf("failed to read CSV: %v", err)
+        }
+
+        records = append(records, models.ImportTaskRecord{
+            ExternalID:  importExternalID(field(row, "external_id"), field(row, "id")),
+            Title:       field(row, "title"),
+            Description: field(row, "description"),
+            Status:      models.TaskStatus(field(row, "status")),
+            DueDate:     field(row, "due_date"),
+            Project:     field(row, "project"),
+            Labels:      splitLabels(field(row, "labels")),
+            AssigneeID:  field(row, "assignee_id"),
+            Priority:    models.TaskPriority(field(row, "priority")),
+            Recurrence:  field(row, "recurrence"),
+        })
+    }
+
+    return records, nil
+}
+
+func parseJSONImport(r io.Reader) ([]models.ImportTaskRecord, error) {
+    // Accept the export format as-is, so an export can be re-imported elsewhere
+    var rows []struct {
+        models.ImportTaskRecord
+        ID string `json:"id"`
+    }
+    if err := json.NewDecoder(r).Decode(&rows); err != nil {
+        return nil, fmt.Errorf("invalid JSON, expected an array of tasks: %v", err)
+    }
+
+    records := make([]models.ImportTaskRecord, len(rows))
+    for i, row := range rows {
+        records[i] = row.ImportTaskRecord
+        records[i].ExternalID = importExternalID(row.ExternalID, row.ID)
+    }
+    return records, nil
+}
+
+// importExternalID falls back to the exported task ID, so re-importing an
+// export is idempotent even for tasks that were never imported themselves
+func importExternalID(externalID, id string) string {
+    if externalID != "" {
+        return externalID
+    }
+    return id
+}
+
+func importFormatFromContentType(contentType string) string {
+    mediaType, _, _ := mime.ParseMediaType(contentType)
+    switch mediaType {
+    case "text/csv", "application/csv":
+        return "csv"
+    case "application/json", "":
+        return "json"
+    }
+    return mediaType
+}
+
+func splitLabels(value string) []string {
+    var labels []string
+    for _, label := range strings.Split(value, ",") {
+        if label = strings.TrimSpace(label); label != "" {
+            labels = append(labels, label)
+        }
+    }
+    return labels
+}
+
+func parseImportDate(value string) (time.Time, error) {
+    if t, err := time.Parse(time.RFC3339, value); err == nil {
+        return t, nil
+    }
+    return time.Parse("2006-01-02", value)
+}
+
+func formatTime(t *time.Time) string {
+    if t == nil {
+        return ""
+    }
+    return t.Format(time.RFC3339)
+}
+
+func stringValue(s *string) string {
+    if s == nil {
+        return ""
+    }
+    return *s
+}
+
+func jsonEscape(s string) string {
+    b, _ := json.Marshal(s)
+    return string(b[1 : len(b)-1])
+}
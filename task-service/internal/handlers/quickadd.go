@@ -0,0 +1,78 @@
+package handlers
+
+import (
+    "encoding/json"
+    "net/http"
+    "strings"
+    "time"
+    "taskservice/internal/models"
+    "taskservice/internal/quickadd"
+)
+
+// QuickAdd parses a free-text task such as "Call mom tomorrow 6pm #family !high"
+// and returns the parsed fields. With create=true the task is also created.
+func (h *TaskHandler) QuickAdd(w http.ResponseWriter, r *http.Request) {
+    var req models.QuickAddRequest
+    if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+        http.Error(w, `{"error": "Invalid request body"}`, http.StatusBadRequest)
+        return
+    }
+
+    if strings.TrimSpace(req.Text) == "" {
+        http.Error(w, `{"error": "Text is required"}`, http.StatusBadRequest)
+        return
+    }
+    if req.UserID == "" {
+        req.UserID = actorID(r)
+    }
+    if req.Create && req.UserID == "" {
+        http.Error(w, `{"error": "user_id is required to create the task"}`, http.StatusBadRequest)
+        return
+    }
+
+    loc := time.UTC
+    if req.Timezone != "" {
+        var err error
+        if loc, err = time.LoadLocation(req.Timezone); err != nil {
+            http.Error(w, `{"error": "Invalid timezone"}`, http.StatusBadRequest)
+            return
+        }
+    }
+
+    parsed, err := quickadd.Parse(req.Text, time.Now(), loc)
+    if err != nil {
+        http.Error(w, `{"error": "`+jsonEscape(err.Error())+`"}`, http.StatusBadRequest)
+        return
+    }
+
+    response := models.QuickAddResponse{Parsed: parsed}
+    status := http.StatusOK
+
+    if req.Create {
+        task := &models.Task{
+            Title:      parsed.Title,
+            UserID:     req.UserID,
+            Labels:     parsed.Labels,
+            Priority:   parsed.Priority,
+            Recurrence: parsed.Recurrence,
+            Status:     models.StatusPending,
+        }
+        if parsed.DueDate != nil {
+            due := parsed.DueDate.UTC()
+            task.DueDate = &due
+        }
+
+        if err := h.db.CreateTask(task); err != nil {
+            http.Error(w, `{"error": "`+err.Error()+`"}`, http.StatusInternalServerError)
+            return
+        }
+        response.Task = task
+        status = http.StatusCreated
+    }
+
+    w.Header().Set("Content-Type", "application/json")
+    w.WriteHeader(status)
+    if err := json.NewEncoder(w).Encode(response); err != nil {
+        http.Error(w, `{"error": "Error encoding response"}`, http.StatusInternalServerError)
+    }
+}
@@ -0,0 +1,60 @@
+package handlers
+
+import (
+    "encoding/json"
+    "net/http"
+    "time"
+
+    "github.com/gorilla/mux"
+)
+
+const (
+    defaultStatsDays = 30
+    maxStatsDays     = 366
+)
+
+// GetTaskStats returns the productivity dashboard for a user. The optional
+// from and to query parameters (YYYY-MM-DD, inclusive) default to the last 30 days.
+func (h *TaskHandler) GetTaskStats(w http.ResponseWriter, r *http.Request) {
+    userID := mux.Vars(r)["user_id"]
+
+    to := time.Now().UTC().Truncate(24 * time.Hour)
+    if toStr := r.URL.Query().Get("to"); toStr != "" {
+        parsed, err := time.Parse("2006-01-02", toStr)
+        if err != nil {
+            http.Error(w, `{"error": "Invalid to date. Use YYYY-MM-DD"}`, http.StatusBadRequest)
+            return
+        }
+        to = parsed
+    }
+
+    from := to.AddDate(0, 0, -(defaultStatsDays - 1))
+    if fromStr := r.URL.Query().Get("from"); fromStr != "" {
+        parsed, err := time.Parse("2006-01-02", fromStr)
+        if err != nil {
+            http.Error(w, `{"error": "Invalid from date. Use YYYY-MM-DD"}`, http.StatusBadRequest)
+            return
+        }
+        from = parsed
+    }
+
+    if from.After(to) {
+        http.Error(w, `{"error": "from must not be after to"}`, http.StatusBadRequest)
+        return
+    }
+    if to.Sub(from) >= maxStatsDays*24*time.Hour {
+        http.Error(w, `{"error": "Date range must not exceed 366 days"}`, http.StatusBadRequest)
+        return
+    }
+
+    stats, err := h.db.GetTaskStats(userID, from, to)
+    if err != nil {
+        http.Error(w, `{"error": "`+err.Error()+`"}`, http.StatusInternalServerError)
+        return
+    }
+
+    w.Header().Set("Content-Type", "application/json")
+    if err := json.NewEncoder(w).Encode(stats); err != nil {
+        http.Error(w, `{"error": "Error encoding response"}`, http.StatusInternalServerError)
+    }
+}
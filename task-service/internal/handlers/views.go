@@ -0,0 +1,226 @@
+package handlers
+
+import (
+    "encoding/json"
+    "fmt"
+    "net/http"
+    "regexp"
+    "strconv"
+    "strings"
+    "taskservice/internal/database"
+    "taskservice/internal/models"
+
+    "github.com/gorilla/mux"
+)
+
+const (
+    defaultPageSize = 50
+    maxPageSize     = 200
+)
+
+var uuidPattern = regexp.MustCompile(`^[0-9a-fA-F]{8}-[0-9a-fA-F]{4}-[0-9a-fA-F]{4}-[0-9a-fA-F]{4}-[0-9a-fA-F]{12}$`)
+
+func (h *TaskHandler) CreateSavedView(w http.ResponseWriter, r *http.Request) {
+    userID := mux.Vars(r)["user_id"]
+
+    view, ok := decodeSavedView(w, r)
+    if !ok {
+        return
+    }
+    view.UserID = userID
+
+    if err := h.db.CreateSavedView(view); err != nil {
+        http.Error(w, `{"error": "`+err.Error()+`"}`, savedViewErrorStatus(err))
+        return
+    }
+
+    w.Header().Set("Content-Type", "application/json")
+    w.WriteHeader(http.StatusCreated)
+    if err := json.NewEncoder(w).Encode(models.SavedViewResponse{View: view}); err != nil {
+        http.Error(w, `{"error": "Error encoding response"}`, http.StatusInternalServerError)
+    }
+}
+
+func (h *TaskHandler) GetSavedViews(w http.ResponseWriter, r *http.Request) {
+    userID := mux.Vars(r)["user_id"]
+
+    views, err := h.db.GetSavedViewsByUserID(userID)
+    if err != nil {
+        http.Error(w, `{"error": "`+err.Error()+`"}`, http.StatusInternalServerError)
+        return
+    }
+
+    w.Header().Set("Content-Type", "application/json")
+    if err := json.NewEncoder(w).Encode(models.SavedViewsResponse{Views: views, Total: len(views)}); err != nil {
+        http.Error(w, `{"error": "Error encoding response"}`, http.StatusInternalServerError)
+    }
+}
+
+func (h *TaskHandler) GetSavedView(w http.ResponseWriter, r *http.Request) {
+    vars := mux.Vars(r)
+
+    view, err := h.db.GetSavedView(vars["user_id"], vars["id"])
+    if err != nil {
+        http.Error(w, `{"error": "`+err.Error()+`"}`, http.StatusNotFound)
+        return
+    }
+
+    w.Header().Set("Content-Type", "application/json")
+    if err := json.NewEncoder(w).Encode(models.SavedViewResponse{View: view}); err != nil {
+        http.Error(w, `{"error": "Error encoding response"}`, http.StatusInternalServerError)
+    }
+}
+
+func (h *TaskHandler) UpdateSavedView(w http.ResponseWriter, r *http.Request) {
+    vars := mux.Vars(r)
+
+    view, ok := decodeSavedView(w, r)
+    if !ok {
+        return
+    }
+    view.ID = vars["id"]
+    view.UserID = vars["user_id"]
+
+    if err := h.db.UpdateSavedView(view); err != nil {
+        http.Error(w, `{"error": "`+err.Error()+`"}`, savedViewErrorStatus(err))
+        return
+    }
+
+    w.Header().Set("Content-Type", "application/json")
+    if err := json.NewEncoder(w).Encode(models.SavedViewResponse{View: view}); err != nil {
+        http.Error(w, `{"error": "Error encoding response"}`, http.StatusInternalServerError)
+    }
+}
+
+func (h *TaskHandler) DeleteSavedView(w http.ResponseWriter, r *http.Request) {
+    vars := mux.Vars(r)
+
+    if err := h.db.DeleteSavedView(vars["user_id"], vars["id"]); err != nil {
+        http.Error(w, `{"error": "`+err.Error()+`"}`, http.StatusNotFound)
+        return
+    }
+
+    w.Header().Set("Content-Type", "application/json")
+    w.WriteHeader(http.StatusNoContent)
+}
+
+// GetSavedViewTasks runs a saved view and returns one page of matching tasks
+func (h *TaskHandler) GetSavedViewTasks(w http.ResponseWriter, r *http.Request) {
+    vars := mux.Vars(r)
+
+    view, err := h.db.GetSavedView(vars["user_id"], vars["id"])
+    if err != nil {
+        http.Error(w, `{"error": "`+err.Error()+`"}`, http.StatusNotFound)
+        return
+    }
+
+    limit, offset := pagination(r)
+    tasks, total, err := h.db.FindTasks(view.UserID, view.Filter, view.Sort, limit, offset)
+    if err != nil {
+        http.Error(w, `{"error": "`+err.Error()+`"}`, http.StatusInternalServerError)
+        return
+    }
+
+    response := models.PagedTasksResponse{
+        Tasks:  tasks,
+        Total:  total,
+        Limit:  limit,
+        Offset: offset,
+    }
+
+    w.Header().Set("Content-Type", "application/json")
+    if err := json.NewEncoder(w).Encode(response); err != nil {
+        http.Error(w, `{"error": "Error encoding response"}`, http.StatusInternalServerError)
+    }
+}
+
+// decodeSavedView reads and validates a view definition, writing the error
+// response itself when the request is rejected
+func decodeSavedView(w http.ResponseWriter, r *http.Request) (*models.SavedView, bool) {
+    var req models.SavedViewRequest
+    if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+        http.Error(w, `{"error": "Invalid request body"}`, http.StatusBadRequest)
+        return nil, false
+    }
+
+    view := &models.SavedView{
+        Name:   strings.TrimSpace(req.Name),
+        Filter: req.Filter,
+        Sort:   models.TaskSort{Field: "created_at", Direction: models.SortDesc},
+    }
+    if req.Sort != nil {
+        view.Sort = *req.Sort
+    }
+
+    if err := validateSavedView(view); err != nil {
+        http.Error(w, `{"error": "`+jsonEscape(err.Error())+`"}`, http.StatusBadRequest)
+        return nil, false
+    }
+
+    return view, true
+}
+
+func validateSavedView(view *models.SavedView) error {
+    if view.Name == "" || len(view.Name) > 100 {
+        return fmt.Errorf("name is required and must be at most 100 characters")
+    }
+
+    for _, status := range view.Filter.Statuses {
+        if !status.IsValid() {
+            return fmt.Errorf("invalid status %q, must be pending, in_progress, or completed", status)
+        }
+    }
+
+    if view.Filter.AssigneeID != "" && !uuidPattern.MatchString(view.Filter.AssigneeID) {
+        return fmt.Errorf("assignee_id must be a UUID")
+    }
+
+    if view.Filter.DueFrom != nil && view.Filter.DueTo != nil && view.Filter.DueFrom.After(*view.Filter.DueTo) {
+        return fmt.Errorf("due_from must not be after due_to")
+    }
+
+    if view.Filter.DueWithinDays != nil && *view.Filter.DueWithinDays < 0 {
+        return fmt.Errorf("due_within_days must not be negative")
+    }
+
+    if !database.IsValidSortField(view.Sort.Field) {
+        return fmt.Errorf("invalid sort field %q, must be created_at, updated_at, due_date, title, or status", view.Sort.Field)
+    }
+
+    if view.Sort.Direction == "" {
+        view.Sort.Direction = models.SortDesc
+    }
+    if view.Sort.Direction != models.SortAsc && view.Sort.Direction != models.SortDesc {
+        return fmt.Errorf("sort direction must be asc or desc")
+    }
+
+    return nil
+}
+
+func savedViewErrorStatus(err error) int {
+    switch err.Error() {
+    case "view not found":
+        return http.StatusNotFound
+    case "a view with this name already exists":
+        return http.StatusConflict
+    }
+    return http.StatusInternalServerError
+}
+
+func pagination(r *http.Request) (int, int) {
+    limit := defaultPageSize
+    offset := 0
+
+    if l, err := strconv.Atoi(r.URL.Query().Get("limit")); err == nil && l > 0 {
+        limit = l
+    }
+    if limit > maxPageSize {
+        limit = maxPageSize
+    }
+
+    if o, err := strconv.Atoi(r.URL.Query().Get("offset")); err == nil && o >= 0 {
+        offset = o
+    }
+
+    return limit, offset
+}
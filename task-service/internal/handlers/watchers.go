@@ -0,0 +1,158 @@
+package handlers
+
+import (
+    "encoding/json"
+    "log"
+    "net/http"
+    "strings"
+    "taskservice/internal/models"
+
+    "github.com/gorilla/mux"
+)
+
+// actorID is the authenticated user the API gateway forwards with each request
+func actorID(r *http.Request) string {
+    return r.Header.Get("X-User-ID")
+}
+
+func (h *TaskHandler) WatchTask(w http.ResponseWriter, r *http.Request) {
+    taskID := mux.Vars(r)["id"]
+
+    var req models.WatchRequest
+    if r.ContentLength != 0 {
+        if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+            http.Error(w, `{"error": "Invalid request body"}`, http.StatusBadRequest)
+            return
+        }
+    }
+    // Users watch tasks for themselves; only service calls, which carry no
+    // user, may name someone else
+    if actor := actorID(r); actor != "" {
+        if req.UserID != "" && req.UserID != actor {
+            http.Error(w, `{"error": "Cannot add another user as a watcher"}`, http.StatusForbidden)
+            return
+        }
+        req.UserID = actor
+    }
+    if !uuidPattern.MatchString(req.UserID) {
+        http.Error(w, `{"error": "A valid user_id is required"}`, http.StatusBadRequest)
+        return
+    }
+
+    watcher, err := h.db.AddWatcher(taskID, req.UserID)
+    if err != nil {
+        status := http.StatusInternalServerError
+        if err.Error() == "task not found" {
+            status = http.StatusNotFound
+        }
+        http.Error(w, `{"error": "`+err.Error()+`"}`, status)
+        return
+    }
+
+    w.Header().Set("Content-Type", "application/json")
+    w.WriteHeader(http.StatusCreated)
+    if err := json.NewEncoder(w).Encode(models.WatcherResponse{Watcher: watcher}); err != nil {
+        http.Error(w, `{"error": "Error encoding response"}`, http.StatusInternalServerError)
+    }
+}
+
+func (h *TaskHandler) UnwatchTask(w http.ResponseWriter, r *http.Request) {
+    vars := mux.Vars(r)
+    if actor := actorID(r); actor != "" && actor != vars["user_id"] {
+        http.Error(w, `{"error": "Cannot remove another user as a watcher"}`, http.StatusForbidden)
+        return
+    }
+
+    if err := h.db.RemoveWatcher(vars["id"], vars["user_id"]); err != nil {
+        http.Error(w, `{"error": "`+err.Error()+`"}`, http.StatusNotFound)
+        return
+    }
+
+    w.Header().Set("Content-Type", "application/json")
+    w.WriteHeader(http.StatusNoContent)
+}
+
+func (h *TaskHandler) GetWatchers(w http.ResponseWriter, r *http.Request) {
+    taskID := mux.Vars(r)["id"]
+
+    watchers, err := h.db.GetWatchers(taskID)
+    if err != nil {
+        http.Error(w, `{"error": "`+err.Error()+`"}`, http.StatusInternalServerError)
+        return
+    }
+
+    w.Header().Set("Content-Type", "application/json")
+    if err := json.NewEncoder(w).Encode(models.WatchersResponse{Watchers: watchers, Total: len(watchers)}); err != nil {
+        http.Error(w, `{"error": "Error encoding response"}`, http.StatusInternalServerError)
+    }
+}
+
+func (h *TaskHandler) CreateComment(w http.ResponseWriter, r *http.Request) {
+    taskID := mux.Vars(r)["id"]
+
+    var req models.CreateCommentRequest
+    if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+        http.Error(w, `{"error": "Invalid request body"}`, http.StatusBadRequest)
+        return
+    }
+    if actor := actorID(r); actor != "" {
+        req.UserID = actor
+    }
+
+    if strings.TrimSpace(req.Body) == "" || !uuidPattern.MatchString(req.UserID) {
+        http.Error(w, `{"error": "Body and a valid user_id are required"}`, http.StatusBadRequest)
+        return
+    }
+    if !uuidPattern.MatchString(taskID) {
+        http.Error(w, `{"error": "task not found"}`, http.StatusNotFound)
+        return
+    }
+
+    comment := &models.Comment{
+        TaskID: taskID,
+        UserID: req.UserID,
+        Body:   req.Body,
+    }
+    if err := h.db.CreateComment(comment); err != nil {
+        status := http.StatusInternalServerError
+        if err.Error() == "task not found" {
+            status = http.StatusNotFound
+        }
+        http.Error(w, `{"error": "`+err.Error()+`"}`, status)
+        return
+    }
+
+    w.Header().Set("Content-Type", "application/json")
+    w.WriteHeader(http.StatusCreated)
+    if err := json.NewEncoder(w).Encode(models.CommentResponse{Comment: comment}); err != nil {
+        http.Error(w, `{"error": "Error encoding response"}`, http.StatusInternalServerError)
+    }
+}
+
+func (h *TaskHandler) GetComments(w http.ResponseWriter, r *http.Request) {
+    taskID := mux.Vars(r)["id"]
+
+    comments, err := h.db.GetComments(taskID)
+    if err != nil {
+        http.Error(w, `{"error": "`+err.Error()+`"}`, http.StatusInternalServerError)
+        return
+    }
+
+    w.Header().Set("Content-Type", "application/json")
+    if err := json.NewEncoder(w).Encode(models.CommentsResponse{Comments: comments, Total: len(comments)}); err != nil {
+        http.Error(w, `{"error": "Error encoding response"}`, http.StatusInternalServerError)
+    }
+}
+
+// CheckOverdueTasks records overdue activity for tasks that have become
+// overdue; the outbox relay delivers it to their watchers
+func (h *TaskHandler) CheckOverdueTasks() {
+    tasks, err := h.db.ClaimOverdueTasks()
+    if err != nil {
+        log.Printf("❌ Overdue check failed: %v", err)
+        return
+    }
+    if len(tasks) > 0 {
+        log.Printf("⏰ %d tasks became overdue", len(tasks))
+    }
+}
@@ -0,0 +1,17 @@
+package models
+
+import (
+    "encoding/json"
+    "time"
+)
+
+// OutboxEvent is a domain event waiting in the outbox to be published
+type OutboxEvent struct {
+    ID          int64           `json:"id"`
+    EventID     string          `json:"event_id"`
+    Type        string          `json:"type"`
+    AggregateID string          `json:"aggregate_id"`
+    Payload     json.RawMessage `json:"payload"`
+    CreatedAt   time.Time       `json:"created_at"`
+    Attempts    int             `json:"attempts"`
+}
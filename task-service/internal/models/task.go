@@ -1,47 +1,296 @@
-package models
-
-import (
-    "time"
-)
-
-type TaskStatus string
-
-const (
-    StatusPending   TaskStatus = "pending"
-    StatusInProgress TaskStatus = "in_progress"
-    StatusCompleted TaskStatus = "completed"
-)
-
-type Task struct {
-    ID          string     `json:"id"`
-    Title       string     `json:"title"`
-    Description string     `json:"description"`
-    Status      TaskStatus `json:"status"`
-    UserID      string     `json:"user_id"`
-    DueDate     *time.Time `json:"due_date,omitempty"`
-    CreatedAt   time.Time  `json:"created_at"`
-    UpdatedAt   time.Time  `json:"updated_at"`
-}
-
-type CreateTaskRequest struct {
-    Title       string     `json:"title"`
-    Description string     `json:"description"`
-    UserID      string     `json:"user_id"`
-    DueDate     *time.Time `json:"due_date,omitempty"`
-}
-
-type UpdateTaskRequest struct {
-    Title       string     `json:"title,omitempty"`
-    Description string     `json:"description,omitempty"`
-    Status      TaskStatus `json:"status,omitempty"`
-    DueDate     *time.Time `json:"due_date,omitempty"`
-}
-
-type TaskResponse struct {
-    Task *Task `json:"task"`
-}
-
-type TasksResponse struct {
-    Tasks []*Task `json:"tasks"`
-    Total int     `json:"total"`
-}
+package models
+
+import (
+    "time"
+)
+
+type TaskStatus string
+
+const (
+    StatusPending   TaskStatus = "pending"
+    StatusInProgress TaskStatus = "in_progress"
+    StatusCompleted TaskStatus = "completed"
+)
+
+func (s TaskStatus) IsValid() bool {
+    return s == StatusPending || s == StatusInProgress || s == StatusCompleted
+}
+
+type TaskPriority string
+
+const (
+    PriorityLow    TaskPriority = "low"
+    PriorityMedium TaskPriority = "medium"
+    PriorityHigh   TaskPriority = "high"
+    PriorityUrgent TaskPriority = "urgent"
+)
+
+func (p TaskPriority) IsValid() bool {
+    return p == PriorityLow || p == PriorityMedium || p == PriorityHigh || p == PriorityUrgent
+}
+
+type Task struct {
+    ID          string       `json:"id"`
+    Title       string       `json:"title"`
+    Description string       `json:"description"`
+    Status      TaskStatus   `json:"status"`
+    UserID      string       `json:"user_id"`
+    DueDate     *time.Time   `json:"due_date,omitempty"`
+    ExternalID  *string      `json:"external_id,omitempty"`
+    Project     string       `json:"project,omitempty"`
+    Labels      []string     `json:"labels"`
+    AssigneeID  *string      `json:"assignee_id,omitempty"`
+    Priority    TaskPriority `json:"priority"`
+    // Recurrence is an RFC 5545 RRULE value such as FREQ=MONTHLY;BYMONTHDAY=1
+    Recurrence  string       `json:"recurrence,omitempty"`
+    CompletedAt *time.Time   `json:"completed_at,omitempty"`
+    CreatedAt   time.Time    `json:"created_at"`
+    UpdatedAt   time.Time    `json:"updated_at"`
+}
+
+type CreateTaskRequest struct {
+    Title       string       `json:"title"`
+    Description string       `json:"description"`
+    UserID      string       `json:"user_id"`
+    DueDate     *time.Time   `json:"due_date,omitempty"`
+    ExternalID  *string      `json:"external_id,omitempty"`
+    Project     string       `json:"project,omitempty"`
+    Labels      []string     `json:"labels,omitempty"`
+    AssigneeID  *string      `json:"assignee_id,omitempty"`
+    Priority    TaskPriority `json:"priority,omitempty"`
+    Recurrence  string       `json:"recurrence,omitempty"`
+}
+
+type UpdateTaskRequest struct {
+    Title       string       `json:"title,omitempty"`
+    Description string       `json:"description,omitempty"`
+    Status      TaskStatus   `json:"status,omitempty"`
+    DueDate     *time.Time   `json:"due_date,omitempty"`
+    Project     *string      `json:"project,omitempty"`
+    Labels      []string     `json:"labels,omitempty"`
+    // AssigneeID is left unchanged when omitted and cleared when empty
+    AssigneeID  *string      `json:"assignee_id,omitempty"`
+    Priority    TaskPriority `json:"priority,omitempty"`
+    Recurrence  *string      `json:"recurrence,omitempty"`
+}
+
+type TaskResponse struct {
+    Task *Task `json:"task"`
+}
+
+type TasksResponse struct {
+    Tasks []*Task `json:"tasks"`
+    Total int     `json:"total"`
+}
+
+// ImportTaskRecord is one task as it appears in an import file
+type ImportTaskRecord struct {
+    ExternalID  string       `json:"external_id"`
+    Title       string       `json:"title"`
+    Description string       `json:"description"`
+    Status      TaskStatus   `json:"status,omitempty"`
+    DueDate     string       `json:"due_date,omitempty"`
+    Project     string       `json:"project,omitempty"`
+    Labels      []string     `json:"labels,omitempty"`
+    AssigneeID  string       `json:"assignee_id,omitempty"`
+    Priority    TaskPriority `json:"priority,omitempty"`
+    Recurrence  string       `json:"recurrence,omitempty"`
+}
+
+type ImportRowStatus string
+
+const (
+    ImportRowCreated     ImportRowStatus = "created"
+    ImportRowWouldCreate ImportRowStatus = "would_create"
+    ImportRowDuplicate   ImportRowStatus = "duplicate"
+    ImportRowInvalid     ImportRowStatus = "invalid"
+    ImportRowFailed      ImportRowStatus = "failed"
+)
+
+type ImportRowResult struct {
+    Row        int             `json:"row"`
+    ExternalID string          `json:"external_id,omitempty"`
+    Status     ImportRowStatus `json:"status"`
+    TaskID     string          `json:"task_id,omitempty"`
+    Errors     []string        `json:"errors,omitempty"`
+}
+
+type ImportResponse struct {
+    DryRun     bool               `json:"dry_run"`
+    Total      int                `json:"total"`
+    Created    int                `json:"created"`
+    Duplicates int                `json:"duplicates"`
+    Invalid    int                `json:"invalid"`
+    Failed     int                `json:"failed"`
+    Rows       []*ImportRowResult `json:"rows"`
+}
+
+type DailyCount struct {
+    Date  string `json:"date"`
+    Count int    `json:"count"`
+}
+
+type WeeklyCount struct {
+    WeekStart string `json:"week_start"`
+    Count     int    `json:"count"`
+}
+
+type BurndownPoint struct {
+    Date      string `json:"date"`
+    Remaining int    `json:"remaining"`
+}
+
+type ProjectBurndown struct {
+    Project string           `json:"project"`
+    Points  []*BurndownPoint `json:"points"`
+}
+
+type TaskStatsResponse struct {
+    From             string             `json:"from"`
+    To               string             `json:"to"`
+    Total            int                `json:"total"`
+    ByStatus         map[TaskStatus]int `json:"by_status"`
+    Overdue          int                `json:"overdue"`
+    CompletedPerDay  []*DailyCount      `json:"completed_per_day"`
+    CompletedPerWeek []*WeeklyCount     `json:"completed_per_week"`
+    AvgLeadTimeHours *float64           `json:"avg_lead_time_hours"`
+    Burndown         []*ProjectBurndown `json:"burndown"`
+}
+
+type SortDirection string
+
+const (
+    SortAsc  SortDirection = "asc"
+    SortDesc SortDirection = "desc"
+)
+
+// TaskFilter narrows the tasks a user owns or is assigned to. Empty fields
+// do not filter; labels must all be present on a task to match.
+type TaskFilter struct {
+    Statuses      []TaskStatus `json:"statuses,omitempty"`
+    Labels        []string     `json:"labels,omitempty"`
+    DueFrom       *time.Time   `json:"due_from,omitempty"`
+    DueTo         *time.Time   `json:"due_to,omitempty"`
+    // DueWithinDays matches tasks due between now and now+N days at execution time
+    DueWithinDays *int         `json:"due_within_days,omitempty"`
+    AssigneeID    string       `json:"assignee_id,omitempty"`
+}
+
+type TaskSort struct {
+    Field     string        `json:"field"`
+    Direction SortDirection `json:"direction"`
+}
+
+type SavedView struct {
+    ID        string     `json:"id"`
+    UserID    string     `json:"user_id"`
+    Name      string     `json:"name"`
+    Filter    TaskFilter `json:"filter"`
+    Sort      TaskSort   `json:"sort"`
+    CreatedAt time.Time  `json:"created_at"`
+    UpdatedAt time.Time  `json:"updated_at"`
+}
+
+type SavedViewRequest struct {
+    Name   string     `json:"name"`
+    Filter TaskFilter `json:"filter"`
+    Sort   *TaskSort  `json:"sort,omitempty"`
+}
+
+type SavedViewResponse struct {
+    View *SavedView `json:"view"`
+}
+
+type SavedViewsResponse struct {
+    Views []*SavedView `json:"views"`
+    Total int          `json:"total"`
+}
+
+type PagedTasksResponse struct {
+    Tasks  []*Task `json:"tasks"`
+    Total  int     `json:"total"`
+    Limit  int     `json:"limit"`
+    Offset int     `json:"offset"`
+}
+
+type Watcher struct {
+    TaskID    string    `json:"task_id"`
+    UserID    string    `json:"user_id"`
+    CreatedAt time.Time `json:"created_at"`
+}
+
+type WatchRequest struct {
+    UserID string `json:"user_id"`
+}
+
+type WatcherResponse struct {
+    Watcher *Watcher `json:"watcher"`
+}
+
+type WatchersResponse struct {
+    Watchers []*Watcher `json:"watchers"`
+    Total    int        `json:"total"`
+}
+
+type Comment struct {
+    ID        string    `json:"id"`
+    TaskID    string    `json:"task_id"`
+    UserID    string    `json:"user_id"`
+    Body      string    `json:"body"`
+    CreatedAt time.Time `json:"created_at"`
+}
+
+type CreateCommentRequest struct {
+    UserID string `json:"user_id"`
+    Body   string `json:"body"`
+}
+
+type CommentResponse struct {
+    Comment *Comment `json:"comment"`
+}
+
+type CommentsResponse struct {
+    Comments []*Comment `json:"comments"`
+    Total    int        `json:"total"`
+}
+
+type ActivityType string
+
+const (
+    ActivityStatusChanged ActivityType = "status_changed"
+    ActivityCommented     ActivityType = "commented"
+    ActivityOverdue       ActivityType = "overdue"
+)
+
+// TaskActivity is a change to a watched task, published to notify the
+// watchers listed in Recipients
+type TaskActivity struct {
+    Type       ActivityType `json:"type"`
+    TaskID     string       `json:"task_id"`
+    TaskTitle  string       `json:"task_title"`
+    ActorID    string       `json:"actor_id,omitempty"`
+    Detail     string       `json:"detail,omitempty"`
+    OccurredAt time.Time    `json:"occurred_at"`
+    Recipients []string     `json:"recipients"`
+}
+
+type QuickAddRequest struct {
+    Text     string `json:"text"`
+    UserID   string `json:"user_id"`
+    // Timezone is an IANA name used to resolve relative dates, UTC by default
+    Timezone string `json:"timezone,omitempty"`
+    Create   bool   `json:"create"`
+}
+
+type QuickAddPreview struct {
+    Title      string       `json:"title"`
+    DueDate    *time.Time   `json:"due_date,omitempty"`
+    Recurrence string       `json:"recurrence,omitempty"`
+    Labels     []string     `json:"labels"`
+    Priority   TaskPriority `json:"priority,omitempty"`
+}
+
+type QuickAddResponse struct {
+    Parsed *QuickAddPreview `json:"parsed"`
+    Task   *Task            `json:"task,omitempty"`
+}
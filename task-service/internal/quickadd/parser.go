@@ -0,0 +1,636 @@
+package quickadd
+
+import (
+    "fmt"
+    "regexp"
+    "strconv"
+    "strings"
+    "time"
+    "taskservice/internal/models"
+)
+
+// Parse turns free text such as
+//
+//     Pay rent every month on the 1st #finance !high tomorrow 9am
+//
+// into task fields. Recognised phrases are removed and whatever remains
+// becomes the title. Relative dates are resolved against now in loc; a due
+// date without a time of day means the end of that day.
+func Parse(text string, now time.Time, loc *time.Location) (*models.QuickAddPreview, error) {
+    p := newParser(text, now.In(loc))
+    p.parse()
+
+    title := p.title()
+    if title == "" {
+        return nil, fmt.Errorf("text must contain a title besides dates, labels and priority")
+    }
+
+    preview := &models.QuickAddPreview{
+        Title:      title,
+        DueDate:    p.dueDate(),
+        Recurrence: p.rrule(),
+        Labels:     p.labels,
+        Priority:   p.priority,
+    }
+    if preview.Labels == nil {
+        preview.Labels = []string{}
+    }
+    return preview, nil
+}
+
+var (
+    labelPattern   = regexp.MustCompile(`^#([\pL\pN_\-/]+)$`)
+    clockPattern   = regexp.MustCompile(`^(\d{1,2})(?::(\d{2}))?(am|pm)?$`)
+    isoDatePattern = regexp.MustCompile(`^\d{4}-\d{2}-\d{2}$`)
+    ordinalPattern = regexp.MustCompile(`^(\d{1,2})(st|nd|rd|th)?$`)
+    trailingPunct  = regexp.MustCompile(`[,.;]+$`)
+)
+
+var priorities = map[string]models.TaskPriority{
+    "low":    models.PriorityLow,
+    "medium": models.PriorityMedium,
+    "med":    models.PriorityMedium,
+    "normal": models.PriorityMedium,
+    "high":   models.PriorityHigh,
+    "urgent": models.PriorityUrgent,
+}
+
+var weekdays = map[string]time.Weekday{
+    "sunday": time.Sunday, "sun": time.Sunday,
+    "monday": time.Monday, "mon": time.Monday,
+    "tuesday": time.Tuesday, "tue": time.Tuesday, "tues": time.Tuesday,
+    "wednesday": time.Wednesday, "wed": time.Wednesday,
+    "thursday": time.Thursday, "thu": time.Thursday, "thurs": time.Thursday,
+    "friday": time.Friday, "fri": time.Friday,
+    "saturday": time.Saturday, "sat": time.Saturday,
+}
+
+var rruleDays = map[time.Weekday]string{
+    time.Sunday: "SU", time.Monday: "MO", time.Tuesday: "TU", time.Wednesday: "WE",
+    time.Thursday: "TH", time.Friday: "FR", time.Saturday: "SA",
+}
+
+var months = map[string]time.Month{
+    "january": time.January, "jan": time.January,
+    "february": time.February, "feb": time.February,
+    "march": time.March, "mar": time.March,
+    "april": time.April, "apr": time.April,
+    "may": time.May,
+    "june": time.June, "jun": time.June,
+    "july": time.July, "jul": time.July,
+    "august": time.August, "aug": time.August,
+    "september": time.September, "sep": time.September, "sept": time.September,
+    "october": time.October, "oct": time.October,
+    "november": time.November, "nov": time.November,
+    "december": time.December, "dec": time.December,
+}
+
+var units = map[string]string{
+    "day": "DAILY", "days": "DAILY",
+    "week": "WEEKLY", "weeks": "WEEKLY",
+    "month": "MONTHLY", "months": "MONTHLY",
+    "year": "YEARLY", "years": "YEARLY",
+}
+
+type parser struct {
+    now    time.Time
+    tokens []string
+    words  []string
+    used   []bool
+
+    labels   []string
+    priority models.TaskPriority
+
+    date    *time.Time
+    hasTime bool
+    hour    int
+    minute  int
+
+    freq       string
+    interval   int
+    byDay      []time.Weekday
+    byMonthDay int
+}
+
+func newParser(text string, now time.Time) *parser {
+    tokens := strings.Fields(text)
+    words := make([]string, len(tokens))
+    for i, token := range tokens {
+        words[i] = trailingPunct.ReplaceAllString(strings.ToLower(token), "")
+    }
+    return &parser{
+        now:    now,
+        tokens: tokens,
+        words:  words,
+        used:   make([]bool, len(tokens)),
+    }
+}
+
+func (p *parser) parse() {
+    for i := 0; i < len(p.tokens); i++ {
+        if p.used[i] {
+            continue
+        }
+        matchers := []func(int) int{p.label, p.priorityMarker, p.recurrence, p.datePhrase, p.timePhrase}
+        for _, match := range matchers {
+            if n := match(i); n > 0 {
+                p.consume(i, n)
+                i += n - 1
+                break
+            }
+        }
+    }
+}
+
+func (p *parser) consume(start, n int) {
+    for i := start; i < start+n && i < len(p.used); i++ {
+        p.used[i] = true
+    }
+}
+
+// word returns the normalised token at i, or "" past the end or if consumed
+func (p *parser) word(i int) string {
+    if i < 0 || i >= len(p.words) || p.used[i] {
+        return ""
+    }
+    return p.words[i]
+}
+
+func (p *parser) title() string {
+    var parts []string
+    for i, token := range p.tokens {
+        if !p.used[i] {
+            parts = append(parts, token)
+        }
+    }
+    return strings.TrimSpace(strings.Join(parts, " "))
+}
+
+func (p *parser) label(i int) int {
+    m := labelPattern.FindStringSubmatch(trailingPunct.ReplaceAllString(p.tokens[i], ""))
+    if m == nil {
+        return 0
+    }
+    label := strings.ToLower(m[1])
+    for _, existing := range p.labels {
+        if existing == label {
+            return 1
+        }
+    }
+    p.labels = append(p.labels, label)
+    return 1
+}
+
+func (p *parser) priorityMarker(i int) int {
+    word := p.word(i)
+    if !strings.HasPrefix(word, "!") {
+        return 0
+    }
+    priority, ok := priorities[strings.TrimPrefix(word, "!")]
+    if !ok {
+        return 0
+    }
+    p.priority = priority
+    return 1
+}
+
+// recurrence matches "daily", "every week", "every 2 months", "every other day",
+// "every weekday", "every monday and thursday", optionally followed by an
+// anchor such as "on the 1st" or "on friday"
+func (p *parser) recurrence(i int) int {
+    if p.freq != "" {
+        return 0
+    }
+
+    n := 0
+    switch p.word(i) {
+    case "daily":
+        p.freq, n = "DAILY", 1
+    case "weekly":
+        p.freq, n = "WEEKLY", 1
+    case "monthly":
+        p.freq, n = "MONTHLY", 1
+    case "yearly", "annually":
+        p.freq, n = "YEARLY", 1
+    case "every":
+        n = p.everyPhrase(i + 1)
+        if n == 0 {
+            return 0
+        }
+        n++
+    default:
+        return 0
+    }
+
+    return n + p.recurrenceAnchor(i+n)
+}
+
+func (p *parser) everyPhrase(i int) int {
+    word := p.word(i)
+
+    if freq, ok := units[word]; ok {
+        p.freq = freq
+        return 1
+    }
+
+    if word == "weekday" || word == "weekdays" {
+        p.freq = "WEEKLY"
+        p.byDay = []time.Weekday{time.Monday, time.Tuesday, time.Wednesday, time.Thursday, time.Friday}
+        return 1
+    }
+
+    if word == "other" {
+        if freq, ok := units[p.word(i + 1)]; ok {
+            p.freq, p.interval = freq, 2
+            return 2
+        }
+        return 0
+    }
+
+    if interval, err := strconv.Atoi(word); err == nil && interval > 0 {
+        if freq, ok := units[p.word(i + 1)]; ok {
+            p.freq, p.interval = freq, interval
+            return 2
+        }
+        return 0
+    }
+
+    if n := p.weekdayList(i); n > 0 {
+        p.freq = "WEEKLY"
+        return n
+    }
+
+    return 0
+}
+
+// weekdayList matches "monday", "mon, wed and fri" and similar
+func (p *parser) weekdayList(i int) int {
+    n := 0
+    for {
+        day, ok := weekdays[strings.TrimSuffix(p.word(i+n), "s")]
+        if !ok {
+            if day, ok = weekdays[p.word(i + n)]; !ok {
+                break
+            }
+        }
+        p.byDay = append(p.byDay, day)
+        n++
+        if p.word(i+n) == "and" || p.word(i+n) == "&" {
+            if _, ok := weekdays[strings.TrimSuffix(p.word(i+n+1), "s")]; ok {
+                n++
+            }
+        }
+    }
+    return n
+}
+
+func (p *parser) recurrenceAnchor(i int) int {
+    if p.word(i) != "on" {
+        return 0
+    }
+
+    switch p.freq {
+    case "MONTHLY":
+        j := i + 1
+        if p.word(j) == "the" {
+            j++
+        }
+        if day := ordinal(p.word(j)); day > 0 {
+            p.byMonthDay = day
+            return j - i + 1
+        }
+    case "WEEKLY":
+        if len(p.byDay) == 0 {
+            if n := p.weekdayList(i + 1); n > 0 {
+                return n + 1
+            }
+        }
+    }
+    return 0
+}
+
+// datePhrase matches an optional "on", "by" or "due" followed by a date.
+// Abbreviated weekday and month names such as "sun" or "mar" are ordinary
+// words too, so without the preposition only full names are dates.
+func (p *parser) datePhrase(i int) int {
+    if p.date != nil {
+        return 0
+    }
+
+    switch p.word(i) {
+    case "on", "by", "due":
+        if n := p.dateValue(i+1, true); n > 0 {
+            return n + 1
+        }
+        return 0
+    }
+    return p.dateValue(i, false)
+}
+
+// dateValue matches a date; abbreviated names only count when prefixed
+func (p *parser) dateValue(i int, prefixed bool) int {
+    today := startOfDay(p.now)
+    word := p.word(i)
+
+    switch word {
+    case "today":
+        p.setDate(today)
+        return 1
+    case "tonight":
+        p.setDate(today)
+        if !p.hasTime {
+            p.setTime(20, 0)
+        }
+        return 1
+    case "tomorrow", "tmr", "tmrw":
+        p.setDate(today.AddDate(0, 0, 1))
+        return 1
+    case "next":
+        next := p.word(i + 1)
+        switch next {
+        case "week":
+            p.setDate(nextWeekday(today.AddDate(0, 0, 1), time.Monday))
+            return 2
+        case "month":
+            p.setDate(time.Date(today.Year(), today.Month()+1, 1, 0, 0, 0, 0, today.Location()))
+            return 2
+        case "year":
+            p.setDate(time.Date(today.Year()+1, time.January, 1, 0, 0, 0, 0, today.Location()))
+            return 2
+        }
+        if day, ok := weekdays[next]; ok {
+            p.setDate(nextWeekday(today.AddDate(0, 0, 1), day))
+            return 2
+        }
+        return 0
+    case "in":
+        amount := p.word(i + 1)
+        count, err := strconv.Atoi(amount)
+        if amount == "a" || amount == "an" {
+            count, err = 1, nil
+        }
+        if err != nil || count <= 0 {
+            return 0
+        }
+        switch units[p.word(i+2)] {
+        case "DAILY":
+            p.setDate(today.AddDate(0, 0, count))
+        case "WEEKLY":
+            p.setDate(today.AddDate(0, 0, 7*count))
+        case "MONTHLY":
+            p.setDate(today.AddDate(0, count, 0))
+        case "YEARLY":
+            p.setDate(today.AddDate(count, 0, 0))
+        default:
+            return 0
+        }
+        return 3
+    }
+
+    if day, ok := weekdays[word]; ok && (prefixed || word == strings.ToLower(day.String())) {
+        p.setDate(nextWeekday(today, day))
+        return 1
+    }
+
+    if isoDatePattern.MatchString(word) {
+        if t, err := time.ParseInLocation("2006-01-02", word, p.now.Location()); err == nil {
+            p.setDate(t)
+            return 1
+        }
+    }
+
+    // "nov 3", "november 3rd, 2027"
+    if month, ok := months[word]; ok && (prefixed || word == strings.ToLower(month.String())) {
+        if day := ordinal(p.word(i + 1)); day > 0 {
+            year, n := p.year(i+2), 2
+            if year > 0 {
+                n = 3
+            }
+            p.setDate(p.calendarDate(year, month, day))
+            return n
+        }
+    }
+
+    // "3 nov", "3rd of november 2027"
+    if day := ordinal(word); day > 0 {
+        j := i + 1
+        if p.word(j) == "of" {
+            j++
+        }
+        if month, ok := months[p.word(j)]; ok && (prefixed || p.word(j) == strings.ToLower(month.String())) {
+            year, n := p.year(j+1), j-i+1
+            if year > 0 {
+                n++
+            }
+            p.setDate(p.calendarDate(year, month, day))
+            return n
+        }
+    }
+
+    return 0
+}
+
+// timePhrase matches "9am", "9:30 pm", "17:00", "noon" and "midnight",
+// optionally preceded by "at"
+func (p *parser) timePhrase(i int) int {
+    if p.hasTime {
+        return 0
+    }
+
+    if p.word(i) == "at" {
+        if n := p.timeValue(i + 1); n > 0 {
+            return n + 1
+        }
+        return 0
+    }
+    return p.timeValue(i)
+}
+
+func (p *parser) timeValue(i int) int {
+    word := p.word(i)
+    switch word {
+    case "noon":
+        p.setTime(12, 0)
+        return 1
+    case "midnight":
+        p.setTime(0, 0)
+        return 1
+    }
+
+    m := clockPattern.FindStringSubmatch(word)
+    if m == nil {
+        return 0
+    }
+
+    n := 1
+    meridiem := m[3]
+    if meridiem == "" {
+        if next := p.word(i + 1); next == "am" || next == "pm" {
+            meridiem, n = next, 2
+        }
+    }
+    // A bare number is only a time with am/pm or minutes, otherwise it is part of the title
+    if meridiem == "" && m[2] == "" {
+        return 0
+    }
+
+    hour, _ := strconv.Atoi(m[1])
+    minute := 0
+    if m[2] != "" {
+        minute, _ = strconv.Atoi(m[2])
+    }
+    if minute > 59 {
+        return 0
+    }
+
+    switch meridiem {
+    case "am":
+        if hour < 1 || hour > 12 {
+            return 0
+        }
+        if hour == 12 {
+            hour = 0
+        }
+    case "pm":
+        if hour < 1 || hour > 12 {
+            return 0
+        }
+        if hour != 12 {
+            hour += 12
+        }
+    default:
+        if hour > 23 {
+            return 0
+        }
+    }
+
+    p.setTime(hour, minute)
+    return n
+}
+
+func (p *parser) year(i int) int {
+    year, err := strconv.Atoi(p.word(i))
+    if err != nil || year < 1970 || year > 9999 {
+        return 0
+    }
+    return year
+}
+
+// calendarDate resolves a month and day without a year to its next occurrence
+func (p *parser) calendarDate(year int, month time.Month, day int) time.Time {
+    loc := p.now.Location()
+    if year > 0 {
+        return time.Date(year, month, day, 0, 0, 0, 0, loc)
+    }
+    t := time.Date(p.now.Year(), month, day, 0, 0, 0, 0, loc)
+    if t.Before(startOfDay(p.now)) {
+        t = t.AddDate(1, 0, 0)
+    }
+    return t
+}
+
+func (p *parser) setDate(t time.Time) {
+    p.date = &t
+}
+
+func (p *parser) setTime(hour, minute int) {
+    p.hasTime, p.hour, p.minute = true, hour, minute
+}
+
+func (p *parser) dueDate() *time.Time {
+    var day time.Time
+    switch {
+    case p.date != nil:
+        day = *p.date
+    case p.freq != "":
+        day = p.firstOccurrence(startOfDay(p.now))
+        if p.hasTime && time.Date(day.Year(), day.Month(), day.Day(), p.hour, p.minute, 0, 0, day.Location()).Before(p.now) {
+            day = p.firstOccurrence(startOfDay(p.now).AddDate(0, 0, 1))
+        }
+    case p.hasTime:
+        day = startOfDay(p.now)
+        at := time.Date(day.Year(), day.Month(), day.Day(), p.hour, p.minute, 0, 0, day.Location())
+        if at.Before(p.now) {
+            day = day.AddDate(0, 0, 1)
+        }
+    default:
+        return nil
+    }
+
+    var due time.Time
+    if p.hasTime {
+        due = time.Date(day.Year(), day.Month(), day.Day(), p.hour, p.minute, 0, 0, day.Location())
+    } else {
+        due = time.Date(day.Year(), day.Month(), day.Day(), 23, 59, 59, 0, day.Location())
+    }
+    return &due
+}
+
+// firstOccurrence is the first day on or after today matching the recurrence
+func (p *parser) firstOccurrence(today time.Time) time.Time {
+    if p.byMonthDay > 0 {
+        for m := 0; m < 12; m++ {
+            first := time.Date(today.Year(), today.Month()+time.Month(m), 1, 0, 0, 0, 0, today.Location())
+            candidate := first.AddDate(0, 0, p.byMonthDay-1)
+            if candidate.Month() == first.Month() && !candidate.Before(today) {
+                return candidate
+            }
+        }
+    }
+
+    if len(p.byDay) > 0 {
+        best := nextWeekday(today, p.byDay[0])
+        for _, day := range p.byDay[1:] {
+            if candidate := nextWeekday(today, day); candidate.Before(best) {
+                best = candidate
+            }
+        }
+        return best
+    }
+
+    return today
+}
+
+// rrule renders the recurrence as an RFC 5545 RRULE value
+func (p *parser) rrule() string {
+    if p.freq == "" {
+        return ""
+    }
+
+    parts := []string{"FREQ=" + p.freq}
+    if p.interval > 1 {
+        parts = append(parts, "INTERVAL="+strconv.Itoa(p.interval))
+    }
+    if len(p.byDay) > 0 {
+        days := make([]string, len(p.byDay))
+        for i, day := range p.byDay {
+            days[i] = rruleDays[day]
+        }
+        parts = append(parts, "BYDAY="+strings.Join(days, ","))
+    }
+    if p.byMonthDay > 0 {
+        parts = append(parts, "BYMONTHDAY="+strconv.Itoa(p.byMonthDay))
+    }
+    return strings.Join(parts, ";")
+}
+
+func ordinal(word string) int {
+    m := ordinalPattern.FindStringSubmatch(word)
+    if m == nil {
+        return 0
+    }
+    day, _ := strconv.Atoi(m[1])
+    if day < 1 || day > 31 {
+        return 0
+    }
+    return day
+}
+
+func startOfDay(t time.Time) time.Time {
+    return time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, t.Location())
+}
+
+// nextWeekday returns the first day on or after from that falls on day
+func nextWeekday(from time.Time, day time.Weekday) time.Time {
+    return from.AddDate(0, 0, (int(day)-int(from.Weekday())+7)%7)
+}
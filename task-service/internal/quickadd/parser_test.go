@@ -0,0 +1,123 @@
+package quickadd
+
+import (
+    "reflect"
+    "testing"
+    "time"
+    "taskservice/internal/models"
+)
+
+// now is a Monday morning
+var now = time.Date(2026, time.October, 19, 10, 0, 0, 0, time.UTC)
+
+func endOfDay(year int, month time.Month, day int) *time.Time {
+    t := time.Date(year, month, day, 23, 59, 59, 0, time.UTC)
+    return &t
+}
+
+func at(year int, month time.Month, day, hour, minute int) *time.Time {
+    t := time.Date(year, month, day, hour, minute, 0, 0, time.UTC)
+    return &t
+}
+
+func TestParse(t *testing.T) {
+    tests := []struct {
+        text       string
+        title      string
+        due        *time.Time
+        recurrence string
+        labels     []string
+        priority   models.TaskPriority
+    }{
+        {text: "Wear sun hat", title: "Wear sun hat"},
+        {text: "Buy 3 mar bars", title: "Buy 3 mar bars"},
+        {text: "Sat exam prep wed", title: "Sat exam prep wed"},
+        {text: "Wear sun hat on sun", title: "Wear sun hat", due: endOfDay(2026, time.October, 25)},
+        {text: "Call mom sunday", title: "Call mom", due: endOfDay(2026, time.October, 25)},
+        {text: "Submit report due fri", title: "Submit report", due: endOfDay(2026, time.October, 23)},
+        {text: "Submit report next fri", title: "Submit report", due: endOfDay(2026, time.October, 23)},
+        {text: "Dentist by mar 3", title: "Dentist", due: endOfDay(2027, time.March, 3)},
+        {text: "Dentist march 3 9am", title: "Dentist", due: at(2027, time.March, 3, 9, 0)},
+        {text: "Renew passport 3rd of november 2027", title: "Renew passport", due: endOfDay(2027, time.November, 3)},
+        {text: "Call home in 2 days", title: "Call home", due: endOfDay(2026, time.October, 21)},
+        {text: "Book flights tomorrow at 5:30 pm", title: "Book flights", due: at(2026, time.October, 20, 17, 30)},
+        {text: "Water plants 9am", title: "Water plants", due: at(2026, time.October, 20, 9, 0)},
+        {text: "Read 2 chapters", title: "Read 2 chapters"},
+        {
+            text:       "Pay rent every month on the 1st #finance !high",
+            title:      "Pay rent",
+            due:        endOfDay(2026, time.November, 1),
+            recurrence: "FREQ=MONTHLY;BYMONTHDAY=1",
+            labels:     []string{"finance"},
+            priority:   models.PriorityHigh,
+        },
+        {
+            text:       "Standup every mon and wed at 9:30am",
+            title:      "Standup",
+            due:        at(2026, time.October, 21, 9, 30),
+            recurrence: "FREQ=WEEKLY;BYDAY=MO,WE",
+        },
+        {
+            text:       "Review backups every other week #ops #Ops",
+            title:      "Review backups",
+            due:        endOfDay(2026, time.October, 19),
+            recurrence: "FREQ=WEEKLY;INTERVAL=2",
+            labels:     []string{"ops"},
+        },
+    }
+
+    for _, tt := range tests {
+        t.Run(tt.text, func(t *testing.T) {
+            preview, err := Parse(tt.text, now, time.UTC)
+            if err != nil {
+                t.Fatalf("Parse returned error: %v", err)
+            }
+            if preview.Title != tt.title {
+                t.Errorf("title = %q, want %q", preview.Title, tt.title)
+            }
+            switch {
+            case tt.due == nil && preview.DueDate != nil:
+                t.Errorf("due = %v, want none", *preview.DueDate)
+            case tt.due != nil && preview.DueDate == nil:
+                t.Errorf("due = none, want %v", *tt.due)
+            case tt.due != nil && !preview.DueDate.Equal(*tt.due):
+                t.Errorf("due = %v, want %v", *preview.DueDate, *tt.due)
+            }
+            if preview.Recurrence != tt.recurrence {
+                t.Errorf("recurrence = %q, want %q", preview.Recurrence, tt.recurrence)
+            }
+            labels := tt.labels
+            if labels == nil {
+                labels = []string{}
+            }
+            if !reflect.DeepEqual(preview.Labels, labels) {
+                t.Errorf("labels = %v, want %v", preview.Labels, labels)
+            }
+            if preview.Priority != tt.priority {
+                t.Errorf("priority = %q, want %q", preview.Priority, tt.priority)
+            }
+        })
+    }
+}
+
+func TestParseResolvesDatesInLocation(t *testing.T) {
+    loc := time.FixedZone("UTC+10", 10*60*60)
+
+    // 20:00 UTC on Monday is already Tuesday in loc
+    preview, err := Parse("Call bank tomorrow", time.Date(2026, time.October, 19, 20, 0, 0, 0, time.UTC), loc)
+    if err != nil {
+        t.Fatalf("Parse returned error: %v", err)
+    }
+    want := time.Date(2026, time.October, 21, 23, 59, 59, 0, loc)
+    if preview.DueDate == nil || !preview.DueDate.Equal(want) {
+        t.Errorf("due = %v, want %v", preview.DueDate, want)
+    }
+}
+
+func TestParseRequiresTitle(t *testing.T) {
+    for _, text := range []string{"", "tomorrow 9am", "#errands !low on sat"} {
+        if _, err := Parse(text, now, time.UTC); err == nil {
+            t.Errorf("Parse(%q) succeeded, want an error", text)
+        }
+    }
+}
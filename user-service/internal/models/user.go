@@ -1,31 +1,31 @@
-package models
-
-import (
-    "time"
-)
-
-type User struct {
-    ID        string    `json:"id"`
-    Email     string    `json:"email"`
-    FirstName string    `json:"first_name"`
-    LastName  string    `json:"last_name"`
-    CreatedAt time.Time `json:"created_at"`
-    UpdatedAt time.Time `json:"updated_at"`
-}
-
-type CreateUserRequest struct {
-    Email     string `json:"email"`
-    FirstName string `json:"first_name"`
-    LastName  string `json:"last_name"`
-    Password  string `json:"password"`
-}
-
-type LoginRequest struct {
-    Email    string `json:"email"`
-    Password string `json:"password"`
-}
-
-type LoginResponse struct {
-    User  *User  `json:"user"`
-    Token string `json:"token"`
+package models
+
+import (
+    "time"
+)
+
+type User struct {
+    ID        string    `json:"id"`
+    Email     string    `json:"email"`
+    FirstName string    `json:"first_name"`
+    LastName  string    `json:"last_name"`
+    CreatedAt time.Time `json:"created_at"`
+    UpdatedAt time.Time `json:"updated_at"`
+}
+
+type CreateUserRequest struct {
+    Email     string `json:"email"`
+    FirstName string `json:"first_name"`
+    LastName  string `json:"last_name"`
+    Password  string `json:"password"`
+}
+
+type LoginRequest struct {
+    Email    string `json:"email"`
+    Password string `json:"password"`
+}
+
+type LoginResponse struct {
+    User  *User  `json:"user"`
+    Token string `json:"token"`
 }
\ No newline at end of file